@@ -0,0 +1,64 @@
+package ip2loc
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrStale is returned by lookups when the database is older than allowed by WithMaxAge with StaleFail.
+var ErrStale = errors.New("ip2loc: database is stale")
+
+// StalePolicy decides what happens when the database exceeds its maximum age.
+type StalePolicy int
+
+const (
+	// StaleWarn keeps answering lookups, reports Stale and emits an EventStale.
+	StaleWarn StalePolicy = iota
+	// StaleFail additionally makes every lookup return ErrStale.
+	StaleFail
+)
+
+// WithMaxAge sets the maximum age of the database, measured from its publish date in the header.
+func WithMaxAge(maxAge time.Duration, policy StalePolicy) Option {
+	return func(d *DB) {
+		d.maxAge = maxAge
+		d.stalePolicy = policy
+	}
+}
+
+// date returns the publish date stored in the database header.
+func (d *dbState) date() time.Time {
+	return time.Date(2000+int(d.meta.databaseYear), time.Month(d.meta.databaseMonth), int(d.meta.databaseDay), 0, 0, 0, 0, time.UTC)
+}
+
+// Date returns the publish date of the database.
+func (d *DB) Date() time.Time {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.dbState == nil {
+		return time.Time{}
+	}
+	return d.date()
+}
+
+// Age returns the time elapsed since the database was published.
+func (d *DB) Age() time.Duration {
+	return time.Since(d.Date())
+}
+
+// Stale reports whether the database is older than the maximum age set by WithMaxAge.
+func (d *DB) Stale() bool {
+	return d.maxAge > 0 && d.Age() > d.maxAge
+}
+
+// isStale must be called with d.mu held.
+func (d *DB) isStale() bool {
+	return d.maxAge > 0 && time.Since(d.date()) > d.maxAge
+}
+
+// notifyStale emits an EventStale the first time a lookup finds the database stale.
+func (d *DB) notifyStale() {
+	if d.maxAge > 0 && d.Stale() && d.health.staleNotified.CompareAndSwap(false, true) {
+		d.emit([]Event{{Type: EventStale, Time: time.Now(), Err: ErrStale}})
+	}
+}
@@ -0,0 +1,77 @@
+package ip2loc
+
+import (
+	"net/netip"
+	"slices"
+)
+
+// AggregatePrefixes returns the smallest list of CIDR prefixes covering the same addresses as
+// prefixes: overlapping and adjacent prefixes are merged and the ranges they make up split into the
+// largest prefixes possible, in address order with IPv4 first. Firewalls and route filters limit the
+// number of their entries, which a list of one prefix per database row easily exceeds. Invalid
+// prefixes are dropped.
+func AggregatePrefixes(prefixes []netip.Prefix) []netip.Prefix {
+	ranges := make([]RangeRecord, 0, len(prefixes))
+	for _, p := range prefixes {
+		if p.IsValid() {
+			p = p.Masked()
+			ranges = append(ranges, RangeRecord{First: p.Addr(), Last: lastAddr(p)})
+		}
+	}
+	slices.SortFunc(ranges, func(a, b RangeRecord) int { return a.First.Compare(b.First) })
+
+	var out []netip.Prefix
+	for _, r := range mergeRanges(ranges) {
+		out = append(out, r.Prefixes()...)
+	}
+	return out
+}
+
+// AggregatedPrefixes scans the database and returns the smallest list of CIDR prefixes covering the
+// rows whose record match accepts, in address order with IPv4 first, e.g. the networks of a country
+// for a firewall:
+//
+//	prefixes, err := db.AggregatedPrefixes(func(x ip2loc.IP2LocationRecord) bool {
+//		return x.CountryShort == "DE"
+//	})
+//
+// Adjacent rows are merged before they are split into prefixes, so the list is usually far shorter
+// than the prefixes of the rows.
+func (d *DB) AggregatedPrefixes(match func(IP2LocationRecord) bool) ([]netip.Prefix, error) {
+	var ranges []RangeRecord
+	err := d.walkAll(func(r RangeRecord) bool {
+		if match(r.Record) {
+			ranges = append(ranges, RangeRecord{First: r.First, Last: r.Last})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var out []netip.Prefix
+	for _, r := range mergeRanges(ranges) {
+		out = append(out, r.Prefixes()...)
+	}
+	return out, nil
+}
+
+// mergeRanges merges the overlapping and adjacent ranges of sorted, which must be ordered by their
+// first address, reusing its array.
+func mergeRanges(sorted []RangeRecord) []RangeRecord {
+	out := sorted[:0]
+	for _, r := range sorted {
+		if n := len(out); n > 0 && out[n-1].Last.Is4() == r.First.Is4() {
+			cur := &out[n-1]
+			// nothing follows the last address of a family
+			if next := cur.Last.Next(); !next.IsValid() || r.First.Compare(next) <= 0 {
+				if r.Last.Compare(cur.Last) > 0 {
+					cur.Last = r.Last
+				}
+				continue
+			}
+		}
+		out = append(out, r)
+	}
+	return out
+}
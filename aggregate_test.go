@@ -0,0 +1,33 @@
+package ip2loc
+
+import (
+	"net/netip"
+	"slices"
+	"testing"
+)
+
+func TestAggregatePrefixes(t *testing.T) {
+	for _, tt := range []struct {
+		in, want []string
+	}{
+		{[]string{"10.0.0.0/25", "10.0.0.128/25"}, []string{"10.0.0.0/24"}},
+		{[]string{"10.0.1.0/24", "10.0.0.0/24", "10.0.0.64/26"}, []string{"10.0.0.0/23"}},
+		{[]string{"10.0.1.0/24", "10.0.2.0/24"}, []string{"10.0.1.0/24", "10.0.2.0/24"}},
+		{[]string{"10.0.0.7/24", "10.0.0.0/24"}, []string{"10.0.0.0/24"}},
+		{[]string{"255.255.255.0/24", "255.255.255.255/32", "0.0.0.0/1", "128.0.0.0/1"}, []string{"0.0.0.0/0"}},
+		{[]string{"2001:db8::/33", "10.0.0.0/8", "2001:db8:8000::/33", "::/0"}, []string{"10.0.0.0/8", "::/0"}},
+		{[]string{"ffff:ffff:ffff:ffff:ffff:ffff:ffff:ff00/120", "255.255.255.255/32"}, []string{"255.255.255.255/32", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:ff00/120"}},
+		{nil, nil},
+	} {
+		var in, want []netip.Prefix
+		for _, s := range tt.in {
+			in = append(in, netip.MustParsePrefix(s))
+		}
+		for _, s := range tt.want {
+			want = append(want, netip.MustParsePrefix(s))
+		}
+		if got := AggregatePrefixes(in); !slices.Equal(got, want) {
+			t.Errorf("AggregatePrefixes(%v) = %v, want %v", tt.in, got, want)
+		}
+	}
+}
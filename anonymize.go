@@ -0,0 +1,56 @@
+package ip2loc
+
+import (
+	"fmt"
+	"net/netip"
+)
+
+// Default prefix lengths kept by anonymization, the truncation commonly accepted for GDPR compliant logs.
+const (
+	DefaultAnonymizeV4Bits = 24
+	DefaultAnonymizeV6Bits = 48
+)
+
+// Anonymize truncates ip to its first v4Bits bits for IPv4 or v6Bits bits for IPv6 addresses and
+// returns the truncated address, e.g. "203.0.113.7" becomes "203.0.113.0" with 24 bits. IPv4-mapped
+// IPv6 addresses are treated as IPv4 and zones are dropped.
+func Anonymize(ip string, v4Bits, v6Bits int) (string, error) {
+	a, err := netip.ParseAddr(ip)
+	if err != nil {
+		return "", fmt.Errorf("%w: %q", ErrInvalidIP, ip)
+	}
+	a = a.Unmap().WithZone("")
+	bits := min(max(v6Bits, 0), 128)
+	if a.Is4() {
+		bits = min(max(v4Bits, 0), 32)
+	}
+	p, err := a.Prefix(bits)
+	if err != nil {
+		return "", err
+	}
+	return p.Addr().String(), nil
+}
+
+// WithAnonymization makes AnonymizedIP truncate addresses to v4Bits bits for IPv4 and v6Bits bits
+// for IPv6. Lookups still use the full address; only the addresses the package logs, caches or
+// reports are truncated.
+func WithAnonymization(v4Bits, v6Bits int) Option {
+	return func(d *DB) {
+		d.anonymize = true
+		d.anonymizeV4Bits, d.anonymizeV6Bits = v4Bits, v6Bits
+	}
+}
+
+// AnonymizedIP returns the form of ip to write to audit logs: the address truncated as configured
+// by WithAnonymization, or ip unchanged if anonymization is off. An invalid address is returned as
+// an empty string when anonymization is on, since it may hold anything.
+func (d *DB) AnonymizedIP(ip string) string {
+	if !d.anonymize {
+		return ip
+	}
+	a, err := Anonymize(ip, d.anonymizeV4Bits, d.anonymizeV6Bits)
+	if err != nil {
+		return ""
+	}
+	return a
+}
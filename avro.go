@@ -0,0 +1,123 @@
+package ip2loc
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"math"
+	"strings"
+)
+
+// AvroSchema is the Avro schema of IP2LocationRecord. Every field is an optional union with a null
+// default, so readers using an older or newer revision of the schema can resolve records written
+// with this one. String fields which are empty or hold a placeholder message (unsupported field,
+// invalid address) are encoded as null.
+var AvroSchema = buildAvroSchema()
+
+func buildAvroSchema() string {
+	var b strings.Builder
+	b.WriteString(`{"type":"record","name":"IP2LocationRecord","namespace":"com.ip2location","fields":[`)
+	for i, f := range recordFields {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		typ := "string"
+		if f.isFloat {
+			typ = "float"
+		}
+		b.WriteString(`{"name":"` + f.name + `","type":["null","` + typ + `"],"default":null}`)
+	}
+	b.WriteString(`]}`)
+	return b.String()
+}
+
+func appendAvroLong(buf []byte, v int64) []byte {
+	return binary.AppendUvarint(buf, uint64((v<<1)^(v>>63)))
+}
+
+func appendAvroString(buf []byte, s string) []byte {
+	buf = appendAvroLong(buf, int64(len(s)))
+	return append(buf, s...)
+}
+
+// AppendAvro appends the Avro binary encoding of x to buf.
+func AppendAvro(buf []byte, x IP2LocationRecord) []byte {
+	for _, f := range recordFields {
+		if f.isFloat {
+			buf = appendAvroLong(buf, 1)
+			buf = binary.LittleEndian.AppendUint32(buf, math.Float32bits(f.float(&x)))
+			continue
+		}
+		s := f.str(&x)
+		if s == "" || isPlaceholder(s) {
+			buf = appendAvroLong(buf, 0)
+			continue
+		}
+		buf = appendAvroLong(buf, 1)
+		buf = appendAvroString(buf, s)
+	}
+	return buf
+}
+
+// AvroEncoder writes records as single Avro messages. If SchemaID is non-zero, every message is
+// prefixed with the Confluent Schema Registry wire header (magic byte 0 and the big-endian schema ID).
+type AvroEncoder struct {
+	w        io.Writer
+	buf      []byte
+	SchemaID uint32
+}
+
+// NewAvroEncoder returns an encoder writing to w.
+func NewAvroEncoder(w io.Writer) *AvroEncoder {
+	return &AvroEncoder{w: w}
+}
+
+// Encode writes a single record.
+func (e *AvroEncoder) Encode(x IP2LocationRecord) error {
+	e.buf = e.buf[:0]
+	if e.SchemaID != 0 {
+		e.buf = append(e.buf, 0)
+		e.buf = binary.BigEndian.AppendUint32(e.buf, e.SchemaID)
+	}
+	e.buf = AppendAvro(e.buf, x)
+	_, err := e.w.Write(e.buf)
+	return err
+}
+
+// WriteAvroContainer writes records as an Avro object container file with AvroSchema embedded,
+// suitable for batch results and files loaded by Avro-aware tooling.
+func WriteAvroContainer(w io.Writer, records []IP2LocationRecord) error {
+	var sync [16]byte
+	if _, err := rand.Read(sync[:]); err != nil {
+		return err
+	}
+
+	var header []byte
+	header = append(header, 'O', 'b', 'j', 1)
+	header = appendAvroLong(header, 2) // metadata map with two entries
+	header = appendAvroString(header, "avro.schema")
+	header = appendAvroString(header, AvroSchema)
+	header = appendAvroString(header, "avro.codec")
+	header = appendAvroString(header, "null")
+	header = appendAvroLong(header, 0) // end of map
+	header = append(header, sync[:]...)
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	if len(records) == 0 {
+		return nil
+	}
+
+	var data []byte
+	for _, x := range records {
+		data = AppendAvro(data, x)
+	}
+	var block []byte
+	block = appendAvroLong(block, int64(len(records)))
+	block = appendAvroLong(block, int64(len(data)))
+	if _, err := w.Write(append(block, data...)); err != nil {
+		return err
+	}
+	_, err := w.Write(sync[:])
+	return err
+}
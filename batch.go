@@ -0,0 +1,189 @@
+package ip2loc
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+// batchRow identifies a matched row of a loaded database within a batch.
+type batchRow struct {
+	state     *dbState
+	iptype    uint32
+	rowoffset int64
+}
+
+// Result is the outcome of looking up one address of a batch: the record and error GetAll would
+// have returned for it.
+type Result struct {
+	IP     string // the address looked up, truncated as configured by WithAnonymization
+	Record IP2LocationRecord
+	Err    error
+}
+
+// batchChunk is the number of distinct addresses a goroutine of GetAllBatchContext looks up at a time.
+const batchChunk = 1024
+
+// BatchOptions configures GetAllBatchContext.
+type BatchOptions struct {
+	// Concurrency is the number of goroutines looking up the batch, each taking chunks of distinct
+	// addresses; 1 if 0. The caller is one of them, the others run in the WorkerGroup of the DB.
+	// More goroutines than the database can serve in parallel, e.g. more than the CPUs for a
+	// database in memory, gain nothing.
+	Concurrency int
+}
+
+// GetAllBatch looks up all geolocation fields of every address in ips and returns the results in
+// the order of ips. A failed lookup, e.g. of an invalid address or one which is not found, only
+// fails its own Result, so enrichment jobs continue past bad rows.
+//
+// Each distinct address is searched only once and each matched row read only once, so batches from
+// log files, which are mostly repeated addresses or neighbours in the same range, cost a fraction
+// of a GetAll per address.
+func (d *DB) GetAllBatch(ips []string) []Result {
+	out, _ := d.GetAllBatchContext(context.Background(), ips, BatchOptions{})
+	return out
+}
+
+// GetAllBatchContext is GetAllBatch looking up the batch with the concurrency of opts. It stops
+// when ctx is done and returns its error and no results, or ErrStopped if the WorkerGroup of the DB
+// stops its goroutines first, e.g. because the DB is closed.
+func (d *DB) GetAllBatchContext(ctx context.Context, ips []string, opts BatchOptions) ([]Result, error) {
+	// the first index of every distinct address, in order
+	first := make(map[string]int, len(ips))
+	var distinct []int
+	for i, ip := range ips {
+		if _, ok := first[ip]; !ok {
+			first[ip] = i
+			distinct = append(distinct, i)
+		}
+	}
+
+	out := make([]Result, len(ips))
+	var healthy atomic.Bool
+	var next atomic.Int64 // the start of the next chunk
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	lookup := func(ctx context.Context) error {
+		// the rows read by this goroutine, reused by all the chunks it takes
+		rows := make(map[batchRow]IP2LocationRecord)
+		for {
+			start := int(next.Add(batchChunk)) - batchChunk
+			if start >= len(distinct) {
+				return nil
+			}
+			for _, i := range distinct[start:min(start+batchChunk, len(distinct))] {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+				x, err := d.batchLookup(ctx, ips[i], rows)
+				if err == nil || errors.Is(err, ErrNotFound) {
+					healthy.Store(true)
+				}
+				out[i] = Result{IP: d.AnonymizedIP(ips[i]), Record: x, Err: wrapError(err)}
+			}
+		}
+	}
+
+	// the caller takes chunks too; the other goroutines run in the group of the DB, so that Close
+	// waits for them, and if it is not running the caller looks up the batch alone
+	g := d.workerGroup()
+	var waits []func() error
+	workers := min(max(opts.Concurrency, 1), (len(distinct)+batchChunk-1)/batchChunk)
+	for range workers - 1 {
+		wait, err := g.task(ctx, "batch lookup", func(ctx context.Context) error {
+			err := lookup(ctx)
+			if err != nil {
+				cancel()
+			}
+			return err
+		})
+		if err != nil {
+			break
+		}
+		waits = append(waits, wait)
+	}
+	errs := []error{lookup(ctx)}
+	if errs[0] != nil {
+		cancel()
+	}
+	for _, wait := range waits {
+		errs = append(errs, wait())
+	}
+	if err := batchError(parent, errs); err != nil {
+		return nil, err
+	}
+
+	for i, ip := range ips {
+		if j := first[ip]; j != i {
+			out[i] = out[j]
+		}
+	}
+	if healthy.Load() {
+		d.markHealthy()
+	}
+	d.notifyStale()
+	return out, nil
+}
+
+// batchError returns the error of a batch from the errors of its goroutines: the error of ctx if it
+// is done, the first one not caused by cancelling the others, or ErrStopped if the group of the DB
+// stopped them.
+func batchError(ctx context.Context, errs []error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	stopped := false
+	for _, err := range errs {
+		if errors.Is(err, context.Canceled) {
+			stopped = true
+		} else if err != nil {
+			return err
+		}
+	}
+	if stopped {
+		return ErrStopped
+	}
+	return nil
+}
+
+// batchLookup looks up ip like queryOnce, reading the record of a row found before from rows.
+func (d *DB) batchLookup(ctx context.Context, ip string, rows map[batchRow]IP2LocationRecord) (IP2LocationRecord, error) {
+	lookup := func() (*dbState, IP2LocationRecord, error) {
+		d.mu.RLock()
+		defer d.mu.RUnlock()
+		state := d.dbState
+		defer startLookup(state)()
+		iptype, m, x, err := d.find(ctx, ip, all)
+		if err != nil {
+			return state, x, err
+		}
+		key := batchRow{state, iptype, m.rowoffset}
+		if x, ok := rows[key]; ok {
+			return state, x, nil
+		}
+		x, err = d.readRecord(ctx, iptype, m.rowoffset, all)
+		if err == nil {
+			rows[key] = x
+		}
+		return state, x, err
+	}
+
+	var (
+		state *dbState
+		x     IP2LocationRecord
+	)
+	retried := func() error {
+		var err error
+		state, x, err = lookup()
+		return err
+	}
+	err := d.retrying(ctx, retried)
+	if errors.Is(err, ErrTruncated) && d.recover(state, err) {
+		if err = d.retrying(ctx, retried); errors.Is(err, ErrTruncated) {
+			d.recover(state, err)
+		}
+	}
+	return x, err
+}
@@ -0,0 +1,48 @@
+package ip2loc
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+func TestGetAllBatchContext(t *testing.T) {
+	db, err := OpenBytes(buildDB(benchRows(1<<12), testRows.v6), WithWorkerGroup(&WorkerGroup{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	ips := benchIPs(3 * batchChunk)
+	ips = slices.Concat(ips, ips[:100], []string{"bogus", "::1"})
+
+	check := func(out []Result) {
+		t.Helper()
+		for i, r := range out {
+			x, err := db.GetAll(ips[i])
+			if r.Record != x || CodeOf(r.Err) != CodeOf(err) {
+				t.Errorf("result %d for %s = %+v, %v, GetAll returns %+v, %v", i, ips[i], r.Record, r.Err, x, err)
+			}
+		}
+	}
+
+	// the group of the DB is not running, so the caller looks up the batch alone
+	out, err := db.GetAllBatchContext(context.Background(), ips, BatchOptions{Concurrency: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(out)
+
+	db.workers.Start(context.Background())
+	defer db.workers.Stop()
+	out, err = db.GetAllBatchContext(context.Background(), ips, BatchOptions{Concurrency: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(out)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := db.GetAllBatchContext(ctx, ips, BatchOptions{Concurrency: 4}); err != context.Canceled {
+		t.Errorf("cancelled batch: %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,140 @@
+//go:build !ip2loc_nofs
+
+package ip2loc
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"net/netip"
+	"testing"
+)
+
+// benchModes opens the database at path in every mode of OpenDB and its siblings.
+var benchModes = []struct {
+	name string
+	open func(path string, opts ...Option) (*DB, error)
+}{
+	{"disk", OpenDB},
+	{"memory", OpenInMemoryDB},
+	{"mmap", OpenMmapDB},
+	{"cached", func(path string, opts ...Option) (*DB, error) {
+		return OpenCachedDB(path, DefaultBlockSize, 1<<20, opts...)
+	}},
+}
+
+func benchDB(b *testing.B, open func(string, ...Option) (*DB, error)) *DB {
+	b.Helper()
+	db, err := open(writeDB(b, buildDB(benchRows(1<<16), testRows.v6)))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { db.Close() })
+	return db
+}
+
+func BenchmarkGetAll(b *testing.B) {
+	ips := benchIPs(1024)
+	for _, m := range benchModes {
+		b.Run(m.name, func(b *testing.B) {
+			db := benchDB(b, m.open)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := db.GetAll(ips[i%len(ips)]); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkGetCountryShort(b *testing.B) {
+	ips := benchIPs(1024)
+	for _, m := range benchModes {
+		b.Run(m.name, func(b *testing.B) {
+			db := benchDB(b, m.open)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := db.GetCountryShort(ips[i%len(ips)]); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkGetAllParallel(b *testing.B) {
+	ips := benchIPs(1024)
+	for _, m := range benchModes {
+		b.Run(m.name, func(b *testing.B) {
+			db := benchDB(b, m.open)
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				i := rand.IntN(len(ips))
+				for pb.Next() {
+					if _, err := db.GetAll(ips[i%len(ips)]); err != nil {
+						b.Error(err)
+						return
+					}
+					i++
+				}
+			})
+		})
+	}
+}
+
+func BenchmarkGetAllBatch(b *testing.B) {
+	db := benchDB(b, benchModes[1].open)
+	for _, n := range []int{16, 1024} {
+		ips := benchIPs(n)
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				db.GetAllBatch(ips)
+			}
+		})
+	}
+}
+
+func BenchmarkSearchStrategy(b *testing.B) {
+	ips := benchIPs(1024)
+	path := writeDB(b, buildDB(benchRows(1<<16), testRows.v6))
+	for _, s := range []struct {
+		name     string
+		strategy SearchStrategy
+	}{{"binary", BinarySearch}, {"interpolation", InterpolationSearch}} {
+		b.Run(s.name, func(b *testing.B) {
+			db, err := OpenInMemoryDB(path, WithSearchStrategy(s.strategy))
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer db.Close()
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := db.GetCountryShort(ips[i%len(ips)]); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkCountryMatcher(b *testing.B) {
+	db := benchDB(b, benchModes[1].open)
+	m, err := CompileCountryMatcher(db, "US", "NL")
+	if err != nil {
+		b.Fatal(err)
+	}
+	addrs := make([]netip.Addr, 1024)
+	for i, ip := range benchIPs(len(addrs)) {
+		addrs[i] = netip.MustParseAddr(ip)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		m.Contains(addrs[i%len(addrs)])
+	}
+}
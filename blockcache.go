@@ -0,0 +1,146 @@
+package ip2loc
+
+import (
+	"container/list"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultBlockSize is the block size used by NewBlockCacheReader when none is given.
+const DefaultBlockSize = 16 << 10
+
+// BlockCacheReader is a DBReader which reads the underlying reader in fixed-size blocks and keeps the
+// most recently used blocks in memory, like the page cache of the operating system but with a memory
+// budget of its own. The index and the upper levels of the binary search are hit by every lookup,
+// so a cache of a small fraction of the database answers most reads without touching the disk.
+type BlockCacheReader struct {
+	r         DBReader
+	blockSize int64
+	maxBlocks int
+	pos       int64
+
+	mu     sync.Mutex
+	blocks map[int64]*list.Element // of *cachedBlock, keyed by block number
+	lru    list.List               // most recently used first
+
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+type cachedBlock struct {
+	n    int64
+	data []byte // shorter than the block size at the end of the file; never modified
+}
+
+// NewBlockCacheReader returns a BlockCacheReader reading r in blocks of blockSize bytes and caching up
+// to budget bytes of them. A blockSize <= 0 selects DefaultBlockSize; at least one block is cached.
+// Closing the BlockCacheReader closes r.
+func NewBlockCacheReader(r DBReader, blockSize int, budget int64) *BlockCacheReader {
+	if blockSize <= 0 {
+		blockSize = DefaultBlockSize
+	}
+	return &BlockCacheReader{
+		r:         r,
+		blockSize: int64(blockSize),
+		maxBlocks: int(max(budget/int64(blockSize), 1)),
+		blocks:    map[int64]*list.Element{},
+	}
+}
+
+func (r *BlockCacheReader) ReadAt(p []byte, off int64) (int, error) {
+	var n int
+	for n < len(p) {
+		pos := off + int64(n)
+		b, err := r.block(pos / r.blockSize)
+		if err != nil {
+			return n, err
+		}
+		start := pos - b.n*r.blockSize
+		if start >= int64(len(b.data)) {
+			return n, io.EOF
+		}
+		n += copy(p[n:], b.data[start:])
+	}
+	return n, nil
+}
+
+// block returns block number n from the cache, reading it on a miss.
+func (r *BlockCacheReader) block(n int64) (*cachedBlock, error) {
+	r.mu.Lock()
+	if e, ok := r.blocks[n]; ok {
+		r.lru.MoveToFront(e)
+		r.mu.Unlock()
+		r.hits.Add(1)
+		return e.Value.(*cachedBlock), nil
+	}
+	r.mu.Unlock()
+	r.misses.Add(1)
+
+	// concurrent misses of the same block read it twice rather than holding the lock during the read
+	data := make([]byte, r.blockSize)
+	got, err := r.r.ReadAt(data, n*r.blockSize)
+	if got < len(data) && err != nil && err != io.EOF {
+		return nil, err
+	}
+	b := &cachedBlock{n: n, data: data[:got]}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if e, ok := r.blocks[n]; ok {
+		r.lru.MoveToFront(e)
+		return e.Value.(*cachedBlock), nil
+	}
+	r.blocks[n] = r.lru.PushFront(b)
+	for len(r.blocks) > r.maxBlocks {
+		e := r.lru.Back()
+		r.lru.Remove(e)
+		delete(r.blocks, e.Value.(*cachedBlock).n)
+	}
+	return b, nil
+}
+
+func (r *BlockCacheReader) Read(p []byte) (int, error) {
+	n, err := r.ReadAt(p, r.pos)
+	r.pos += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+// Close drops the cached blocks and closes the underlying reader.
+func (r *BlockCacheReader) Close() error {
+	r.mu.Lock()
+	r.blocks = map[int64]*list.Element{}
+	r.lru.Init()
+	r.mu.Unlock()
+	return r.r.Close()
+}
+
+// countLookup counts a lookup on the underlying reader if it is an InstrumentedReader.
+func (r *BlockCacheReader) countLookup(took time.Duration) {
+	if c, ok := r.r.(lookupCounter); ok {
+		c.countLookup(took)
+	}
+}
+
+// BlockCacheStats describes the use of a BlockCacheReader.
+type BlockCacheStats struct {
+	Hits   uint64 // block reads answered from the cache
+	Misses uint64 // block reads which went to the underlying reader
+	Blocks int    // blocks cached right now
+	Bytes  int64  // bytes cached right now
+}
+
+// Stats returns the counters of the cache.
+func (r *BlockCacheReader) Stats() BlockCacheStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := BlockCacheStats{Hits: r.hits.Load(), Misses: r.misses.Load(), Blocks: len(r.blocks)}
+	for e := r.lru.Front(); e != nil; e = e.Next() {
+		s.Bytes += int64(len(e.Value.(*cachedBlock).data))
+	}
+	return s
+}
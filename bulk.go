@@ -0,0 +1,335 @@
+package ip2loc
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// BulkFormat is the output format of a BulkEnricher.
+type BulkFormat int
+
+const (
+	// BulkCSV writes a header row followed by one row per address: the address, every record field
+	// and an error code. Fields holding a placeholder message are left empty.
+	BulkCSV BulkFormat = iota
+	// BulkJSON writes one JSON object per line with the address, the populated record fields and an error code if any.
+	BulkJSON
+)
+
+// BulkEnricher looks up IP addresses read one per line and writes the enriched results in input order.
+type BulkEnricher struct {
+	db      *DB
+	workers int
+	format  BulkFormat
+
+	progressInterval time.Duration
+	onProgress       func(BulkProgress)
+}
+
+// NewBulkEnricher returns a BulkEnricher using the given number of lookup workers;
+// zero or less uses one worker per CPU.
+func NewBulkEnricher(db *DB, workers int, format BulkFormat) *BulkEnricher {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	return &BulkEnricher{db: db, workers: workers, format: format}
+}
+
+// BulkProgress reports how far a BulkEnricher got.
+type BulkProgress struct {
+	Lines   int64         // results written
+	Errors  int64         // results with a lookup error
+	Elapsed time.Duration // since the start of the run
+	Rate    float64       // results written per second
+	// ETA estimates the time left from the share of the input read so far; 0 if the size of the
+	// input is unknown, i.e. it is not a file.
+	ETA  time.Duration
+	Done bool // set on the last report of a run
+}
+
+// OnProgress makes Run report its progress to fn every interval, 10s if 0, and once more at the end.
+// fn is called from the goroutine writing the results, so it should return quickly.
+func (b *BulkEnricher) OnProgress(interval time.Duration, fn func(BulkProgress)) {
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	b.progressInterval, b.onProgress = interval, fn
+}
+
+type bulkJob struct {
+	seq int
+	ip  string
+}
+
+type bulkResult struct {
+	seq int
+	ip  string
+	x   IP2LocationRecord
+	err error
+}
+
+// Run reads addresses from r until EOF and writes one result per non-empty line to w.
+// Lookup errors are reported per line; Run only fails on read or write errors.
+func (b *BulkEnricher) Run(r io.Reader, w io.Writer) error {
+	return b.RunContext(context.Background(), r, w)
+}
+
+// RunContext is Run stopping cleanly when ctx is done: no more lines are read, the results of the
+// lines read before are written and w is flushed, and ctx.Err() is returned. A read of r which
+// blocks, e.g. on a pipe, is waited for.
+//
+// The reader and lookup workers run in the WorkerGroup of the DB; once it is stopped, e.g. by
+// closing the DB, Run returns ErrStopped.
+func (b *BulkEnricher) RunContext(ctx context.Context, r io.Reader, w io.Writer) error {
+	out, err := newBulkWriter(w, b.format)
+	if err != nil {
+		return err
+	}
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var total int64
+	if f, ok := r.(interface{ Stat() (fs.FileInfo, error) }); ok {
+		if fi, err := f.Stat(); err == nil && fi.Mode().IsRegular() {
+			total = fi.Size()
+		}
+	}
+	in := &countingReader{r: r}
+
+	jobs := make(chan bulkJob, b.workers*2)
+	results := make(chan bulkResult, b.workers*2)
+
+	// the workers run in the group of the DB, which stops them when the DB is closed; a panic of one
+	// fails Run instead of the program
+	g := b.db.workerGroup()
+	readErr := make(chan error, 1)
+	waitReader, err := g.task(ctx, "bulk reader", func(ctx context.Context) error {
+		defer close(jobs)
+		sc := bufio.NewScanner(in)
+		seq := 0
+		for sc.Scan() {
+			ip := strings.TrimSpace(sc.Text())
+			if ip == "" {
+				continue
+			}
+			select {
+			case jobs <- bulkJob{seq: seq, ip: ip}:
+			case <-ctx.Done():
+				readErr <- nil
+				return nil
+			}
+			seq++
+		}
+		readErr <- sc.Err()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	waits := []func() error{waitReader}
+
+	// the lines read before ctx is done are still looked up; only stopping the group ends the lookups
+	lookupCtx := context.WithoutCancel(ctx)
+	var lookups sync.WaitGroup
+	for i := 0; i < b.workers && err == nil; i++ {
+		lookups.Add(1)
+		var wait func() error
+		wait, err = g.task(lookupCtx, "bulk lookup", func(ctx context.Context) error {
+			defer lookups.Done()
+			for j := range jobs {
+				x, err := b.db.GetAll(j.ip)
+				select {
+				case results <- bulkResult{seq: j.seq, ip: j.ip, x: x, err: err}:
+				case <-ctx.Done():
+					return ErrStopped
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			lookups.Done()
+			break
+		}
+		waits = append(waits, wait)
+	}
+	if err == nil {
+		var wait func() error
+		wait, err = g.task(lookupCtx, "bulk results", func(context.Context) error {
+			lookups.Wait()
+			close(results)
+			return nil
+		})
+		if err == nil {
+			waits = append(waits, wait)
+		}
+	}
+	if err != nil {
+		// the group stopped, which cancels the workers started so far
+		cancel()
+		for _, wait := range waits {
+			_ = wait()
+		}
+		return err
+	}
+
+	start := time.Now()
+	var progress BulkProgress
+	report := func(done bool) {
+		if b.onProgress == nil {
+			return
+		}
+		progress.Elapsed = time.Since(start)
+		progress.Rate = float64(progress.Lines) / progress.Elapsed.Seconds()
+		progress.ETA = 0
+		if read := in.n.Load(); total > 0 && read > 0 && !done {
+			progress.ETA = time.Duration(float64(progress.Elapsed) * float64(max(total-read, 0)) / float64(read))
+		}
+		progress.Done = done
+		b.onProgress(progress)
+	}
+	var tick <-chan time.Time
+	if b.onProgress != nil {
+		ticker := time.NewTicker(b.progressInterval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	// results arrive out of order; hold them back until their predecessors are written
+	var writeErr error
+	pending := map[int]bulkResult{}
+	next := 0
+results:
+	for {
+		var res bulkResult
+		select {
+		case <-tick:
+			report(false)
+			continue
+		case r, ok := <-results:
+			if !ok {
+				break results
+			}
+			res = r
+		}
+		if writeErr != nil {
+			continue // drain so the workers can finish
+		}
+		pending[res.seq] = res
+		for {
+			res, ok := pending[next]
+			if !ok {
+				break
+			}
+			delete(pending, next)
+			next++
+			if writeErr = out.write(res); writeErr != nil {
+				cancel()
+				break
+			}
+			progress.Lines++
+			if res.err != nil {
+				progress.Errors++
+			}
+		}
+	}
+	if writeErr == nil {
+		writeErr = out.flush()
+	}
+	report(true)
+	cancel() // the reader waits for a free worker if one panicked
+	var workerErrs []error
+	for _, wait := range waits {
+		workerErrs = append(workerErrs, wait())
+	}
+	select {
+	case err := <-readErr:
+		if err != nil {
+			return err
+		}
+	default:
+		// the reader panicked
+	}
+	if err := errors.Join(workerErrs...); err != nil {
+		return err
+	}
+	if writeErr != nil {
+		return writeErr
+	}
+	return parent.Err()
+}
+
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r io.Reader
+	n atomic.Int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+type bulkWriter struct {
+	format BulkFormat
+	csv    *csv.Writer
+	json   *json.Encoder
+	bw     *bufio.Writer
+}
+
+func newBulkWriter(w io.Writer, format BulkFormat) (*bulkWriter, error) {
+	bw := bufio.NewWriter(w)
+	out := &bulkWriter{format: format, bw: bw}
+	switch format {
+	case BulkCSV:
+		out.csv = csv.NewWriter(bw)
+		header := []string{"ip"}
+		for _, f := range recordFields {
+			header = append(header, f.name)
+		}
+		return out, out.csv.Write(append(header, "error"))
+	case BulkJSON:
+		out.json = json.NewEncoder(bw)
+		return out, nil
+	}
+	return nil, fmt.Errorf("ip2loc: unknown bulk format %d", format)
+}
+
+func (o *bulkWriter) write(res bulkResult) error {
+	if o.format == BulkCSV {
+		row := []string{res.ip}
+		for _, f := range recordFields {
+			row = append(row, f.value(&res.x))
+		}
+		return o.csv.Write(append(row, string(CodeOf(res.err))))
+	}
+
+	props := recordProperties(&res.x)
+	props["ip"] = res.ip
+	if res.err != nil {
+		props["error"] = CodeOf(res.err)
+	}
+	return o.json.Encode(props)
+}
+
+func (o *bulkWriter) flush() error {
+	if o.csv != nil {
+		o.csv.Flush()
+		if err := o.csv.Error(); err != nil {
+			return err
+		}
+	}
+	return o.bw.Flush()
+}
@@ -0,0 +1,152 @@
+package ip2loc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// License is the license class of a database product.
+type License int
+
+const (
+	// LicenseUnknown is reported for products whose LITE and commercial editions share the layout
+	// when neither the header nor the file name tells them apart.
+	LicenseUnknown License = iota
+	// LicenseLite is the free LITE edition, distributed in files named like IP2LOCATION-LITE-DB11.BIN.
+	LicenseLite
+	// LicenseCommercial is a product without a LITE edition.
+	LicenseCommercial
+)
+
+func (l License) String() string {
+	switch l {
+	case LicenseLite:
+		return "LITE"
+	case LicenseCommercial:
+		return "commercial"
+	}
+	return "unknown"
+}
+
+// Header license codes, written since 2021 after the product code.
+const (
+	licenseCodeCommercial = 1
+	licenseCodeLite       = 2
+)
+
+// liteTypes are the database types with a LITE edition.
+var liteTypes = map[uint8]bool{1: true, 3: true, 5: true, 9: true, 11: true}
+
+// capabilitySample is the number of rows Capabilities reads to tell populated fields from empty ones.
+const capabilitySample = 256
+
+// FieldCapability tells whether lookups return a field and, if not, why.
+type FieldCapability struct {
+	Name      string // as returned by FieldNames
+	Populated bool   // the field holds values in the sampled rows
+	Reason    string // why the field comes back empty, if it is not populated
+}
+
+// Capabilities describes the product of a database and the fields lookups return.
+type Capabilities struct {
+	Product      string // e.g. "DB11 LITE", "DB24" or "PX11"
+	DatabaseType uint8
+	ProductCode  uint8 // from the header: 1 for IP2Location and 2 for IP2Proxy; 0 in files before 2021
+	LicenseCode  uint8 // from the header: 1 for commercial and 2 for LITE editions; 0 in files before 2021
+	License      License
+	Fields       []FieldCapability // in the order of FieldNames, without region_code
+}
+
+// Capabilities reports the product of the database, its license class and, for every record field,
+// whether lookups return it, so that support tooling can explain why a field comes back empty: the
+// product does not have it, WithFields left it out, or its column holds no values, as in a database
+// written by StripDB. Columns are checked by reading a few hundred random rows.
+//
+// The license class is taken from the license code of the header. Files before 2021 have none; for
+// them it is inferred: products without a LITE edition are commercial, and the others are LITE if
+// the file name says so, as it does in the files distributed by IP2Location.
+func (d *DB) Capabilities() (Capabilities, error) {
+	d.mu.RLock()
+	if d.closed {
+		d.mu.RUnlock()
+		return Capabilities{}, wrapError(ErrClosed)
+	}
+	if d.dbState == nil || !d.metaOk {
+		d.mu.RUnlock()
+		return Capabilities{}, wrapError(ErrNotLoaded)
+	}
+	dbt, supported, unknownType := d.meta.databaseType, d.supported, d.unknownType
+	code, err := d.readUint8(context.Background(), 30)
+	var license uint8
+	if err == nil {
+		license, err = d.readUint8(context.Background(), 31)
+	}
+	d.mu.RUnlock()
+	if err != nil {
+		return Capabilities{}, wrapError(err)
+	}
+
+	c := Capabilities{DatabaseType: dbt, ProductCode: code, LicenseCode: license, License: LicenseUnknown}
+	prefix := "DB"
+	if code == 2 {
+		prefix = "PX"
+	}
+	switch {
+	case license == licenseCodeLite:
+		c.License = LicenseLite
+	case license == licenseCodeCommercial:
+		c.License = LicenseCommercial
+	case license != 0:
+		// a code of a later format; the file name is no better guess
+	case strings.Contains(strings.ToUpper(baseName(d.path)), "LITE"):
+		c.License = LicenseLite
+	case code != 2 && !liteTypes[dbt]:
+		c.License = LicenseCommercial
+	}
+	c.Product = fmt.Sprintf("%s%d", prefix, dbt)
+	if c.License == LicenseLite {
+		c.Product += " LITE"
+	}
+
+	sample, err := d.Sample(capabilitySample, 0)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return Capabilities{}, err
+	}
+	for _, f := range recordFields {
+		if f.name == "region_code" {
+			continue
+		}
+		fc := FieldCapability{Name: f.name}
+		switch {
+		case f.mode&supported != 0:
+			for _, r := range sample {
+				if v := f.value(&r.Record); v != "" && v != "-" && v != "0" {
+					fc.Populated = true
+					break
+				}
+			}
+			if !fc.Populated {
+				fc.Reason = fmt.Sprintf("the column is empty in all %d sampled rows", len(sample))
+			}
+		case d.fields != 0 && f.mode&d.fields == 0:
+			fc.Reason = "left out by WithFields"
+		case unknownType:
+			fc.Reason = fmt.Sprintf("the columns of %s are unknown to this version of the package", c.Product)
+		default:
+			fc.Reason = fmt.Sprintf("%s does not have it", c.Product)
+			if t := projectedType(f.mode); t != 0 {
+				fc.Reason += fmt.Sprintf("; DB%d is the smallest product with it", t)
+			}
+		}
+		c.Fields = append(c.Fields, fc)
+	}
+	return c, nil
+}
+
+// baseName returns the last element of path, like filepath.Base for the slashes of any platform,
+// without depending on a file system.
+func baseName(path string) string {
+	return path[strings.LastIndexAny(path, `/\`)+1:]
+}
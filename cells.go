@@ -0,0 +1,153 @@
+package ip2loc
+
+import "math"
+
+// geohashAlphabet is the base32 alphabet of geohashes.
+const geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// MaxGeoHashPrecision is the longest geohash GeoHash returns, about 3.7cm by 1.9cm.
+const MaxGeoHashPrecision = 12
+
+// GeoHash returns the geohash of the record location with the given number of characters, clamped
+// to 1 through MaxGeoHashPrecision. Five characters cover about 4.9km by 4.9km, a city-sized bucket;
+// beyond eight characters the cells are finer than the published precision of the coordinates.
+func (x IP2LocationRecord) GeoHash(precision int) string {
+	precision = min(max(precision, 1), MaxGeoHashPrecision)
+	lat := [2]float64{-90, 90}
+	lon := [2]float64{-180, 180}
+
+	latv, lonv := x.Latitude64(), x.Longitude64()
+
+	hash := make([]byte, precision)
+	even := true // bits alternate between longitude and latitude, starting with longitude
+	for i := range hash {
+		var c byte
+		for bit := 4; bit >= 0; bit-- {
+			r, v := &lat, latv
+			if even {
+				r, v = &lon, lonv
+			}
+			mid := (r[0] + r[1]) / 2
+			if v >= mid {
+				c |= 1 << bit
+				r[0] = mid
+			} else {
+				r[1] = mid
+			}
+			even = !even
+		}
+		hash[i] = geohashAlphabet[c]
+	}
+	return string(hash)
+}
+
+// MaxS2Level is the finest S2 cell level, about 1cm across.
+const MaxS2Level = 30
+
+// S2 cell ids are built from the face and a position along the Hilbert curve of that face, see
+// https://s2geometry.io. The curve is walked four bits of i and j at a time with a lookup table.
+const (
+	s2LookupBits = 4
+	s2SwapMask   = 1
+	s2InvertMask = 2
+	s2PosBits    = 2*MaxS2Level + 1
+	s2MaxSize    = 1 << MaxS2Level
+)
+
+// s2PosToIJ maps an orientation and a position along the curve to the (i, j) quadrant, i in the high bit.
+var s2PosToIJ = [4][4]int{
+	{0, 1, 3, 2}, // canonical order
+	{0, 2, 3, 1}, // axes swapped
+	{3, 2, 0, 1}, // bits inverted
+	{3, 1, 0, 2}, // swapped and inverted
+}
+
+// s2PosToOrientation is the orientation change of the sub-curve at each position.
+var s2PosToOrientation = [4]int{s2SwapMask, 0, 0, s2InvertMask | s2SwapMask}
+
+var s2LookupPos [1 << (2*s2LookupBits + 2)]int
+
+func init() {
+	for o := 0; o < 4; o++ {
+		initS2Lookup(0, 0, 0, o, 0, o)
+	}
+}
+
+func initS2Lookup(level, i, j, origOrientation, pos, orientation int) {
+	if level == s2LookupBits {
+		ij := i<<s2LookupBits + j
+		s2LookupPos[ij<<2+origOrientation] = pos<<2 + orientation
+		return
+	}
+	level++
+	i <<= 1
+	j <<= 1
+	pos <<= 2
+	for k, r := range s2PosToIJ[orientation] {
+		initS2Lookup(level, i+r>>1, j+r&1, origOrientation, pos+k, orientation^s2PosToOrientation[k])
+	}
+}
+
+// S2CellID returns the id of the S2 cell at the given level, clamped to 0 through MaxS2Level,
+// that contains the record location. Level 10 cells are about 10km across, level 13 about 1km.
+func (x IP2LocationRecord) S2CellID(level int) uint64 {
+	level = min(max(level, 0), MaxS2Level)
+	v := unitVector(x.Latitude64(), x.Longitude64())
+
+	// the face is the cube side the point projects onto
+	face := 0
+	if math.Abs(v[1]) > math.Abs(v[face]) {
+		face = 1
+	}
+	if math.Abs(v[2]) > math.Abs(v[face]) {
+		face = 2
+	}
+	if v[face] < 0 {
+		face += 3
+	}
+
+	var u, w float64
+	switch face {
+	case 0:
+		u, w = v[1]/v[0], v[2]/v[0]
+	case 1:
+		u, w = -v[0]/v[1], v[2]/v[1]
+	case 2:
+		u, w = -v[0]/v[2], -v[1]/v[2]
+	case 3:
+		u, w = v[2]/v[0], v[1]/v[0]
+	case 4:
+		u, w = v[2]/v[1], -v[0]/v[1]
+	default:
+		u, w = -v[1]/v[2], -v[0]/v[2]
+	}
+	i, j := s2STToIJ(s2UVToST(u)), s2STToIJ(s2UVToST(w))
+
+	id := uint64(face) << (s2PosBits - 1)
+	bits := face & s2SwapMask
+	const mask = 1<<s2LookupBits - 1
+	for k := 7; k >= 0; k-- {
+		bits += (i >> (k * s2LookupBits) & mask) << (s2LookupBits + 2)
+		bits += (j >> (k * s2LookupBits) & mask) << 2
+		bits = s2LookupPos[bits]
+		id |= uint64(bits>>2) << (k * 2 * s2LookupBits)
+		bits &= s2SwapMask | s2InvertMask
+	}
+	id = id*2 + 1
+
+	// truncate the leaf cell to its ancestor at level
+	lsb := uint64(1) << (2 * (MaxS2Level - level))
+	return id&-lsb | lsb
+}
+
+// s2UVToST applies the quadratic projection which makes cells closer to equal in area.
+func s2UVToST(u float64) float64 {
+	if u >= 0 {
+		return 0.5 * math.Sqrt(1+3*u)
+	}
+	return 1 - 0.5*math.Sqrt(1-3*u)
+}
+
+func s2STToIJ(s float64) int {
+	return min(max(int(math.Floor(s2MaxSize*s)), 0), s2MaxSize-1)
+}
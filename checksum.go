@@ -0,0 +1,17 @@
+package ip2loc
+
+import "errors"
+
+// ErrChecksumMismatch is returned when a database file does not have the expected SHA-256 checksum.
+var ErrChecksumMismatch = errors.New("ip2loc: checksum mismatch")
+
+// WithChecksum verifies the SHA-256 checksum of the database file when it is opened, hashing the
+// file actually opened. The checksum pins that one file: Reload and the reopen after a truncated read
+// do not check it, since the updates renamed over the file have checksums of their own; verify
+// those with VerifyChecksum or ReplaceDatabaseFile before they are swapped in.
+// It only applies to databases opened from a path.
+func WithChecksum(sha256hex string) Option {
+	return func(d *DB) {
+		d.checksum = sha256hex
+	}
+}
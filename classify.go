@@ -0,0 +1,114 @@
+package ip2loc
+
+// NetworkType is the kind of network an address belongs to, as inferred by Classify.
+type NetworkType int
+
+const (
+	NetworkUnknown      NetworkType = iota
+	NetworkResidential              // fixed line consumer ISP
+	NetworkMobile                   // mobile carrier
+	NetworkDataCenter               // hosting provider, CDN or search engine spider
+	NetworkAnonymizer               // VPN, Tor, public, web or residential proxy, from IP2Proxy data
+	NetworkOrganization             // company, government, military, education or library network
+)
+
+func (t NetworkType) String() string {
+	switch t {
+	case NetworkResidential:
+		return "residential"
+	case NetworkMobile:
+		return "mobile"
+	case NetworkDataCenter:
+		return "datacenter"
+	case NetworkAnonymizer:
+		return "anonymizer"
+	case NetworkOrganization:
+		return "organization"
+	}
+	return "unknown"
+}
+
+// Classification is the network type of an address with the confidence of the inference, from 0
+// for NetworkUnknown to 1.
+type Classification struct {
+	Type       NetworkType
+	Confidence float64
+}
+
+// Classify infers the network type of a record from its usage type and, lacking one, its net
+// speed. proxy is the proxy type of the address from an IP2Proxy database, ProxyTypeUnknown if
+// there is none; a known proxy type takes precedence over the usage type.
+//
+// The confidence is highest for a single usage type code and lower when the codes point in
+// different directions, e.g. "DCH/ISP", or when only the net speed is known.
+func Classify(x IP2LocationRecord, proxy ProxyType) Classification {
+	switch {
+	case proxy.IsAnonymizer():
+		return Classification{NetworkAnonymizer, 0.95}
+	case proxy.IsHosting():
+		return Classification{NetworkDataCenter, 0.95}
+	case proxy == ProxyTypeEnterprise:
+		return Classification{NetworkOrganization, 0.8}
+	}
+
+	var hosting, mobile, access, org int
+	codes := x.UsageTypes()
+	for _, u := range codes {
+		switch {
+		case u.IsHosting():
+			hosting++
+		case u == UsageTypeMobile:
+			mobile++
+		case u == UsageTypeISP:
+			access++
+		case u != UsageTypeReserved:
+			org++
+		}
+	}
+	// every code beyond the first lowers the confidence
+	mixed := 0.1 * float64(max(len(codes)-1, 0))
+	speed := x.NetSpeedType()
+	switch {
+	case hosting > 0:
+		return Classification{NetworkDataCenter, 0.9 - mixed}
+	case mobile > 0:
+		return Classification{NetworkMobile, 0.9 - mixed/2}
+	case access > 0 && org == 0:
+		c := Classification{NetworkResidential, 0.8}
+		if speed == NetSpeedDSL || speed == NetSpeedDial {
+			c.Confidence = 0.9
+		} else if speed.IsCorporate() {
+			c.Confidence = 0.6
+		}
+		return c
+	case org > 0:
+		return Classification{NetworkOrganization, 0.7 - mixed}
+	case speed == NetSpeedDSL || speed == NetSpeedDial:
+		return Classification{NetworkResidential, 0.5}
+	case speed.IsCorporate():
+		return Classification{NetworkOrganization, 0.4}
+	}
+	return Classification{NetworkUnknown, 0}
+}
+
+// Classify looks up ip and returns Classify of its record without proxy data. It returns
+// ErrUnsupportedField if the database has neither usage types nor net speeds.
+func (d *DB) Classify(ip string) (Classification, error) {
+	x, err := d.query(ip, usageType|netSpeed)
+	if err != nil {
+		return Classification{}, err
+	}
+	return Classify(x, ProxyTypeUnknown), nil
+}
+
+// IsDatacenterIP reports whether ip belongs to a hosting provider, CDN or search engine spider.
+func (d *DB) IsDatacenterIP(ip string) (bool, error) {
+	c, err := d.Classify(ip)
+	return c.Type == NetworkDataCenter, err
+}
+
+// IsResidentialIP reports whether ip belongs to a fixed line or mobile consumer ISP.
+func (d *DB) IsResidentialIP(ip string) (bool, error) {
+	c, err := d.Classify(ip)
+	return c.Type == NetworkResidential || c.Type == NetworkMobile, err
+}
@@ -0,0 +1,59 @@
+package ip2loc
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// tsvEscaper escapes values the way ClickHouse's TabSeparated format reads them.
+var tsvEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+// ClickHouseDictionarySchema returns the attribute list of a ClickHouse ip_trie dictionary
+// matching the output of ExportClickHouseTSV, for use in CREATE DICTIONARY:
+//
+//	CREATE DICTIONARY ip2location (<schema>)
+//	PRIMARY KEY prefix
+//	SOURCE(FILE(path '/var/lib/clickhouse/user_files/ip2location.tsv' format 'TabSeparated'))
+//	LAYOUT(IP_TRIE)
+//	LIFETIME(3600)
+func ClickHouseDictionarySchema() string {
+	cols := []string{"prefix String"}
+	for _, f := range recordFields {
+		typ := "String"
+		if f.isFloat {
+			typ = "Float32"
+		}
+		cols = append(cols, f.name+" "+typ)
+	}
+	return strings.Join(cols, ", ")
+}
+
+// ExportClickHouseTSV writes every row of the database to w as TabSeparated lines in the layout
+// of an ip_trie dictionary: the CIDR prefix followed by all record fields in the order of
+// ClickHouseDictionarySchema. Rows are split into the prefixes covering them; fields the database
+// does not contain are empty.
+func (d *DB) ExportClickHouseTSV(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for r, err := range d.Records() {
+		if err != nil {
+			return err
+		}
+		var line strings.Builder
+		for _, f := range recordFields {
+			line.WriteByte('\t')
+			line.WriteString(tsvEscaper.Replace(f.value(&r.Record)))
+		}
+		line.WriteByte('\n')
+
+		for _, p := range r.Prefixes() {
+			if _, err := bw.WriteString(p.String()); err != nil {
+				return err
+			}
+			if _, err := bw.WriteString(line.String()); err != nil {
+				return err
+			}
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand/v2"
+	"net/netip"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ferluci/ip2loc"
+)
+
+func bench(args []string) error {
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	dbPath := fs.String("db", "", "path to the BIN `file`")
+	mode := fs.String("mode", "disk", "how the database is read: disk, memory or mmap")
+	ipsPath := fs.String("ips", "", "`file` of addresses to look up, one per line; random IPv4 and IPv6 addresses by default")
+	n := fs.Int("n", 1000000, "number of lookups")
+	workers := fs.Int("workers", runtime.GOMAXPROCS(0), "number of concurrent lookups")
+	fs.Parse(args)
+	if *dbPath == "" {
+		return errors.New("bench: -db is required")
+	}
+	if *n < 1 || *workers < 1 {
+		return errors.New("bench: -n and -workers must be positive")
+	}
+
+	ips, err := benchIPs(*ipsPath)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	db, err := openDB(*dbPath, *mode)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	opened := time.Since(start)
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	latencies := make([][]time.Duration, *workers)
+	failures := make([]map[string]int, *workers) // counts by error message
+	// a panicked lookup fails the benchmark rather than the program
+	var group ip2loc.WorkerGroup
+	start = time.Now()
+	for w := range *workers {
+		count := *n / *workers
+		if w < *n%*workers {
+			count++
+		}
+		group.GoOnce("bench", func(context.Context) error {
+			lat := make([]time.Duration, 0, count)
+			errs := map[string]int{}
+			for i := range count {
+				ip := ips[(w+i**workers)%len(ips)]
+				t := time.Now()
+				_, err := db.GetAll(ip)
+				lat = append(lat, time.Since(t))
+				if err != nil {
+					errs[err.Error()]++
+				}
+			}
+			latencies[w], failures[w] = lat, errs
+			return nil
+		})
+	}
+	group.Start(context.Background())
+	err = group.Wait()
+	group.Stop()
+	if err != nil {
+		return err
+	}
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+
+	all := slices.Concat(latencies...)
+	slices.Sort(all)
+	pct := func(p float64) time.Duration {
+		return all[min(int(p*float64(len(all))), len(all)-1)]
+	}
+	errs := map[string]int{}
+	for _, f := range failures {
+		for msg, c := range f {
+			errs[msg] += c
+		}
+	}
+
+	fmt.Printf("database    %s (%s mode, opened in %s, %d bytes held in memory)\n",
+		*dbPath, *mode, opened.Round(time.Microsecond), db.MemoryFootprint().Total())
+	fmt.Printf("lookups     %d with %d workers in %s\n", len(all), *workers, elapsed.Round(time.Millisecond))
+	fmt.Printf("throughput  %.0f lookups/s\n", float64(len(all))/elapsed.Seconds())
+	fmt.Printf("latency     p50 %s  p90 %s  p99 %s  p99.9 %s  max %s\n",
+		pct(0.5), pct(0.9), pct(0.99), pct(0.999), all[len(all)-1])
+	fmt.Printf("allocations %.1f allocs/lookup, %.0f bytes/lookup\n",
+		float64(after.Mallocs-before.Mallocs)/float64(len(all)), float64(after.TotalAlloc-before.TotalAlloc)/float64(len(all)))
+	for msg, c := range errs {
+		fmt.Printf("errors      %d %s\n", c, msg)
+	}
+	return nil
+}
+
+// benchIPs reads the addresses from path, or generates random ones if path is empty.
+func benchIPs(path string) ([]string, error) {
+	if path == "" {
+		ips := make([]string, 100000)
+		r := rand.New(rand.NewPCG(1, 2))
+		for i := range ips {
+			if i%4 == 3 {
+				var b [16]byte
+				for j := range b {
+					b[j] = byte(r.Uint32())
+				}
+				b[0] = 0x20 // global unicast
+				ips[i] = netip.AddrFrom16(b).String()
+			} else {
+				var b [4]byte
+				for j := range b {
+					b[j] = byte(r.Uint32())
+				}
+				ips[i] = netip.AddrFrom4(b).String()
+			}
+		}
+		return ips, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var ips []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if line := strings.TrimSpace(sc.Text()); line != "" && !strings.HasPrefix(line, "#") {
+			ips = append(ips, line)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("bench: no addresses in %s", path)
+	}
+	return ips, nil
+}
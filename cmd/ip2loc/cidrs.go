@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"os"
+	"strings"
+
+	"github.com/ferluci/ip2loc"
+)
+
+// cidrs prints the aggregated CIDR prefixes of the networks of some countries.
+func cidrs(args []string) error {
+	fs := flag.NewFlagSet("cidrs", flag.ExitOnError)
+	dbPath := fs.String("db", "", "path to the BIN `file`")
+	mode := fs.String("mode", "mmap", "how to read the database: disk, memory or mmap")
+	countries := fs.String("country", "", "comma-separated ISO 3166-1 alpha-2 `codes` of the countries")
+	invert := fs.Bool("v", false, "print the networks of all other countries instead")
+	family := fs.Int("ip", 0, "print only IPv4 (4) or IPv6 (6) prefixes")
+	fs.Parse(args)
+	if *dbPath == "" || *countries == "" {
+		return errors.New("cidrs: -db and -country are required")
+	}
+
+	set := map[string]bool{}
+	for _, c := range strings.Split(*countries, ",") {
+		set[strings.ToUpper(strings.TrimSpace(c))] = true
+	}
+	d, err := openDB(*dbPath, *mode)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	prefixes, err := d.AggregatedPrefixes(func(x ip2loc.IP2LocationRecord) bool {
+		return set[x.CountryShort] != *invert
+	})
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	for _, p := range prefixes {
+		if *family == 4 && !p.Addr().Is4() || *family == 6 && p.Addr().Is4() {
+			continue
+		}
+		w.WriteString(p.String())
+		w.WriteByte('\n')
+	}
+	return w.Flush()
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ferluci/ip2loc"
+)
+
+// db manages the releases kept by ip2loc update -history.
+func db(args []string) error {
+	if len(args) == 0 {
+		return errors.New("db: want a subcommand: releases, rollback or unpin")
+	}
+	fs := flag.NewFlagSet("db "+args[0], flag.ExitOnError)
+	var u ip2loc.Updater
+	fs.StringVar(&u.Path, "o", "", "path of the database `file` the releases replace")
+	fs.StringVar(&u.History, "history", "", "`directory` of the releases, as given to ip2loc update")
+	date := fs.String("date", "", "publish `date` of the release to roll back to, e.g. 2024-05-01")
+	fs.Parse(args[1:])
+	if u.History == "" {
+		return errors.New("db: -history is required")
+	}
+
+	switch args[0] {
+	case "releases":
+		releases, pinned, err := u.Releases()
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "DATE\tTYPE\tSIZE\tDOWNLOADED\tFILE\t")
+		for _, r := range releases {
+			mark := ""
+			if r.File == pinned {
+				mark = "pinned"
+			}
+			fmt.Fprintf(w, "%s\tDB%d\t%d\t%s\t%s\t%s\n", r.Date.Format(time.DateOnly), r.DatabaseType, r.Size,
+				r.Downloaded.Format(time.RFC3339), r.File, mark)
+		}
+		return w.Flush()
+	case "rollback":
+		if u.Path == "" || *date == "" {
+			return errors.New("db rollback: -o and -date are required")
+		}
+		t, err := time.Parse(time.DateOnly, *date)
+		if err != nil {
+			return err
+		}
+		if err := u.RollbackTo(t); err != nil {
+			return err
+		}
+		fmt.Printf("%s is the release of %s and pinned; send SIGHUP to servers reading it\n", u.Path, *date)
+		return nil
+	case "unpin":
+		return u.Unpin()
+	}
+	return fmt.Errorf("db: unknown subcommand %q, want releases, rollback or unpin", args[0])
+}
@@ -0,0 +1,14 @@
+module github.com/ferluci/ip2loc/cmd/ip2loc
+
+go 1.23.0
+
+require (
+	github.com/ferluci/ip2loc v0.0.0-20261016234639-b580dc0a984c
+	github.com/ferluci/ip2loc/ip2locconfig v0.0.0-20261016234639-b580dc0a984c
+)
+
+require (
+	golang.org/x/sync v0.15.0 // indirect
+	golang.org/x/sys v0.33.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
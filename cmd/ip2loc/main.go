@@ -0,0 +1,97 @@
+// Command ip2loc works with IP2Location BIN databases.
+//
+// Usage:
+//
+//	ip2loc serve -db FILE [-listen ADDR]...   answer lookups over HTTP
+//	ip2loc serve -config FILE                 the same, configured by a YAML or JSON file
+//	ip2loc bench -db FILE [-mode MODE]        measure lookup throughput and latency
+//	ip2loc strip -db FILE -o FILE -fields F   write a smaller BIN with only some fields
+//	ip2loc split -db FILE -dir DIR            split a BIN into shards by IP prefix
+//	ip2loc update -url URL -o FILE            download a new release of a BIN, resuming if interrupted
+//	ip2loc db rollback -history DIR -o FILE -date DATE   revert to a release kept by update -history
+//	ip2loc tzcheck -db FILE                   list timezones which disagree with the coordinates
+//	ip2loc cidrs -db FILE -country CC,...     print the aggregated CIDR prefixes of countries
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ferluci/ip2loc"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	var err error
+	switch os.Args[1] {
+	case "serve":
+		err = serve(os.Args[2:])
+	case "bench":
+		err = bench(os.Args[2:])
+	case "strip":
+		err = strip(os.Args[2:])
+	case "split":
+		err = split(os.Args[2:])
+	case "update":
+		err = update(os.Args[2:])
+	case "db":
+		err = db(os.Args[2:])
+	case "tzcheck":
+		err = tzcheck(os.Args[2:])
+	case "cidrs":
+		err = cidrs(os.Args[2:])
+	case "-h", "-help", "--help", "help":
+		usage()
+	default:
+		fmt.Fprintf(os.Stderr, "ip2loc: unknown command %q\n", os.Args[1])
+		usage()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "ip2loc:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `usage: ip2loc <command> [flags]
+
+commands:
+  serve   answer lookups over HTTP
+  bench   measure lookup throughput and latency
+  strip   write a smaller BIN with only some fields
+  split   split a BIN into shards by IP prefix
+  update  download a new release of a BIN, resuming if interrupted
+  db      list, roll back to and unpin the releases kept by update -history
+  tzcheck list timezones which disagree with the coordinates
+  cidrs   print the aggregated CIDR prefixes of countries, e.g. for a firewall
+
+Run "ip2loc <command> -h" for the flags of a command.`)
+	os.Exit(2)
+}
+
+// openDB opens the database at path in the given mode: disk, memory or mmap.
+func openDB(path, mode string, opts ...ip2loc.Option) (*ip2loc.DB, error) {
+	switch mode {
+	case "disk":
+		return ip2loc.OpenDB(path, opts...)
+	case "memory":
+		return ip2loc.OpenCompressedDB(path, opts...)
+	case "mmap":
+		return ip2loc.OpenMmapDB(path, opts...)
+	}
+	return nil, fmt.Errorf("unknown mode %q, want disk, memory or mmap", mode)
+}
+
+// listFlag is a flag which may be given several times.
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return fmt.Sprint(*l)
+}
+
+func (l *listFlag) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ferluci/ip2loc"
+	"github.com/ferluci/ip2loc/ip2locconfig"
+	"github.com/ferluci/ip2loc/ip2locserver"
+)
+
+func serve(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	configPath := fs.String("config", "", "YAML or JSON config `file`; the other flags override its settings")
+	dbPath := fs.String("db", "", "path to the BIN `file`")
+	mode := fs.String("mode", "mmap", "how the database is read: disk, memory, mmap or cached")
+	refresh := fs.Duration("refresh", 0, "reload the database at this interval, e.g. 1h; 0 only reloads on SIGHUP")
+	maxAge := fs.Duration("max-age", 0, "maximum age of the database before /readyz fails, e.g. 1080h; 0 disables the check")
+	admin := fs.Bool("admin", false, "enable POST /admin/reload")
+	trustFF := fs.Bool("trust-forwarded-for", false, "locate clients by X-Forwarded-For; only behind a proxy setting it")
+	debug := fs.Bool("debug", false, "enable expvar counters at /debug/vars")
+	withPprof := fs.Bool("pprof", false, "enable pprof profiles at /debug/pprof/; implies -debug")
+	socketMode := fs.String("socket-mode", "", "permissions of Unix sockets, e.g. 0660")
+	tlsCert := fs.String("tls-cert", "", "PEM certificate `file`; enables TLS")
+	tlsKey := fs.String("tls-key", "", "PEM private key `file`")
+	tlsClientCA := fs.String("tls-client-ca", "", "PEM `file` of the CAs client certificates are verified against")
+	tlsRequire := fs.Bool("tls-require-client-cert", false, "reject clients without a valid certificate")
+	var listen listFlag
+	fs.Var(&listen, "listen", "`address` to listen on: host:port, unix:/path or systemd[:name]; may be repeated (default :8080)")
+	fs.Parse(args)
+
+	var cfg ip2loc.Config
+	if *configPath != "" {
+		var err error
+		if cfg, err = ip2locconfig.Read(*configPath); err != nil {
+			return err
+		}
+	}
+	if *dbPath != "" {
+		cfg.Databases = []ip2loc.DatabaseConfig{{Path: *dbPath}}
+		cfg.Server.Database = ""
+	}
+	if len(cfg.Databases) == 0 {
+		return errors.New("serve: -db or -config is required")
+	}
+	// the flags given override the config; without one, the flag defaults apply to the -db database
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	served := &cfg.Databases[0]
+	for i, c := range cfg.Databases {
+		if c.Name != "" && c.Name == cfg.Server.Database {
+			served = &cfg.Databases[i]
+		}
+	}
+	if set["mode"] || *configPath == "" {
+		served.Mode = *mode
+	}
+	if set["refresh"] {
+		served.Refresh = *refresh
+	}
+	if set["max-age"] {
+		served.MaxAge = *maxAge
+	}
+	srvCfg := &cfg.Server
+	if set["admin"] {
+		srvCfg.Admin = *admin
+	}
+	if set["trust-forwarded-for"] {
+		srvCfg.TrustForwardedFor = *trustFF
+	}
+	if set["debug"] {
+		srvCfg.Debug = *debug
+	}
+	if set["pprof"] {
+		srvCfg.Pprof = *withPprof
+	}
+	if set["socket-mode"] {
+		srvCfg.SocketMode = *socketMode
+	}
+	if set["tls-cert"] {
+		srvCfg.TLS.CertFile = *tlsCert
+	}
+	if set["tls-key"] {
+		srvCfg.TLS.KeyFile = *tlsKey
+	}
+	if set["tls-client-ca"] {
+		srvCfg.TLS.ClientCAFile = *tlsClientCA
+	}
+	if set["tls-require-client-cert"] {
+		srvCfg.TLS.RequireClientCert = *tlsRequire
+	}
+	if len(listen) > 0 {
+		srvCfg.Listen = listen
+	}
+	if len(srvCfg.Listen) == 0 {
+		srvCfg.Listen = []string{":8080"}
+	}
+
+	srv := &http.Server{ReadHeaderTimeout: 10 * time.Second}
+	if srvCfg.TLS.CertFile == "" && srvCfg.TLS.KeyFile == "" {
+		// client certificates without TLS would silently serve plain HTTP to every client
+		if srvCfg.TLS.ClientCAFile != "" || srvCfg.TLS.RequireClientCert {
+			return errors.New("serve: -tls-client-ca and -tls-require-client-cert need -tls-cert and -tls-key")
+		}
+	} else {
+		tlsOpts := ip2locserver.TLSOptions{
+			CertFile:          srvCfg.TLS.CertFile,
+			KeyFile:           srvCfg.TLS.KeyFile,
+			ClientCAFile:      srvCfg.TLS.ClientCAFile,
+			RequireClientCert: srvCfg.TLS.RequireClientCert,
+		}
+		tlsConfig, err := tlsOpts.TLSConfig()
+		if err != nil {
+			return err
+		}
+		srv.TLSConfig = tlsConfig
+	}
+
+	m, err := ip2loc.NewManager(cfg)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+	db := m.Default()
+
+	var listeners []net.Listener
+	for _, addr := range srvCfg.Listen {
+		l, err := ip2locserver.Listen(addr)
+		if err != nil {
+			return err
+		}
+		if path, ok := strings.CutPrefix(addr, "unix:"); ok && srvCfg.SocketMode != "" {
+			perm, err := strconv.ParseUint(srvCfg.SocketMode, 8, 32)
+			if err != nil {
+				return errors.New("serve: invalid socket mode " + srvCfg.SocketMode)
+			}
+			if err := os.Chmod(path, os.FileMode(perm)); err != nil {
+				return err
+			}
+		}
+		listeners = append(listeners, l)
+	}
+
+	handler := ip2locserver.New(db)
+	if srvCfg.Admin {
+		handler.EnableAdmin()
+	}
+	if srvCfg.TrustForwardedFor {
+		handler.TrustForwardedFor()
+	}
+	if srvCfg.Debug || srvCfg.Pprof {
+		handler.EnableDebug(srvCfg.Pprof)
+	}
+	srv.Handler = handler
+	errc := make(chan error, len(listeners))
+	var workers ip2loc.WorkerGroup
+	workers.OnError = func(_ string, err error) { errc <- err } // a panicked listener stops the server
+	for _, l := range listeners {
+		log.Printf("listening on %s %s", l.Addr().Network(), l.Addr())
+		workers.GoOnce("listener "+l.Addr().String(), func(context.Context) error {
+			if srv.TLSConfig != nil {
+				errc <- srv.ServeTLS(l, "", "")
+			} else {
+				errc <- srv.Serve(l)
+			}
+			return nil
+		})
+	}
+	workers.Start(context.Background())
+
+	// SIGHUP reloads the database, e.g. after an updater renamed a new file over it
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+wait:
+	for {
+		select {
+		case <-hup:
+			if err := handler.Reload(); err != nil {
+				log.Printf("reload failed: %v", err)
+			} else {
+				log.Printf("reloaded database of %s", db.Date().Format(time.DateOnly))
+			}
+		case err = <-errc:
+			break wait
+		case <-ctx.Done():
+			log.Print("shutting down")
+			break wait
+		}
+	}
+	shutdown, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if e := srv.Shutdown(shutdown); err == nil || errors.Is(err, http.ErrServerClosed) {
+		err = e
+	}
+	workers.Stop()
+	return err
+}
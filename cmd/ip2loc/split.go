@@ -0,0 +1,42 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/ferluci/ip2loc"
+)
+
+func split(args []string) error {
+	fs := flag.NewFlagSet("split", flag.ExitOnError)
+	dbPath := fs.String("db", "", "path to the BIN `file`, which may be zipped or gzipped")
+	dir := fs.String("dir", "", "`directory` to write the shards to, created if needed")
+	var opts ip2loc.ShardOptions
+	fs.IntVar(&opts.IPv4Bits, "ipv4-bits", 4, "prefix length of the IPv4 shards")
+	fs.IntVar(&opts.IPv6Bits, "ipv6-bits", 16, "prefix length of the IPv6 shards")
+	fs.Parse(args)
+	if *dbPath == "" || *dir == "" {
+		return errors.New("split: -db and -dir are required")
+	}
+
+	in, err := os.Open(*dbPath)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	if err := os.MkdirAll(*dir, 0o755); err != nil {
+		return err
+	}
+	if err := ip2loc.SplitDB(in, *dir, opts); err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(*dir)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("wrote %d shards and %s to %s\n", len(entries)-1, ip2loc.ShardManifest, *dir)
+	return nil
+}
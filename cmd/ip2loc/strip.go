@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ferluci/ip2loc"
+)
+
+func strip(args []string) error {
+	fs := flag.NewFlagSet("strip", flag.ExitOnError)
+	dbPath := fs.String("db", "", "path to the BIN `file`, which may be zipped or gzipped")
+	outPath := fs.String("o", "", "path of the stripped BIN `file` to write")
+	fieldList := fs.String("fields", "", "comma separated `names` of the fields to keep, e.g. country,city")
+	fs.Parse(args)
+	if *dbPath == "" || *outPath == "" || *fieldList == "" {
+		return errors.New("strip: -db, -o and -fields are required")
+	}
+
+	var fields ip2loc.Field
+	for _, name := range strings.Split(*fieldList, ",") {
+		f, err := ip2loc.ParseField(strings.TrimSpace(name))
+		if err != nil {
+			return err
+		}
+		fields |= f
+	}
+
+	in, err := os.Open(*dbPath)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	// written next to the target and renamed, so a server mapping the file never sees it half written
+	out, err := os.CreateTemp(filepath.Dir(*outPath), filepath.Base(*outPath)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(out.Name())
+	if err := ip2loc.StripDB(out, in, fields); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(out.Name(), *outPath); err != nil {
+		return err
+	}
+
+	before, err := ip2loc.OpenMetadataOnly(*dbPath)
+	after, err2 := ip2loc.OpenMetadataOnly(*outPath)
+	if err == nil && err2 == nil {
+		fmt.Printf("DB%d, %d bytes -> DB%d, %d bytes: %s\n", before.DatabaseType, before.Size,
+			after.DatabaseType, after.Size, strings.Join(after.Fields, ", "))
+	} else if err2 == nil {
+		fmt.Printf("DB%d, %d bytes: %s\n", after.DatabaseType, after.Size, strings.Join(after.Fields, ", "))
+	}
+	return nil
+}
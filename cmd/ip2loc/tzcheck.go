@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/ferluci/ip2loc/ip2loctz"
+)
+
+// tzcheck lists the locations whose Timezone field disagrees with the time zone at their coordinates.
+func tzcheck(args []string) error {
+	fs := flag.NewFlagSet("tzcheck", flag.ExitOnError)
+	dbPath := fs.String("db", "", "path to the BIN `file`")
+	mode := fs.String("mode", "mmap", "how to read the database: disk, memory or mmap")
+	limit := fs.Int("n", 50, "list at most `n` mismatches, all if 0")
+	fs.Parse(args)
+	if *dbPath == "" {
+		return errors.New("tzcheck: -db is required")
+	}
+
+	d, err := openDB(*dbPath, *mode)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	report, err := ip2loctz.Check(d)
+	if err != nil {
+		return err
+	}
+
+	ranges := 0
+	for _, m := range report.Mismatches {
+		ranges += m.Ranges
+	}
+	fmt.Printf("%d of %d ranges checked, %d locations covering %d ranges disagree\n",
+		report.Checked, report.Ranges, len(report.Mismatches), ranges)
+	if len(report.Mismatches) == 0 {
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "RANGES\tFIRST\tCOUNTRY\tREGION\tCITY\tLAT\tLON\tTIMEZONE\tEXPECTED\t")
+	for i, m := range report.Mismatches {
+		if *limit > 0 && i == *limit {
+			break
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%g\t%g\t%s\t%s\t\n", m.Ranges, m.First, m.CountryShort, m.Region,
+			m.City, m.Latitude, m.Longitude, m.Timezone, m.Zone)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/ferluci/ip2loc"
+)
+
+func update(args []string) error {
+	fs := flag.NewFlagSet("update", flag.ExitOnError)
+	var u ip2loc.Updater
+	fs.StringVar(&u.URL, "url", "", "`URL` of the database file")
+	fs.StringVar(&u.Path, "o", "", "path of the database `file` to replace")
+	fs.IntVar(&u.Retries, "retries", 5, "attempts after the first failed one")
+	fs.DurationVar(&u.MaxBackoff, "max-backoff", time.Minute, "longest wait between attempts")
+	fs.Int64Var(&u.BytesPerSecond, "limit-rate", 0, "maximum download rate in `bytes` per second; 0 is unlimited")
+	fs.StringVar(&u.History, "history", "", "`directory` keeping previous releases for ip2loc db rollback")
+	fs.IntVar(&u.Keep, "keep", 3, "releases kept in the -history directory")
+	fs.StringVar(&u.Checksum, "sha256", "", "hex encoded SHA-256 `checksum` the download must have")
+	fs.Parse(args)
+	if u.URL == "" || u.Path == "" {
+		return errors.New("update: -url and -o are required")
+	}
+	if u.Retries == 0 {
+		u.Retries = -1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := u.Download(ctx); err != nil {
+		return err
+	}
+	if u.History != "" {
+		if _, pinned, err := u.Releases(); err == nil && pinned != "" {
+			fmt.Printf("kept in %s; %s stays pinned to %s\n", u.History, u.Path, pinned)
+			return nil
+		}
+	}
+	if md, err := ip2loc.OpenMetadataOnly(u.Path); err == nil {
+		fmt.Printf("DB%d of %s, %d bytes\n", md.DatabaseType, md.Date.Format(time.DateOnly), md.Size)
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package ip2loc
+
+import (
+	"math/big"
+	"sort"
+)
+
+// GroupStats counts the ranges and addresses of a group of rows.
+type GroupStats struct {
+	Ranges        int
+	IPv4Addresses uint64
+	IPv6Addresses *big.Int
+}
+
+func (g *GroupStats) add(r RangeRecord) {
+	g.Ranges++
+	_, first := addrNumber(r.First)
+	iptype, n := addrNumber(r.Last)
+	n.Sub(n, first)
+	n.Add(n, big.NewInt(1))
+	if iptype == 4 {
+		g.IPv4Addresses += n.Uint64()
+		return
+	}
+	if g.IPv6Addresses == nil {
+		g.IPv6Addresses = new(big.Int)
+	}
+	g.IPv6Addresses.Add(g.IPv6Addresses, n)
+}
+
+// merge adds the counts of o to g.
+func (g *GroupStats) merge(o *GroupStats) {
+	g.Ranges += o.Ranges
+	g.IPv4Addresses += o.IPv4Addresses
+	if o.IPv6Addresses != nil {
+		if g.IPv6Addresses == nil {
+			g.IPv6Addresses = new(big.Int)
+		}
+		g.IPv6Addresses.Add(g.IPv6Addresses, o.IPv6Addresses)
+	}
+}
+
+// Composition describes the data of a database: how many ranges and addresses map to each
+// country and usage type. Comparing the composition of consecutive releases shows anomalies
+// such as a country losing half of its ranges.
+type Composition struct {
+	Total       GroupStats
+	ByCountry   map[string]*GroupStats // keyed by ISO-3166 country code, "-" for unassigned ranges
+	ByUsageType map[string]*GroupStats // keyed by the raw usage type, empty if the database has none
+}
+
+// Composition scans every row of the database and returns its composition.
+func (d *DB) Composition() (*Composition, error) {
+	c := &Composition{
+		ByCountry:   map[string]*GroupStats{},
+		ByUsageType: map[string]*GroupStats{},
+	}
+	group := func(m map[string]*GroupStats, key string) *GroupStats {
+		g := m[key]
+		if g == nil {
+			g = &GroupStats{}
+			m[key] = g
+		}
+		return g
+	}
+	add := func(r RangeRecord) bool {
+		c.Total.add(r)
+		if x := r.Record.CountryShort; !isPlaceholder(x) {
+			group(c.ByCountry, x).add(r)
+		}
+		if x := r.Record.UsageType; x != "" && !isPlaceholder(x) {
+			group(c.ByUsageType, x).add(r)
+		}
+		return true
+	}
+
+	if err := d.walkAll(add); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// CompositionChange is a country whose number of ranges changed between two compositions.
+type CompositionChange struct {
+	Country string
+	Before  int // ranges in the previous composition
+	After   int // ranges in this composition
+}
+
+// Changes compares the ranges per country with a previous composition and returns the countries
+// whose count changed by more than ratio of the previous count (e.g. 0.5 for half), including
+// countries which appeared or disappeared, ordered by country code.
+func (c *Composition) Changes(prev *Composition, ratio float64) []CompositionChange {
+	var out []CompositionChange
+	seen := map[string]bool{}
+	check := func(country string) {
+		if seen[country] {
+			return
+		}
+		seen[country] = true
+
+		var before, after int
+		if g := prev.ByCountry[country]; g != nil {
+			before = g.Ranges
+		}
+		if g := c.ByCountry[country]; g != nil {
+			after = g.Ranges
+		}
+		diff := float64(after - before)
+		if diff < 0 {
+			diff = -diff
+		}
+		if before == 0 && after != 0 || before != 0 && diff > ratio*float64(before) {
+			out = append(out, CompositionChange{Country: country, Before: before, After: after})
+		}
+	}
+	for country := range prev.ByCountry {
+		check(country)
+	}
+	for country := range c.ByCountry {
+		check(country)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Country < out[j].Country })
+	return out
+}
+
+// ByContinent adds up the countries of c per continent, so reports can aggregate at the continent
+// level. Countries of no known continent are added up under ContinentUnknown.
+func (c *Composition) ByContinent() map[Continent]*GroupStats {
+	out := map[Continent]*GroupStats{}
+	for country, g := range c.ByCountry {
+		continent := ContinentOf(country)
+		if out[continent] == nil {
+			out[continent] = &GroupStats{}
+		}
+		out[continent].merge(g)
+	}
+	return out
+}
@@ -0,0 +1,51 @@
+package ip2loc
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// decompress detects gzip and zip data by their magic bytes and returns the contained database.
+func decompress(data []byte) ([]byte, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0x1f, 0x8b}):
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		return io.ReadAll(zr)
+
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")):
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, err
+		}
+		f, err := zipDatabase(zr)
+		if err != nil {
+			return nil, err
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return io.ReadAll(rc)
+	}
+	return data, nil
+}
+
+// zipDatabase returns the first entry of a zip archive with a .BIN extension.
+func zipDatabase(zr *zip.Reader) (*zip.File, error) {
+	for _, f := range zr.File {
+		if strings.EqualFold(path.Ext(f.Name), ".bin") {
+			return f, nil
+		}
+	}
+	return nil, fmt.Errorf("no .BIN file in zip archive")
+}
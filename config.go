@@ -0,0 +1,241 @@
+//go:build !ip2loc_nofs
+
+package ip2loc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Config describes the databases of a Manager and the settings of the server answering lookups
+// from them. It is read from a YAML or JSON file or from the environment by the ip2locconfig
+// module; durations are written like "24h".
+type Config struct {
+	Databases []DatabaseConfig `yaml:"databases" json:"databases"`
+	Server    ServerConfig     `yaml:"server" json:"server"`
+}
+
+// DatabaseConfig describes a database opened by a Manager.
+type DatabaseConfig struct {
+	Name string `yaml:"name" json:"name"` // name of the database in the Manager; the path if empty
+	Path string `yaml:"path" json:"path"`
+	// Mode is how the database is read: disk (OpenDB), memory (OpenCompressedDB), mmap (OpenMmapDB)
+	// or cached (OpenCachedDB); disk if empty.
+	Mode      string `yaml:"mode" json:"mode"`
+	CacheSize int64  `yaml:"cache_size" json:"cache_size"` // memory budget of the cached mode in bytes
+	BlockSize int    `yaml:"block_size" json:"block_size"` // block size of the cached mode, DefaultBlockSize if 0
+	// Refresh checks the database file at this interval and reloads it if it changed, picking up
+	// updates renamed over it; 0 never reloads it.
+	Refresh     time.Duration `yaml:"refresh" json:"refresh"`
+	MaxAge      time.Duration `yaml:"max_age" json:"max_age"`           // WithMaxAge
+	StalePolicy string        `yaml:"stale_policy" json:"stale_policy"` // warn or fail, warn if empty
+	Fields      []string      `yaml:"fields" json:"fields"`             // WithFields, as accepted by ParseField
+	Checksum    string        `yaml:"checksum" json:"checksum"`         // WithChecksum
+	// Webhook is a URL every swap of the database is posted to, as by Webhook, unless the options
+	// passed to NewManager set a swap handler of their own.
+	Webhook string `yaml:"webhook" json:"webhook"`
+}
+
+// ServerConfig holds the settings of the serve command of cmd/ip2loc.
+type ServerConfig struct {
+	Database   string   `yaml:"database" json:"database"` // name of the database served, the first if empty
+	Listen     []string `yaml:"listen" json:"listen"`     // host:port, unix:/path or systemd[:name]
+	SocketMode string   `yaml:"socket_mode" json:"socket_mode"`
+	Admin      bool     `yaml:"admin" json:"admin"`
+	Debug      bool     `yaml:"debug" json:"debug"`
+	Pprof      bool     `yaml:"pprof" json:"pprof"`
+	TLS        struct {
+		CertFile          string `yaml:"cert_file" json:"cert_file"`
+		KeyFile           string `yaml:"key_file" json:"key_file"`
+		ClientCAFile      string `yaml:"client_ca_file" json:"client_ca_file"`
+		RequireClientCert bool   `yaml:"require_client_cert" json:"require_client_cert"`
+	} `yaml:"tls" json:"tls"`
+	// TrustForwardedFor locates clients by X-Forwarded-For, as by Server.TrustForwardedFor of
+	// ip2locserver; only for servers behind a proxy setting the header.
+	TrustForwardedFor bool `yaml:"trust_forwarded_for" json:"trust_forwarded_for"`
+}
+
+// Manager holds the databases described by a Config and reloads them on their refresh schedules.
+type Manager struct {
+	cfg   Config
+	names []string
+	dbs   map[string]*DB
+
+	workers  WorkerGroup // the refreshes, webhook requests and workers of the databases
+	webhooks []*Webhook
+	once     sync.Once
+}
+
+// NewManager opens the databases of cfg, applying opts to each after the options of its DatabaseConfig.
+// A failed refresh keeps the old database and emits an EventReopenFailed to the handler set by
+// WithEventHandler. If a database cannot be opened, the ones opened before it are closed again.
+// The workers of the databases, e.g. of a BulkEnricher, and the webhook requests run in a
+// WorkerGroup of the Manager, which Close stops.
+func NewManager(cfg Config, opts ...Option) (*Manager, error) {
+	if len(cfg.Databases) == 0 {
+		return nil, errors.New("ip2loc: the config lists no databases")
+	}
+	m := &Manager{cfg: cfg, dbs: make(map[string]*DB)}
+	m.workers.OnError = func(name string, err error) {
+		// a refresh panicked; it is restarted
+		m.dbs[name].emit([]Event{{Type: EventReopenFailed, Time: time.Now(), Err: err}})
+	}
+	for _, c := range cfg.Databases {
+		name := c.Name
+		if name == "" {
+			name = c.Path
+		}
+		if _, ok := m.dbs[name]; ok {
+			m.Close()
+			return nil, fmt.Errorf("ip2loc: database %q is configured twice", name)
+		}
+		var hook *Webhook
+		if c.Webhook != "" {
+			hook = &Webhook{URL: c.Webhook, Workers: &m.workers}
+			m.webhooks = append(m.webhooks, hook)
+		}
+		// taken before the open, so that a file renamed over it meanwhile is reloaded
+		last, _ := os.Stat(c.Path)
+		db, err := c.open(hook, append([]Option{WithWorkerGroup(&m.workers)}, opts...))
+		if err != nil {
+			m.Close()
+			return nil, fmt.Errorf("ip2loc: opening database %q: %w", name, err)
+		}
+		m.names = append(m.names, name)
+		m.dbs[name] = db
+		if c.Refresh > 0 {
+			m.workers.Go(name, func(ctx context.Context) error {
+				m.refresh(ctx, db, c.Refresh, last)
+				return nil
+			})
+		}
+	}
+	if name := cfg.Server.Database; name != "" && m.dbs[name] == nil {
+		m.Close()
+		return nil, fmt.Errorf("ip2loc: the server database %q is not configured", name)
+	}
+	m.workers.Start(context.Background())
+	return m, nil
+}
+
+// open opens the database described by c, posting its swaps to hook if it is not nil.
+func (c DatabaseConfig) open(hook *Webhook, extra []Option) (*DB, error) {
+	var opts []Option
+	if c.MaxAge > 0 {
+		policy := StaleWarn
+		switch c.StalePolicy {
+		case "", "warn":
+		case "fail":
+			policy = StaleFail
+		default:
+			return nil, fmt.Errorf("unknown stale policy %q, want warn or fail", c.StalePolicy)
+		}
+		opts = append(opts, WithMaxAge(c.MaxAge, policy))
+	}
+	if len(c.Fields) > 0 {
+		var fields Field
+		for _, name := range c.Fields {
+			f, err := ParseField(name)
+			if err != nil {
+				return nil, err
+			}
+			fields |= f
+		}
+		opts = append(opts, WithFields(fields))
+	}
+	if c.Checksum != "" {
+		opts = append(opts, WithChecksum(c.Checksum))
+	}
+	if hook != nil {
+		opts = append(opts, WithSwapHandler(hook.Notify))
+	}
+	opts = append(opts, extra...)
+
+	switch c.Mode {
+	case "", "disk":
+		return OpenDB(c.Path, opts...)
+	case "memory":
+		return OpenCompressedDB(c.Path, opts...)
+	case "mmap":
+		return OpenMmapDB(c.Path, opts...)
+	case "cached":
+		if c.CacheSize <= 0 {
+			return nil, errors.New("the cached mode needs a cache_size")
+		}
+		return OpenCachedDB(c.Path, c.BlockSize, c.CacheSize, opts...)
+	}
+	return nil, fmt.Errorf("unknown mode %q, want disk, memory, mmap or cached", c.Mode)
+}
+
+// refresh reloads db every interval if its file changed since it was last loaded, that is if it was
+// replaced by another file or its size or modification time differ from last. A failed reload is
+// retried on the next tick.
+func (m *Manager) refresh(ctx context.Context, db *DB, interval time.Duration, last os.FileInfo) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			fi, err := os.Stat(db.path)
+			if err == nil && !fileChanged(last, fi) {
+				continue
+			}
+			if err := db.Reload(); err != nil {
+				if !errors.Is(err, ErrClosed) {
+					db.emit([]Event{{Type: EventReopenFailed, Time: time.Now(), Err: err}})
+				}
+				continue
+			}
+			last = fi
+		}
+	}
+}
+
+// fileChanged reports whether the file described by fi is not the one described by last.
+func fileChanged(last, fi os.FileInfo) bool {
+	return last == nil || !os.SameFile(last, fi) || last.Size() != fi.Size() || !last.ModTime().Equal(fi.ModTime())
+}
+
+// Config returns the Config the Manager was created from.
+func (m *Manager) Config() Config {
+	return m.cfg
+}
+
+// DB returns the database of the given name, or nil if there is none.
+func (m *Manager) DB(name string) *DB {
+	return m.dbs[name]
+}
+
+// Default returns the database named by the server settings, or the first one.
+func (m *Manager) Default() *DB {
+	if name := m.cfg.Server.Database; name != "" {
+		return m.dbs[name]
+	}
+	return m.dbs[m.names[0]]
+}
+
+// Names returns the names of the databases in the order of the Config.
+func (m *Manager) Names() []string {
+	return append([]string(nil), m.names...)
+}
+
+// Close stops the refreshes, cancels the pending webhook requests and closes the databases.
+func (m *Manager) Close() error {
+	var errs []error
+	m.once.Do(func() {
+		errs = append(errs, m.workers.Stop())
+		for _, hook := range m.webhooks {
+			errs = append(errs, hook.Close())
+		}
+		for _, name := range m.names {
+			errs = append(errs, m.dbs[name].Close())
+		}
+	})
+	return errors.Join(errs...)
+}
@@ -0,0 +1,78 @@
+//go:build !ip2loc_nofs
+
+package ip2loc
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// renameOver writes data to a temporary file next to path and renames it over path.
+func renameOver(t *testing.T, path string, data []byte) {
+	t.Helper()
+	tmp := filepath.Join(filepath.Dir(path), "next.BIN")
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestManagerRefresh(t *testing.T) {
+	for _, mode := range []string{"disk", "memory", "mmap", "cached"} {
+		t.Run(mode, func(t *testing.T) {
+			path := testDB(t)
+			failed := make(chan error, 1)
+			m, err := NewManager(Config{Databases: []DatabaseConfig{{
+				Path: path, Mode: mode, CacheSize: 1 << 20, Refresh: 10 * time.Millisecond,
+			}}}, WithEventHandler(func(e Event) {
+				if e.Type == EventReopenFailed {
+					select {
+					case failed <- e.Err:
+					default:
+					}
+				}
+			}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer m.Close()
+			db := m.Default()
+
+			rows := append([]testRow(nil), testRows.v4...)
+			rows[1].country = "CA"
+			renameOver(t, path, buildDB(rows, testRows.v6))
+			deadline := time.Now().Add(5 * time.Second)
+			for {
+				x, err := db.GetCountryShort("8.8.8.8")
+				if err == nil && x.CountryShort == "CA" {
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatalf("the new file was not loaded: %+v, %v", x, err)
+				}
+				time.Sleep(5 * time.Millisecond)
+			}
+
+			renameOver(t, path, []byte("not a database"))
+			select {
+			case err := <-failed:
+				if err == nil || errors.Is(err, ErrClosed) {
+					t.Errorf("EventReopenFailed with error %v", err)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("no EventReopenFailed after the file was replaced by an invalid one")
+			}
+			if x, err := db.GetCountryShort("8.8.8.8"); err != nil || x.CountryShort != "CA" {
+				t.Errorf("after the failed refresh: %+v, %v, want the last loaded file", x, err)
+			}
+			if !db.Healthy() {
+				t.Error("unhealthy after the failed refresh")
+			}
+		})
+	}
+}
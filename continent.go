@@ -0,0 +1,103 @@
+package ip2loc
+
+import (
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Continent is a continent by the two-letter code GeoNames and GeoIP2 use.
+type Continent string
+
+const (
+	ContinentUnknown      Continent = ""
+	ContinentAfrica       Continent = "AF"
+	ContinentAntarctica   Continent = "AN"
+	ContinentAsia         Continent = "AS"
+	ContinentEurope       Continent = "EU"
+	ContinentNorthAmerica Continent = "NA" // including Central America and the Caribbean
+	ContinentOceania      Continent = "OC"
+	ContinentSouthAmerica Continent = "SA"
+)
+
+// Continents lists the continents in the order of their codes.
+var Continents = []Continent{
+	ContinentAfrica, ContinentAntarctica, ContinentAsia, ContinentEurope,
+	ContinentNorthAmerica, ContinentOceania, ContinentSouthAmerica,
+}
+
+// Name returns the English name of c, or "" if it is unknown.
+func (c Continent) Name() string {
+	switch c {
+	case ContinentAfrica:
+		return "Africa"
+	case ContinentAntarctica:
+		return "Antarctica"
+	case ContinentAsia:
+		return "Asia"
+	case ContinentEurope:
+		return "Europe"
+	case ContinentNorthAmerica:
+		return "North America"
+	case ContinentOceania:
+		return "Oceania"
+	case ContinentSouthAmerica:
+		return "South America"
+	}
+	return ""
+}
+
+// continentCountries lists the ISO 3166-1 alpha-2 country codes of every continent, assigned like
+// GeoNames does: Russia and Cyprus to Europe, Turkey and the Caucasus to Asia, the dependencies to
+// the continent they lie in. XK is the code IP2Location uses for Kosovo.
+var continentCountries = map[Continent]string{
+	ContinentAfrica: "AO BF BI BJ BW CD CF CG CI CM CV DJ DZ EG EH ER ET GA GH GM GN GQ GW KE KM LR LS LY MA " +
+		"MG ML MR MU MW MZ NA NE NG RE RW SC SD SH SL SN SO SS ST SZ TD TG TN TZ UG YT ZA ZM ZW",
+	ContinentAntarctica: "AQ BV GS HM TF",
+	ContinentAsia: "AE AF AM AZ BD BH BN BT CC CN CX GE HK ID IL IN IO IQ IR JO JP KG KH KP KR KW KZ LA LB " +
+		"LK MM MN MO MV MY NP OM PH PK PS QA SA SG SY TH TJ TL TM TR TW UZ VN YE",
+	ContinentEurope: "AD AL AT AX BA BE BG BY CH CY CZ DE DK EE ES FI FO FR GB GG GI GR HR HU IE IM IS IT JE " +
+		"LI LT LU LV MC MD ME MK MT NL NO PL PT RO RS RU SE SI SJ SK SM UA VA XK",
+	ContinentNorthAmerica: "AG AI AW BB BL BM BQ BS BZ CA CR CU CW DM DO GD GL GP GT HN HT JM KN KY LC MF MQ MS " +
+		"MX NI PA PM PR SV SX TC TT US VC VG VI",
+	ContinentOceania:      "AS AU CK FJ FM GU KI MH MP NC NF NR NU NZ PF PG PN PW SB TK TO TV UM VU WF WS",
+	ContinentSouthAmerica: "AR BO BR CL CO EC FK GF GY PE PY SR UY VE",
+}
+
+// countryContinents maps a country code to its continent.
+var countryContinents = func() map[string]Continent {
+	m := map[string]Continent{}
+	for c, countries := range continentCountries {
+		for _, code := range strings.Fields(countries) {
+			m[code] = c
+		}
+	}
+	return m
+}()
+
+// ContinentOf returns the continent of the ISO 3166-1 alpha-2 country code, or ContinentUnknown for
+// an unknown code and the "-" of reserved ranges.
+func ContinentOf(countryShort string) Continent {
+	return countryContinents[strings.ToUpper(countryShort)]
+}
+
+// Continent returns the continent of the country of x, or ContinentUnknown if the country is unknown.
+func (x IP2LocationRecord) Continent() Continent {
+	return ContinentOf(x.CountryShort)
+}
+
+// CountriesOf returns the ISO 3166-1 alpha-2 codes of the countries of continent c, sorted.
+func CountriesOf(c Continent) []string {
+	return strings.Fields(continentCountries[c])
+}
+
+// GroupByContinent adds up values keyed by country code per continent, e.g. the lookups per country
+// of a report, so dashboards can aggregate at the continent level. Values of unknown countries are
+// added up under ContinentUnknown.
+func GroupByContinent[N int | int64 | uint64 | float64](byCountry map[string]N) map[Continent]N {
+	out := make(map[Continent]N, len(Continents)+1)
+	for _, country := range slices.Sorted(maps.Keys(byCountry)) {
+		out[ContinentOf(country)] += byCountry[country]
+	}
+	return out
+}
@@ -0,0 +1,34 @@
+package ip2loc
+
+import "testing"
+
+func TestCompositionByContinent(t *testing.T) {
+	db, err := OpenBytes(buildDB(testRows.v4, testRows.v6))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	c, err := db.Composition()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	byContinent := c.ByContinent()
+	if len(byContinent) != 3 {
+		t.Fatalf("continents %v, want Europe, North America and the unassigned ranges", byContinent)
+	}
+	if unknown := byContinent[ContinentUnknown]; unknown == nil || unknown.Ranges != c.ByCountry["-"].Ranges {
+		t.Errorf("unassigned ranges: %+v, want those of %q", unknown, "-")
+	}
+	// GB, NL and IE
+	if eu := byContinent[ContinentEurope]; eu == nil || eu.Ranges != 3 || eu.IPv4Addresses != 256+8192 {
+		t.Errorf("Europe: %+v, want 3 ranges and %d IPv4 addresses", eu, 256+8192)
+	}
+	// US in both tables
+	if na := byContinent[ContinentNorthAmerica]; na == nil || na.Ranges != 2 || na.IPv4Addresses != 256 || na.IPv6Addresses == nil {
+		t.Errorf("North America: %+v, want 2 ranges", na)
+	}
+	if got := GroupByContinent(map[string]int{"us": 2, "CA": 3, "GB": 1, "-": 4}); got[ContinentNorthAmerica] != 5 || got[ContinentEurope] != 1 || got[ContinentUnknown] != 4 {
+		t.Errorf("GroupByContinent: %v", got)
+	}
+}
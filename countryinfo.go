@@ -0,0 +1,125 @@
+package ip2loc
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// CountryInfo holds the country details from the IP2Location country information CSV.
+type CountryInfo struct {
+	Code           string // ISO 3166-1 alpha-2
+	Name           string
+	Alpha3Code     string
+	NumericCode    string
+	Capital        string
+	Demonym        string
+	TotalArea      string // square kilometers
+	Population     string
+	CallingCode    string
+	CurrencyCode   string
+	CurrencyName   string
+	CurrencySymbol string
+	LanguageCode   string
+	LanguageName   string
+	TLD            string
+	Continent      string // only if the CSV has a continent column
+	IsEU           bool   // member state of the European Union
+}
+
+// euMembers lists the member states of the European Union by ISO 3166-1 alpha-2 code.
+var euMembers = map[string]bool{
+	"AT": true, "BE": true, "BG": true, "HR": true, "CY": true, "CZ": true, "DK": true,
+	"EE": true, "FI": true, "FR": true, "DE": true, "GR": true, "HU": true, "IE": true,
+	"IT": true, "LV": true, "LT": true, "LU": true, "MT": true, "NL": true, "PL": true,
+	"PT": true, "RO": true, "SK": true, "SI": true, "ES": true, "SE": true,
+}
+
+// IsEU reports whether the ISO 3166-1 alpha-2 country code belongs to a member state of the European Union.
+func IsEU(countryShort string) bool {
+	return euMembers[strings.ToUpper(countryShort)]
+}
+
+// CountryTable is a set of CountryInfo keyed by country code.
+type CountryTable struct {
+	countries map[string]CountryInfo
+}
+
+// LoadCountryInfo reads the IP2Location country information CSV. The header row is required;
+// columns are matched by name, so unknown columns are ignored and their order does not matter.
+func LoadCountryInfo(r io.Reader) (*CountryTable, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("ip2loc: reading country information header: %w", err)
+	}
+
+	cols := map[string]int{}
+	for i, name := range header {
+		cols[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := cols["country_code"]; !ok {
+		return nil, fmt.Errorf("ip2loc: country information has no country_code column")
+	}
+
+	t := &CountryTable{countries: map[string]CountryInfo{}}
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ip2loc: reading country information: %w", err)
+		}
+		get := func(names ...string) string {
+			for _, name := range names {
+				if i, ok := cols[name]; ok && i < len(row) {
+					return row[i]
+				}
+			}
+			return ""
+		}
+
+		c := CountryInfo{
+			Code:           strings.ToUpper(get("country_code")),
+			Name:           get("country_name"),
+			Alpha3Code:     get("country_alpha3_code"),
+			NumericCode:    get("country_numeric_code"),
+			Capital:        get("capital"),
+			Demonym:        get("country_demonym"),
+			TotalArea:      get("total_area"),
+			Population:     get("population"),
+			CallingCode:    get("idd_code"),
+			CurrencyCode:   get("currency_code"),
+			CurrencyName:   get("currency_name"),
+			CurrencySymbol: get("currency_symbol"),
+			LanguageCode:   get("lang_code"),
+			LanguageName:   get("lang_name"),
+			TLD:            get("cctld"),
+			Continent:      get("continent", "continent_name"),
+		}
+		c.IsEU = IsEU(c.Code)
+		t.countries[c.Code] = c
+	}
+	return t, nil
+}
+
+// Lookup returns the details of a country by its ISO 3166-1 alpha-2 code.
+func (t *CountryTable) Lookup(countryShort string) (CountryInfo, bool) {
+	c, ok := t.countries[strings.ToUpper(countryShort)]
+	return c, ok
+}
+
+// Len returns the number of known countries.
+func (t *CountryTable) Len() int {
+	return len(t.countries)
+}
+
+// WithCountryInfo fills the Country field of every lookup which includes the country.
+func WithCountryInfo(t *CountryTable) Option {
+	return func(d *DB) {
+		d.countryInfo = t
+	}
+}
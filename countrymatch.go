@@ -0,0 +1,154 @@
+package ip2loc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+// CountryMatcher tells whether an address belongs to one of a set of countries, e.g. for geo-blocking
+// with an allow or deny list. It holds the address ranges of those countries, adjacent ranges merged,
+// so a check is a binary search over integers which neither reads the database nor allocates. It is
+// a snapshot of the database it was compiled from; compile it again after a reload.
+type CountryMatcher struct {
+	v4 []uint32 // starts and ends, inclusive, of the ranges: v4[2*i] to v4[2*i+1]
+	v6 []uint128
+
+	// v4index holds, for each /16 and one past the last, the index of the first bound of v4 in it or
+	// above, narrowing the search of large lists to the bounds of one /16; nil for short lists
+	v4index []uint32
+}
+
+// countryIndexMin is the number of IPv4 bounds from which a CountryMatcher indexes them by /16.
+const countryIndexMin = 4096
+
+// CompileCountryMatcher scans db once and returns a CountryMatcher of the addresses located in the
+// given countries, named by their ISO 3166-1 alpha-2 codes as in IP2LocationRecord.CountryShort and
+// matched regardless of case. It returns ErrUnsupportedField if db has no country field.
+func CompileCountryMatcher(db *DB, countries ...string) (*CountryMatcher, error) {
+	db.mu.RLock()
+	enabled := db.dbState != nil && db.countryEnabled
+	db.mu.RUnlock()
+	if !enabled {
+		return nil, wrapError(fmt.Errorf("%w: CountryShort", ErrUnsupportedField))
+	}
+
+	set := make(map[string]bool, len(countries))
+	for _, c := range countries {
+		set[strings.ToUpper(strings.TrimSpace(c))] = true
+	}
+	m := &CountryMatcher{}
+	err := db.walkAll(func(r RangeRecord) bool {
+		if !set[r.Record.CountryShort] {
+			return true
+		}
+		if r.First.Is4() {
+			m.v4 = appendRange(m.v4, binary.BigEndian.Uint32(r.First.AsSlice()), binary.BigEndian.Uint32(r.Last.AsSlice()),
+				func(last, first uint32) bool { return last+1 == first })
+		} else {
+			m.v6 = appendRange(m.v6, addrUint128(r.First), addrUint128(r.Last),
+				func(last, first uint128) bool { return last.next() == first })
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	m.v4, m.v6 = slices.Clip(m.v4), slices.Clip(m.v6)
+	if len(m.v4) >= countryIndexMin {
+		m.v4index = make([]uint32, 1<<16+1)
+		i := 0
+		for p := range m.v4index {
+			for i < len(m.v4) && uint64(m.v4[i]) < uint64(p)<<16 {
+				i++
+			}
+			m.v4index[p] = uint32(i)
+		}
+	}
+	return m, nil
+}
+
+// appendRange appends first to last to the ranges of s, extending the last range if it ends right
+// before first.
+func appendRange[T any](s []T, first, last T, adjacent func(last, first T) bool) []T {
+	if n := len(s); n > 0 && adjacent(s[n-1], first) {
+		s[n-1] = last
+		return s
+	}
+	return append(s, first, last)
+}
+
+// Contains reports whether addr is located in one of the countries of m. Like lookups, IPv4-mapped,
+// 6to4 and Teredo addresses are matched by their IPv4 address.
+func (m *CountryMatcher) Contains(addr netip.Addr) bool {
+	if addr.Is6() {
+		b := addr.As16()
+		switch {
+		case addr.Is4In6():
+			addr = addr.Unmap()
+		case b[0] == 0x20 && b[1] == 0x02:
+			// 6to4
+			return m.contains4(binary.BigEndian.Uint32(b[2:6]))
+		case b[0] == 0x20 && b[1] == 0x01 && b[2] == 0 && b[3] == 0:
+			// Teredo
+			return m.contains4(^binary.BigEndian.Uint32(b[12:]))
+		default:
+			return m.contains6(uint128{binary.BigEndian.Uint64(b[:8]), binary.BigEndian.Uint64(b[8:])})
+		}
+	}
+	if !addr.Is4() {
+		return false
+	}
+	b := addr.As4()
+	return m.contains4(binary.BigEndian.Uint32(b[:]))
+}
+
+// ContainsIP is Contains for an address string; it is false for an invalid address.
+func (m *CountryMatcher) ContainsIP(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	return err == nil && m.Contains(addr)
+}
+
+// Ranges returns the number of address ranges of m, after merging adjacent ones.
+func (m *CountryMatcher) Ranges() int {
+	return len(m.v4)/2 + len(m.v6)/2
+}
+
+func (m *CountryMatcher) contains4(n uint32) bool {
+	lo, hi := 0, len(m.v4)
+	if m.v4index != nil {
+		lo, hi = int(m.v4index[n>>16]), int(m.v4index[n>>16+1])
+	}
+	// the index of the first bound above n is odd inside a range
+	i, found := slices.BinarySearch(m.v4[lo:hi], n)
+	return found || (lo+i)%2 == 1
+}
+
+func (m *CountryMatcher) contains6(n uint128) bool {
+	i, found := slices.BinarySearchFunc(m.v6, n, func(a, b uint128) int {
+		switch {
+		case a.less(b):
+			return -1
+		case b.less(a):
+			return 1
+		}
+		return 0
+	})
+	return found || i%2 == 1
+}
+
+// addrUint128 returns the number of an IPv6 address.
+func addrUint128(a netip.Addr) uint128 {
+	b := a.As16()
+	return uint128{hi: binary.BigEndian.Uint64(b[:8]), lo: binary.BigEndian.Uint64(b[8:])}
+}
+
+// next returns a+1, wrapping around after the last address.
+func (a uint128) next() uint128 {
+	if a.lo == ^uint64(0) {
+		return uint128{hi: a.hi + 1}
+	}
+	return uint128{hi: a.hi, lo: a.lo + 1}
+}
@@ -0,0 +1,74 @@
+package ip2loc
+
+import (
+	"encoding/binary"
+	"math/rand/v2"
+	"net/netip"
+	"testing"
+)
+
+func TestCountryMatcherAgreesWithLookups(t *testing.T) {
+	db, err := OpenBytes(buildDB(testRows.v4, testRows.v6))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	m, err := CompileCountryMatcher(db, "us", "GB")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ips := []string{
+		"8.8.8.0", "8.8.8.255", "8.8.9.0", "8.8.7.255", "81.2.69.160", "193.0.10.1", "0.0.0.0", "255.255.255.255",
+		"2001:4860::8888", "2001:4861::", "2a00:1450:4001::1", "::", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff",
+		"::ffff:8.8.4.4", "::ffff:8.8.8.8", "2002:808:808::1", "2002:c100:1::", "2001:0:1234::f7f7:f7f7",
+	}
+	for _, ip := range ips {
+		x, err := db.GetCountryShort(ip)
+		if err != nil {
+			t.Fatalf("%s: %v", ip, err)
+		}
+		want := x.CountryShort == "US" || x.CountryShort == "GB"
+		if got := m.Contains(netip.MustParseAddr(ip)); got != want {
+			t.Errorf("Contains(%s) = %v, the country is %s", ip, got, x.CountryShort)
+		}
+	}
+}
+
+func TestCountryMatcherIndex(t *testing.T) {
+	// enough ranges to be indexed by /16, a few of them within a /16
+	countries := []string{"US", "GB", "NL", "-"}
+	var rows []testRow
+	for i := range 1 << 13 {
+		from := uint32(i) << 19
+		if i%5 == 0 {
+			from |= uint32(i) & 0xffff
+		}
+		rows = append(rows, testRow{from: netip.AddrFrom4([4]byte(binary.BigEndian.AppendUint32(nil, from))).String(), country: countries[i*7%len(countries)]})
+	}
+	db, err := OpenBytes(buildDB(rows, testRows.v6))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	m, err := CompileCountryMatcher(db, "US", "NL")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.v4index == nil {
+		t.Fatalf("%d ranges are not indexed", m.Ranges())
+	}
+
+	r := rand.New(rand.NewPCG(1, 2))
+	for range 10000 {
+		addr := netip.AddrFrom4([4]byte(binary.BigEndian.AppendUint32(nil, r.Uint32())))
+		x, err := db.GetCountryShort(addr.String())
+		if err != nil {
+			t.Fatalf("%s: %v", addr, err)
+		}
+		want := x.CountryShort == "US" || x.CountryShort == "NL"
+		if got := m.Contains(addr); got != want {
+			t.Fatalf("Contains(%s) = %v, the country is %s", addr, got, x.CountryShort)
+		}
+	}
+}
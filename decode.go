@@ -0,0 +1,161 @@
+package ip2loc
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// decodePlan maps the tagged fields of a struct type to record fields.
+type decodePlan struct {
+	mode     uint32
+	bindings []decodeBinding
+}
+
+type decodeBinding struct {
+	index []int
+	field recordField
+}
+
+var decodePlans sync.Map // of reflect.Type to *decodePlan
+
+// DecodeInto looks up ip and fills the struct dst points to. Struct fields are mapped to record fields
+// with tags holding their snake_case names, as used by the other output formats:
+//
+//	type Visitor struct {
+//		Country string  `ip2loc:"country_short"`
+//		City    string  `ip2loc:"city"`
+//		Lat     float64 `ip2loc:"latitude"`
+//	}
+//
+// Only the mapped fields are read from the database. Fields may be of any string or float kind;
+// float record fields may also be decoded into strings. Placeholder messages become empty strings.
+// Fields without a tag or tagged "-" are left alone. Nil pointers to embedded structs holding
+// mapped fields are allocated; an unexported one cannot be and is an error. As with Scanner, dst is
+// filled for addresses which are invalid or not found, and the error is returned.
+func (d *DB) DecodeInto(ip string, dst any) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("ip2loc: DecodeInto needs a non-nil pointer to a struct, got %T", dst)
+	}
+	plan, err := planFor(v.Elem().Type())
+	if err != nil {
+		return err
+	}
+	if len(plan.bindings) == 0 {
+		return nil
+	}
+
+	x, err := d.query(ip, plan.mode)
+	if err != nil && !errors.Is(err, ErrInvalidIP) && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	v = v.Elem()
+	for _, b := range plan.bindings {
+		f, ferr := v.FieldByIndexErr(b.index)
+		if ferr != nil {
+			if f, ferr = allocField(v, b.index); ferr != nil {
+				return ferr
+			}
+		}
+		switch {
+		case f.Kind() == reflect.String:
+			f.SetString(b.field.value(&x))
+		case b.field.isFloat && f.Kind() == reflect.Float32:
+			f.SetFloat(float64(b.field.float(&x)))
+		default:
+			f.SetFloat(b.field.float64(&x))
+		}
+	}
+	return err
+}
+
+// allocField returns the field of the struct v at index, allocating the nil pointers to embedded
+// structs on the way. Unexported embedded pointers cannot be set and are an error.
+func allocField(v reflect.Value, index []int) (reflect.Value, error) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				if !v.CanSet() {
+					return reflect.Value{}, fmt.Errorf("ip2loc: cannot allocate the nil embedded %s to decode into", v.Type())
+				}
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, nil
+}
+
+// planFor returns the cached decodePlan of t, building it on first use.
+func planFor(t reflect.Type) (*decodePlan, error) {
+	if p, ok := decodePlans.Load(t); ok {
+		return p.(*decodePlan), nil
+	}
+
+	plan := &decodePlan{}
+	for _, sf := range reflect.VisibleFields(t) {
+		tag, ok := sf.Tag.Lookup("ip2loc")
+		if !ok || tag == "-" {
+			continue
+		}
+		if !sf.IsExported() {
+			return nil, fmt.Errorf("ip2loc: field %s.%s is tagged but unexported", t, sf.Name)
+		}
+		f, ok := fieldByName(tag)
+		if !ok {
+			return nil, fmt.Errorf("ip2loc: unknown field %q in tag of %s.%s", tag, t, sf.Name)
+		}
+		switch sf.Type.Kind() {
+		case reflect.String, reflect.Float32, reflect.Float64:
+		default:
+			return nil, fmt.Errorf("ip2loc: cannot decode %s into %s.%s of type %s", tag, t, sf.Name, sf.Type)
+		}
+		plan.mode |= f.mode
+		plan.bindings = append(plan.bindings, decodeBinding{index: sf.Index, field: f})
+	}
+
+	p, _ := decodePlans.LoadOrStore(t, plan)
+	return p.(*decodePlan), nil
+}
+
+// GetField looks up ip and returns the field called name, e.g. "city", so that fields can be chosen
+// at run time, for example from a configuration file. Only that field is read from the database.
+// T may be string, float32, float64 or any; strings hold floats in their shortest representation
+// and placeholder messages are returned as empty strings. The zero value is returned with any error.
+func GetField[T any](db *DB, ip, name string) (T, error) {
+	var zero T
+	f, ok := fieldByName(name)
+	if !ok {
+		return zero, fmt.Errorf("ip2loc: unknown field %q", name)
+	}
+	x, err := db.query(ip, f.mode)
+	if err != nil {
+		return zero, err
+	}
+
+	var v any
+	switch any(zero).(type) {
+	case string:
+		v = f.value(&x)
+	case float32:
+		if !f.isFloat {
+			return zero, fmt.Errorf("ip2loc: field %q is not a number", name)
+		}
+		v = f.float(&x)
+	case float64:
+		if !f.isFloat {
+			return zero, fmt.Errorf("ip2loc: field %q is not a number", name)
+		}
+		v = f.float64(&x)
+	default:
+		v, _ = x.Field(name)
+	}
+	t, ok := v.(T)
+	if !ok {
+		return zero, fmt.Errorf("ip2loc: cannot return field %q as %T", name, zero)
+	}
+	return t, nil
+}
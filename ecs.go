@@ -0,0 +1,63 @@
+package ip2loc
+
+import "strings"
+
+// ecsFields maps record fields to Elastic Common Schema fields, relative to the prefix.
+var ecsFields = []struct {
+	name string
+	str  func(x *IP2LocationRecord) string
+}{
+	{"geo.country_iso_code", func(x *IP2LocationRecord) string { return x.CountryShort }},
+	{"geo.country_name", func(x *IP2LocationRecord) string { return x.CountryLong }},
+	{"geo.region_name", func(x *IP2LocationRecord) string { return x.Region }},
+	{"geo.region_iso_code", func(x *IP2LocationRecord) string { return x.RegionCode }},
+	{"geo.city_name", func(x *IP2LocationRecord) string { return x.City }},
+	{"geo.postal_code", func(x *IP2LocationRecord) string { return x.ZipCode }},
+	{"geo.timezone", func(x *IP2LocationRecord) string { return x.Timezone }},
+	{"as.organization.name", func(x *IP2LocationRecord) string { return x.Isp }},
+}
+
+// ECSFlat returns the record as Elastic Common Schema fields with dotted names below prefix, e.g.
+// "client.geo.city_name" for the prefix "client". Use the prefix of the address that was looked up,
+// such as "client", "source" or "destination"; an empty prefix gives top-level "geo.*" names.
+// Empty fields, the "-" IP2Location uses for unknown values and placeholder messages are left out.
+// The location is set as a geo_point {"lat": ..., "lon": ...} unless both coordinates are zero,
+// which is what databases without coordinates return.
+func (x IP2LocationRecord) ECSFlat(prefix string) map[string]any {
+	if prefix != "" && !strings.HasSuffix(prefix, ".") {
+		prefix += "."
+	}
+	m := make(map[string]any, len(ecsFields)+1)
+	for _, f := range ecsFields {
+		if s := f.str(&x); s != "" && !isPlaceholder(s) && s != "-" {
+			m[prefix+f.name] = s
+		}
+	}
+	if x.Latitude != 0 || x.Longitude != 0 {
+		m[prefix+"geo.location"] = map[string]any{"lat": x.Latitude64(), "lon": x.Longitude64()}
+	}
+	return m
+}
+
+// ECS returns the fields of ECSFlat as nested objects, as Elasticsearch stores them:
+//
+//	{"client": {"geo": {"city_name": "Mountain View", "location": {"lat": 37.405991, "lon": -122.078514}}}}
+//
+// The result can be merged into an event document before it is shipped by Filebeat, Vector or Fluentd.
+func (x IP2LocationRecord) ECS(prefix string) map[string]any {
+	doc := map[string]any{}
+	for name, v := range x.ECSFlat(prefix) {
+		obj := doc
+		path := strings.Split(name, ".")
+		for _, key := range path[:len(path)-1] {
+			next, ok := obj[key].(map[string]any)
+			if !ok {
+				next = map[string]any{}
+				obj[key] = next
+			}
+			obj = next
+		}
+		obj[path[len(path)-1]] = v
+	}
+	return doc
+}
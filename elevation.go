@@ -0,0 +1,108 @@
+package ip2loc
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidElevation is returned by GetElevation and ElevationValue when the elevation column of
+	// the matched row does not hold a number.
+	ErrInvalidElevation = errors.New("ip2loc: invalid elevation")
+	// ErrNoElevation is returned by ElevationValue for a record without an elevation: the database
+	// has no elevation column, the field was not looked up, or the row holds "-".
+	ErrNoElevation = errors.New("ip2loc: no elevation")
+)
+
+// ElevationUnit is the unit of IP2LocationRecord.Elevation.
+type ElevationUnit int
+
+const (
+	// ElevationMeters is the unit of the database. It is the default.
+	ElevationMeters ElevationUnit = iota
+	// ElevationFeet converts elevations to international feet.
+	ElevationFeet
+)
+
+// metersPerFoot is the length of the international foot.
+const metersPerFoot = 0.3048
+
+// WithElevationUnit sets the unit of the elevations of the records looked up.
+func WithElevationUnit(u ElevationUnit) Option {
+	return func(d *DB) {
+		d.elevUnit = u
+	}
+}
+
+// elevationState tells whether the elevation of a record was read from the database.
+type elevationState uint8
+
+const (
+	elevationAbsent elevationState = iota
+	elevationValid
+	elevationInvalid
+)
+
+// setElevation parses the elevation column of a row, in meters.
+func setElevation(x *IP2LocationRecord, s string) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "-" {
+		x.elevation = elevationAbsent
+		return
+	}
+	f, err := strconv.ParseFloat(s, 32)
+	if err != nil {
+		x.elevation, x.elevationStr = elevationInvalid, s
+		return
+	}
+	x.Elevation, x.elevation = float32(f), elevationValid
+}
+
+// convertElevation converts the elevation of x to the unit of d. A lookup of the elevation alone
+// fails if the row does not hold a number, rather than returning 0, a real elevation.
+func (d *DB) convertElevation(x *IP2LocationRecord, mode uint32) error {
+	if mode&elevation == 0 {
+		return nil
+	}
+	switch x.elevation {
+	case elevationValid:
+		if d.elevUnit == ElevationFeet {
+			x.Elevation = float32(float64(x.Elevation) / metersPerFoot)
+		}
+	case elevationInvalid:
+		if mode == elevation {
+			return x.elevationErr()
+		}
+	}
+	return nil
+}
+
+// HasElevation reports whether the elevation of the record was read from the database, so that
+// an Elevation of 0 is sea level rather than a missing or malformed value.
+func (x IP2LocationRecord) HasElevation() bool {
+	return x.elevation == elevationValid
+}
+
+// ElevationValue returns the elevation of the record, or ErrNoElevation if it has none and an
+// error wrapping ErrInvalidElevation if the database holds something other than a number. Unlike
+// GetElevation, lookups of several fields do not fail on such a value.
+func (x IP2LocationRecord) ElevationValue() (float32, error) {
+	switch x.elevation {
+	case elevationValid:
+		return x.Elevation, nil
+	case elevationInvalid:
+		return 0, x.elevationErr()
+	}
+	return 0, ErrNoElevation
+}
+
+// elevationErr returns the error wrapping ErrInvalidElevation if the elevation column of the row
+// of x did not hold a number, and nil otherwise.
+func (x IP2LocationRecord) elevationErr() error {
+	if x.elevation != elevationInvalid {
+		return nil
+	}
+	return fmt.Errorf("%w: %q", ErrInvalidElevation, x.elevationStr)
+}
@@ -0,0 +1,47 @@
+package ip2loc
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestElevation(t *testing.T) {
+	for _, tt := range []struct {
+		column  string
+		unit    ElevationUnit
+		want    float32
+		has     bool
+		wantErr error
+	}{
+		{"0", ElevationMeters, 0, true, nil},
+		{" 1609 ", ElevationMeters, 1609, true, nil},
+		{"-28", ElevationMeters, -28, true, nil},
+		{"3048", ElevationFeet, 10000, true, nil},
+		{"-", ElevationMeters, 0, false, ErrNoElevation},
+		{"", ElevationFeet, 0, false, ErrNoElevation},
+		{"n/a", ElevationMeters, 0, false, ErrInvalidElevation},
+	} {
+		d := &DB{elevUnit: tt.unit}
+		var x IP2LocationRecord
+		setElevation(&x, tt.column)
+		// a missing elevation is no error of a lookup
+		lookupErr := tt.wantErr
+		if lookupErr == ErrNoElevation {
+			lookupErr = nil
+		}
+		if err := d.convertElevation(&x, elevation); !errors.Is(err, lookupErr) {
+			t.Errorf("%q: lookup of the elevation alone: %v", tt.column, err)
+		}
+		x = IP2LocationRecord{}
+		setElevation(&x, tt.column)
+		if err := d.convertElevation(&x, all); err != nil {
+			t.Errorf("%q: lookup of all fields: %v", tt.column, err)
+		}
+
+		got, err := x.ElevationValue()
+		if x.HasElevation() != tt.has || !errors.Is(err, tt.wantErr) || math.Abs(float64(got-tt.want)) > 1e-3 {
+			t.Errorf("%q in unit %d: %v, %v, HasElevation %v, want %v, %v, %v", tt.column, tt.unit, got, err, x.HasElevation(), tt.want, tt.wantErr, tt.has)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package ip2loc
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrDecrypt is returned when an encrypted database cannot be decrypted with the given key.
+var ErrDecrypt = errors.New("ip2loc: cannot decrypt database")
+
+// EncryptDB encrypts the database read from r with AES-GCM and writes it to w in the format read by
+// OpenEncryptedDB: a random 12 byte nonce followed by the sealed data.
+func EncryptDB(w io.Writer, r io.Reader, key []byte) error {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+	plaintext, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	_, err = w.Write(gcm.Seal(nonce, nonce, plaintext, nil))
+	return err
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("ip2loc: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+func decrypt(data, key []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("%w: file too short", ErrDecrypt)
+	}
+	nonce, sealed := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	plaintext, err := gcm.Open(sealed[:0], nonce, sealed, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrDecrypt, err)
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,49 @@
+package ip2loc
+
+// enrichesCountry reports whether enrich needs the country of the row for a lookup of mode.
+func (d *DB) enrichesCountry(mode uint32) bool {
+	if !d.countryEnabled {
+		return false
+	}
+	return d.regionCodes != nil && mode&region != 0 && d.regionEnabled ||
+		d.countryInfo != nil && mode&(countryShort|countryLong) != 0 ||
+		d.translations != nil && mode&(countryLong|region|city) != 0
+}
+
+// enrich fills the record fields which come from supplementary data sets rather than the BIN file.
+// country is the short country name of the row, read if enrichesCountry.
+func (d *DB) enrich(x *IP2LocationRecord, country string, mode uint32) {
+	if !d.enrichesCountry(mode) {
+		return
+	}
+	wantRegionCode := d.regionCodes != nil && mode&region != 0 && d.regionEnabled
+	wantCountry := d.countryInfo != nil && mode&(countryShort|countryLong) != 0
+	wantTranslation := d.translations != nil && mode&(countryLong|region|city) != 0
+
+	if wantRegionCode {
+		x.RegionCode, _ = d.regionCodes.Lookup(country, x.Region)
+	}
+	if wantCountry {
+		if c, ok := d.countryInfo.Lookup(country); ok {
+			x.Country = &c
+		}
+	}
+	if wantTranslation {
+		// region and city are translated by their English names, so this has to come last
+		if mode&countryLong != 0 {
+			if s, ok := d.translations.Country(d.language, country); ok {
+				x.CountryLong = s
+			}
+		}
+		if mode&region != 0 && d.regionEnabled {
+			if s, ok := d.translations.Region(d.language, country, x.Region); ok {
+				x.Region = s
+			}
+		}
+		if mode&city != 0 && d.cityEnabled {
+			if s, ok := d.translations.City(d.language, country, x.City); ok {
+				x.City = s
+			}
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package ip2loc
+
+import "strings"
+
+// NetSpeed is the Internet connection type reported in the NetSpeed column.
+type NetSpeed string
+
+const (
+	NetSpeedUnknown NetSpeed = ""
+	NetSpeedDial    NetSpeed = "DIAL" // dial-up
+	NetSpeedDSL     NetSpeed = "DSL"  // broadband, cable, fiber or mobile
+	NetSpeedCompany NetSpeed = "COMP" // company network
+	NetSpeedT1      NetSpeed = "T1"   // high speed leased line
+)
+
+// ParseNetSpeed converts a raw NetSpeed column value into a NetSpeed.
+// Unrecognised values return NetSpeedUnknown.
+func ParseNetSpeed(s string) NetSpeed {
+	switch n := NetSpeed(strings.ToUpper(strings.TrimSpace(s))); n {
+	case NetSpeedDial, NetSpeedDSL, NetSpeedCompany, NetSpeedT1:
+		return n
+	}
+	return NetSpeedUnknown
+}
+
+// Known reports whether n is one of the documented connection types.
+func (n NetSpeed) Known() bool {
+	return n != NetSpeedUnknown
+}
+
+// IsDialUp reports whether n is a dial-up connection.
+func (n NetSpeed) IsDialUp() bool {
+	return n == NetSpeedDial
+}
+
+// IsBroadband reports whether n is a consumer broadband connection.
+func (n NetSpeed) IsBroadband() bool {
+	return n == NetSpeedDSL
+}
+
+// IsCorporate reports whether n is a company network or leased line.
+func (n NetSpeed) IsCorporate() bool {
+	return n == NetSpeedCompany || n == NetSpeedT1
+}
+
+// UsageType is a single usage type code reported in the UsageType column.
+type UsageType string
+
+const (
+	UsageTypeCommercial   UsageType = "COM"
+	UsageTypeOrganization UsageType = "ORG"
+	UsageTypeGovernment   UsageType = "GOV"
+	UsageTypeMilitary     UsageType = "MIL"
+	UsageTypeEducation    UsageType = "EDU"
+	UsageTypeLibrary      UsageType = "LIB"
+	UsageTypeCDN          UsageType = "CDN"
+	UsageTypeISP          UsageType = "ISP"
+	UsageTypeMobile       UsageType = "MOB"
+	UsageTypeDataCenter   UsageType = "DCH"
+	UsageTypeSearchEngine UsageType = "SES"
+	UsageTypeReserved     UsageType = "RSV"
+	UsageTypeUnknown      UsageType = ""
+)
+
+// ParseUsageType splits a raw UsageType column value such as "ISP/MOB" into its codes.
+// Unrecognised codes are dropped.
+func ParseUsageType(s string) []UsageType {
+	var res []UsageType
+	for _, c := range strings.Split(s, "/") {
+		switch u := UsageType(strings.ToUpper(strings.TrimSpace(c))); u {
+		case UsageTypeCommercial, UsageTypeOrganization, UsageTypeGovernment, UsageTypeMilitary,
+			UsageTypeEducation, UsageTypeLibrary, UsageTypeCDN, UsageTypeISP, UsageTypeMobile,
+			UsageTypeDataCenter, UsageTypeSearchEngine, UsageTypeReserved:
+			res = append(res, u)
+		}
+	}
+	return res
+}
+
+// IsHosting reports whether u belongs to a data center, CDN or search engine spider.
+func (u UsageType) IsHosting() bool {
+	return u == UsageTypeDataCenter || u == UsageTypeCDN || u == UsageTypeSearchEngine
+}
+
+// IsAccessProvider reports whether u belongs to a fixed line or mobile ISP.
+func (u UsageType) IsAccessProvider() bool {
+	return u == UsageTypeISP || u == UsageTypeMobile
+}
+
+// ProxyType is the proxy type reported by IP2Proxy databases.
+type ProxyType string
+
+const (
+	ProxyTypeUnknown     ProxyType = ""
+	ProxyTypeVPN         ProxyType = "VPN" // anonymizing VPN service
+	ProxyTypeTor         ProxyType = "TOR" // Tor exit node
+	ProxyTypeDataCenter  ProxyType = "DCH" // hosting provider, data center or CDN
+	ProxyTypePublic      ProxyType = "PUB" // public proxy
+	ProxyTypeWeb         ProxyType = "WEB" // web proxy
+	ProxyTypeSearchBot   ProxyType = "SES" // search engine robot
+	ProxyTypeResidential ProxyType = "RES" // residential proxy
+	ProxyTypeConsumerVPN ProxyType = "CPN" // consumer privacy network
+	ProxyTypeEnterprise  ProxyType = "EPN" // enterprise private network
+)
+
+// ParseProxyType converts a raw IP2Proxy ProxyType column value into a ProxyType.
+// Unrecognised values return ProxyTypeUnknown.
+func ParseProxyType(s string) ProxyType {
+	switch p := ProxyType(strings.ToUpper(strings.TrimSpace(s))); p {
+	case ProxyTypeVPN, ProxyTypeTor, ProxyTypeDataCenter, ProxyTypePublic, ProxyTypeWeb,
+		ProxyTypeSearchBot, ProxyTypeResidential, ProxyTypeConsumerVPN, ProxyTypeEnterprise:
+		return p
+	}
+	return ProxyTypeUnknown
+}
+
+// Known reports whether p is one of the documented proxy types.
+func (p ProxyType) Known() bool {
+	return p != ProxyTypeUnknown
+}
+
+// IsAnonymizer reports whether p hides the real client address.
+func (p ProxyType) IsAnonymizer() bool {
+	switch p {
+	case ProxyTypeVPN, ProxyTypeTor, ProxyTypePublic, ProxyTypeWeb, ProxyTypeResidential, ProxyTypeConsumerVPN:
+		return true
+	}
+	return false
+}
+
+// IsHosting reports whether p is hosting infrastructure rather than an end user.
+func (p ProxyType) IsHosting() bool {
+	return p == ProxyTypeDataCenter || p == ProxyTypeSearchBot
+}
+
+// NetSpeedType returns the typed form of the NetSpeed field.
+func (x IP2LocationRecord) NetSpeedType() NetSpeed {
+	return ParseNetSpeed(x.NetSpeed)
+}
+
+// UsageTypes returns the typed codes of the UsageType field.
+func (x IP2LocationRecord) UsageTypes() []UsageType {
+	return ParseUsageType(x.UsageType)
+}
@@ -0,0 +1,145 @@
+package ip2loc
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// ErrorCode is a stable, machine-readable classification of an error. The string values are
+// part of the public contract and are the same in Go errors, HTTP bodies and gRPC status details.
+type ErrorCode string
+
+const (
+	CodeInvalidIP        ErrorCode = "INVALID_IP"
+	CodeNotFound         ErrorCode = "NOT_FOUND"
+	CodeUnsupportedField ErrorCode = "UNSUPPORTED_FIELD"
+	CodeDBCorrupt        ErrorCode = "DB_CORRUPT"
+	CodeDBStale          ErrorCode = "DB_STALE"
+	CodeTimeout          ErrorCode = "TIMEOUT"
+	CodeInternal         ErrorCode = "INTERNAL"
+)
+
+var (
+	// ErrInvalidIP is returned when the queried string is not an IPv4 or IPv6 address.
+	ErrInvalidIP = errors.New("ip2loc: invalid IP address")
+	// ErrNotFound is returned when no range of the database contains the queried address. The record
+	// then holds the default messages, as it did when the lookup succeeded with empty values.
+	ErrNotFound = errors.New("ip2loc: address not found in the database")
+	// ErrUnsupportedField is returned when a single field is requested that the loaded database does not contain.
+	ErrUnsupportedField = errors.New("ip2loc: field is unavailable for the loaded database")
+	// ErrUnsupportedDBType is returned when the database type is newer than this package. Such a database
+	// can still be opened if its country column can be located; looking up any other field returns this error.
+	ErrUnsupportedDBType = errors.New("ip2loc: unsupported database type")
+	// ErrIPVersionDisabled is returned when querying an address of the IP version excluded by
+	// WithIPv4Only or WithIPv6Only, or of an IP version the database has no rows for, such as an
+	// IPv6 address in an IPv4 edition.
+	ErrIPVersionDisabled = errors.New("ip2loc: IP version is disabled for this database")
+	// ErrClosed is returned by lookups on a closed DB.
+	ErrClosed = errors.New("ip2loc: database is closed")
+	// ErrNotLoaded is returned when querying a DB whose metadata could not be read.
+	ErrNotLoaded = errors.New("ip2loc: invalid database file")
+)
+
+// Error is the error type returned by lookups. Use errors.Is with the sentinel errors or
+// CodeOf to branch on the kind of failure.
+type Error struct {
+	Code ErrorCode
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// MarshalJSON encodes the error as {"code": "...", "message": "..."}.
+func (e *Error) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Code    ErrorCode `json:"code"`
+		Message string    `json:"message"`
+	}{e.Code, e.Error()})
+}
+
+// CodeOf returns the code of err. It is empty for a nil error and CodeInternal for errors
+// that did not originate from a lookup.
+func CodeOf(err error) ErrorCode {
+	if err == nil {
+		return ""
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return CodeInternal
+}
+
+// HTTPStatus returns the HTTP status code matching c.
+func (c ErrorCode) HTTPStatus() int {
+	switch c {
+	case "":
+		return 200 // OK
+	case CodeInvalidIP:
+		return 400 // Bad Request
+	case CodeNotFound:
+		return 404 // Not Found
+	case CodeUnsupportedField:
+		return 501 // Not Implemented
+	case CodeDBStale, CodeDBCorrupt:
+		return 503 // Service Unavailable
+	case CodeTimeout:
+		return 504 // Gateway Timeout
+	}
+	return 500 // Internal Server Error
+}
+
+// GRPCCode returns the numeric gRPC status code (as defined by google.golang.org/grpc/codes) matching c.
+func (c ErrorCode) GRPCCode() uint32 {
+	switch c {
+	case "":
+		return 0 // OK
+	case CodeInvalidIP:
+		return 3 // InvalidArgument
+	case CodeNotFound:
+		return 5 // NotFound
+	case CodeUnsupportedField:
+		return 12 // Unimplemented
+	case CodeDBStale:
+		return 14 // Unavailable
+	case CodeDBCorrupt:
+		return 15 // DataLoss
+	case CodeTimeout:
+		return 4 // DeadlineExceeded
+	}
+	return 13 // Internal
+}
+
+// wrapError attaches a code to an error returned by a lookup.
+func wrapError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return err
+	}
+
+	code := CodeInternal
+	switch {
+	case errors.Is(err, ErrInvalidIP):
+		code = CodeInvalidIP
+	case errors.Is(err, ErrNotFound):
+		code = CodeNotFound
+	case errors.Is(err, ErrUnsupportedField), errors.Is(err, ErrUnsupportedDBType), errors.Is(err, ErrIPVersionDisabled):
+		code = CodeUnsupportedField
+	case errors.Is(err, ErrTruncated), errors.Is(err, ErrNotLoaded), errors.Is(err, ErrInvalidElevation):
+		code = CodeDBCorrupt
+	case errors.Is(err, ErrStale):
+		code = CodeDBStale
+	case errors.Is(err, ErrTimeout):
+		code = CodeTimeout
+	}
+	return &Error{Code: code, Err: err}
+}
@@ -0,0 +1,157 @@
+package ip2loc
+
+import "strconv"
+
+// recordField describes a single IP2LocationRecord field under its snake_case name,
+// in the order the fields are declared. It is shared by the serialization formats.
+type recordField struct {
+	name    string
+	mode    uint32 // bit selecting the field in a query
+	isFloat bool
+	str     func(x *IP2LocationRecord) string
+	float   func(x *IP2LocationRecord) float32
+}
+
+var recordFields = []recordField{
+	{name: "country_short", mode: countryShort, str: func(x *IP2LocationRecord) string { return x.CountryShort }},
+	{name: "country_long", mode: countryLong, str: func(x *IP2LocationRecord) string { return x.CountryLong }},
+	{name: "region", mode: region, str: func(x *IP2LocationRecord) string { return x.Region }},
+	{name: "city", mode: city, str: func(x *IP2LocationRecord) string { return x.City }},
+	{name: "isp", mode: isp, str: func(x *IP2LocationRecord) string { return x.Isp }},
+	{name: "latitude", mode: latitude, isFloat: true, float: func(x *IP2LocationRecord) float32 { return x.Latitude }},
+	{name: "longitude", mode: longitude, isFloat: true, float: func(x *IP2LocationRecord) float32 { return x.Longitude }},
+	{name: "domain", mode: domain, str: func(x *IP2LocationRecord) string { return x.Domain }},
+	{name: "zip_code", mode: zipCode, str: func(x *IP2LocationRecord) string { return x.ZipCode }},
+	{name: "timezone", mode: timezone, str: func(x *IP2LocationRecord) string { return x.Timezone }},
+	{name: "net_speed", mode: netSpeed, str: func(x *IP2LocationRecord) string { return x.NetSpeed }},
+	{name: "idd_code", mode: iddCode, str: func(x *IP2LocationRecord) string { return x.IddCode }},
+	{name: "area_code", mode: areaCode, str: func(x *IP2LocationRecord) string { return x.AreaCode }},
+	{name: "weather_station_code", mode: weatherStationCode, str: func(x *IP2LocationRecord) string { return x.WeatherStationCode }},
+	{name: "weather_station_name", mode: weatherStationName, str: func(x *IP2LocationRecord) string { return x.WeatherStationName }},
+	{name: "mcc", mode: mcc, str: func(x *IP2LocationRecord) string { return x.MCC }},
+	{name: "mnc", mode: mnc, str: func(x *IP2LocationRecord) string { return x.MNC }},
+	{name: "mobile_brand", mode: mobileBrand, str: func(x *IP2LocationRecord) string { return x.MobileBrand }},
+	{name: "elevation", mode: elevation, isFloat: true, float: func(x *IP2LocationRecord) float32 { return x.Elevation }},
+	{name: "usage_type", mode: usageType, str: func(x *IP2LocationRecord) string { return x.UsageType }},
+	{name: "region_code", mode: region, str: func(x *IP2LocationRecord) string { return x.RegionCode }},
+}
+
+// fieldByName returns the recordField called name.
+func fieldByName(name string) (recordField, bool) {
+	for _, f := range recordFields {
+		if f.name == name {
+			return f, true
+		}
+	}
+	return recordField{}, false
+}
+
+// FieldNames returns the snake_case names of the record fields, in the order they are declared.
+// These are the names accepted by Field, GetField and DecodeInto tags.
+func FieldNames() []string {
+	names := make([]string, len(recordFields))
+	for i, f := range recordFields {
+		names[i] = f.name
+	}
+	return names
+}
+
+// Field returns the field of the record called name, e.g. "city", as a string or, for latitude,
+// longitude and elevation, a float32. Placeholder messages are returned as they are. ok is false
+// for an unknown name.
+func (x IP2LocationRecord) Field(name string) (value any, ok bool) {
+	f, ok := fieldByName(name)
+	if !ok {
+		return nil, false
+	}
+	if f.isFloat {
+		return f.float(&x), true
+	}
+	return f.str(&x), true
+}
+
+// GetAllAsMap looks up ip and returns the fields the loaded database contains keyed by their
+// snake_case names, with the values Field returns. Fields of other products are left out rather
+// than holding placeholder messages, as is region_code unless WithRegionCodes is set. The map is
+// nil when the lookup fails, including when the address is not found.
+func (d *DB) GetAllAsMap(ip string) (map[string]any, error) {
+	x, err := d.GetAll(ip)
+	if err != nil {
+		return nil, err
+	}
+
+	supported := d.loadedFields()
+	m := make(map[string]any, len(recordFields))
+	for _, f := range recordFields {
+		if f.mode&supported == 0 || f.name == "region_code" && d.regionCodes == nil {
+			continue
+		}
+		m[f.name], _ = x.Field(f.name)
+	}
+	return m, nil
+}
+
+// float64 returns the field of x as float64: coordinates are rounded as by Latitude64 and
+// string fields are parsed, giving 0 if they do not hold a number.
+func (f recordField) float64(x *IP2LocationRecord) float64 {
+	if f.isFloat {
+		return roundCoordinate(f.float(x))
+	}
+	g, _ := strconv.ParseFloat(f.value(x), 64)
+	return g
+}
+
+// loadedFields returns the query bits of the fields the loaded database contains.
+func (d *DB) loadedFields() uint32 {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.dbState == nil {
+		return 0
+	}
+	return d.supported
+}
+
+func isPlaceholder(s string) bool {
+	return s == parameterIsNotSupported || s == invalidAddress || s == missingFile
+}
+
+// value returns the field of x formatted as a string; floats use their shortest representation
+// and placeholder messages become empty.
+func (f recordField) value(x *IP2LocationRecord) string {
+	if f.isFloat {
+		return strconv.FormatFloat(float64(f.float(x)), 'f', -1, 32)
+	}
+	if s := f.str(x); !isPlaceholder(s) {
+		return s
+	}
+	return ""
+}
+
+// recordProperties returns the populated fields of x keyed by name, leaving out
+// empty fields and fields holding a placeholder message.
+func recordProperties(x *IP2LocationRecord) map[string]interface{} {
+	props := make(map[string]interface{}, len(recordFields))
+	for _, f := range recordFields {
+		if f.isFloat {
+			props[f.name] = jsonFloat32(f.float(x))
+			continue
+		}
+		if s := f.str(x); s != "" && !isPlaceholder(s) {
+			props[f.name] = s
+		}
+	}
+	return props
+}
+
+// WithoutPlaceholders returns a copy of the record with the messages filled in for unsupported
+// fields, invalid addresses and missing databases replaced by empty strings.
+func (x IP2LocationRecord) WithoutPlaceholders() IP2LocationRecord {
+	for _, s := range []*string{&x.CountryShort, &x.CountryLong, &x.Region, &x.City, &x.Isp, &x.Domain,
+		&x.ZipCode, &x.Timezone, &x.NetSpeed, &x.IddCode, &x.AreaCode, &x.WeatherStationCode,
+		&x.WeatherStationName, &x.MCC, &x.MNC, &x.MobileBrand, &x.UsageType, &x.RegionCode} {
+		if isPlaceholder(*s) {
+			*s = ""
+		}
+	}
+	return x
+}
@@ -0,0 +1,227 @@
+//go:build !ip2loc_nofs
+
+// Opening a database by its path needs a file system. Edge runtimes such as js/wasm workers and
+// TinyGo targets without one build with the ip2loc_nofs tag and open an embedded database with OpenBytes.
+
+package ip2loc
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// OpenInMemoryDB takes the path to the IP2Location BIN database file. It will read all file data
+// and return the underlining DB object.
+func OpenInMemoryDB(dbpath string, opts ...Option) (*DB, error) {
+	open := func(d *DB, sum string) (DBReader, error) {
+		fileData, err := d.readLocked(dbpath, sum)
+		if err != nil {
+			return nil, err
+		}
+		return NewByteSliceReader(fileData), nil
+	}
+	return openFile(dbpath, open, opts)
+}
+
+// Open takes the path to the IP2Location BIN database file. It will read all the metadata required to
+// be able to extract the embedded geolocation data, and return the underlining DB object.
+func OpenDB(dbpath string, opts ...Option) (*DB, error) {
+	open := func(d *DB, sum string) (DBReader, error) {
+		return d.openLocked(dbpath, sum)
+	}
+	return openFile(dbpath, open, opts)
+}
+
+// openFile opens the database file at dbpath with open, which is kept to reopen the file later. The
+// checksum given with WithChecksum is verified by the first open only: it pins the file being opened,
+// not the updates later renamed over it.
+func openFile(dbpath string, open func(d *DB, sum string) (DBReader, error), opts []Option) (*DB, error) {
+	db := newDB(opts)
+	db.path = dbpath
+	db.reopen = func() (DBReader, error) {
+		return open(db, "")
+	}
+
+	reader, err := open(db, db.checksum)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.init(reader); err != nil {
+		return nil, err
+	}
+	if db.replaceWatch {
+		db.watchReplace(dbpath)
+	}
+	return db, nil
+}
+
+// openLocked opens the file at path read-only under a shared lock, failing with ErrLocked if it is
+// locked exclusively and WithFailIfLocked is given. Unless sum is empty, the file opened must have
+// the SHA-256 checksum sum; it is hashed through the same descriptor, so it cannot be swapped between
+// the check and the open.
+func (d *DB) openLocked(path, sum string) (*os.File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if !lockShared(f) && d.strictLock {
+		_ = f.Close()
+		return nil, fmt.Errorf("%w: %s", ErrLocked, path)
+	}
+	if sum != "" {
+		// a section reader leaves the offset of f at the start for the reads that follow
+		if err := verifySum(io.NewSectionReader(f, 0, 1<<63-1), path, sum); err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// readLocked reads the file at path like os.ReadFile, under a shared lock taken by openLocked. Unless
+// sum is empty, the data read must have the SHA-256 checksum sum.
+func (d *DB) readLocked(path, sum string) ([]byte, error) {
+	f, err := d.openLocked(path, "")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var buf bytes.Buffer
+	if fi, err := f.Stat(); err == nil {
+		buf.Grow(int(fi.Size()) + bytes.MinRead)
+	}
+	if _, err = buf.ReadFrom(f); err != nil {
+		return nil, err
+	}
+	if sum != "" {
+		if err := verifySum(bytes.NewReader(buf.Bytes()), path, sum); err != nil {
+			return nil, err
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// OpenCompressedDB takes the path to a zipped or gzipped IP2Location BIN database file, as shipped by
+// IP2Location. The database is decompressed into memory and opened like OpenInMemoryDB. The first
+// entry with a .BIN extension is used from a zip archive. Uncompressed files are opened as they are.
+func OpenCompressedDB(dbpath string, opts ...Option) (*DB, error) {
+	open := func(d *DB, sum string) (DBReader, error) {
+		data, err := d.readLocked(dbpath, sum)
+		if err != nil {
+			return nil, err
+		}
+		if data, err = decompress(data); err != nil {
+			return nil, fmt.Errorf("ip2loc: decompressing %s: %w", dbpath, err)
+		}
+		return NewByteSliceReader(data), nil
+	}
+	return openFile(dbpath, open, opts)
+}
+
+// OpenEncryptedDB takes the path to an AES-GCM encrypted IP2Location BIN database file, as written by
+// EncryptDB, and the 16, 24 or 32 byte AES key. The database is decrypted into memory and opened like
+// OpenInMemoryDB; the plaintext never touches the disk. The decrypted data may be zipped or gzipped.
+func OpenEncryptedDB(dbpath string, key []byte, opts ...Option) (*DB, error) {
+	open := func(d *DB, sum string) (DBReader, error) {
+		data, err := d.readLocked(dbpath, sum)
+		if err != nil {
+			return nil, err
+		}
+		if data, err = decrypt(data, key); err != nil {
+			return nil, err
+		}
+		if data, err = decompress(data); err != nil {
+			return nil, fmt.Errorf("ip2loc: decompressing %s: %w", dbpath, err)
+		}
+		return NewByteSliceReader(data), nil
+	}
+	return openFile(dbpath, open, opts)
+}
+
+// OpenCachedDB takes the path to the IP2Location BIN database file and opens it like OpenDB, reading
+// the file through a BlockCacheReader which holds up to budget bytes in blocks of blockSize bytes.
+func OpenCachedDB(dbpath string, blockSize int, budget int64, opts ...Option) (*DB, error) {
+	open := func(d *DB, sum string) (DBReader, error) {
+		f, err := d.openLocked(dbpath, sum)
+		if err != nil {
+			return nil, err
+		}
+		return NewBlockCacheReader(f, blockSize, budget), nil
+	}
+	return openFile(dbpath, open, opts)
+}
+
+// Metadata describes a BIN database file without opening it for lookups.
+type Metadata struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+	Header
+	Fields []string // names of the fields the database contains, as returned by FieldNames
+}
+
+// OpenMetadataOnly reads and validates the header of the IP2Location BIN database file at dbpath and
+// closes the file again, so tools can catalog many databases without keeping them open. Besides
+// failing like OpenDB, it returns ErrTruncated if the tables declared by the header do not fit in the file.
+func OpenMetadataOnly(dbpath string) (Metadata, error) {
+	f, err := os.Open(dbpath)
+	if err != nil {
+		return Metadata{}, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return Metadata{}, err
+	}
+	state, err := (&DB{}).load(f)
+	if err != nil {
+		return Metadata{}, err
+	}
+	// the header of a file that is no database may parse; its tables then reach beyond the end
+	db := &DB{dbState: state}
+	if err := db.checkTable(state.meta.ipv4DatabaseAddr, state.meta.ipv4DatabaseCount, state.meta.ipv4ColumnSize); err != nil {
+		return Metadata{}, err
+	}
+	if err := db.checkTable(state.meta.ipv6DatabaseAddr, state.meta.ipv6DatabaseCount, state.meta.ipv6ColumnSize); err != nil {
+		return Metadata{}, err
+	}
+
+	md := Metadata{Path: dbpath, Size: fi.Size(), ModTime: fi.ModTime(), Header: state.header()}
+	for _, field := range recordFields {
+		if field.mode&state.supported != 0 && field.name != "region_code" {
+			md.Fields = append(md.Fields, field.name)
+		}
+	}
+	return md, nil
+}
+
+// VerifyChecksum checks that the SHA-256 checksum of the file at path equals the hex encoded sha256hex.
+func VerifyChecksum(path, sha256hex string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return verifySum(f, path, sha256hex)
+}
+
+// verifySum checks that the SHA-256 checksum of the data of r, read from the file at path, equals
+// the hex encoded sha256hex.
+func verifySum(r io.Reader, path, sha256hex string) error {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return err
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); sum != strings.ToLower(strings.TrimSpace(sha256hex)) {
+		return fmt.Errorf("%w: %s has sha256 %s, expected %s", ErrChecksumMismatch, path, sum, sha256hex)
+	}
+	return nil
+}
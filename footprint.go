@@ -0,0 +1,72 @@
+package ip2loc
+
+import "unsafe"
+
+// mapEntryOverhead approximates the bytes a map entry of two strings takes besides the string data.
+const mapEntryOverhead = 48
+
+// Footprint is the memory held by a DB in bytes, broken down by what holds it.
+type Footprint struct {
+	Data        int64 // database held in memory, e.g. by OpenInMemoryDB or cached by OpenCachedDB; 0 when it is read from disk
+	RangeIndex  int64 // addresses loaded by WithRangeIndex
+	PrefixTable int64 // row spans loaded by WithPrefixTable
+	Tables      int64 // estimate for the region code, country information and translation tables
+}
+
+// Total returns the sum of all parts.
+func (f Footprint) Total() int64 {
+	return f.Data + f.RangeIndex + f.PrefixTable + f.Tables
+}
+
+// MemoryFootprint reports the memory held by the database, so the cost of the in-memory modes and
+// of the lookup tables can be weighed before enabling them. Memory of the page cache used when the
+// database is read from disk is not included.
+func (d *DB) MemoryFootprint() Footprint {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	var f Footprint
+	if d.dbState != nil && !d.closed {
+		switch r := d.f.(type) {
+		case *ByteSliceReader:
+			f.Data = int64(len(r.Bytes()))
+		case *InMemoryDBReader:
+			f.Data = r.Size()
+		case *partialReader:
+			f.Data = r.len()
+		case *BlockCacheReader:
+			f.Data = r.Stats().Bytes
+		}
+		if d.ranges != nil {
+			f.RangeIndex = int64(len(d.ranges.v4))*4 + int64(len(d.ranges.v4buckets))*4 +
+				int64(len(d.ranges.v6))*int64(unsafe.Sizeof(uint128{})) + int64(len(d.ranges.v6buckets))*4
+		}
+		f.PrefixTable = int64(len(d.prefixes)) * 4
+	}
+
+	if d.regionCodes != nil {
+		f.Tables += stringMapSize(d.regionCodes.codes)
+	}
+	if d.translations != nil {
+		f.Tables += stringMapSize(d.translations.names)
+	}
+	if d.countryInfo != nil {
+		for code, c := range d.countryInfo.countries {
+			f.Tables += int64(len(code)) + int64(unsafe.Sizeof(c)) + mapEntryOverhead
+			for _, s := range []string{c.Code, c.Name, c.Alpha3Code, c.NumericCode, c.Capital, c.Demonym,
+				c.TotalArea, c.Population, c.CallingCode, c.CurrencyCode, c.CurrencyName, c.CurrencySymbol,
+				c.LanguageCode, c.LanguageName, c.TLD, c.Continent} {
+				f.Tables += int64(len(s))
+			}
+		}
+	}
+	return f
+}
+
+func stringMapSize(m map[string]string) int64 {
+	var n int64
+	for k, v := range m {
+		n += int64(len(k)+len(v)) + mapEntryOverhead
+	}
+	return n
+}
@@ -0,0 +1,116 @@
+package ip2loc
+
+import (
+	"math/big"
+	"net/netip"
+	"time"
+)
+
+// The functions in this file expose the parsing steps of the package on plain inputs, so they can
+// be driven by go test -fuzz or other fuzzers without a database file on disk.
+
+// IPClass is the kind of address a lookup string was classified as.
+type IPClass int
+
+const (
+	IPInvalid  IPClass = iota // not an IP address
+	IPv4                      // an IPv4 address
+	IPv6                      // an IPv6 address looked up in the IPv6 table
+	IPv4Mapped                // ::ffff:a.b.c.d, looked up as a.b.c.d
+	IP6to4                    // 2002::/16, looked up as the embedded IPv4 address
+	IPTeredo                  // 2001::/32, looked up as the inverted IPv4 client address
+)
+
+func (c IPClass) String() string {
+	switch c {
+	case IPv4:
+		return "IPv4"
+	case IPv6:
+		return "IPv6"
+	case IPv4Mapped:
+		return "IPv4-mapped"
+	case IP6to4:
+		return "6to4"
+	case IPTeredo:
+		return "Teredo"
+	}
+	return "invalid"
+}
+
+// ClassifyIP classifies ip the way a lookup does and returns the address that is searched for:
+// the IPv4 address embedded in IPv4-mapped, 6to4 and Teredo addresses, ip itself otherwise.
+// The address is invalid if the class is IPInvalid.
+func ClassifyIP(ip string) (IPClass, netip.Addr) {
+	iptype, ipno := classifyIP(ip)
+	if iptype == 0 {
+		return IPInvalid, netip.Addr{}
+	}
+	addr := numberAddr(iptype, ipno)
+	if iptype == 6 {
+		return IPv6, addr
+	}
+
+	orig := big.NewInt(0)
+	if p, err := netip.ParseAddr(ip); err == nil && !p.Is4() {
+		b := p.As16()
+		orig.SetBytes(b[:])
+	}
+	switch {
+	case orig.Sign() == 0:
+		return IPv4, addr
+	case orig.Cmp(fromV4mapped) >= 0 && orig.Cmp(toV4mapped) <= 0:
+		return IPv4Mapped, addr
+	case orig.Cmp(from6to4) >= 0 && orig.Cmp(to6to4) <= 0:
+		return IP6to4, addr
+	}
+	return IPTeredo, addr
+}
+
+// Header is the header of a BIN database.
+type Header struct {
+	DatabaseType  uint8 // product number, e.g. 11 for DB11
+	Columns       uint8 // columns of a row, including the first address
+	Date          time.Time
+	IPv4Count     uint32 // rows of the IPv4 table
+	IPv4Addr      uint32 // 1-based offset of the IPv4 table
+	IPv6Count     uint32
+	IPv6Addr      uint32
+	IPv4IndexAddr uint32 // 1-based offset of the IPv4 index, 0 if there is none
+	IPv6IndexAddr uint32
+}
+
+// ParseHeader parses and validates the header at the start of the database data. It fails like
+// opening a database would, e.g. with ErrTruncated if data is too short or ErrUnsupportedDBType
+// if the declared columns cannot hold the fields of the database type.
+func ParseHeader(data []byte) (Header, error) {
+	state, err := (&DB{}).load(NewByteSliceReader(data))
+	if err != nil {
+		return Header{}, err
+	}
+	return state.header(), nil
+}
+
+func (d *dbState) header() Header {
+	return Header{
+		DatabaseType:  d.meta.databaseType,
+		Columns:       d.meta.databaseColumn,
+		Date:          d.date(),
+		IPv4Count:     d.meta.ipv4DatabaseCount,
+		IPv4Addr:      d.meta.ipv4DatabaseAddr,
+		IPv6Count:     d.meta.ipv6DatabaseCount,
+		IPv6Addr:      d.meta.ipv6DatabaseAddr,
+		IPv4IndexAddr: d.meta.ipv4IndexBaseAddr,
+		IPv6IndexAddr: d.meta.ipv6IndexBaseAddr,
+	}
+}
+
+// LookupBytes opens the database held in data and looks up ip. Malformed data makes it return an
+// error, never panic, which makes it the entry point for fuzzing the reader with hostile files.
+func LookupBytes(data []byte, ip string, opts ...Option) (IP2LocationRecord, error) {
+	db, err := OpenDBWithReader(NewByteSliceReader(data), opts...)
+	if err != nil {
+		return IP2LocationRecord{}, err
+	}
+	defer db.Close()
+	return db.GetAll(ip)
+}
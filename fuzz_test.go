@@ -0,0 +1,67 @@
+package ip2loc
+
+import (
+	"errors"
+	"net/netip"
+	"testing"
+)
+
+func FuzzClassifyIP(f *testing.F) {
+	for _, ip := range []string{
+		"8.8.8.8", "0.0.0.0", "255.255.255.255", "::", "::1", "::ffff:8.8.8.8", "2002:808:808::",
+		"2001:0:4136:e378:8000:63bf:3fff:fdd2", "2a00:1450::1", "fe80::1%eth0", "1.2.3", "01.2.3.4",
+		"::ffff:1.2.3.4.5", "", " 8.8.8.8", "8.8.8.8/24", "[::1]",
+	} {
+		f.Add(ip)
+	}
+	f.Fuzz(func(t *testing.T, ip string) {
+		class, addr := ClassifyIP(ip)
+		if class == IPInvalid {
+			if addr.IsValid() {
+				t.Errorf("%q: invalid with address %v", ip, addr)
+			}
+			return
+		}
+		if _, err := netip.ParseAddr(ip); err != nil {
+			t.Errorf("%q: classified as %v, but does not parse: %v", ip, class, err)
+		}
+		if (class == IPv6) != addr.Is6() {
+			t.Errorf("%q: class %v with address %v", ip, class, addr)
+		}
+	})
+}
+
+func FuzzParseHeader(f *testing.F) {
+	db := buildDB(testRows.v4, testRows.v6)
+	f.Add(db)
+	f.Add(db[:64])
+	f.Add(db[:10])
+	f.Add([]byte{})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		h, err := ParseHeader(data)
+		if err == nil && h.Columns == 0 {
+			t.Errorf("header without columns accepted: %+v", h)
+		}
+	})
+}
+
+// FuzzLookupBytes looks up addresses in corrupted copies of a small database: the reader must
+// fail with an error rather than panic or read out of bounds.
+func FuzzLookupBytes(f *testing.F) {
+	db := buildDB(testRows.v4, testRows.v6)
+	for _, ip := range []string{"8.8.8.8", "81.2.69.160", "2001:4860::1", "::ffff:193.0.0.1"} {
+		f.Add(uint(0), byte(0), ip)
+	}
+	f.Add(uint(5), byte(0xff), "8.8.8.8")      // IPv4 row count
+	f.Add(uint(9), byte(0xff), "8.8.8.8")      // IPv4 table offset
+	f.Add(uint(1), byte(0x07), "2001:4860::1") // column count
+	f.Add(uint(len(db)-30), byte(0xff), "2a00:1450::1")
+	f.Fuzz(func(t *testing.T, off uint, b byte, ip string) {
+		data := append([]byte(nil), db...)
+		data[off%uint(len(data))] ^= b
+		_, err := LookupBytes(data, ip)
+		if b == 0 && err != nil && !errors.Is(err, ErrInvalidIP) {
+			t.Errorf("%s in the intact database: %v", ip, err)
+		}
+	})
+}
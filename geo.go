@@ -0,0 +1,118 @@
+package ip2loc
+
+import "math"
+
+// earthRadiusKm is the mean radius of the Earth used by the haversine formula.
+const earthRadiusKm = 6371.0088
+
+func toRadians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+func toDegrees(rad float64) float64 {
+	return rad * 180 / math.Pi
+}
+
+// CoordinateDecimals is the number of decimals IP2Location publishes latitudes and longitudes with.
+const CoordinateDecimals = 6
+
+// roundCoordinate widens a float32 coordinate to float64, rounded to CoordinateDecimals decimals.
+// The BIN format stores float32, whose precision is below a millionth of a degree far from zero,
+// so the result can differ from the published value in the last decimal.
+func roundCoordinate(f float32) float64 {
+	return math.Round(float64(f)*1e6) / 1e6
+}
+
+// Latitude64 returns the latitude as float64, rounded to CoordinateDecimals decimals. Unlike a plain
+// conversion of Latitude, it does not carry the float32 representation error into float64 consumers,
+// e.g. 37.405991 instead of 37.40599060058594.
+func (x IP2LocationRecord) Latitude64() float64 {
+	return roundCoordinate(x.Latitude)
+}
+
+// Longitude64 returns the longitude as float64, rounded to CoordinateDecimals decimals.
+func (x IP2LocationRecord) Longitude64() float64 {
+	return roundCoordinate(x.Longitude)
+}
+
+// Distance returns the great-circle distance in kilometers between two coordinates
+// using the haversine formula.
+func Distance(lat1, lon1, lat2, lon2 float64) float64 {
+	dLat := toRadians(lat2 - lat1)
+	dLon := toRadians(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRadians(lat1))*math.Cos(toRadians(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// DistanceTo returns the distance in kilometers between the record location and the given coordinates.
+func (x IP2LocationRecord) DistanceTo(lat, lon float64) float64 {
+	return Distance(x.Latitude64(), x.Longitude64(), lat, lon)
+}
+
+// Distance returns the distance in kilometers between the locations of two records.
+func (x IP2LocationRecord) Distance(y IP2LocationRecord) float64 {
+	return x.DistanceTo(y.Latitude64(), y.Longitude64())
+}
+
+// WithinRadius reports whether the queried IP address is located within km kilometers of the given
+// coordinates. It returns false if the address is not found or the database has no latitude and longitude columns.
+func (d *DB) WithinRadius(ip string, lat, lon, km float64) (bool, error) {
+	p, err := d.GeoPoint(ip)
+	if err != nil || !p.Valid {
+		return false, err
+	}
+	return Distance(roundCoordinate(p.Latitude), roundCoordinate(p.Longitude), lat, lon) <= km, nil
+}
+
+// BoundingBox is a latitude/longitude rectangle. A box crossing the antimeridian has MinLon > MaxLon.
+type BoundingBox struct {
+	MinLat float64
+	MinLon float64
+	MaxLat float64
+	MaxLon float64
+}
+
+// BoundingBoxAround returns the smallest box containing every point within km kilometers of the
+// given coordinates. It is a cheap pre-filter before an exact Distance check.
+func BoundingBoxAround(lat, lon, km float64) BoundingBox {
+	dLat := toDegrees(km / earthRadiusKm)
+	b := BoundingBox{MinLat: lat - dLat, MaxLat: lat + dLat, MinLon: -180, MaxLon: 180}
+	if b.MinLat <= -90 || b.MaxLat >= 90 {
+		// the circle contains a pole, so every longitude is covered
+		b.MinLat = math.Max(b.MinLat, -90)
+		b.MaxLat = math.Min(b.MaxLat, 90)
+		return b
+	}
+
+	dLon := toDegrees(math.Asin(math.Min(1, math.Sin(km/earthRadiusKm)/math.Cos(toRadians(lat)))))
+	b.MinLon = normalizeLongitude(lon - dLon)
+	b.MaxLon = normalizeLongitude(lon + dLon)
+	return b
+}
+
+func normalizeLongitude(lon float64) float64 {
+	for lon < -180 {
+		lon += 360
+	}
+	for lon > 180 {
+		lon -= 360
+	}
+	return lon
+}
+
+// Contains reports whether the given coordinates lie inside the box.
+func (b BoundingBox) Contains(lat, lon float64) bool {
+	if lat < b.MinLat || lat > b.MaxLat {
+		return false
+	}
+	if b.MinLon <= b.MaxLon {
+		return lon >= b.MinLon && lon <= b.MaxLon
+	}
+	return lon >= b.MinLon || lon <= b.MaxLon
+}
+
+// Within reports whether the record location lies inside the box.
+func (x IP2LocationRecord) Within(b BoundingBox) bool {
+	return b.Contains(x.Latitude64(), x.Longitude64())
+}
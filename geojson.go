@@ -0,0 +1,36 @@
+package ip2loc
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+type geoJSONGeometry struct {
+	Type        string        `json:"type"`
+	Coordinates []json.Number `json:"coordinates"`
+}
+
+type geoJSONFeature struct {
+	Type       string                 `json:"type"`
+	Geometry   geoJSONGeometry        `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// float32 values are formatted with their shortest representation so that
+// e.g. 37.405992 is not rendered as 37.40599060058594.
+func jsonFloat32(f float32) json.Number {
+	return json.Number(strconv.FormatFloat(float64(f), 'f', -1, 32))
+}
+
+// GeoJSON returns the record as a GeoJSON Feature with a Point geometry at its coordinates.
+// The populated fields become the feature properties; fields holding a placeholder message are left out.
+func (x IP2LocationRecord) GeoJSON() ([]byte, error) {
+	return json.Marshal(geoJSONFeature{
+		Type: "Feature",
+		Geometry: geoJSONGeometry{
+			Type:        "Point",
+			Coordinates: []json.Number{jsonFloat32(x.Longitude), jsonFloat32(x.Latitude)},
+		},
+		Properties: recordProperties(&x),
+	})
+}
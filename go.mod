@@ -1,3 +1,9 @@
 module github.com/ferluci/ip2loc
 
-go 1.14
+go 1.23.0
+
+require (
+	golang.org/x/sync v0.15.0
+	golang.org/x/sys v0.33.0
+	google.golang.org/protobuf v1.36.12
+)
@@ -0,0 +1,175 @@
+package ip2loc
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// ErrTruncated is returned when a read comes back short because the database file
+// shrank while it was open, typically because an updater rewrote it in place.
+var ErrTruncated = errors.New("ip2loc: database file is truncated")
+
+// minReopenInterval limits how often a broken database is reopened.
+const minReopenInterval = time.Second
+
+// EventType identifies a change in the health of a DB.
+type EventType int
+
+const (
+	// EventTruncated means a read came back short and the DB was marked unhealthy.
+	EventTruncated EventType = iota + 1
+	// EventReopened means the database was reopened and the DB is healthy again.
+	EventReopened
+	// EventReopenFailed means the database could not be reopened; it will be retried on a later lookup.
+	EventReopenFailed
+	// EventRecovered means reads succeed again without reopening, e.g. because the file was restored in place.
+	EventRecovered
+	// EventStale means the database is older than the maximum age set by WithMaxAge.
+	EventStale
+	// EventReloaded means the database was reloaded by Reload.
+	EventReloaded
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventTruncated:
+		return "truncated"
+	case EventReopened:
+		return "reopened"
+	case EventReopenFailed:
+		return "reopen failed"
+	case EventRecovered:
+		return "recovered"
+	case EventStale:
+		return "stale"
+	case EventReloaded:
+		return "reloaded"
+	}
+	return "unknown"
+}
+
+// Event describes a change in the health of a DB.
+type Event struct {
+	Type EventType
+	Time time.Time
+	Err  error
+}
+
+type health struct {
+	unhealthy     atomic.Bool
+	staleNotified atomic.Bool
+	lastReopen    time.Time // guarded by DB.mu
+}
+
+// Healthy reports whether the last lookups could read the database file completely.
+func (d *DB) Healthy() bool {
+	return !d.health.unhealthy.Load()
+}
+
+// markHealthy is called after a successful lookup.
+func (d *DB) markHealthy() {
+	if d.health.unhealthy.CompareAndSwap(true, false) {
+		d.emit([]Event{{Type: EventRecovered, Time: time.Now()}})
+	}
+}
+
+func (d *DB) emit(events []Event) {
+	if d.onEvent == nil {
+		return
+	}
+	for _, e := range events {
+		d.onEvent(e)
+	}
+}
+
+// recover marks the DB unhealthy after a truncated read of state and tries to reopen the database.
+// It reports whether a fresh state is in place so that the failed lookup can be retried.
+func (d *DB) recover(state *dbState, cause error) bool {
+	var events []Event
+	var old, swapped *dbState
+	defer func() {
+		d.emit(events)
+		if swapped != nil {
+			d.notifySwap(old, swapped, time.Now())
+		}
+	}()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		return false
+	}
+	if d.dbState != state {
+		// another lookup already reopened the database
+		return true
+	}
+
+	now := time.Now()
+	if d.health.unhealthy.CompareAndSwap(false, true) {
+		events = append(events, Event{Type: EventTruncated, Time: now, Err: cause})
+	}
+	if d.reopen == nil || now.Sub(d.health.lastReopen) < minReopenInterval {
+		return false
+	}
+	d.health.lastReopen = now
+
+	reader, err := d.reopen()
+	if err == nil {
+		state, err = d.load(reader)
+	}
+	if err != nil {
+		events = append(events, Event{Type: EventReopenFailed, Time: now, Err: err})
+		return false
+	}
+
+	_ = d.f.Close()
+	old, swapped = d.dbState, state
+	d.dbState = state
+	d.health.unhealthy.Store(false)
+	d.health.staleNotified.Store(false)
+	events = append(events, Event{Type: EventReopened, Time: now})
+	return true
+}
+
+// ErrNotReloadable is returned by Reload for a DB which was not opened from a path.
+var ErrNotReloadable = errors.New("ip2loc: database was not opened from a path")
+
+// Reload opens the database file again and swaps it in, e.g. after a monthly update was renamed over
+// it. The new file is loaded while lookups keep being answered from the old one; lookups running at
+// the swap finish on the old file before it is closed. If the new file cannot be loaded, the old one
+// stays in use and the error is returned.
+func (d *DB) Reload() error {
+	if d.reopen == nil {
+		return ErrNotReloadable
+	}
+	reader, err := d.reopen()
+	if err != nil {
+		return err
+	}
+	state, err := d.load(reader)
+	if err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		_ = state.f.Close()
+		return ErrClosed
+	}
+	old := d.dbState
+	d.dbState = state
+	d.health.unhealthy.Store(false)
+	d.health.staleNotified.Store(false)
+	d.mu.Unlock()
+
+	if old != nil {
+		_ = old.f.Close()
+	}
+	now := time.Now()
+	d.emit([]Event{{Type: EventReloaded, Time: now}})
+	d.notifySwap(old, state, now)
+	return nil
+}
@@ -0,0 +1,214 @@
+//go:build !ip2loc_nofs
+
+package ip2loc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ReleaseManifest is the name of the file listing the releases kept in the History of an Updater.
+const ReleaseManifest = "releases.json"
+
+// ErrNoRelease is returned by RollbackTo when no kept release was published on the requested date.
+var ErrNoRelease = errors.New("ip2loc: no such release is kept")
+
+// Release is a downloaded database file kept in the History of an Updater.
+type Release struct {
+	File         string    `json:"file"`          // name in the History directory
+	Date         time.Time `json:"date"`          // publish date from the header
+	DatabaseType uint8     `json:"database_type"` // product number from the header
+	Size         int64     `json:"size"`
+	SHA256       string    `json:"sha256"`
+	Downloaded   time.Time `json:"downloaded"`
+}
+
+type releaseManifest struct {
+	Releases []Release `json:"releases"` // oldest download first
+	Pinned   string    `json:"pinned,omitempty"`
+}
+
+// Releases returns the releases kept in the History, oldest download first, and the file name of
+// the release pinned by RollbackTo, if any.
+func (u *Updater) Releases() ([]Release, string, error) {
+	m, err := u.readManifest()
+	return m.Releases, m.Pinned, err
+}
+
+// RollbackTo renames the newest kept release published on the date of t over Path and pins it:
+// until Unpin, Download keeps new releases in the History without replacing Path. Reload the DBs
+// reading Path afterwards, e.g. with Update or SIGHUP to the server.
+func (u *Updater) RollbackTo(t time.Time) error {
+	m, err := u.readManifest()
+	if err != nil {
+		return err
+	}
+	day := t.Format(time.DateOnly)
+	for i := len(m.Releases) - 1; i >= 0; i-- {
+		r := m.Releases[i]
+		if r.Date.Format(time.DateOnly) != day {
+			continue
+		}
+		if err := linkOrCopy(filepath.Join(u.History, r.File), u.Path); err != nil {
+			return err
+		}
+		m.Pinned = r.File
+		return u.writeManifest(m)
+	}
+	return fmt.Errorf("%w: %s in %s", ErrNoRelease, day, u.History)
+}
+
+// Unpin lets the next Download replace Path again after RollbackTo.
+func (u *Updater) Unpin() error {
+	m, err := u.readManifest()
+	if err != nil {
+		return err
+	}
+	m.Pinned = ""
+	return u.writeManifest(m)
+}
+
+// archive keeps the completed download part in the History and drops the oldest releases beyond
+// Keep. It reports whether a release is pinned, in which case part must not replace Path.
+func (u *Updater) archive(part string) (bool, error) {
+	m, err := u.readManifest()
+	if err != nil {
+		return false, err
+	}
+	r, err := describeRelease(part)
+	if err != nil {
+		return false, err
+	}
+	r.File = fmt.Sprintf("%s-%s-%s", r.Date.Format(time.DateOnly), r.SHA256[:12], filepath.Base(u.Path))
+
+	kept := m.Releases[:0]
+	for _, old := range m.Releases {
+		if old.File != r.File {
+			kept = append(kept, old)
+		}
+	}
+	m.Releases = append(kept, r)
+	if err := os.MkdirAll(u.History, 0o755); err != nil {
+		return false, err
+	}
+	if err := linkOrCopy(part, filepath.Join(u.History, r.File)); err != nil {
+		return false, err
+	}
+
+	keep := u.Keep
+	if keep <= 0 {
+		keep = 3
+	}
+	for len(m.Releases) > keep {
+		i := 0
+		if m.Releases[0].File == m.Pinned {
+			i = 1
+		}
+		_ = os.Remove(filepath.Join(u.History, m.Releases[i].File))
+		m.Releases = append(m.Releases[:i], m.Releases[i+1:]...)
+	}
+	return m.Pinned != "", u.writeManifest(m)
+}
+
+// describeRelease reads the header and checksum of a downloaded database, unpacked by validate.
+func describeRelease(path string) (Release, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Release{}, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return Release{}, err
+	}
+
+	md, err := OpenMetadataOnly(path)
+	if err != nil {
+		return Release{}, fmt.Errorf("ip2loc: downloaded file is no database: %w", err)
+	}
+	return Release{
+		Date:         md.Header.Date,
+		DatabaseType: md.Header.DatabaseType,
+		Size:         size,
+		SHA256:       hex.EncodeToString(h.Sum(nil)),
+		Downloaded:   time.Now().UTC(),
+	}, nil
+}
+
+func (u *Updater) readManifest() (releaseManifest, error) {
+	var m releaseManifest
+	if u.History == "" {
+		return m, errors.New("ip2loc: the updater keeps no history")
+	}
+	b, err := os.ReadFile(filepath.Join(u.History, ReleaseManifest))
+	if errors.Is(err, os.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return m, err
+	}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return m, fmt.Errorf("ip2loc: reading %s: %w", ReleaseManifest, err)
+	}
+	return m, nil
+}
+
+func (u *Updater) writeManifest(m releaseManifest) error {
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(u.History, ReleaseManifest)
+	if err := os.WriteFile(path+".tmp", append(b, '\n'), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// linkOrCopy replaces dst by a hard link to src, or a copy if src cannot be linked, by renaming it
+// over dst so that readers of dst never see a partial file.
+func linkOrCopy(src, dst string) error {
+	tmp := dst + ".tmp"
+	_ = os.Remove(tmp)
+	if err := os.Link(src, tmp); err != nil {
+		if err := copyFile(src, tmp); err != nil {
+			_ = os.Remove(tmp)
+			return err
+		}
+	}
+	return os.Rename(tmp, dst)
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	return writeFile(dst, in)
+}
+
+// writeFile writes what is read from r to the file at dst and flushes it to disk.
+func writeFile(dst string, r io.Reader) error {
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,98 @@
+//go:build !ip2loc_nofs
+
+package ip2loc
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+// release returns the database of testRows published on the given day of January 2024.
+func release(day int) []byte {
+	data := buildDB(testRows.v4, testRows.v6)
+	data[4] = byte(day)
+	return data
+}
+
+func TestRollbackTo(t *testing.T) {
+	dir := t.TempDir()
+	u := &Updater{
+		Path:    filepath.Join(dir, "IP2LOCATION-DB5.BIN"),
+		History: filepath.Join(dir, "history"),
+		Keep:    2,
+		Retries: -1,
+	}
+	download := func(day int) {
+		t.Helper()
+		u.URL = newReleaseServer(t, release(day), `"v1"`).URL
+		if err := u.Download(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// checkKept checks the days of the kept releases, oldest download first, and of the live file
+	checkKept := func(live int, days ...int) {
+		t.Helper()
+		if got, err := os.ReadFile(u.Path); err != nil || got[4] != byte(live) {
+			t.Errorf("live file: %v, want the release of day %d", err, live)
+		}
+		releases, _, err := u.Releases()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var kept []int
+		files := []string{ReleaseManifest}
+		for _, r := range releases {
+			kept = append(kept, r.Date.Day())
+			files = append(files, r.File)
+		}
+		if !slices.Equal(kept, days) {
+			t.Errorf("kept the releases of days %v, want %v", kept, days)
+		}
+		entries, err := os.ReadDir(u.History)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		slices.Sort(files)
+		if !slices.Equal(names, files) {
+			t.Errorf("history holds %v, want %v", names, files)
+		}
+	}
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+
+	for d := 1; d <= 3; d++ {
+		download(d)
+	}
+	checkKept(3, 2, 3)
+
+	if err := u.RollbackTo(day(1)); !errors.Is(err, ErrNoRelease) {
+		t.Errorf("rollback to a pruned release: %v, want ErrNoRelease", err)
+	}
+	if err := u.RollbackTo(day(2)); err != nil {
+		t.Fatal(err)
+	}
+	checkKept(2, 2, 3)
+	if _, pinned, _ := u.Releases(); pinned == "" {
+		t.Error("no release is pinned after the rollback")
+	}
+
+	// the pinned release stays live and kept while newer ones replace each other
+	download(4)
+	checkKept(2, 2, 4)
+	download(5)
+	checkKept(2, 2, 5)
+
+	if err := u.Unpin(); err != nil {
+		t.Fatal(err)
+	}
+	download(6)
+	checkKept(6, 5, 6)
+}
@@ -0,0 +1,173 @@
+package ip2loc
+
+import (
+	"io"
+	"slices"
+	"sync/atomic"
+	"time"
+)
+
+// readLatencyBuckets are the upper bounds of the read and lookup latency histograms.
+var readLatencyBuckets = [...]time.Duration{
+	time.Microsecond, 10 * time.Microsecond, 50 * time.Microsecond, 100 * time.Microsecond,
+	500 * time.Microsecond, time.Millisecond, 5 * time.Millisecond, 25 * time.Millisecond, 100 * time.Millisecond,
+}
+
+// InstrumentedReader is a DBReader counting the reads, bytes and read latency of the reader it wraps,
+// and the lookups and lookup latency of the DB reading it. Comparing the reads per lookup with and without a cache, or
+// wrapping the reader under a BlockCacheReader to count the reads which miss it, shows what a cache or
+// mmap saves and how many reads a lookup costs.
+//
+// A DB counts its lookups on an InstrumentedReader it reads directly or through a BlockCacheReader.
+type InstrumentedReader struct {
+	r DBReader
+
+	reads   atomic.Uint64
+	bytes   atomic.Uint64
+	errors  atomic.Uint64
+	lookups atomic.Uint64
+	total   atomic.Int64 // nanoseconds
+	latency latencyHistogram
+
+	lookupTotal   atomic.Int64 // nanoseconds
+	lookupLatency latencyHistogram
+}
+
+// latencyHistogram counts durations by the bucket of readLatencyBuckets they fall in.
+type latencyHistogram [len(readLatencyBuckets) + 1]atomic.Uint64
+
+func (h *latencyHistogram) observe(took time.Duration) {
+	i := 0
+	for i < len(readLatencyBuckets) && took > readLatencyBuckets[i] {
+		i++
+	}
+	h[i].Add(1)
+}
+
+func (h *latencyHistogram) load() []uint64 {
+	out := make([]uint64, len(h))
+	for i := range h {
+		out[i] = h[i].Load()
+	}
+	return out
+}
+
+// NewInstrumentedReader returns an InstrumentedReader reading r. Closing it closes r.
+func NewInstrumentedReader(r DBReader) *InstrumentedReader {
+	return &InstrumentedReader{r: r}
+}
+
+func (r *InstrumentedReader) ReadAt(p []byte, off int64) (int, error) {
+	start := time.Now()
+	n, err := r.r.ReadAt(p, off)
+	r.observe(time.Since(start), n, err)
+	return n, err
+}
+
+func (r *InstrumentedReader) Read(p []byte) (int, error) {
+	start := time.Now()
+	n, err := r.r.Read(p)
+	r.observe(time.Since(start), n, err)
+	return n, err
+}
+
+func (r *InstrumentedReader) Close() error {
+	return r.r.Close()
+}
+
+func (r *InstrumentedReader) observe(took time.Duration, n int, err error) {
+	r.reads.Add(1)
+	r.bytes.Add(uint64(n))
+	if err != nil && err != io.EOF {
+		r.errors.Add(1)
+	}
+	r.total.Add(int64(took))
+	r.latency.observe(took)
+}
+
+// countLookup counts a lookup of the DB reading r which took took.
+func (r *InstrumentedReader) countLookup(took time.Duration) {
+	r.lookups.Add(1)
+	r.lookupTotal.Add(int64(took))
+	r.lookupLatency.observe(took)
+}
+
+// lookupCounter is implemented by readers counting the lookups made through them.
+type lookupCounter interface {
+	countLookup(took time.Duration)
+}
+
+// ReaderStats describes the reads made through an InstrumentedReader.
+type ReaderStats struct {
+	Reads      uint64        // calls of ReadAt and Read
+	Bytes      uint64        // bytes read
+	Errors     uint64        // reads failing other than at the end of the file
+	Lookups    uint64        // lookups of the DB reading the reader
+	ReadTime   time.Duration // total time spent reading
+	LookupTime time.Duration // total time spent in lookups, reads included
+
+	// Latency counts the reads by duration: Latency[i] the reads which took at most
+	// ReadLatencyBuckets()[i] and longer than the bucket before, the last one those which took
+	// longer than all buckets.
+	Latency []uint64
+
+	// LookupLatency counts the lookups by duration in the buckets of Latency.
+	LookupLatency []uint64
+}
+
+// ReadLatencyBuckets returns the upper bounds of the buckets of ReaderStats.Latency and
+// ReaderStats.LookupLatency.
+func ReadLatencyBuckets() []time.Duration {
+	return slices.Clone(readLatencyBuckets[:])
+}
+
+// ReadsPerLookup returns the average number of reads of a lookup, which includes the reads of
+// scans, exports and reloads made in between.
+func (s ReaderStats) ReadsPerLookup() float64 {
+	if s.Lookups == 0 {
+		return 0
+	}
+	return float64(s.Reads) / float64(s.Lookups)
+}
+
+// BytesPerLookup returns the average number of bytes read by a lookup, like ReadsPerLookup.
+func (s ReaderStats) BytesPerLookup() float64 {
+	if s.Lookups == 0 {
+		return 0
+	}
+	return float64(s.Bytes) / float64(s.Lookups)
+}
+
+// Stats returns the counters of the reader.
+func (r *InstrumentedReader) Stats() ReaderStats {
+	return ReaderStats{
+		Reads:         r.reads.Load(),
+		Bytes:         r.bytes.Load(),
+		Errors:        r.errors.Load(),
+		Lookups:       r.lookups.Load(),
+		ReadTime:      time.Duration(r.total.Load()),
+		LookupTime:    time.Duration(r.lookupTotal.Load()),
+		Latency:       r.latency.load(),
+		LookupLatency: r.lookupLatency.load(),
+	}
+}
+
+// lookupCounterOf returns the reader of state if it counts lookups.
+func lookupCounterOf(state *dbState) (lookupCounter, bool) {
+	if state == nil {
+		return nil, false
+	}
+	c, ok := state.f.(lookupCounter)
+	return c, ok
+}
+
+// startLookup returns a function counting a lookup on state started now, which does nothing unless
+// the reader of state counts lookups, so uninstrumented lookups are not timed.
+func startLookup(state *dbState) func() {
+	c, ok := lookupCounterOf(state)
+	if !ok {
+		return func() {}
+	}
+	start := time.Now()
+	return func() { c.countLookup(time.Since(start)) }
+}
@@ -0,0 +1,33 @@
+package ip2loc
+
+import "testing"
+
+func TestInstrumentedReaderCountsLookups(t *testing.T) {
+	r := NewInstrumentedReader(NewByteSliceReader(buildDB(testRows.v4, testRows.v6)))
+	db, err := OpenDBWithReader(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	opened := r.Stats()
+
+	if _, err := db.GetAll("8.8.8.8"); err != nil {
+		t.Fatal(err)
+	}
+	db.GetAllBatch([]string{"81.2.69.160", "193.0.0.1", "81.2.69.160"})
+
+	s := r.Stats()
+	if s.Lookups != 3 {
+		t.Errorf("Lookups = %d, want 3: one lookup and two distinct batch addresses", s.Lookups)
+	}
+	if s.Reads <= opened.Reads {
+		t.Errorf("lookups made no reads: %d before, %d after", opened.Reads, s.Reads)
+	}
+	var n uint64
+	for _, c := range s.LookupLatency {
+		n += c
+	}
+	if n != s.Lookups {
+		t.Errorf("LookupLatency counts %d lookups, want %d", n, s.Lookups)
+	}
+}
@@ -0,0 +1,276 @@
+// Package ip2locarrow converts ip2loc databases and batch lookups to Apache Arrow record batches,
+// so analysts can hand them to DataFusion, DuckDB, Polars or pyarrow without a CSV round-trip.
+package ip2locarrow
+
+import (
+	"io"
+	"iter"
+	"net/netip"
+	"sync/atomic"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/ferluci/ip2loc"
+)
+
+// batchSize is the number of rows of a record batch if Options.BatchSize is 0.
+const batchSize = 1024
+
+// recordFields are the columns holding the fields of a record, named like the columns of
+// ip2locparquet. Fields the database does not contain are null.
+var recordFields = []arrow.Field{
+	{Name: "country_short", Type: arrow.BinaryTypes.String, Nullable: true},
+	{Name: "country_long", Type: arrow.BinaryTypes.String, Nullable: true},
+	{Name: "region", Type: arrow.BinaryTypes.String, Nullable: true},
+	{Name: "city", Type: arrow.BinaryTypes.String, Nullable: true},
+	{Name: "isp", Type: arrow.BinaryTypes.String, Nullable: true},
+	{Name: "latitude", Type: arrow.PrimitiveTypes.Float32},
+	{Name: "longitude", Type: arrow.PrimitiveTypes.Float32},
+	{Name: "domain", Type: arrow.BinaryTypes.String, Nullable: true},
+	{Name: "zip_code", Type: arrow.BinaryTypes.String, Nullable: true},
+	{Name: "timezone", Type: arrow.BinaryTypes.String, Nullable: true},
+	{Name: "net_speed", Type: arrow.BinaryTypes.String, Nullable: true},
+	{Name: "idd_code", Type: arrow.BinaryTypes.String, Nullable: true},
+	{Name: "area_code", Type: arrow.BinaryTypes.String, Nullable: true},
+	{Name: "weather_station_code", Type: arrow.BinaryTypes.String, Nullable: true},
+	{Name: "weather_station_name", Type: arrow.BinaryTypes.String, Nullable: true},
+	{Name: "mcc", Type: arrow.BinaryTypes.String, Nullable: true},
+	{Name: "mnc", Type: arrow.BinaryTypes.String, Nullable: true},
+	{Name: "mobile_brand", Type: arrow.BinaryTypes.String, Nullable: true},
+	{Name: "elevation", Type: arrow.PrimitiveTypes.Float32, Nullable: true},
+	{Name: "usage_type", Type: arrow.BinaryTypes.String, Nullable: true},
+	{Name: "region_code", Type: arrow.BinaryTypes.String, Nullable: true},
+}
+
+// Schema is the schema of an exported record batch. Each row covers the addresses ip_from to ip_to
+// inclusive; network is only set by a CIDR export.
+var Schema = arrow.NewSchema(append([]arrow.Field{
+	{Name: "ip_from", Type: arrow.BinaryTypes.String},
+	{Name: "ip_to", Type: arrow.BinaryTypes.String},
+	{Name: "network", Type: arrow.BinaryTypes.String, Nullable: true},
+	{Name: "ip_version", Type: arrow.PrimitiveTypes.Int32},
+}, recordFields...), nil)
+
+// ResultSchema is the schema of the record batch of a batch lookup. error is null for the addresses
+// which were found; for the others the fields are null and the coordinates 0.
+var ResultSchema = arrow.NewSchema(append([]arrow.Field{
+	{Name: "ip", Type: arrow.BinaryTypes.String},
+	{Name: "error", Type: arrow.BinaryTypes.String, Nullable: true},
+}, recordFields...), nil)
+
+// Options controls an export.
+type Options struct {
+	// CIDR splits every range into the CIDR prefixes covering it and writes one row per prefix
+	// with network set, for engines joining on networks rather than address ranges.
+	CIDR bool
+	// BatchSize is the number of rows of a record batch; 1024 if 0.
+	BatchSize int
+	// Allocator allocates the buffers of the record batches; memory.DefaultAllocator if nil.
+	Allocator memory.Allocator
+}
+
+func (o Options) allocator() memory.Allocator {
+	if o.Allocator == nil {
+		return memory.DefaultAllocator
+	}
+	return o.Allocator
+}
+
+// Export writes every row of db to w as an Arrow IPC stream, which pyarrow.ipc.open_stream, DuckDB's
+// read_arrow and DataFusion read without conversion.
+func Export(w io.Writer, db *ip2loc.DB, opts Options) error {
+	rr := NewReader(db, opts)
+	defer rr.Release()
+
+	iw := ipc.NewWriter(w, ipc.WithSchema(Schema), ipc.WithAllocator(opts.allocator()))
+	for rr.Next() {
+		if err := iw.Write(rr.Record()); err != nil {
+			iw.Close()
+			return err
+		}
+	}
+	if err := rr.Err(); err != nil {
+		iw.Close()
+		return err
+	}
+	return iw.Close()
+}
+
+// NewReader returns a reader of every row of db as record batches of Schema. The batches are built
+// as the reader advances, so an export never holds more than one batch in memory. Each batch is
+// only valid until the next call to Next; Retain it to keep it. Release the reader when done.
+func NewReader(db *ip2loc.DB, opts Options) array.RecordReader {
+	next, stop := iter.Pull2(db.Records())
+	size := opts.BatchSize
+	if size <= 0 {
+		size = batchSize
+	}
+	r := &reader{
+		next: next,
+		stop: stop,
+		cidr: opts.CIDR,
+		size: size,
+		b:    array.NewRecordBuilder(opts.allocator(), Schema),
+	}
+	r.refs.Store(1)
+	return r
+}
+
+// reader is the array.RecordReader of NewReader.
+type reader struct {
+	refs atomic.Int64
+	next func() (ip2loc.RangeRecord, error, bool)
+	stop func()
+	cidr bool
+	size int
+	b    *array.RecordBuilder
+	rec  arrow.Record
+	err  error
+	done bool
+
+	// the prefixes of the last range of a CIDR export left for the next batch
+	prefixes []netip.Prefix
+	x        ip2loc.IP2LocationRecord
+}
+
+func (r *reader) Retain() { r.refs.Add(1) }
+
+func (r *reader) Release() {
+	if r.refs.Add(-1) != 0 {
+		return
+	}
+	r.stop()
+	if r.rec != nil {
+		r.rec.Release()
+		r.rec = nil
+	}
+	r.b.Release()
+}
+
+func (r *reader) Schema() *arrow.Schema { return Schema }
+
+func (r *reader) Record() arrow.Record { return r.rec }
+
+func (r *reader) Err() error { return r.err }
+
+func (r *reader) Next() bool {
+	if r.rec != nil {
+		r.rec.Release()
+		r.rec = nil
+	}
+	rows := 0
+	for rows < r.size {
+		if len(r.prefixes) > 0 {
+			p := r.prefixes[0]
+			r.prefixes = r.prefixes[1:]
+			appendRange(r.b, p.Addr(), lastAddr(p), p.String(), r.x)
+			rows++
+			continue
+		}
+		if r.done {
+			break
+		}
+		rg, err, ok := r.next()
+		if err != nil {
+			r.err = err
+		}
+		if !ok || err != nil {
+			r.done = true
+			break
+		}
+		if r.cidr {
+			r.prefixes, r.x = rg.Prefixes(), rg.Record
+			continue
+		}
+		appendRange(r.b, rg.First, rg.Last, "", rg.Record)
+		rows++
+	}
+	if rows == 0 || r.err != nil {
+		return false
+	}
+	r.rec = r.b.NewRecord()
+	return true
+}
+
+// Results returns the record batch of the results of a batch lookup, e.g. of DB.GetAllBatch, in
+// the order of results. The caller must release it.
+func Results(mem memory.Allocator, results []ip2loc.Result) arrow.Record {
+	if mem == nil {
+		mem = memory.DefaultAllocator
+	}
+	b := array.NewRecordBuilder(mem, ResultSchema)
+	defer b.Release()
+	b.Reserve(len(results))
+
+	ips := b.Field(0).(*array.StringBuilder)
+	errs := b.Field(1).(*array.StringBuilder)
+	for _, r := range results {
+		ips.Append(r.IP)
+		if r.Err != nil {
+			errs.Append(r.Err.Error())
+		} else {
+			errs.AppendNull()
+		}
+		appendRecord(b, 2, r.Record)
+	}
+	return b.NewRecord()
+}
+
+// appendRange appends the row of Schema for a record covering first to last.
+func appendRange(b *array.RecordBuilder, first, last netip.Addr, network string, x ip2loc.IP2LocationRecord) {
+	version := int32(4)
+	if first.Is6() {
+		version = 6
+	}
+	b.Field(0).(*array.StringBuilder).Append(first.String())
+	b.Field(1).(*array.StringBuilder).Append(last.String())
+	appendString(b.Field(2), network)
+	b.Field(3).(*array.Int32Builder).Append(version)
+	appendRecord(b, 4, x)
+}
+
+// appendRecord appends the fields of x to the columns of b from the column at start, in the order
+// of recordFields.
+func appendRecord(b *array.RecordBuilder, start int, x ip2loc.IP2LocationRecord) {
+	x = x.WithoutPlaceholders()
+	values := [...]any{
+		x.CountryShort, x.CountryLong, x.Region, x.City, x.Isp, x.Latitude, x.Longitude, x.Domain,
+		x.ZipCode, x.Timezone, x.NetSpeed, x.IddCode, x.AreaCode, x.WeatherStationCode,
+		x.WeatherStationName, x.MCC, x.MNC, x.MobileBrand, x.Elevation, x.UsageType, x.RegionCode,
+	}
+	if !x.HasElevation() {
+		values[18] = nil // rather than 0, a real elevation
+	}
+	for i, v := range values {
+		f := b.Field(start + i)
+		switch v := v.(type) {
+		case float32:
+			f.(*array.Float32Builder).Append(v)
+		case string:
+			appendString(f, v)
+		default:
+			f.AppendNull()
+		}
+	}
+}
+
+// appendString appends s to a string column, or null if s is empty.
+func appendString(f array.Builder, s string) {
+	if s == "" {
+		f.AppendNull()
+		return
+	}
+	f.(*array.StringBuilder).Append(s)
+}
+
+// lastAddr returns the last address of p.
+func lastAddr(p netip.Prefix) netip.Addr {
+	b := p.Masked().Addr().AsSlice()
+	for i := p.Bits(); i < len(b)*8; i++ {
+		b[i/8] |= 0x80 >> (i % 8)
+	}
+	a, _ := netip.AddrFromSlice(b)
+	return a
+}
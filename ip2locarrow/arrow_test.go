@@ -0,0 +1,188 @@
+package ip2locarrow
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/apache/arrow-go/v18/arrow"
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	"github.com/ferluci/ip2loc"
+)
+
+// testDB is the database of the tests of the ip2loc package, written by its TestTestdataDB.
+const testDB = "../testdata/IP2LOCATION-DB5.BIN"
+
+// str returns the value of the string column name at row i, or "" if it is null.
+func str(rec arrow.Record, name string, i int) string {
+	col := rec.Column(rec.Schema().FieldIndices(name)[0]).(*array.String)
+	if col.IsNull(i) {
+		return ""
+	}
+	return col.Value(i)
+}
+
+// float returns the value of the float column name at row i and whether it is set.
+func float(rec arrow.Record, name string, i int) (float32, bool) {
+	col := rec.Column(rec.Schema().FieldIndices(name)[0]).(*array.Float32)
+	return col.Value(i), col.IsValid(i)
+}
+
+// checkRecord reports the differences between the fields of row i of rec and x.
+func checkRecord(t *testing.T, rec arrow.Record, i int, x ip2loc.IP2LocationRecord) {
+	t.Helper()
+	x = x.WithoutPlaceholders()
+	for name, want := range map[string]string{
+		"country_short": x.CountryShort,
+		"country_long":  x.CountryLong,
+		"region":        x.Region,
+		"city":          x.City,
+		"isp":           "", // not in a DB5 database
+		"timezone":      "",
+	} {
+		if got := str(rec, name, i); got != want {
+			t.Errorf("row %d: %s %q, want %q", i, name, got, want)
+		}
+	}
+	if lat, _ := float(rec, "latitude", i); lat != x.Latitude {
+		t.Errorf("row %d: latitude %v, want %v", i, lat, x.Latitude)
+	}
+	if lon, _ := float(rec, "longitude", i); lon != x.Longitude {
+		t.Errorf("row %d: longitude %v, want %v", i, lon, x.Longitude)
+	}
+	if _, ok := float(rec, "elevation", i); ok {
+		t.Errorf("row %d: elevation is set", i)
+	}
+}
+
+func TestNewReader(t *testing.T) {
+	db, err := ip2loc.OpenDB(testDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var ranges []ip2loc.RangeRecord
+	prefixes := 0
+	for r, err := range db.Records() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		ranges = append(ranges, r)
+		prefixes += len(r.Prefixes())
+	}
+
+	for _, tt := range []struct {
+		name string
+		cidr bool
+		rows int
+	}{
+		{"ranges", false, len(ranges)},
+		{"cidr", true, prefixes},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+			defer mem.AssertSize(t, 0)
+
+			rr := NewReader(db, Options{CIDR: tt.cidr, BatchSize: 4, Allocator: mem})
+			defer rr.Release()
+			rows, batches := 0, 0
+			for rr.Next() {
+				rec := rr.Record()
+				batches++
+				if !rec.Schema().Equal(Schema) || rec.NumRows() > 4 {
+					t.Fatalf("batch %d: %d rows of %v", batches, rec.NumRows(), rec.Schema())
+				}
+				for i := range int(rec.NumRows()) {
+					from := str(rec, "ip_from", i)
+					x, err := db.GetAll(from)
+					if err != nil {
+						t.Fatal(err)
+					}
+					checkRecord(t, rec, i, x)
+					if x2, err := db.GetAll(str(rec, "ip_to", i)); err != nil || x2 != x {
+						t.Errorf("%s to %s: lookups of the ends differ", from, str(rec, "ip_to", i))
+					}
+					version := rec.Column(3).(*array.Int32).Value(i)
+					if network := str(rec, "network", i); (network != "") != tt.cidr || version != 4 && version != 6 {
+						t.Errorf("%s: network %q, ip_version %d", from, network, version)
+					}
+				}
+				rows += int(rec.NumRows())
+			}
+			if err := rr.Err(); err != nil {
+				t.Fatal(err)
+			}
+			if rows != tt.rows || batches != (tt.rows+3)/4 {
+				t.Errorf("read %d rows in %d batches, want %d", rows, batches, tt.rows)
+			}
+		})
+	}
+}
+
+func TestExport(t *testing.T) {
+	db, err := ip2loc.OpenDB(testDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var b bytes.Buffer
+	if err := Export(&b, db, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	r, err := ipc.NewReader(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Release()
+	if !r.Schema().Equal(Schema) {
+		t.Fatalf("schema %v", r.Schema())
+	}
+	var london bool
+	for r.Next() {
+		rec := r.Record()
+		for i := range int(rec.NumRows()) {
+			london = london || str(rec, "ip_from", i) == "81.2.69.0" && str(rec, "ip_to", i) == "81.2.69.255" &&
+				str(rec, "city", i) == "London"
+		}
+	}
+	if err := r.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if !london {
+		t.Error("the stream lacks the London range")
+	}
+}
+
+func TestResults(t *testing.T) {
+	db, err := ip2loc.OpenDB(testDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	mem := memory.NewCheckedAllocator(memory.NewGoAllocator())
+	defer mem.AssertSize(t, 0)
+	ips := []string{"81.2.69.160", "2a00:1450::1", "81.2.69", "1.1.1.1", "81.2.69.160"}
+	rec := Results(mem, db.GetAllBatch(ips))
+	defer rec.Release()
+
+	if !rec.Schema().Equal(ResultSchema) || rec.NumRows() != int64(len(ips)) {
+		t.Fatalf("%d rows of %v", rec.NumRows(), rec.Schema())
+	}
+	for i, ip := range ips {
+		if got := str(rec, "ip", i); got != ip {
+			t.Errorf("row %d: ip %q, want %q", i, got, ip)
+		}
+		x, err := db.GetAll(ip)
+		if got := str(rec, "error", i); (got != "") != (err != nil) || err != nil && got != err.Error() {
+			t.Errorf("%s: error %q, want %v", ip, got, err)
+		}
+		if err != nil {
+			x = ip2loc.IP2LocationRecord{}
+		}
+		checkRecord(t, rec, i, x)
+	}
+}
@@ -4,16 +4,19 @@
 package ip2loc
 
 import (
-	"bufio"
 	"bytes"
+	"context"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 	"math"
 	"math/big"
 	"net"
-	"os"
-	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
 )
 
 type DBReader interface {
@@ -21,6 +24,9 @@ type DBReader interface {
 	io.ReaderAt
 }
 
+// InMemoryDBReader is a DBReader over a bytes.Reader.
+//
+// Deprecated: use ByteSliceReader, which also gives access to the underlying bytes.
 type InMemoryDBReader struct {
 	*bytes.Reader
 }
@@ -29,6 +35,28 @@ func (r *InMemoryDBReader) Close() error {
 	return nil
 }
 
+// ByteSliceReader is a DBReader over a byte slice holding a complete BIN database, e.g. a
+// decompressed or decrypted buffer or a memory-mapped region. Close does not release the slice.
+type ByteSliceReader struct {
+	*bytes.Reader
+	data []byte
+}
+
+// NewByteSliceReader returns a ByteSliceReader reading from b. The slice must not be modified
+// while the reader is in use.
+func NewByteSliceReader(b []byte) *ByteSliceReader {
+	return &ByteSliceReader{Reader: bytes.NewReader(b), data: b}
+}
+
+// Bytes returns the underlying slice.
+func (r *ByteSliceReader) Bytes() []byte {
+	return r.data
+}
+
+func (r *ByteSliceReader) Close() error {
+	return nil
+}
+
 type ip2LocationMeta struct {
 	databaseType      uint8
 	databaseColumn    uint8
@@ -68,11 +96,21 @@ type IP2LocationRecord struct {
 	MobileBrand        string
 	Elevation          float32
 	UsageType          string
+	RegionCode         string       // ISO 3166-2 subdivision code, requires WithRegionCodes
+	Country            *CountryInfo // country details, requires WithCountryInfo
+	RawRow             *RawRow      // matched database row, requires WithRawRow
+
+	elevation    elevationState
+	elevationStr string // the elevation column if it does not hold a number
 }
 
-type DB struct {
-	f    DBReader
-	meta ip2LocationMeta
+// dbState holds the reader and everything derived from the database header.
+// It is replaced as a whole when the database is reopened.
+type dbState struct {
+	f        DBReader
+	meta     ip2LocationMeta
+	ranges   *rangeIndex // set by WithRangeIndex
+	prefixes []uint32    // set by WithPrefixTable
 
 	countryPositionOffset            uint32
 	regionPositionOffset             uint32
@@ -114,7 +152,56 @@ type DB struct {
 	elevationEnabled          bool
 	usageTypeEnabled          bool
 
-	metaOk bool
+	metaOk      bool
+	supported   uint32         // fields available in the loaded database type
+	strColumns  []stringColumn // of the enabled fields
+	strData     []byte         // the database in memory, which strings alias; set by WithUnsafeStrings
+	unknownType bool           // database type newer than the position tables, only the country is read
+}
+
+type DB struct {
+	*dbState
+
+	mu      sync.RWMutex // held for reading by every lookup, so Close waits for them
+	closed  bool
+	path    string // of the database file, if opened from one
+	reopen  func() (DBReader, error)
+	onEvent func(Event)
+	onSwap  func(Swap)
+	health  health
+
+	regionCodes  *RegionCodes
+	countryInfo  *CountryTable
+	translations *Translations
+	language     string
+	rawRow       bool
+	unsafeStrs   bool
+	strictLock   bool
+	replaceWatch bool
+	replaceKey   string        // the key of the DB in replaceWatchers once it is registered
+	timeout      time.Duration // of every lookup, set by WithLookupTimeout
+	checksum     string
+	maxAge       time.Duration
+	stalePolicy  StalePolicy
+	last         lastMatch
+	strategy     SearchStrategy
+	elevUnit     ElevationUnit
+	rangeIndex   bool
+	prefixTable  bool
+	ipVersion    int    // 4 or 6 if restricted by WithIPv4Only or WithIPv6Only
+	fields       uint32 // query bits of the fields selected by WithFields, 0 for all
+
+	anonymize       bool
+	anonymizeV4Bits int
+	anonymizeV6Bits int
+
+	flight *singleflight.Group // set by WithSingleflight
+	reads  *readLimiter        // set by WithMaxConcurrentReads
+	retry  *readRetry          // set by WithReadRetries
+
+	workers     *WorkerGroup // set by WithWorkerGroup or created by workerGroup
+	ownWorkers  bool
+	workersOnce sync.Once
 }
 
 var countryPosition = [25]uint8{0, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2, 2}
@@ -149,6 +236,15 @@ var fromTeredo = big.NewInt(0)
 var toTeredo = big.NewInt(0)
 var last32bits = big.NewInt(4294967295)
 
+// the IPv6 bounds are set once rather than by every load, which may run concurrently with lookups
+func init() {
+	maxIpv6Range.SetString("340282366920938463463374607431768211455", 10)
+	from6to4.SetString("42545680458834377588178886921629466624", 10)
+	to6to4.SetString("42550872755692912415807417417958686719", 10)
+	fromTeredo.SetString("42540488161975842760550356425300246528", 10)
+	toTeredo.SetString("42540488241204005274814694018844196863", 10)
+}
+
 const countryShort uint32 = 0x00001
 const countryLong uint32 = 0x00002
 const region uint32 = 0x00004
@@ -177,11 +273,17 @@ const missingFile string = "Invalid database file."
 const parameterIsNotSupported string = "This parameter is unavailable for selected data file. Please upgrade the data file."
 
 // get IP type and calculate IP number; calculates index too if exists
-func (d *DB) checkIP(ip string) (ipType uint32, ipNum *big.Int, ipIndex uint32) {
+func (d *DB) checkIP(ip string) (ipType uint32, ipNum *big.Int, ipIndex int64) {
+	ipType, ipNum = classifyIP(ip)
+	ipIndex = d.indexOf(ipType, ipNum)
+	return
+}
+
+// classifyIP returns the IP type and number ip is looked up with. IPv4-mapped, 6to4 and
+// Teredo addresses are looked up by their IPv4 address. The type is 0 for an invalid address.
+func classifyIP(ip string) (ipType uint32, ipNum *big.Int) {
 	ipType = 0
 	ipNum = big.NewInt(0)
-	ipNumTmp := big.NewInt(0)
-	ipIndex = 0
 	ipaddress := net.ParseIP(ip)
 
 	if ipaddress != nil {
@@ -215,30 +317,52 @@ func (d *DB) checkIP(ip string) (ipType uint32, ipNum *big.Int, ipIndex uint32)
 			}
 		}
 	}
+	return
+}
+
+// indexOf returns the position of the index entry for ipNum, or 0 if the database has no index.
+func (d *DB) indexOf(ipType uint32, ipNum *big.Int) int64 {
+	ipNumTmp := big.NewInt(0)
 	if ipType == 4 {
 		if d.meta.ipv4IndexBaseAddr > 0 {
 			ipNumTmp.Rsh(ipNum, 16)
 			ipNumTmp.Lsh(ipNumTmp, 3)
-			ipIndex = uint32(ipNumTmp.Add(ipNumTmp, big.NewInt(int64(d.meta.ipv4IndexBaseAddr))).Uint64())
+			return ipNumTmp.Add(ipNumTmp, big.NewInt(int64(d.meta.ipv4IndexBaseAddr))).Int64()
 		}
 	} else if ipType == 6 {
 		if d.meta.ipv6IndexBaseAddr > 0 {
 			ipNumTmp.Rsh(ipNum, 112)
 			ipNumTmp.Lsh(ipNumTmp, 3)
-			ipIndex = uint32(ipNumTmp.Add(ipNumTmp, big.NewInt(int64(d.meta.ipv6IndexBaseAddr))).Uint64())
+			return ipNumTmp.Add(ipNumTmp, big.NewInt(int64(d.meta.ipv6IndexBaseAddr))).Int64()
 		}
 	}
-	return
+	return 0
 }
 
+// read exactly len(p) bytes; a short read means the file shrank underneath us
+func (d *DB) readAt(ctx context.Context, p []byte, off int64) error {
+	n, err := d.readFile(ctx, p, off)
+	if n == len(p) {
+		return nil
+	}
+	if err == nil || err == io.EOF || err == io.ErrUnexpectedEOF {
+		return fmt.Errorf("%w: read %d of %d bytes at offset %d", ErrTruncated, n, len(p), off)
+	}
+	return err
+}
+
+// readBufs pools the buffers of the small reads below, which would otherwise escape to the heap
+// through the DBReader on every call.
+var readBufs = sync.Pool{New: func() any { return new([maxStrSize]byte) }}
+
 // read byte
-func (d *DB) readUint8(pos int64) (uint8, error) {
-	data := make([]byte, 1)
-	_, err := d.f.ReadAt(data, pos-1)
-	if err != nil {
+func (d *DB) readUint8(ctx context.Context, pos int64) (uint8, error) {
+	buf := readBufs.Get().(*[maxStrSize]byte)
+	defer readBufs.Put(buf)
+	if err := d.readAt(ctx, buf[:1], pos-1); err != nil {
 		return 0, err
 	}
-	return data[0], nil
+	return buf[0], nil
 }
 
 // read unsigned 32-bit integer from slices
@@ -248,29 +372,21 @@ func (d *DB) readUint32Row(row []byte, pos uint32) uint32 {
 }
 
 // read unsigned 32-bit integer
-func (d *DB) readUint32(pos uint32) (uint32, error) {
-	pos2 := int64(pos)
-	data := make([]byte, 4)
-	_, err := d.f.ReadAt(data, pos2-1)
-	if err != nil {
+func (d *DB) readUint32(ctx context.Context, pos int64) (uint32, error) {
+	buf := readBufs.Get().(*[maxStrSize]byte)
+	defer readBufs.Put(buf)
+	if err := d.readAt(ctx, buf[:4], pos-1); err != nil {
 		return 0, err
 	}
-	buf := bytes.NewReader(data)
-
-	var res uint32
-	err = binary.Read(buf, binary.LittleEndian, &res)
-	if err != nil {
-		fmt.Printf("binary read failed: %v", err)
-	}
-	return res, nil
+	return binary.LittleEndian.Uint32(buf[:4]), nil
 }
 
 // read unsigned 128-bit integer
-func (d *DB) readUint128(pos uint32) (*big.Int, error) {
-	pos2 := int64(pos)
-	data := make([]byte, 16)
-	_, err := d.f.ReadAt(data, pos2-1)
-	if err != nil {
+func (d *DB) readUint128(ctx context.Context, pos int64) (*big.Int, error) {
+	buf := readBufs.Get().(*[maxStrSize]byte)
+	defer readBufs.Put(buf)
+	data := buf[:16]
+	if err := d.readAt(ctx, data, pos-1); err != nil {
 		return nil, err
 	}
 
@@ -282,20 +398,17 @@ func (d *DB) readUint128(pos uint32) (*big.Int, error) {
 }
 
 // read string
-func (d *DB) readStr(pos uint32) (string, error) {
-	pos2 := int64(pos)
-	lenbyte := make([]byte, 1)
-	_, err := d.f.ReadAt(lenbyte, pos2)
-	if err != nil {
+func (d *DB) readStr(ctx context.Context, pos int64) (string, error) {
+	buf := readBufs.Get().(*[maxStrSize]byte)
+	defer readBufs.Put(buf)
+	if err := d.readAt(ctx, buf[:1], pos); err != nil {
 		return "", err
 	}
-	strlen := lenbyte[0]
-	data := make([]byte, strlen)
-	_, err = d.f.ReadAt(data, pos2+1)
-	if err != nil {
+	data := buf[1 : 1+int(buf[0])]
+	if err := d.readAt(ctx, data, pos+1); err != nil {
 		return "", err
 	}
-	return string(data[:strlen]), nil
+	return string(data), nil
 }
 
 // read float from slices
@@ -306,127 +419,138 @@ func (d *DB) readFloatRow(row []byte, pos uint32) float32 {
 }
 
 // read float
-func (d *DB) readFloat(pos uint32) (float32, error) {
-	pos2 := int64(pos)
-	data := make([]byte, 4)
-	_, err := d.f.ReadAt(data, pos2-1)
-	if err != nil {
+func (d *DB) readFloat(ctx context.Context, pos int64) (float32, error) {
+	buf := readBufs.Get().(*[maxStrSize]byte)
+	defer readBufs.Put(buf)
+	if err := d.readAt(ctx, buf[:4], pos-1); err != nil {
 		return 0, err
 	}
-	buf := bytes.NewReader(data)
-	var res float32
-	err = binary.Read(buf, binary.LittleEndian, &res)
-	if err != nil {
-		fmt.Printf("binary read failed: %v", err)
-	}
-	return res, nil
+	return math.Float32frombits(binary.LittleEndian.Uint32(buf[:4])), nil
 }
 
-func fatal(db *DB, err error) (*DB, error) {
+func fatal(db *dbState, err error) error {
 	_ = db.f.Close()
-	return nil, err
+	return err
 }
 
-// OpenInMemoryDB takes the path to the IP2Location BIN database file. It will read all file data
-// and return the underlining DB object.
-func OpenInMemoryDB(dbpath string) (*DB, error) {
-	dbFile, err := os.Open(dbpath)
-	defer dbFile.Close()
+// OpenDBWithReader takes a DBReader to the IP2Location BIN database file. It will read all the metadata required to
+// be able to extract the embedded geolocation data, and return the underlining DB object.
+// Since the reader cannot be recreated, a DB opened this way is never reopened after a truncated read.
+func OpenDBWithReader(reader DBReader, opts ...Option) (*DB, error) {
+	return newDB(opts).init(reader)
+}
 
+// OpenBytes opens the IP2Location BIN database held in data, e.g. a database embedded with go:embed.
+// Zipped or gzipped data is decompressed first. It needs no file system and is available in builds
+// with the ip2loc_nofs tag. data must not be modified while the DB is in use.
+func OpenBytes(data []byte, opts ...Option) (*DB, error) {
+	data, err := decompress(data)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("ip2loc: decompressing database: %w", err)
 	}
+	return OpenDBWithReader(NewByteSliceReader(data), opts...)
+}
 
-	info, err := dbFile.Stat()
-	if err != nil {
-		return nil, err
+func newDB(opts []Option) *DB {
+	var db = &DB{}
+	for _, opt := range opts {
+		opt(db)
 	}
-
-	// calculate the bytes size
-	var size = info.Size()
-	fileData := make([]byte, size)
-
-	// read into buffer
-	buffer := bufio.NewReader(dbFile)
-	_, err = buffer.Read(fileData)
-
-	return OpenDBWithReader(&InMemoryDBReader{bytes.NewReader(fileData)})
+	return db
 }
 
-// Open takes the path to the IP2Location BIN database file. It will read all the metadata required to
-// be able to extract the embedded geolocation data, and return the underlining DB object.
-func OpenDB(dbpath string) (*DB, error) {
-	f, err := os.Open(dbpath)
+func (d *DB) init(reader DBReader) (*DB, error) {
+	state, err := d.load(reader)
 	if err != nil {
 		return nil, err
 	}
-
-	return OpenDBWithReader(f)
+	d.dbState = state
+	return d, nil
 }
 
-// OpenDBWithReader takes a DBReader to the IP2Location BIN database file. It will read all the metadata required to
-// be able to extract the embedded geolocation data, and return the underlining DB object.
-func OpenDBWithReader(reader DBReader) (*DB, error) {
-	var db = &DB{}
-
-	maxIpv6Range.SetString("340282366920938463463374607431768211455", 10)
-	from6to4.SetString("42545680458834377588178886921629466624", 10)
-	to6to4.SetString("42550872755692912415807417417958686719", 10)
-	fromTeredo.SetString("42540488161975842760550356425300246528", 10)
-	toTeredo.SetString("42540488241204005274814694018844196863", 10)
-
-	db.f = reader
+// load reads the metadata from the database header.
+func (d *DB) load(reader DBReader) (*dbState, error) {
+	var db = &DB{dbState: &dbState{f: reader}}
+	ctx := context.Background()
 
 	var err error
-	db.meta.databaseType, err = db.readUint8(1)
+	db.meta.databaseType, err = db.readUint8(ctx, 1)
 	if err != nil {
-		return fatal(db, err)
+		return nil, fatal(db.dbState, err)
 	}
-	db.meta.databaseColumn, err = db.readUint8(2)
+	db.meta.databaseColumn, err = db.readUint8(ctx, 2)
 	if err != nil {
-		return fatal(db, err)
+		return nil, fatal(db.dbState, err)
 	}
-	db.meta.databaseYear, err = db.readUint8(3)
+	db.meta.databaseYear, err = db.readUint8(ctx, 3)
 	if err != nil {
-		return fatal(db, err)
+		return nil, fatal(db.dbState, err)
 	}
-	db.meta.databaseMonth, err = db.readUint8(4)
+	db.meta.databaseMonth, err = db.readUint8(ctx, 4)
 	if err != nil {
-		return fatal(db, err)
+		return nil, fatal(db.dbState, err)
 	}
-	db.meta.databaseDay, err = db.readUint8(5)
+	db.meta.databaseDay, err = db.readUint8(ctx, 5)
 	if err != nil {
-		return fatal(db, err)
+		return nil, fatal(db.dbState, err)
 	}
-	db.meta.ipv4DatabaseCount, err = db.readUint32(6)
+	db.meta.ipv4DatabaseCount, err = db.readUint32(ctx, 6)
 	if err != nil {
-		return fatal(db, err)
+		return nil, fatal(db.dbState, err)
 	}
-	db.meta.ipv4DatabaseAddr, err = db.readUint32(10)
+	db.meta.ipv4DatabaseAddr, err = db.readUint32(ctx, 10)
 	if err != nil {
-		return fatal(db, err)
+		return nil, fatal(db.dbState, err)
 	}
-	db.meta.ipv6DatabaseCount, err = db.readUint32(14)
+	db.meta.ipv6DatabaseCount, err = db.readUint32(ctx, 14)
 	if err != nil {
-		return fatal(db, err)
+		return nil, fatal(db.dbState, err)
 	}
-	db.meta.ipv6DatabaseAddr, err = db.readUint32(18)
+	db.meta.ipv6DatabaseAddr, err = db.readUint32(ctx, 18)
 	if err != nil {
-		return fatal(db, err)
+		return nil, fatal(db.dbState, err)
 	}
-	db.meta.ipv4IndexBaseAddr, err = db.readUint32(22)
+	db.meta.ipv4IndexBaseAddr, err = db.readUint32(ctx, 22)
 	if err != nil {
-		return fatal(db, err)
+		return nil, fatal(db.dbState, err)
 	}
-	db.meta.ipv6IndexBaseAddr, err = db.readUint32(26)
+	db.meta.ipv6IndexBaseAddr, err = db.readUint32(ctx, 26)
 	if err != nil {
-		return fatal(db, err)
+		return nil, fatal(db.dbState, err)
 	}
-	db.meta.ipv4ColumnSize = uint32(db.meta.databaseColumn << 2)              // 4 bytes each column
-	db.meta.ipv6ColumnSize = uint32(16 + ((db.meta.databaseColumn - 1) << 2)) // 4 bytes each column, except IPFrom column which is 16 bytes
+	// computed in 32 bits so that a corrupt column count does not wrap around
+	db.meta.ipv4ColumnSize = uint32(db.meta.databaseColumn) << 2        // 4 bytes each column
+	db.meta.ipv6ColumnSize = 16 + (uint32(db.meta.databaseColumn)-1)<<2 // 4 bytes each column, except IPFrom column which is 16 bytes
 
 	dbt := db.meta.databaseType
 
+	if !knownDBType(dbt) {
+		// a newer product: every known layout stores the country in the second column,
+		// the other columns cannot be located without their position tables
+		if db.meta.databaseColumn < 2 {
+			return nil, fatal(db.dbState, fmt.Errorf("%w: DB%d with %d columns", ErrUnsupportedDBType, dbt, db.meta.databaseColumn))
+		}
+		db.countryEnabled = true
+		db.unknownType = true
+		db.restrictFields(d.fields)
+		db.supported = db.supportedFields()
+		db.strColumns = db.stringColumns()
+		db.strData = d.aliasedData(db.f)
+		db.metaOk = true
+		return db.dbState, nil
+	}
+	if n := requiredColumns(dbt); db.meta.databaseColumn < n {
+		return nil, fatal(db.dbState, fmt.Errorf("%w: DB%d needs %d columns, header declares %d", ErrUnsupportedDBType, dbt, n, db.meta.databaseColumn))
+	}
+	if r, ok := reader.(interface{ Bytes() []byte }); ok && d.fields != 0 {
+		if data, ok, err := project(r.Bytes(), db.meta, d.fields); err != nil {
+			return nil, fatal(db.dbState, err)
+		} else if ok {
+			_ = reader.Close()
+			return d.load(NewByteSliceReader(data))
+		}
+	}
+
 	if countryPosition[dbt] != 0 {
 		db.countryPositionOffset = uint32(countryPosition[dbt]-2) << 2
 		db.countryEnabled = true
@@ -504,9 +628,66 @@ func OpenDBWithReader(reader DBReader) (*DB, error) {
 		db.usageTypeEnabled = true
 	}
 
+	db.restrictFields(d.fields)
+	db.supported = db.supportedFields()
+	db.strColumns = db.stringColumns()
+	if r, ok := reader.(*ByteSliceReader); ok && d.ipVersion != 0 {
+		db.f = dropIPVersion(r.Bytes(), db.meta, d.ipVersion)
+	}
+	if d.rangeIndex {
+		if db.ranges, err = db.buildRangeIndex(d.ipVersion); err != nil {
+			return nil, fatal(db.dbState, err)
+		}
+	} else if d.prefixTable && d.ipVersion != 6 {
+		if db.prefixes, err = db.buildPrefixTable(); err != nil {
+			return nil, fatal(db.dbState, err)
+		}
+	}
+	db.strData = d.aliasedData(db.f)
 	db.metaOk = true
 
-	return db, nil
+	return db.dbState, nil
+}
+
+// fieldFlags returns the enabled flag of every column together with the query mode bits it serves.
+func (d *dbState) fieldFlags() []fieldFlag {
+	return []fieldFlag{
+		{&d.countryEnabled, countryShort | countryLong},
+		{&d.regionEnabled, region},
+		{&d.cityEnabled, city},
+		{&d.ispEnabled, isp},
+		{&d.latitudeEnabled, latitude},
+		{&d.longitudeEnabled, longitude},
+		{&d.domainEnabled, domain},
+		{&d.zipcodeEnabled, zipCode},
+		{&d.timeZoneEnabled, timezone},
+		{&d.netSpeedEnabled, netSpeed},
+		{&d.iddCodeEnabled, iddCode},
+		{&d.areaCodeEnabled, areaCode},
+		{&d.weatherStationCodeEnabled, weatherStationCode},
+		{&d.weatherStationNameEnabled, weatherStationName},
+		{&d.mccEnabled, mcc},
+		{&d.mncEnabled, mnc},
+		{&d.mobileBrandEnabled, mobileBrand},
+		{&d.elevationEnabled, elevation},
+		{&d.usageTypeEnabled, usageType},
+	}
+}
+
+type fieldFlag struct {
+	enabled *bool
+	mode    uint32
+}
+
+// supportedFields returns the query mode bits of the columns present in the database.
+func (d *dbState) supportedFields() uint32 {
+	var mode uint32
+	for _, f := range d.fieldFlags() {
+		if *f.enabled {
+			mode |= f.mode
+		}
+	}
+	return mode
 }
 
 // ApiVersion returns the version of the component.
@@ -634,7 +815,8 @@ func (d *DB) GetMobileBrand(ip string) (IP2LocationRecord, error) {
 	return d.query(ip, mobileBrand)
 }
 
-// GetElevation will return the elevation in meters based on the queried IP address.
+// GetElevation will return the elevation in meters, or the unit set by WithElevationUnit, based on
+// the queried IP address. It returns ErrInvalidElevation if the database holds no number for it.
 func (d *DB) GetElevation(ip string) (IP2LocationRecord, error) {
 	return d.query(ip, elevation)
 }
@@ -646,12 +828,58 @@ func (d *DB) GetUsageType(ip string) (IP2LocationRecord, error) {
 
 // main query
 func (d *DB) query(ip string, mode uint32) (IP2LocationRecord, error) {
-	x := loadMessage(parameterIsNotSupported) // default message
+	return d.queryContext(context.Background(), ip, mode)
+}
+
+func (d *DB) queryContext(ctx context.Context, ip string, mode uint32) (IP2LocationRecord, error) {
+	if d.flight != nil {
+		return d.sharedQuery(ctx, ip, mode)
+	}
+	return d.queryOnce(ctx, ip, mode)
+}
+
+func (d *DB) queryOnce(ctx context.Context, ip string, mode uint32) (IP2LocationRecord, error) {
+	state, x, err := d.lookupBounded(ctx, ip, mode)
+
+	if errors.Is(err, ErrTruncated) && d.recover(state, err) {
+		state, x, err = d.lookupBounded(ctx, ip, mode)
+
+		if errors.Is(err, ErrTruncated) {
+			// the reopened file is still short; stay unhealthy until a later retry
+			d.recover(state, err)
+		}
+	}
+	if err == nil || errors.Is(err, ErrNotFound) {
+		d.markHealthy()
+	}
+	d.notifyStale()
+	return x, wrapError(err)
+}
+
+func (d *DB) lookup(ctx context.Context, ip string, mode uint32) (IP2LocationRecord, error) {
+	iptype, m, x, err := d.find(ctx, ip, mode)
+	if err != nil {
+		return x, err
+	}
+	return d.readRecord(ctx, iptype, m.rowoffset, mode)
+}
+
+// find returns the IP type of ip and the row matching it. If it fails, x is the record to return.
+func (d *DB) find(ctx context.Context, ip string, mode uint32) (iptype uint32, m *match, x IP2LocationRecord, err error) {
+	x = loadMessage(parameterIsNotSupported) // default message
+
+	if d.closed {
+		return 0, nil, x, ErrClosed
+	}
 
 	// read metadata
-	if !d.metaOk {
+	if d.dbState == nil || !d.metaOk {
 		x = loadMessage(missingFile)
-		return x, nil
+		return 0, nil, x, ErrNotLoaded
+	}
+
+	if d.stalePolicy == StaleFail && d.isStale() {
+		return 0, nil, x, ErrStale
 	}
 
 	// check IP type and return IP number & index (if exists)
@@ -659,247 +887,125 @@ func (d *DB) query(ip string, mode uint32) (IP2LocationRecord, error) {
 
 	if iptype == 0 {
 		x = loadMessage(invalidAddress)
-		return x, nil
+		if d.anonymize {
+			return 0, nil, x, ErrInvalidIP // the input may hold anything, so it is not echoed
+		}
+		return 0, nil, x, fmt.Errorf("%w: %q", ErrInvalidIP, ip)
 	}
 
-	var err error
-	var colsize uint32
-	var baseaddr uint32
-	var low uint32
-	var high uint32
-	var mid uint32
-	var rowoffset uint32
-	var rowoffset2 uint32
-	ipfrom := big.NewInt(0)
-	ipto := big.NewInt(0)
-	maxip := big.NewInt(0)
-
-	if iptype == 4 {
-		baseaddr = d.meta.ipv4DatabaseAddr
-		high = d.meta.ipv4DatabaseCount
-		maxip = maxIpv4Range
-		colsize = d.meta.ipv4ColumnSize
-	} else {
-		baseaddr = d.meta.ipv6DatabaseAddr
-		high = d.meta.ipv6DatabaseCount
-		maxip = maxIpv6Range
-		colsize = d.meta.ipv6ColumnSize
+	if !d.ipVersionEnabled(iptype) {
+		return 0, nil, x, fmt.Errorf("%w: IPv%d", ErrIPVersionDisabled, iptype)
 	}
 
-	// reading index
-	if ipindex > 0 {
-		low, err = d.readUint32(ipindex)
-		if err != nil {
-			return x, err
-		}
-		high, err = d.readUint32(ipindex + 4)
-		if err != nil {
-			return x, err
+	if mode != all && mode&d.supported == 0 {
+		if d.unknownType {
+			return 0, nil, x, fmt.Errorf("%w: DB%d", ErrUnsupportedDBType, d.meta.databaseType)
 		}
+		return 0, nil, x, ErrUnsupportedField
 	}
 
-	if ipno.Cmp(maxip) >= 0 {
-		ipno.Sub(ipno, big.NewInt(1))
+	m, err = d.search(ctx, iptype, ipno, ipindex)
+	if err != nil {
+		return 0, nil, x, err
 	}
+	if m == nil {
+		return 0, nil, x, ErrNotFound
+	}
+	return iptype, m, x, nil
+}
 
-	for low <= high {
-		mid = (low + high) >> 1
-		rowoffset = baseaddr + (mid * colsize)
-		rowoffset2 = rowoffset + colsize
+// readRecord reads the fields selected by mode from the row at rowoffset.
+func (d *DB) readRecord(ctx context.Context, iptype uint32, rowoffset int64, mode uint32) (IP2LocationRecord, error) {
+	colsize := d.meta.ipv4ColumnSize
+	if iptype == 6 {
+		colsize = d.meta.ipv6ColumnSize
+	}
 
-		if iptype == 4 {
-			ipfrom32, err := d.readUint32(rowoffset)
-			if err != nil {
-				return x, err
-			}
-			ipfrom = big.NewInt(int64(ipfrom32))
+	var firstcol uint32 = 4 // 4 bytes for ip from
+	if iptype == 6 {
+		firstcol = 16 // 16 bytes for ipv6
+	}
 
-			ipto32, err := d.readUint32(rowoffset2)
-			if err != nil {
-				return x, err
-			}
-			ipto = big.NewInt(int64(ipto32))
+	row := make([]byte, colsize-firstcol) // exclude the ip from field
+	if err := d.readAt(ctx, row, rowoffset+int64(firstcol)-1); err != nil {
+		return loadMessage(parameterIsNotSupported), err
+	}
+	return d.decodeRow(ctx, iptype, rowoffset, row, mode, nil)
+}
 
-		} else {
-			ipfrom, err = d.readUint128(rowoffset)
-			if err != nil {
-				return x, err
-			}
+// decodeRow reads the fields selected by mode from row, the row at rowoffset without its ip from
+// column. The strings are read through scan if it is not nil.
+func (d *DB) decodeRow(ctx context.Context, iptype uint32, rowoffset int64, row []byte, mode uint32, scan *scanBuffer) (IP2LocationRecord, error) {
+	x := loadMessage(parameterIsNotSupported) // default message
 
-			ipto, err = d.readUint128(rowoffset2)
-			if err != nil {
-				return x, err
-			}
+	var err error
+	// the country is also needed to enrich the record when the short name was not asked for
+	want := mode
+	if d.enrichesCountry(mode) {
+		want |= countryShort
+	}
+	refs := make([]strRef, 0, len(d.strColumns))
+	for i := range d.strColumns {
+		if c := &d.strColumns[i]; want&c.mode != 0 {
+			refs = append(refs, strRef{int64(d.readUint32Row(row, c.offset)) + c.skip, c})
 		}
+	}
+	if scan != nil && d.strData == nil {
+		err = scan.readStrings(ctx, d, refs, &x)
+	} else {
+		err = d.readStrings(ctx, refs, &x)
+	}
+	if err != nil {
+		return x, err
+	}
+	country := x.CountryShort
+	if mode&countryShort == 0 {
+		x.CountryShort = parameterIsNotSupported
+	}
 
-		if ipno.Cmp(ipfrom) >= 0 && ipno.Cmp(ipto) < 0 {
-			var firstcol uint32 = 4 // 4 bytes for ip from
-			if iptype == 6 {
-				firstcol = 16 // 16 bytes for ipv6
-			}
-
-			row := make([]byte, colsize-firstcol) // exclude the ip from field
-			_, err := d.f.ReadAt(row, int64(rowoffset+firstcol-1))
-			if err != nil {
-				return x, err
-			}
-
-			if mode&countryShort == 1 && d.countryEnabled {
-				if x.CountryShort, err = d.readStr(d.readUint32Row(row, d.countryPositionOffset)); err != nil {
-					return x, err
-				}
-			}
-
-			if mode&countryLong != 0 && d.countryEnabled {
-				if x.CountryLong, err = d.readStr(d.readUint32Row(row, d.countryPositionOffset) + 3); err != nil {
-					return x, err
-				}
-			}
-
-			if mode&region != 0 && d.regionEnabled {
-				if x.Region, err = d.readStr(d.readUint32Row(row, d.regionPositionOffset)); err != nil {
-					return x, err
-				}
-			}
-
-			if mode&city != 0 && d.cityEnabled {
-				if x.City, err = d.readStr(d.readUint32Row(row, d.cityPositionOffset)); err != nil {
-					return x, err
-				}
-			}
-
-			if mode&isp != 0 && d.ispEnabled {
-				if x.Isp, err = d.readStr(d.readUint32Row(row, d.ispPositionOffset)); err != nil {
-					return x, err
-				}
-			}
-
-			if mode&latitude != 0 && d.latitudeEnabled {
-				x.Latitude = d.readFloatRow(row, d.latitudePositionOffset)
-			}
-
-			if mode&longitude != 0 && d.longitudeEnabled {
-				x.Longitude = d.readFloatRow(row, d.longitudePositionOffset)
-			}
-
-			if mode&domain != 0 && d.domainEnabled {
-				if x.Domain, err = d.readStr(d.readUint32Row(row, d.domainPositionOffset)); err != nil {
-					return x, err
-				}
-			}
-
-			if mode&zipCode != 0 && d.zipcodeEnabled {
-				if x.ZipCode, err = d.readStr(d.readUint32Row(row, d.zipcodePositionOffset)); err != nil {
-					return x, err
-				}
-			}
-
-			if mode&timezone != 0 && d.timeZoneEnabled {
-				if x.Timezone, err = d.readStr(d.readUint32Row(row, d.timezonePositionOffset)); err != nil {
-					return x, err
-				}
-			}
-
-			if mode&netSpeed != 0 && d.netSpeedEnabled {
-				if x.NetSpeed, err = d.readStr(d.readUint32Row(row, d.netSpeedPositionOffset)); err != nil {
-					return x, err
-				}
-			}
-
-			if mode&iddCode != 0 && d.iddCodeEnabled {
-				if x.IddCode, err = d.readStr(d.readUint32Row(row, d.iddCodePositionOffset)); err != nil {
-					return x, err
-				}
-			}
-
-			if mode&areaCode != 0 && d.areaCodeEnabled {
-				if x.AreaCode, err = d.readStr(d.readUint32Row(row, d.areaCodePositionOffset)); err != nil {
-					return x, err
-				}
-			}
-
-			if mode&weatherStationCode != 0 && d.weatherStationCodeEnabled {
-				if x.WeatherStationCode, err = d.readStr(d.readUint32Row(row, d.weatherStationCodePositionOffset)); err != nil {
-					return x, err
-				}
-			}
-
-			if mode&weatherStationName != 0 && d.weatherStationNameEnabled {
-				if x.WeatherStationName, err = d.readStr(d.readUint32Row(row, d.weatherStationNamePositionOffset)); err != nil {
-					return x, err
-				}
-			}
-
-			if mode&mcc != 0 && d.mccEnabled {
-				if x.MCC, err = d.readStr(d.readUint32Row(row, d.mccPositionOffset)); err != nil {
-					return x, err
-				}
-			}
-
-			if mode&mnc != 0 && d.mncEnabled {
-				if x.MNC, err = d.readStr(d.readUint32Row(row, d.mncPositionOffset)); err != nil {
-					return x, err
-				}
-			}
-
-			if mode&mobileBrand != 0 && d.mobileBrandEnabled {
-				if x.MobileBrand, err = d.readStr(d.readUint32Row(row, d.mobileBrandPositionOffset)); err != nil {
-					return x, err
-				}
-			}
+	if mode&latitude != 0 && d.latitudeEnabled {
+		x.Latitude = d.readFloatRow(row, d.latitudePositionOffset)
+	}
 
-			if mode&elevation != 0 && d.elevationEnabled {
-				res, err := d.readStr(d.readUint32Row(row, d.elevationPositionOffset))
-				if err != nil {
-					return x, err
-				}
+	if mode&longitude != 0 && d.longitudeEnabled {
+		x.Longitude = d.readFloatRow(row, d.longitudePositionOffset)
+	}
 
-				f, _ := strconv.ParseFloat(res, 32)
-				x.Elevation = float32(f)
-			}
+	if err = d.convertElevation(&x, mode); err != nil {
+		return x, err
+	}
 
-			if mode&usageType != 0 && d.usageTypeEnabled {
-				if x.UsageType, err = d.readStr(d.readUint32Row(row, d.usageTypePositionOffset)); err != nil {
-					return x, err
-				}
-			}
+	d.enrich(&x, country, mode)
 
-			return x, nil
-		} else {
-			if ipno.Cmp(ipfrom) < 0 {
-				high = mid - 1
-			} else {
-				low = mid + 1
-			}
+	if d.rawRow {
+		colsize := d.meta.ipv4ColumnSize
+		if iptype == 6 {
+			colsize = d.meta.ipv6ColumnSize
+		}
+		if x.RawRow, err = d.readRawRow(ctx, iptype, rowoffset, colsize); err != nil {
+			return x, err
 		}
 	}
+
 	return x, nil
 }
 
-func (d *DB) Close() {
-	_ = d.f.Close()
-}
-
-// PrintRecord is used to output the geolocation data for debugging purposes.
-func PrintRecord(x IP2LocationRecord) {
-	fmt.Printf("countryShort: %s\n", x.CountryShort)
-	fmt.Printf("countryLong: %s\n", x.CountryLong)
-	fmt.Printf("region: %s\n", x.Region)
-	fmt.Printf("city: %s\n", x.City)
-	fmt.Printf("isp: %s\n", x.Isp)
-	fmt.Printf("latitude: %f\n", x.Latitude)
-	fmt.Printf("longitude: %f\n", x.Longitude)
-	fmt.Printf("domain: %s\n", x.Domain)
-	fmt.Printf("zipCode: %s\n", x.ZipCode)
-	fmt.Printf("timezone: %s\n", x.Timezone)
-	fmt.Printf("netSpeed: %s\n", x.NetSpeed)
-	fmt.Printf("iddCode: %s\n", x.IddCode)
-	fmt.Printf("areaCode: %s\n", x.AreaCode)
-	fmt.Printf("weatherStationCode: %s\n", x.WeatherStationCode)
-	fmt.Printf("weatherStationName: %s\n", x.WeatherStationName)
-	fmt.Printf("mcc: %s\n", x.MCC)
-	fmt.Printf("mnc: %s\n", x.MNC)
-	fmt.Printf("mobileBrand: %s\n", x.MobileBrand)
-	fmt.Printf("elevation: %f\n", x.Elevation)
-	fmt.Printf("usageType: %s\n", x.UsageType)
+// Close closes the database. It stops the workers of the DB and waits for running lookups to
+// finish; lookups started afterwards return ErrClosed. Calling Close more than once is a no-op.
+func (d *DB) Close() error {
+	// the workers may wait for the lock themselves
+	d.stopWorkers()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.closed {
+		return nil
+	}
+	d.closed = true
+	d.unwatchReplace()
+	if d.dbState == nil {
+		return nil
+	}
+	return d.f.Close()
 }
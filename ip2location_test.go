@@ -0,0 +1,57 @@
+//go:build !ip2loc_nofs
+
+package ip2loc
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+func TestCloseDuringLookups(t *testing.T) {
+	path := testDB(t)
+	for _, m := range benchModes {
+		t.Run(m.name, func(t *testing.T) {
+			db, err := m.open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var wg sync.WaitGroup
+			started := make(chan struct{}, 8)
+			errs := make(chan error, 8)
+			for g := range 8 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for i := g; ; i++ {
+						_, err := db.GetAll(testIPs[i%len(testIPs)])
+						if i == g {
+							started <- struct{}{}
+						}
+						if err != nil && !errors.Is(err, ErrNotFound) {
+							errs <- err
+							return
+						}
+					}
+				}()
+			}
+			for range 8 {
+				<-started
+			}
+			if err := db.Close(); err != nil {
+				t.Fatal(err)
+			}
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				if !errors.Is(err, ErrClosed) {
+					t.Errorf("lookup during Close: %v, want ErrClosed", err)
+				}
+			}
+			if _, err := db.GetAll("8.8.8.8"); !errors.Is(err, ErrClosed) {
+				t.Errorf("lookup after Close: %v, want ErrClosed", err)
+			}
+		})
+	}
+}
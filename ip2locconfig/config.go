@@ -0,0 +1,90 @@
+// Package ip2locconfig reads the ip2loc.Config of a Manager from a YAML or JSON file or from the
+// environment:
+//
+//	m, err := ip2locconfig.LoadConfig("ip2loc.yaml")
+//
+// It is kept in its own module so that the ip2loc module does not depend on a YAML parser.
+package ip2locconfig
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/ferluci/ip2loc"
+)
+
+// Read reads a Config from the YAML or JSON file at path. Unknown keys are an error, so that
+// misspelt settings do not go unnoticed.
+func Read(path string) (ip2loc.Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return ip2loc.Config{}, err
+	}
+	defer f.Close()
+
+	var cfg ip2loc.Config
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil {
+		return ip2loc.Config{}, fmt.Errorf("ip2loc: reading %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// FromEnv returns the Config read from the file named by IP2LOC_CONFIG. Without it, the Config
+// has a single database described by IP2LOC_DB (the path), IP2LOC_MODE, IP2LOC_REFRESH and
+// IP2LOC_MAX_AGE, served on the comma-separated addresses of IP2LOC_LISTEN.
+func FromEnv() (ip2loc.Config, error) {
+	if path := os.Getenv("IP2LOC_CONFIG"); path != "" {
+		return Read(path)
+	}
+	path := os.Getenv("IP2LOC_DB")
+	if path == "" {
+		return ip2loc.Config{}, errors.New("ip2loc: neither IP2LOC_CONFIG nor IP2LOC_DB is set")
+	}
+	db := ip2loc.DatabaseConfig{Path: path, Mode: os.Getenv("IP2LOC_MODE")}
+	for _, d := range []struct {
+		name string
+		v    *time.Duration
+	}{{"IP2LOC_REFRESH", &db.Refresh}, {"IP2LOC_MAX_AGE", &db.MaxAge}} {
+		s := os.Getenv(d.name)
+		if s == "" {
+			continue
+		}
+		v, err := time.ParseDuration(s)
+		if err != nil {
+			return ip2loc.Config{}, fmt.Errorf("ip2loc: %s: %w", d.name, err)
+		}
+		*d.v = v
+	}
+
+	cfg := ip2loc.Config{Databases: []ip2loc.DatabaseConfig{db}}
+	if listen := os.Getenv("IP2LOC_LISTEN"); listen != "" {
+		cfg.Server.Listen = strings.Split(listen, ",")
+	}
+	return cfg, nil
+}
+
+// LoadConfig reads the Config at path with Read and opens its databases with ip2loc.NewManager.
+func LoadConfig(path string, opts ...ip2loc.Option) (*ip2loc.Manager, error) {
+	cfg, err := Read(path)
+	if err != nil {
+		return nil, err
+	}
+	return ip2loc.NewManager(cfg, opts...)
+}
+
+// LoadFromEnv reads the Config from the environment with FromEnv and opens its databases with
+// ip2loc.NewManager.
+func LoadFromEnv(opts ...ip2loc.Option) (*ip2loc.Manager, error) {
+	cfg, err := FromEnv()
+	if err != nil {
+		return nil, err
+	}
+	return ip2loc.NewManager(cfg, opts...)
+}
@@ -0,0 +1,233 @@
+package ip2locconfig
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ferluci/ip2loc"
+)
+
+// testDB is the database of the tests of the ip2loc package, written by its TestTestdataDB.
+const testDB = "../testdata/IP2LOCATION-DB5.BIN"
+
+// writeConfig writes data to a file named name in a temporary directory and returns its path.
+func writeConfig(t *testing.T, name, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRead(t *testing.T) {
+	full := ip2loc.Config{
+		Databases: []ip2loc.DatabaseConfig{
+			{Name: "city", Path: "/var/lib/ip2loc/DB11.BIN", Mode: "mmap", Refresh: 24 * time.Hour, MaxAge: 720 * time.Hour, StalePolicy: "fail", Fields: []string{"country_short", "city"}},
+			{Path: "/var/lib/ip2loc/PX2.BIN", Mode: "cached", CacheSize: 64 << 20, BlockSize: 4096},
+		},
+	}
+	full.Server.Database = "city"
+	full.Server.Listen = []string{":8080", "unix:/run/ip2loc.sock"}
+	full.Server.Admin = true
+	full.Server.TLS.CertFile = "cert.pem"
+	full.Server.TLS.KeyFile = "key.pem"
+
+	for _, tt := range []struct {
+		name string
+		file string
+		data string
+		want ip2loc.Config
+		ok   bool
+	}{
+		{
+			name: "yaml",
+			file: "ip2loc.yaml",
+			data: `databases:
+  - name: city
+    path: /var/lib/ip2loc/DB11.BIN
+    mode: mmap
+    refresh: 24h
+    max_age: 720h
+    stale_policy: fail
+    fields: [country_short, city]
+  - path: /var/lib/ip2loc/PX2.BIN
+    mode: cached
+    cache_size: 67108864
+    block_size: 4096
+server:
+  database: city
+  listen: [":8080", "unix:/run/ip2loc.sock"]
+  admin: true
+  tls:
+    cert_file: cert.pem
+    key_file: key.pem
+`,
+			want: full,
+			ok:   true,
+		},
+		{
+			name: "json",
+			file: "ip2loc.json",
+			data: `{"databases": [{"path": "DB5.BIN", "refresh": "1h30m"}], "server": {"listen": [":8080"]}}`,
+			want: ip2loc.Config{
+				Databases: []ip2loc.DatabaseConfig{{Path: "DB5.BIN", Refresh: 90 * time.Minute}},
+				Server:    ip2loc.ServerConfig{Listen: []string{":8080"}},
+			},
+			ok: true,
+		},
+		{name: "invalid duration", file: "ip2loc.yaml", data: "databases:\n  - path: DB5.BIN\n    refresh: soon\n"},
+		{name: "duration without unit", file: "ip2loc.yaml", data: "databases:\n  - path: DB5.BIN\n    max_age: 3600\n"},
+		{name: "unknown key", file: "ip2loc.yaml", data: "databases:\n  - path: DB5.BIN\n    refresh_interval: 1h\n"},
+		{name: "unknown section", file: "ip2loc.yaml", data: "databases:\n  - path: DB5.BIN\nlogging: debug\n"},
+		{name: "invalid type", file: "ip2loc.yaml", data: "databases:\n  - path: DB5.BIN\nserver:\n  admin: sometimes\n"},
+		{name: "syntax", file: "ip2loc.json", data: `{"databases": [`},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Read(writeConfig(t, tt.file, tt.data))
+			if !tt.ok {
+				if err == nil {
+					t.Fatalf("Read succeeded: %+v", cfg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(cfg, tt.want) {
+				t.Errorf("got %+v, want %+v", cfg, tt.want)
+			}
+		})
+	}
+
+	if _, err := Read(filepath.Join(t.TempDir(), "missing.yaml")); !os.IsNotExist(err) {
+		t.Errorf("missing file: got %v", err)
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	file := writeConfig(t, "ip2loc.yaml", "databases:\n  - path: from-file.BIN\n")
+
+	for _, tt := range []struct {
+		name string
+		env  map[string]string
+		want ip2loc.Config
+		ok   bool
+	}{
+		{
+			name: "database",
+			env:  map[string]string{"IP2LOC_DB": "DB5.BIN"},
+			want: ip2loc.Config{Databases: []ip2loc.DatabaseConfig{{Path: "DB5.BIN"}}},
+			ok:   true,
+		},
+		{
+			name: "all variables",
+			env: map[string]string{
+				"IP2LOC_DB":      "DB5.BIN",
+				"IP2LOC_MODE":    "memory",
+				"IP2LOC_REFRESH": "12h",
+				"IP2LOC_MAX_AGE": "720h",
+				"IP2LOC_LISTEN":  ":8080,unix:/run/ip2loc.sock",
+			},
+			want: ip2loc.Config{
+				Databases: []ip2loc.DatabaseConfig{{Path: "DB5.BIN", Mode: "memory", Refresh: 12 * time.Hour, MaxAge: 720 * time.Hour}},
+				Server:    ip2loc.ServerConfig{Listen: []string{":8080", "unix:/run/ip2loc.sock"}},
+			},
+			ok: true,
+		},
+		{
+			// the file replaces the other variables rather than being overridden by them
+			name: "config file",
+			env:  map[string]string{"IP2LOC_CONFIG": file, "IP2LOC_DB": "DB5.BIN", "IP2LOC_MODE": "memory"},
+			want: ip2loc.Config{Databases: []ip2loc.DatabaseConfig{{Path: "from-file.BIN"}}},
+			ok:   true,
+		},
+		{name: "nothing set", env: map[string]string{}},
+		{name: "missing config file", env: map[string]string{"IP2LOC_CONFIG": file + ".missing", "IP2LOC_DB": "DB5.BIN"}},
+		{name: "invalid refresh", env: map[string]string{"IP2LOC_DB": "DB5.BIN", "IP2LOC_REFRESH": "daily"}},
+		{name: "invalid max age", env: map[string]string{"IP2LOC_DB": "DB5.BIN", "IP2LOC_MAX_AGE": "30"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"IP2LOC_CONFIG", "IP2LOC_DB", "IP2LOC_MODE", "IP2LOC_REFRESH", "IP2LOC_MAX_AGE", "IP2LOC_LISTEN"} {
+				t.Setenv(name, tt.env[name])
+			}
+			cfg, err := FromEnv()
+			if !tt.ok {
+				if err == nil {
+					t.Fatalf("FromEnv succeeded: %+v", cfg)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(cfg, tt.want) {
+				t.Errorf("got %+v, want %+v", cfg, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	path, err := filepath.Abs(testDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name string
+		data string
+		ok   bool
+	}{
+		{"disk", "databases:\n  - name: geo\n    path: " + path + "\n", true},
+		{"memory", "databases:\n  - name: geo\n    path: " + path + "\n    mode: memory\n", true},
+		{"unknown mode", "databases:\n  - name: geo\n    path: " + path + "\n    mode: tape\n", false},
+		{"missing database", "databases:\n  - name: geo\n    path: " + path + ".missing\n", false},
+		{"no databases", "server:\n  listen: [\":8080\"]\n", false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := LoadConfig(writeConfig(t, "ip2loc.yaml", tt.data))
+			if !tt.ok {
+				if err == nil {
+					m.Close()
+					t.Fatal("LoadConfig succeeded")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer m.Close()
+			if m.DB("geo") != m.Default() {
+				t.Errorf("Names() = %v", m.Names())
+			}
+			x, err := m.Default().GetAll("81.2.69.160")
+			if err != nil || x.City != "London" {
+				t.Errorf("got %+v, %v", x, err)
+			}
+		})
+	}
+}
+
+func TestLoadFromEnv(t *testing.T) {
+	t.Setenv("IP2LOC_CONFIG", "")
+	t.Setenv("IP2LOC_DB", testDB)
+	t.Setenv("IP2LOC_MODE", "mmap")
+	m, err := LoadFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	if x, err := m.Default().GetAll("2a00:1450::1"); err != nil || x.CountryShort != "IE" {
+		t.Errorf("got %+v, %v", x, err)
+	}
+
+	t.Setenv("IP2LOC_MODE", "tape")
+	if m, err := LoadFromEnv(); err == nil {
+		m.Close()
+		t.Error("LoadFromEnv succeeded with an unknown mode")
+	}
+}
@@ -0,0 +1,13 @@
+module github.com/ferluci/ip2loc/ip2locconfig
+
+go 1.23.0
+
+require (
+	github.com/ferluci/ip2loc v0.0.0-20261016234639-b580dc0a984c
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	golang.org/x/sync v0.15.0 // indirect
+	golang.org/x/sys v0.33.0 // indirect
+)
@@ -0,0 +1,14 @@
+//go:build !ip2loc_nofs
+
+package ip2locgeoip2
+
+import "github.com/ferluci/ip2loc"
+
+// Open opens the IP2Location BIN database at path.
+func Open(path string, opts ...ip2loc.Option) (*Reader, error) {
+	db, err := ip2loc.OpenDB(path, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Reader{db: db}, nil
+}
@@ -0,0 +1,223 @@
+// Package ip2locgeoip2 is a thin adapter exposing an ip2loc database through the API of
+// github.com/oschwald/geoip2-golang, so services can switch data vendors without rewriting the
+// call sites which read fields such as record.City.Names["en"] or record.Country.IsoCode.
+//
+// Only fields IP2Location provides are filled: names are English only, GeoName IDs are always 0,
+// and Location.TimeZone is empty since IP2Location stores UTC offsets rather than IANA names.
+package ip2locgeoip2
+
+import (
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/ferluci/ip2loc"
+)
+
+// Reader answers GeoIP2 style queries from an ip2loc database.
+type Reader struct {
+	db *ip2loc.DB
+}
+
+// FromDB returns a Reader backed by an opened database.
+func FromDB(db *ip2loc.DB) *Reader {
+	return &Reader{db: db}
+}
+
+// Close closes the database.
+func (r *Reader) Close() error {
+	return r.db.Close()
+}
+
+// The City response, laid out like geoip2.City.
+type City struct {
+	City struct {
+		GeoNameID uint
+		Names     map[string]string
+	}
+	Continent struct {
+		Code      string
+		GeoNameID uint
+		Names     map[string]string
+	}
+	Country struct {
+		GeoNameID         uint
+		IsInEuropeanUnion bool
+		IsoCode           string
+		Names             map[string]string
+	}
+	Location struct {
+		AccuracyRadius uint16
+		Latitude       float64
+		Longitude      float64
+		MetroCode      uint
+		TimeZone       string
+	}
+	Postal struct {
+		Code string
+	}
+	RegisteredCountry struct {
+		GeoNameID         uint
+		IsInEuropeanUnion bool
+		IsoCode           string
+		Names             map[string]string
+	}
+	RepresentedCountry struct {
+		GeoNameID         uint
+		IsInEuropeanUnion bool
+		IsoCode           string
+		Names             map[string]string
+		Type              string
+	}
+	Subdivisions []struct {
+		GeoNameID uint
+		IsoCode   string
+		Names     map[string]string
+	}
+	Traits struct {
+		IsAnonymousProxy    bool
+		IsSatelliteProvider bool
+	}
+}
+
+// The Country response, laid out like geoip2.Country.
+type Country struct {
+	Continent struct {
+		Code      string
+		GeoNameID uint
+		Names     map[string]string
+	}
+	Country struct {
+		GeoNameID         uint
+		IsInEuropeanUnion bool
+		IsoCode           string
+		Names             map[string]string
+	}
+	RegisteredCountry struct {
+		GeoNameID         uint
+		IsInEuropeanUnion bool
+		IsoCode           string
+		Names             map[string]string
+	}
+	RepresentedCountry struct {
+		GeoNameID         uint
+		IsInEuropeanUnion bool
+		IsoCode           string
+		Names             map[string]string
+		Type              string
+	}
+	Traits struct {
+		IsAnonymousProxy    bool
+		IsSatelliteProvider bool
+	}
+}
+
+// The ISP response, laid out like geoip2.ISP. IP2Location has no AS data, so only ISP and
+// Organization are filled, both with the ISP name.
+type ISP struct {
+	AutonomousSystemNumber       uint
+	AutonomousSystemOrganization string
+	ISP                          string
+	MobileCountryCode            string
+	MobileNetworkCode            string
+	Organization                 string
+}
+
+// City returns the city record of ip. Like geoip2, an address without data yields an empty
+// record and a nil error.
+func (r *Reader) City(ip net.IP) (*City, error) {
+	x, err := r.lookup(ip)
+	if err != nil {
+		return &City{}, err
+	}
+
+	var c City
+	c.Country.IsoCode, c.Country.Names, c.Country.IsInEuropeanUnion = country(x)
+	c.RegisteredCountry = c.Country
+	c.Continent.Code, c.Continent.Names = continent(x)
+	if n := names(x.City); n != nil {
+		c.City.Names = n
+	}
+	if n := names(x.Region); n != nil {
+		_, code, _ := strings.Cut(x.RegionCode, "-")
+		c.Subdivisions = append(c.Subdivisions, struct {
+			GeoNameID uint
+			IsoCode   string
+			Names     map[string]string
+		}{IsoCode: code, Names: n})
+	}
+	c.Location.Latitude = x.Latitude64()
+	c.Location.Longitude = x.Longitude64()
+	if x.ZipCode != "-" {
+		c.Postal.Code = x.ZipCode
+	}
+	return &c, nil
+}
+
+// Country returns the country record of ip. Like geoip2, an address without data yields an empty
+// record and a nil error.
+func (r *Reader) Country(ip net.IP) (*Country, error) {
+	x, err := r.lookup(ip)
+	if err != nil {
+		return &Country{}, err
+	}
+
+	var c Country
+	c.Country.IsoCode, c.Country.Names, c.Country.IsInEuropeanUnion = country(x)
+	c.RegisteredCountry = c.Country
+	c.Continent.Code, c.Continent.Names = continent(x)
+	return &c, nil
+}
+
+// ISP returns the ISP record of ip. Like geoip2, an address without data yields an empty record
+// and a nil error.
+func (r *Reader) ISP(ip net.IP) (*ISP, error) {
+	x, err := r.lookup(ip)
+	if err != nil {
+		return &ISP{}, err
+	}
+
+	var i ISP
+	if x.Isp != "-" {
+		i.ISP, i.Organization = x.Isp, x.Isp
+	}
+	if x.MCC != "-" {
+		i.MobileCountryCode, i.MobileNetworkCode = x.MCC, x.MNC
+	}
+	return &i, nil
+}
+
+func (r *Reader) lookup(ip net.IP) (ip2loc.IP2LocationRecord, error) {
+	if ip == nil {
+		return ip2loc.IP2LocationRecord{}, errors.New("ip2locgeoip2: ip address is nil")
+	}
+	x, err := r.db.GetAll(ip.String())
+	if errors.Is(err, ip2loc.ErrNotFound) {
+		return ip2loc.IP2LocationRecord{}, nil
+	}
+	return x.WithoutPlaceholders(), err
+}
+
+func names(s string) map[string]string {
+	if s == "" || s == "-" {
+		return nil
+	}
+	return map[string]string{"en": s}
+}
+
+func country(x ip2loc.IP2LocationRecord) (string, map[string]string, bool) {
+	if x.CountryShort == "" || x.CountryShort == "-" {
+		return "", nil, false
+	}
+	return x.CountryShort, names(x.CountryLong), ip2loc.IsEU(x.CountryShort)
+}
+
+// continent returns the continent of the country of x, named by the country information of
+// ip2loc.WithCountryInfo if it has a continent column.
+func continent(x ip2loc.IP2LocationRecord) (string, map[string]string) {
+	c := x.Continent()
+	if x.Country != nil && x.Country.Continent != "" {
+		return string(c), names(x.Country.Continent)
+	}
+	return string(c), names(c.Name())
+}
@@ -0,0 +1,82 @@
+//go:build !ip2loc_nofs
+
+package ip2locgeoip2
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+// testDB is the database of the tests of the ip2loc package, written by its TestTestdataDB.
+const testDB = "../testdata/IP2LOCATION-DB5.BIN"
+
+func TestReader(t *testing.T) {
+	r, err := Open(testDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	for _, tt := range []struct {
+		ip                     string
+		iso, country           string
+		eu                     bool
+		continent, continentEN string
+		city, region           string
+		latitude, longitude    float64
+	}{
+		{"81.2.69.160", "GB", "United Kingdom of Great Britain and Northern Ireland", false, "EU", "Europe", "London", "England", 51.50853, -0.12574},
+		{"8.8.8.8", "US", "United States of America", false, "NA", "North America", "Mountain View", "California", 37.405991, -122.078514},
+		{"193.0.0.1", "NL", "Netherlands (Kingdom of the)", true, "EU", "Europe", "Amsterdam", "Noord-Holland", 52.374031, 4.88969},
+		{"2a00:1450::1", "IE", "Ireland", true, "EU", "Europe", "Dublin", "Dublin", 53.34399, -6.26719},
+		// ranges without data yield empty records
+		{ip: "1.1.1.1"},
+		{ip: "2001:db8::1"},
+	} {
+		t.Run(tt.ip, func(t *testing.T) {
+			var want City
+			if tt.iso != "" {
+				want.Country.IsoCode = tt.iso
+				want.Country.Names = map[string]string{"en": tt.country}
+				want.Country.IsInEuropeanUnion = tt.eu
+				want.RegisteredCountry = want.Country
+				want.Continent.Code = tt.continent
+				want.Continent.Names = map[string]string{"en": tt.continentEN}
+				want.City.Names = map[string]string{"en": tt.city}
+				want.Subdivisions = append(want.Subdivisions, struct {
+					GeoNameID uint
+					IsoCode   string
+					Names     map[string]string
+				}{Names: map[string]string{"en": tt.region}})
+				want.Location.Latitude, want.Location.Longitude = tt.latitude, tt.longitude
+			}
+
+			c, err := r.City(net.ParseIP(tt.ip))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(*c, want) {
+				t.Errorf("City: got %+v, want %+v", *c, want)
+			}
+
+			country, err := r.Country(net.ParseIP(tt.ip))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(country.Country, want.Country) || !reflect.DeepEqual(country.RegisteredCountry, want.RegisteredCountry) ||
+				!reflect.DeepEqual(country.Continent, want.Continent) {
+				t.Errorf("Country: got %+v, want the country and continent of %+v", *country, want)
+			}
+
+			// the database has no ISP column
+			if i, err := r.ISP(net.ParseIP(tt.ip)); err != nil || *i != (ISP{}) {
+				t.Errorf("ISP: got %+v, %v", *i, err)
+			}
+		})
+	}
+
+	if _, err := r.City(nil); err == nil {
+		t.Error("City(nil) succeeded")
+	}
+}
@@ -0,0 +1,17 @@
+module github.com/ferluci/ip2loc/ip2locgrpc
+
+go 1.23.0
+
+require (
+	github.com/ferluci/ip2loc v0.0.0-20261016234639-b580dc0a984c
+	google.golang.org/grpc v1.73.0
+)
+
+require (
+	golang.org/x/net v0.38.0 // indirect
+	golang.org/x/sync v0.15.0 // indirect
+	golang.org/x/sys v0.33.0 // indirect
+	golang.org/x/text v0.23.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20250324211829-b45e905df463 // indirect
+	google.golang.org/protobuf v1.36.12 // indirect
+)
@@ -0,0 +1,160 @@
+// Package ip2locgrpc provides grpc-go server interceptors which geolocate the peer of every call,
+// attach the record to the call context and optionally reject calls from some countries.
+//
+//	s := grpc.NewServer(
+//		grpc.UnaryInterceptor(ip2locgrpc.UnaryServerInterceptor(db, ip2locgrpc.Options{})),
+//		grpc.StreamInterceptor(ip2locgrpc.StreamServerInterceptor(db, ip2locgrpc.Options{})),
+//	)
+//
+// Handlers read the record with FromContext.
+package ip2locgrpc
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/ferluci/ip2loc"
+)
+
+// Options configures the interceptors.
+type Options struct {
+	// BlockedCountries are ISO-3166 country codes whose calls are rejected with codes.PermissionDenied.
+	// Calls from addresses which are not found are let through.
+	BlockedCountries []string
+	// TrustForwardedFor geolocates the first address of the x-forwarded-for metadata instead of the
+	// peer address, for servers behind a proxy. Only enable it if the proxy sets the header.
+	TrustForwardedFor bool
+	// RejectOnError rejects calls whose peer cannot be looked up because of the database, e.g. when
+	// it is stale under ip2loc.StaleFail, with the status Status returns for the error. Without it,
+	// such calls are let through without a record, like calls from addresses which are not found.
+	RejectOnError bool
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying x.
+func NewContext(ctx context.Context, x ip2loc.IP2LocationRecord) context.Context {
+	return context.WithValue(ctx, contextKey{}, x)
+}
+
+// FromContext returns the record attached to ctx by the interceptors. ok is false if the peer
+// could not be geolocated, e.g. because it connected over a Unix socket.
+func FromContext(ctx context.Context) (x ip2loc.IP2LocationRecord, ok bool) {
+	x, ok = ctx.Value(contextKey{}).(ip2loc.IP2LocationRecord)
+	return x, ok
+}
+
+type interceptor struct {
+	db      *ip2loc.DB
+	opts    Options
+	blocked map[string]bool
+}
+
+func newInterceptor(db *ip2loc.DB, opts Options) *interceptor {
+	i := &interceptor{db: db, opts: opts, blocked: map[string]bool{}}
+	for _, c := range opts.BlockedCountries {
+		i.blocked[strings.ToUpper(c)] = true
+	}
+	return i
+}
+
+// locate attaches the record of the caller to ctx, or returns an error if the call is rejected.
+func (i *interceptor) locate(ctx context.Context) (context.Context, error) {
+	ip := i.callerIP(ctx)
+	if ip == "" {
+		return ctx, nil
+	}
+	x, err := i.db.GetAll(ip)
+	if err != nil {
+		switch ip2loc.CodeOf(err) {
+		case ip2loc.CodeNotFound, ip2loc.CodeInvalidIP:
+			return ctx, nil
+		}
+		if i.opts.RejectOnError {
+			return ctx, Status(err)
+		}
+		return ctx, nil
+	}
+	if i.blocked[x.CountryShort] {
+		return ctx, status.Errorf(codes.PermissionDenied, "ip2loc: calls from %s are not allowed", x.CountryShort)
+	}
+	return NewContext(ctx, x), nil
+}
+
+// Status returns err, an error of an ip2loc lookup, as a gRPC status error with the code of its
+// ip2loc.ErrorCode, e.g. codes.NotFound for ip2loc.ErrNotFound, for handlers returning lookup
+// errors to their callers. It returns nil for a nil error.
+func Status(err error) error {
+	if err == nil {
+		return nil
+	}
+	return status.Error(codes.Code(ip2loc.CodeOf(err).GRPCCode()), err.Error())
+}
+
+// callerIP returns the address of the caller, or "" if it has none.
+func (i *interceptor) callerIP(ctx context.Context) string {
+	if i.opts.TrustForwardedFor {
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if v := md.Get("x-forwarded-for"); len(v) > 0 {
+				first, _, _ := strings.Cut(v[0], ",")
+				return strings.TrimSpace(first)
+			}
+		}
+	}
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	switch a := p.Addr.(type) {
+	case *net.TCPAddr:
+		return a.IP.String()
+	case *net.UnixAddr:
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return ""
+	}
+	return host
+}
+
+// UnaryServerInterceptor returns a unary server interceptor geolocating the peer of every call.
+func UnaryServerInterceptor(db *ip2loc.DB, opts Options) grpc.UnaryServerInterceptor {
+	i := newInterceptor(db, opts)
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := i.locate(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns a stream server interceptor geolocating the peer of every stream.
+func StreamServerInterceptor(db *ip2loc.DB, opts Options) grpc.StreamServerInterceptor {
+	i := newInterceptor(db, opts)
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := i.locate(ss.Context())
+		if err != nil {
+			return err
+		}
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// serverStream overrides the context of a grpc.ServerStream.
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
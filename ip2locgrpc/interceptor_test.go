@@ -0,0 +1,150 @@
+package ip2locgrpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/ferluci/ip2loc"
+)
+
+// testDB is the database of the tests of the ip2loc package, written by its TestTestdataDB.
+const testDB = "../testdata/IP2LOCATION-DB5.BIN"
+
+// addr is a net.Addr of a transport other than TCP and Unix sockets.
+type addr string
+
+func (a addr) Network() string { return "test" }
+func (a addr) String() string  { return string(a) }
+
+// fakeStream is a grpc.ServerStream carrying only a context.
+type fakeStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s fakeStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestInterceptors(t *testing.T) {
+	db, err := ip2loc.OpenDB(testDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	closed, err := ip2loc.OpenDB(testDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed.Close()
+
+	withPeer := func(a net.Addr) context.Context {
+		return peer.NewContext(context.Background(), &peer.Peer{Addr: a})
+	}
+	tcp := func(ip string) context.Context {
+		return withPeer(&net.TCPAddr{IP: net.ParseIP(ip), Port: 50000})
+	}
+	forwarded := func(ctx context.Context, v string) context.Context {
+		return metadata.NewIncomingContext(ctx, metadata.Pairs("x-forwarded-for", v))
+	}
+
+	for _, tt := range []struct {
+		name    string
+		db      *ip2loc.DB
+		opts    Options
+		ctx     context.Context
+		country string // of the record attached to the context, "" if none
+		code    codes.Code
+	}{
+		{name: "tcp", ctx: tcp("81.2.69.160"), country: "GB"},
+		{name: "tcp ipv6", ctx: tcp("2a00:1450::1"), country: "IE"},
+		{name: "other transport", ctx: withPeer(addr("[193.0.0.1]:50000")), country: "NL"},
+		{name: "unix socket", ctx: withPeer(&net.UnixAddr{Name: "/run/ip2loc.sock", Net: "unix"})},
+		{name: "no peer", ctx: context.Background()},
+		{name: "no address", ctx: withPeer(addr("pipe"))},
+		{name: "no location", ctx: tcp("1.1.1.1"), country: "-"},
+		{name: "blocked", opts: Options{BlockedCountries: []string{"nl", "GB"}}, ctx: tcp("81.2.69.160"), code: codes.PermissionDenied},
+		{name: "not blocked", opts: Options{BlockedCountries: []string{"NL"}}, ctx: tcp("81.2.69.160"), country: "GB"},
+		{name: "forwarded", opts: Options{TrustForwardedFor: true}, ctx: forwarded(tcp("10.0.0.1"), "193.0.0.1, 10.0.0.2"), country: "NL"},
+		{name: "forwarded untrusted", ctx: forwarded(tcp("81.2.69.160"), "193.0.0.1"), country: "GB"},
+		{name: "forwarded invalid", opts: Options{TrustForwardedFor: true}, ctx: forwarded(tcp("81.2.69.160"), "unknown")},
+		{name: "forwarded blocked", opts: Options{TrustForwardedFor: true, BlockedCountries: []string{"NL"}}, ctx: forwarded(tcp("81.2.69.160"), "193.0.0.1"), code: codes.PermissionDenied},
+		{name: "database error", db: closed, ctx: tcp("81.2.69.160")},
+		{name: "database error rejected", db: closed, opts: Options{RejectOnError: true}, ctx: tcp("81.2.69.160"), code: codes.Internal},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.db == nil {
+				tt.db = db
+			}
+			check := func(ctx context.Context, called bool, err error) {
+				t.Helper()
+				if status.Code(err) != tt.code {
+					t.Fatalf("got %v, want code %v", err, tt.code)
+				}
+				if called != (tt.code == codes.OK) {
+					t.Fatalf("handler called: %v", called)
+				}
+				if !called {
+					return
+				}
+				x, ok := FromContext(ctx)
+				if ok != (tt.country != "") || x.CountryShort != tt.country {
+					t.Errorf("got record %+v, %v, want country %q", x, ok, tt.country)
+				}
+			}
+
+			t.Run("unary", func(t *testing.T) {
+				var got context.Context
+				_, err := UnaryServerInterceptor(tt.db, tt.opts)(tt.ctx, nil, &grpc.UnaryServerInfo{},
+					func(ctx context.Context, req any) (any, error) {
+						got = ctx
+						return nil, nil
+					})
+				check(got, got != nil, err)
+			})
+
+			t.Run("stream", func(t *testing.T) {
+				var got context.Context
+				err := StreamServerInterceptor(tt.db, tt.opts)(nil, fakeStream{ctx: tt.ctx}, &grpc.StreamServerInfo{},
+					func(srv any, ss grpc.ServerStream) error {
+						got = ss.Context()
+						return nil
+					})
+				check(got, got != nil, err)
+			})
+		})
+	}
+}
+
+func TestStatus(t *testing.T) {
+	db, err := ip2loc.OpenDB(testDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	_, invalid := db.GetAll("81.2.69")
+
+	for _, tt := range []struct {
+		err  error
+		code codes.Code
+	}{
+		{nil, codes.OK},
+		{invalid, codes.InvalidArgument},
+		{ip2loc.ErrNotFound, codes.Internal}, // without the code of a lookup
+		{&ip2loc.Error{Code: ip2loc.CodeNotFound, Err: ip2loc.ErrNotFound}, codes.NotFound},
+		{errors.New("other"), codes.Internal},
+	} {
+		got := Status(tt.err)
+		if status.Code(got) != tt.code || (tt.err == nil) != (got == nil) {
+			t.Errorf("%v: got %v, want code %v", tt.err, got, tt.code)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package ip2locmmdb
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// MaxMind DB data section types.
+const (
+	typeString  = 2
+	typeDouble  = 3
+	typeUint16  = 5
+	typeUint32  = 6
+	typeMap     = 7
+	typeInt32   = 8
+	typeUint64  = 9
+	typeArray   = 11
+	typeBoolean = 14
+	typeFloat   = 15
+)
+
+// encode appends the MaxMind DB encoding of v. Maps are written with their keys sorted, so equal
+// values always encode to equal bytes and can be deduplicated.
+func encode(b []byte, v any) ([]byte, error) {
+	switch v := v.(type) {
+	case string:
+		b = appendControl(b, typeString, len(v))
+		return append(b, v...), nil
+	case float64:
+		b = appendControl(b, typeDouble, 8)
+		return binary.BigEndian.AppendUint64(b, math.Float64bits(v)), nil
+	case float32:
+		b = appendControl(b, typeFloat, 4)
+		return binary.BigEndian.AppendUint32(b, math.Float32bits(v)), nil
+	case bool:
+		n := 0
+		if v {
+			n = 1
+		}
+		return appendControl(b, typeBoolean, n), nil
+	case uint16:
+		return appendUint(b, typeUint16, uint64(v)), nil
+	case uint32:
+		return appendUint(b, typeUint32, uint64(v)), nil
+	case uint64:
+		return appendUint(b, typeUint64, v), nil
+	case int32:
+		b = appendControl(b, typeInt32, 4)
+		return binary.BigEndian.AppendUint32(b, uint32(v)), nil
+	case []any:
+		b = appendControl(b, typeArray, len(v))
+		var err error
+		for _, e := range v {
+			if b, err = encode(b, e); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		b = appendControl(b, typeMap, len(v))
+		var err error
+		for _, k := range keys {
+			if b, err = encode(b, k); err != nil {
+				return nil, err
+			}
+			if b, err = encode(b, v[k]); err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("ip2locmmdb: cannot encode %T", v)
+}
+
+// appendUint writes an unsigned integer with as few bytes as it needs.
+func appendUint(b []byte, typ int, v uint64) []byte {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], v)
+	n := 8
+	for n > 0 && buf[8-n] == 0 {
+		n--
+	}
+	b = appendControl(b, typ, n)
+	return append(b, buf[8-n:]...)
+}
+
+// appendControl writes the control byte of a value: the type in the top three bits, or 0 followed
+// by an extended type byte, and the size in the low five bits followed by up to three size bytes.
+func appendControl(b []byte, typ, size int) []byte {
+	ctrl := byte(0)
+	if typ <= 7 {
+		ctrl = byte(typ) << 5
+	}
+
+	var ext []byte
+	switch {
+	case size < 29:
+		ctrl |= byte(size)
+	case size < 29+256:
+		ctrl |= 29
+		ext = []byte{byte(size - 29)}
+	case size < 285+65536:
+		ctrl |= 30
+		s := size - 285
+		ext = []byte{byte(s >> 8), byte(s)}
+	default:
+		ctrl |= 31
+		s := size - 65821
+		ext = []byte{byte(s >> 16), byte(s >> 8), byte(s)}
+	}
+
+	b = append(b, ctrl)
+	if typ > 7 {
+		b = append(b, byte(typ-7))
+	}
+	return append(b, ext...)
+}
@@ -0,0 +1,266 @@
+// Package ip2locmmdb converts ip2loc databases into the MaxMind DB (MMDB) format, so infrastructure
+// built around GeoIP2 readers, such as the nginx geoip2 module or Envoy, can be fed IP2Location data.
+//
+// The records are laid out like GeoIP2 City records by default; see GeoIP2City.
+package ip2locmmdb
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/ferluci/ip2loc"
+)
+
+// metadataMarker precedes the metadata map at the end of the file.
+const metadataMarker = "\xab\xcd\xefMaxMind.com"
+
+// recordSize is the size in bits of a search tree record; 32 bits allow data sections of up to 4GB.
+const recordSize = 32
+
+// Options controls a conversion.
+type Options struct {
+	// DatabaseType is written to the metadata. Readers such as geoip2-golang check it before
+	// decoding City records, so it defaults to "GeoIP2-City".
+	DatabaseType string
+	// Description is written to the metadata in English.
+	Description string
+	// Record maps a record to the value stored for its networks. It defaults to GeoIP2City.
+	// Returning nil leaves the networks of the record without data.
+	Record func(ip2loc.IP2LocationRecord) map[string]any
+}
+
+// GeoIP2City lays a record out like a GeoIP2 City record: country, subdivisions, city, location
+// and postal with English names, and traits holding the ISP, domain and usage type. The IP2Location
+// time zone is a UTC offset rather than an IANA name, so it is stored as location.utc_offset.
+// Empty fields are left out and records without a country are stored without data.
+func GeoIP2City(x ip2loc.IP2LocationRecord) map[string]any {
+	x = x.WithoutPlaceholders()
+	if x.CountryShort == "" || x.CountryShort == "-" {
+		return nil
+	}
+	names := func(s string) map[string]any {
+		return map[string]any{"en": s}
+	}
+
+	m := map[string]any{
+		"country": map[string]any{"iso_code": x.CountryShort, "names": names(x.CountryLong)},
+	}
+	if x.Region != "" && x.Region != "-" {
+		sub := map[string]any{"names": names(x.Region)}
+		if _, code, ok := strings.Cut(x.RegionCode, "-"); ok {
+			sub["iso_code"] = code
+		}
+		m["subdivisions"] = []any{sub}
+	}
+	if x.City != "" && x.City != "-" {
+		m["city"] = map[string]any{"names": names(x.City)}
+	}
+	if x.Latitude != 0 || x.Longitude != 0 {
+		loc := map[string]any{"latitude": x.Latitude64(), "longitude": x.Longitude64()}
+		if x.Timezone != "" && x.Timezone != "-" {
+			loc["utc_offset"] = x.Timezone
+		}
+		m["location"] = loc
+	}
+	if x.ZipCode != "" && x.ZipCode != "-" {
+		m["postal"] = map[string]any{"code": x.ZipCode}
+	}
+
+	traits := map[string]any{}
+	for k, v := range map[string]string{"isp": x.Isp, "domain": x.Domain, "user_type": x.UsageType} {
+		if v != "" && v != "-" {
+			traits[k] = v
+		}
+	}
+	if len(traits) > 0 {
+		m["traits"] = traits
+	}
+	return m
+}
+
+// node is a node of the search tree. A node without children is a leaf holding the index of its
+// data, or -1 if its networks have no data.
+type node struct {
+	child [2]*node
+	data  int
+}
+
+// insert stores data for the network of the given bit length starting at addr, replacing what
+// was stored for any part of it.
+func (n *node) insert(addr [16]byte, bits, data int) {
+	for i := 0; i < bits; i++ {
+		if n.child[0] == nil {
+			// split the leaf, both halves keep its data
+			n.child[0], n.child[1] = &node{data: n.data}, &node{data: n.data}
+		}
+		bit := addr[i/8] >> (7 - i%8) & 1
+		n = n.child[bit]
+	}
+	n.child = [2]*node{}
+	n.data = data
+}
+
+// Convert writes the rows of db to w as a MaxMind DB. IPv4 rows are stored in the IPv4 subtree
+// at ::/96, which the IPv4-mapped range ::ffff:0:0/96 is aliased to, like in MaxMind's databases.
+func Convert(w io.Writer, db *ip2loc.DB, opts Options) error {
+	if opts.DatabaseType == "" {
+		opts.DatabaseType = "GeoIP2-City"
+	}
+	if opts.Record == nil {
+		opts.Record = GeoIP2City
+	}
+
+	root := &node{data: -1}
+	var values [][]byte
+	index := map[string]int{}
+	var v4 []ip2loc.RangeRecord
+
+	add := func(r ip2loc.RangeRecord) error {
+		data := -1
+		if v := opts.Record(r.Record); v != nil {
+			b, err := encode(nil, v)
+			if err != nil {
+				return err
+			}
+			i, ok := index[string(b)]
+			if !ok {
+				i = len(values)
+				index[string(b)] = i
+				values = append(values, b)
+			}
+			data = i
+		}
+		for _, p := range r.Prefixes() {
+			addr, bits := p.Addr().As16(), p.Bits()
+			if p.Addr().Is4() {
+				// ::a.b.c.d rather than the IPv4-mapped form As16 returns
+				addr = [16]byte{}
+				v4 := p.Addr().As4()
+				copy(addr[12:], v4[:])
+				bits += 96
+			}
+			root.insert(addr, bits, data)
+		}
+		return nil
+	}
+
+	// IPv6 rows first, so the IPv4 rows replace whatever the IPv6 table holds for ::/96
+	for r, err := range db.Records() {
+		if err != nil {
+			return err
+		}
+		if r.First.Is4() {
+			v4 = append(v4, r)
+			continue
+		}
+		if err := add(r); err != nil {
+			return err
+		}
+	}
+	for _, r := range v4 {
+		if err := add(r); err != nil {
+			return err
+		}
+	}
+
+	// alias ::ffff:0:0/96 to the IPv4 subtree
+	if ipv4 := find(root, netip.IPv6Unspecified().As16(), 96); ipv4 != nil {
+		mapped := netip.MustParseAddr("::ffff:0:0").As16()
+		// split the leaves down to the /96, so that its parent has both children
+		find(root, mapped, 96)
+		parent := find(root, mapped, 95)
+		if parent != nil {
+			bit := mapped[95/8] >> (7 - 95%8) & 1
+			parent.child[bit] = ipv4
+		}
+	}
+
+	return write(w, root, values, opts)
+}
+
+// find returns the node at the given depth along addr, splitting leaves on the way.
+func find(n *node, addr [16]byte, bits int) *node {
+	for i := 0; i < bits; i++ {
+		if n.child[0] == nil {
+			n.child[0], n.child[1] = &node{data: n.data}, &node{data: n.data}
+		}
+		n = n.child[addr[i/8]>>(7-i%8)&1]
+	}
+	return n
+}
+
+func write(w io.Writer, root *node, values [][]byte, opts Options) error {
+	// number the inner nodes breadth first; an aliased subtree is numbered once
+	ids := map[*node]uint32{}
+	var nodes []*node
+	if root.child[0] != nil {
+		ids[root] = 0
+		nodes = append(nodes, root)
+	}
+	for i := 0; i < len(nodes); i++ {
+		for _, c := range nodes[i].child {
+			if _, ok := ids[c]; !ok && c.child[0] != nil {
+				ids[c] = uint32(len(nodes))
+				nodes = append(nodes, c)
+			}
+		}
+	}
+	if len(nodes) == 0 {
+		return errors.New("ip2locmmdb: database has no rows")
+	}
+	offsets := make([]uint64, len(values))
+	var size uint64
+	for i, v := range values {
+		offsets[i] = size
+		size += uint64(len(v))
+	}
+	count := uint64(len(nodes))
+	if count+16+size > math.MaxUint32 {
+		return errors.New("ip2locmmdb: database too large for 32 bit records")
+	}
+
+	bw := bufio.NewWriter(w)
+	record := func(c *node) uint32 {
+		if id, ok := ids[c]; ok {
+			return id
+		}
+		if c.data < 0 {
+			return uint32(count)
+		}
+		return uint32(count + 16 + offsets[c.data])
+	}
+	var buf [recordSize / 4]byte
+	for _, n := range nodes {
+		binary.BigEndian.PutUint32(buf[:4], record(n.child[0]))
+		binary.BigEndian.PutUint32(buf[4:], record(n.child[1]))
+		bw.Write(buf[:])
+	}
+	bw.Write(make([]byte, 16)) // data section separator
+	for _, v := range values {
+		bw.Write(v)
+	}
+
+	meta, err := encode(nil, map[string]any{
+		"binary_format_major_version": uint16(2),
+		"binary_format_minor_version": uint16(0),
+		"build_epoch":                 uint64(time.Now().Unix()),
+		"database_type":               opts.DatabaseType,
+		"description":                 map[string]any{"en": opts.Description},
+		"ip_version":                  uint16(6),
+		"languages":                   []any{"en"},
+		"node_count":                  uint32(count),
+		"record_size":                 uint16(recordSize),
+	})
+	if err != nil {
+		return err
+	}
+	bw.WriteString(metadataMarker)
+	bw.Write(meta)
+	return bw.Flush()
+}
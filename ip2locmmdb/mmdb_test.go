@@ -0,0 +1,196 @@
+//go:build !ip2loc_nofs
+
+package ip2locmmdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ferluci/ip2loc"
+)
+
+// testDB is the database of the tests of the ip2loc package, written by its TestTestdataDB.
+const testDB = "../testdata/IP2LOCATION-DB5.BIN"
+
+// mmdb reads a database written by Convert.
+type mmdb struct {
+	t         testing.TB
+	data      []byte
+	nodeCount uint32
+	meta      map[string]any
+}
+
+func readMMDB(t testing.TB, data []byte) *mmdb {
+	t.Helper()
+	i := bytes.LastIndex(data, []byte(metadataMarker))
+	if i < 0 {
+		t.Fatal("no metadata marker")
+	}
+	m := &mmdb{t: t, data: data}
+	meta, _ := m.decode(data, i+len(metadataMarker))
+	m.meta, _ = meta.(map[string]any)
+	if m.meta["record_size"] != uint16(recordSize) || m.meta["ip_version"] != uint16(6) {
+		t.Fatalf("metadata %v", m.meta)
+	}
+	m.nodeCount = m.meta["node_count"].(uint32)
+	return m
+}
+
+// lookup walks the search tree along addr and returns the value stored for it, or nil.
+func (m *mmdb) lookup(addr netip.Addr) any {
+	a := addr.As16()
+	node := uint32(0)
+	for i := 0; i < 128 && node < m.nodeCount; i++ {
+		bit := a[i/8] >> (7 - i%8) & 1
+		node = binary.BigEndian.Uint32(m.data[int(node)*recordSize/4+int(bit)*4:])
+	}
+	switch {
+	case node < m.nodeCount:
+		m.t.Fatalf("%s: no leaf after 128 bits", addr)
+	case node == m.nodeCount:
+		return nil
+	}
+	// data pointers count from the end of the data section separator
+	v, _ := m.decode(m.data, int(m.nodeCount)*recordSize/4+16+int(node-m.nodeCount-16))
+	return v
+}
+
+// decode returns the value encoded at off and the offset after it.
+func (m *mmdb) decode(b []byte, off int) (any, int) {
+	ctrl := b[off]
+	off++
+	typ := int(ctrl >> 5)
+	if typ == 0 {
+		typ = 7 + int(b[off])
+		off++
+	}
+	size := int(ctrl & 31)
+	switch size {
+	case 29:
+		size = 29 + int(b[off])
+		off++
+	case 30:
+		size = 285 + (int(b[off])<<8 | int(b[off+1]))
+		off += 2
+	case 31:
+		size = 65821 + (int(b[off])<<16 | int(b[off+1])<<8 | int(b[off+2]))
+		off += 3
+	}
+
+	uint := func() uint64 {
+		var v uint64
+		for _, c := range b[off : off+size] {
+			v = v<<8 | uint64(c)
+		}
+		return v
+	}
+	switch typ {
+	case typeString:
+		return string(b[off : off+size]), off + size
+	case typeDouble:
+		return math.Float64frombits(binary.BigEndian.Uint64(b[off:])), off + size
+	case typeFloat:
+		return math.Float32frombits(binary.BigEndian.Uint32(b[off:])), off + size
+	case typeUint16:
+		return uint16(uint()), off + size
+	case typeUint32:
+		return uint32(uint()), off + size
+	case typeUint64:
+		return uint(), off + size
+	case typeInt32:
+		return int32(uint32(uint())), off + size
+	case typeBoolean:
+		return size != 0, off
+	case typeArray:
+		a := make([]any, size)
+		for i := range a {
+			a[i], off = m.decode(b, off)
+		}
+		return a, off
+	case typeMap:
+		v := make(map[string]any, size)
+		for range size {
+			var k, e any
+			k, off = m.decode(b, off)
+			e, off = m.decode(b, off)
+			v[k.(string)] = e
+		}
+		return v, off
+	}
+	m.t.Fatalf("unexpected type %d at offset %d", typ, off)
+	return nil, off
+}
+
+func TestEncode(t *testing.T) {
+	m := &mmdb{t: t}
+	for _, v := range []any{
+		"", "London", strings.Repeat("x", 28), strings.Repeat("x", 29), strings.Repeat("x", 284),
+		strings.Repeat("x", 285), strings.Repeat("x", 65820), strings.Repeat("x", 65821),
+		51.508529, float32(-0.12574), true, false,
+		uint16(0), uint16(443), uint32(1 << 31), uint64(math.MaxUint64), int32(-1),
+		[]any{"en", uint16(2)},
+		map[string]any{"names": map[string]any{"en": "London"}, "latitude": 51.508529},
+	} {
+		b, err := encode(nil, v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, off := m.decode(b, 0); !reflect.DeepEqual(got, v) || off != len(b) {
+			t.Errorf("%.40v: decoded %.40v, %d of %d bytes", v, got, off, len(b))
+		}
+	}
+	if _, err := encode(nil, 1); err == nil {
+		t.Error("encoded an int")
+	}
+}
+
+func TestConvert(t *testing.T) {
+	db, err := ip2loc.OpenDB(testDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var b bytes.Buffer
+	if err := Convert(&b, db, Options{Description: "test"}); err != nil {
+		t.Fatal(err)
+	}
+	m := readMMDB(t, b.Bytes())
+	if m.meta["database_type"] != "GeoIP2-City" || !reflect.DeepEqual(m.meta["description"], map[string]any{"en": "test"}) {
+		t.Errorf("metadata %v", m.meta)
+	}
+
+	for _, ip := range []string{
+		"0.0.0.0", "8.8.8.0", "8.8.8.8", "8.8.8.255", "8.8.9.0", "81.2.69.160", "193.0.31.255",
+		"193.0.32.0", "255.255.255.255",
+		"2001:4860::", "2001:4860:ffff::1", "2001:4861::", "2a00:1450::5", "2a00:1451::", "ffff::",
+	} {
+		x, err := db.GetAll(ip)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var want any
+		if v := GeoIP2City(x); v != nil {
+			want = v
+		}
+		addr := netip.MustParseAddr(ip)
+		addrs := []netip.Addr{addr}
+		if addr.Is4() {
+			// the IPv4 subtree at ::/96 and its IPv4-mapped alias
+			v4 := addr.As4()
+			var a [16]byte
+			copy(a[12:], v4[:])
+			addrs = []netip.Addr{netip.AddrFrom16(a), netip.AddrFrom16(addr.As16())}
+		}
+		for _, a := range addrs {
+			if got := m.lookup(a); !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: got %v, want %v", a, got, want)
+			}
+		}
+	}
+}
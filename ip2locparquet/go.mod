@@ -0,0 +1,17 @@
+module github.com/ferluci/ip2loc/ip2locparquet
+
+go 1.23.0
+
+require (
+	github.com/ferluci/ip2loc v0.0.0-20261016234639-b580dc0a984c
+	github.com/parquet-go/parquet-go v0.25.1
+)
+
+require (
+	github.com/andybalholm/brotli v1.1.0 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/klauspost/compress v1.17.9 // indirect
+	github.com/pierrec/lz4/v4 v4.1.21 // indirect
+	golang.org/x/sync v0.15.0 // indirect
+	golang.org/x/sys v0.33.0 // indirect
+)
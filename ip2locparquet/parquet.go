@@ -0,0 +1,132 @@
+// Package ip2locparquet exports ip2loc databases as Parquet files, so a BIN release can be loaded
+// straight into Spark, BigQuery, Athena and other data lake engines.
+package ip2locparquet
+
+import (
+	"io"
+	"net/netip"
+
+	"github.com/ferluci/ip2loc"
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/compress/snappy"
+)
+
+// batchSize is the number of rows buffered before they are handed to the Parquet writer.
+const batchSize = 1024
+
+// Row is the schema of an exported row. Each row covers the addresses IPFrom to IPTo inclusive;
+// Network is only set by a CIDR export. Fields the database does not contain are null.
+type Row struct {
+	IPFrom             string  `parquet:"ip_from"`
+	IPTo               string  `parquet:"ip_to"`
+	Network            string  `parquet:"network,optional"`
+	IPVersion          int32   `parquet:"ip_version"`
+	CountryShort       string  `parquet:"country_short,optional"`
+	CountryLong        string  `parquet:"country_long,optional"`
+	Region             string  `parquet:"region,optional"`
+	City               string  `parquet:"city,optional"`
+	ISP                string  `parquet:"isp,optional"`
+	Latitude           float32 `parquet:"latitude"`
+	Longitude          float32 `parquet:"longitude"`
+	Domain             string  `parquet:"domain,optional"`
+	ZipCode            string  `parquet:"zip_code,optional"`
+	Timezone           string  `parquet:"timezone,optional"`
+	NetSpeed           string  `parquet:"net_speed,optional"`
+	IDDCode            string  `parquet:"idd_code,optional"`
+	AreaCode           string  `parquet:"area_code,optional"`
+	WeatherStationCode string  `parquet:"weather_station_code,optional"`
+	WeatherStationName string  `parquet:"weather_station_name,optional"`
+	MCC                string  `parquet:"mcc,optional"`
+	MNC                string  `parquet:"mnc,optional"`
+	MobileBrand        string  `parquet:"mobile_brand,optional"`
+	Elevation          float32 `parquet:"elevation"`
+	UsageType          string  `parquet:"usage_type,optional"`
+	RegionCode         string  `parquet:"region_code,optional"`
+}
+
+// Options controls an export.
+type Options struct {
+	// CIDR splits every range into the CIDR prefixes covering it and writes one row per prefix
+	// with Network set, for engines joining on networks rather than address ranges.
+	CIDR bool
+}
+
+// Export writes every row of db to w as a Snappy compressed Parquet file.
+func Export(w io.Writer, db *ip2loc.DB, opts Options) error {
+	pw := parquet.NewGenericWriter[Row](w, parquet.Compression(&snappy.Codec{}))
+
+	batch := make([]Row, 0, batchSize)
+	flush := func() error {
+		_, err := pw.Write(batch)
+		batch = batch[:0]
+		return err
+	}
+	for r, err := range db.Records() {
+		if err != nil {
+			return err
+		}
+		if !opts.CIDR {
+			batch = append(batch, NewRow(r.First, r.Last, r.Record))
+		} else {
+			for _, p := range r.Prefixes() {
+				row := NewRow(p.Addr(), lastAddr(p), r.Record)
+				row.Network = p.String()
+				batch = append(batch, row)
+			}
+		}
+		if len(batch) >= batchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+	return pw.Close()
+}
+
+// NewRow returns the row for a record covering first to last.
+func NewRow(first, last netip.Addr, x ip2loc.IP2LocationRecord) Row {
+	x = x.WithoutPlaceholders()
+	version := int32(4)
+	if first.Is6() {
+		version = 6
+	}
+	return Row{
+		IPFrom:             first.String(),
+		IPTo:               last.String(),
+		IPVersion:          version,
+		CountryShort:       x.CountryShort,
+		CountryLong:        x.CountryLong,
+		Region:             x.Region,
+		City:               x.City,
+		ISP:                x.Isp,
+		Latitude:           x.Latitude,
+		Longitude:          x.Longitude,
+		Domain:             x.Domain,
+		ZipCode:            x.ZipCode,
+		Timezone:           x.Timezone,
+		NetSpeed:           x.NetSpeed,
+		IDDCode:            x.IddCode,
+		AreaCode:           x.AreaCode,
+		WeatherStationCode: x.WeatherStationCode,
+		WeatherStationName: x.WeatherStationName,
+		MCC:                x.MCC,
+		MNC:                x.MNC,
+		MobileBrand:        x.MobileBrand,
+		Elevation:          x.Elevation,
+		UsageType:          x.UsageType,
+		RegionCode:         x.RegionCode,
+	}
+}
+
+// lastAddr returns the last address of p.
+func lastAddr(p netip.Prefix) netip.Addr {
+	b := p.Masked().Addr().AsSlice()
+	for i := p.Bits(); i < len(b)*8; i++ {
+		b[i/8] |= 0x80 >> (i % 8)
+	}
+	a, _ := netip.AddrFromSlice(b)
+	return a
+}
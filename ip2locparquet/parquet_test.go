@@ -0,0 +1,75 @@
+package ip2locparquet
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/ferluci/ip2loc"
+	"github.com/parquet-go/parquet-go"
+)
+
+// testDB is the database of the tests of the ip2loc package, written by its TestTestdataDB.
+const testDB = "../testdata/IP2LOCATION-DB5.BIN"
+
+func TestExport(t *testing.T) {
+	db, err := ip2loc.OpenDB(testDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var want, wantCIDR []Row
+	for r, err := range db.Records() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, NewRow(r.First, r.Last, r.Record))
+		for _, p := range r.Prefixes() {
+			row := NewRow(p.Addr(), lastAddr(p), r.Record)
+			row.Network = p.String()
+			wantCIDR = append(wantCIDR, row)
+		}
+	}
+
+	for _, tt := range []struct {
+		name string
+		opts Options
+		want []Row
+	}{
+		{"ranges", Options{}, want},
+		{"cidr", Options{CIDR: true}, wantCIDR},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+			if err := Export(&b, db, tt.opts); err != nil {
+				t.Fatal(err)
+			}
+			got, err := parquet.Read[Row](bytes.NewReader(b.Bytes()), int64(b.Len()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("read %d rows, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("row %d: got %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+
+	// both IP versions and a located range are exported
+	x, err := db.GetAll("81.2.69.160")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found, v6 bool
+	for _, r := range want {
+		found = found || r.City == x.City && r.IPFrom == "81.2.69.0" && r.IPTo == "81.2.69.255"
+		v6 = v6 || r.IPVersion == 6
+	}
+	if !found || !v6 {
+		t.Errorf("rows %+v lack the London range or IPv6 rows", want)
+	}
+}
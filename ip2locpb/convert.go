@@ -0,0 +1,61 @@
+// Package ip2locpb provides the canonical protobuf schema for ip2loc records
+// together with converters from and to the ip2loc Go types.
+package ip2locpb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative ip2location.proto
+
+import "github.com/ferluci/ip2loc"
+
+// FromRecord converts an ip2loc record into its protobuf message.
+func FromRecord(x ip2loc.IP2LocationRecord) *IP2LocationRecord {
+	return &IP2LocationRecord{
+		CountryShort:       x.CountryShort,
+		CountryLong:        x.CountryLong,
+		Region:             x.Region,
+		City:               x.City,
+		Isp:                x.Isp,
+		Latitude:           x.Latitude,
+		Longitude:          x.Longitude,
+		Domain:             x.Domain,
+		ZipCode:            x.ZipCode,
+		Timezone:           x.Timezone,
+		NetSpeed:           x.NetSpeed,
+		IddCode:            x.IddCode,
+		AreaCode:           x.AreaCode,
+		WeatherStationCode: x.WeatherStationCode,
+		WeatherStationName: x.WeatherStationName,
+		Mcc:                x.MCC,
+		Mnc:                x.MNC,
+		MobileBrand:        x.MobileBrand,
+		Elevation:          x.Elevation,
+		UsageType:          x.UsageType,
+		RegionCode:         x.RegionCode,
+	}
+}
+
+// ToRecord converts a protobuf message back into an ip2loc record. A nil message yields an empty record.
+func ToRecord(m *IP2LocationRecord) ip2loc.IP2LocationRecord {
+	return ip2loc.IP2LocationRecord{
+		CountryShort:       m.GetCountryShort(),
+		CountryLong:        m.GetCountryLong(),
+		Region:             m.GetRegion(),
+		City:               m.GetCity(),
+		Isp:                m.GetIsp(),
+		Latitude:           m.GetLatitude(),
+		Longitude:          m.GetLongitude(),
+		Domain:             m.GetDomain(),
+		ZipCode:            m.GetZipCode(),
+		Timezone:           m.GetTimezone(),
+		NetSpeed:           m.GetNetSpeed(),
+		IddCode:            m.GetIddCode(),
+		AreaCode:           m.GetAreaCode(),
+		WeatherStationCode: m.GetWeatherStationCode(),
+		WeatherStationName: m.GetWeatherStationName(),
+		MCC:                m.GetMcc(),
+		MNC:                m.GetMnc(),
+		MobileBrand:        m.GetMobileBrand(),
+		Elevation:          m.GetElevation(),
+		UsageType:          m.GetUsageType(),
+		RegionCode:         m.GetRegionCode(),
+	}
+}
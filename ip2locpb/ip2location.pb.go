@@ -0,0 +1,307 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        (unknown)
+// source: ip2location.proto
+
+package ip2locpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// IP2LocationRecord stores all of the available geolocation info
+// found in the IP2Location database for a single IP address.
+type IP2LocationRecord struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	CountryShort       string                 `protobuf:"bytes,1,opt,name=country_short,json=countryShort,proto3" json:"country_short,omitempty"`
+	CountryLong        string                 `protobuf:"bytes,2,opt,name=country_long,json=countryLong,proto3" json:"country_long,omitempty"`
+	Region             string                 `protobuf:"bytes,3,opt,name=region,proto3" json:"region,omitempty"`
+	City               string                 `protobuf:"bytes,4,opt,name=city,proto3" json:"city,omitempty"`
+	Isp                string                 `protobuf:"bytes,5,opt,name=isp,proto3" json:"isp,omitempty"`
+	Latitude           float32                `protobuf:"fixed32,6,opt,name=latitude,proto3" json:"latitude,omitempty"`
+	Longitude          float32                `protobuf:"fixed32,7,opt,name=longitude,proto3" json:"longitude,omitempty"`
+	Domain             string                 `protobuf:"bytes,8,opt,name=domain,proto3" json:"domain,omitempty"`
+	ZipCode            string                 `protobuf:"bytes,9,opt,name=zip_code,json=zipCode,proto3" json:"zip_code,omitempty"`
+	Timezone           string                 `protobuf:"bytes,10,opt,name=timezone,proto3" json:"timezone,omitempty"`
+	NetSpeed           string                 `protobuf:"bytes,11,opt,name=net_speed,json=netSpeed,proto3" json:"net_speed,omitempty"`
+	IddCode            string                 `protobuf:"bytes,12,opt,name=idd_code,json=iddCode,proto3" json:"idd_code,omitempty"`
+	AreaCode           string                 `protobuf:"bytes,13,opt,name=area_code,json=areaCode,proto3" json:"area_code,omitempty"`
+	WeatherStationCode string                 `protobuf:"bytes,14,opt,name=weather_station_code,json=weatherStationCode,proto3" json:"weather_station_code,omitempty"`
+	WeatherStationName string                 `protobuf:"bytes,15,opt,name=weather_station_name,json=weatherStationName,proto3" json:"weather_station_name,omitempty"`
+	Mcc                string                 `protobuf:"bytes,16,opt,name=mcc,proto3" json:"mcc,omitempty"`
+	Mnc                string                 `protobuf:"bytes,17,opt,name=mnc,proto3" json:"mnc,omitempty"`
+	MobileBrand        string                 `protobuf:"bytes,18,opt,name=mobile_brand,json=mobileBrand,proto3" json:"mobile_brand,omitempty"`
+	Elevation          float32                `protobuf:"fixed32,19,opt,name=elevation,proto3" json:"elevation,omitempty"`
+	UsageType          string                 `protobuf:"bytes,20,opt,name=usage_type,json=usageType,proto3" json:"usage_type,omitempty"`
+	RegionCode         string                 `protobuf:"bytes,21,opt,name=region_code,json=regionCode,proto3" json:"region_code,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
+}
+
+func (x *IP2LocationRecord) Reset() {
+	*x = IP2LocationRecord{}
+	mi := &file_ip2location_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *IP2LocationRecord) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*IP2LocationRecord) ProtoMessage() {}
+
+func (x *IP2LocationRecord) ProtoReflect() protoreflect.Message {
+	mi := &file_ip2location_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use IP2LocationRecord.ProtoReflect.Descriptor instead.
+func (*IP2LocationRecord) Descriptor() ([]byte, []int) {
+	return file_ip2location_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *IP2LocationRecord) GetCountryShort() string {
+	if x != nil {
+		return x.CountryShort
+	}
+	return ""
+}
+
+func (x *IP2LocationRecord) GetCountryLong() string {
+	if x != nil {
+		return x.CountryLong
+	}
+	return ""
+}
+
+func (x *IP2LocationRecord) GetRegion() string {
+	if x != nil {
+		return x.Region
+	}
+	return ""
+}
+
+func (x *IP2LocationRecord) GetCity() string {
+	if x != nil {
+		return x.City
+	}
+	return ""
+}
+
+func (x *IP2LocationRecord) GetIsp() string {
+	if x != nil {
+		return x.Isp
+	}
+	return ""
+}
+
+func (x *IP2LocationRecord) GetLatitude() float32 {
+	if x != nil {
+		return x.Latitude
+	}
+	return 0
+}
+
+func (x *IP2LocationRecord) GetLongitude() float32 {
+	if x != nil {
+		return x.Longitude
+	}
+	return 0
+}
+
+func (x *IP2LocationRecord) GetDomain() string {
+	if x != nil {
+		return x.Domain
+	}
+	return ""
+}
+
+func (x *IP2LocationRecord) GetZipCode() string {
+	if x != nil {
+		return x.ZipCode
+	}
+	return ""
+}
+
+func (x *IP2LocationRecord) GetTimezone() string {
+	if x != nil {
+		return x.Timezone
+	}
+	return ""
+}
+
+func (x *IP2LocationRecord) GetNetSpeed() string {
+	if x != nil {
+		return x.NetSpeed
+	}
+	return ""
+}
+
+func (x *IP2LocationRecord) GetIddCode() string {
+	if x != nil {
+		return x.IddCode
+	}
+	return ""
+}
+
+func (x *IP2LocationRecord) GetAreaCode() string {
+	if x != nil {
+		return x.AreaCode
+	}
+	return ""
+}
+
+func (x *IP2LocationRecord) GetWeatherStationCode() string {
+	if x != nil {
+		return x.WeatherStationCode
+	}
+	return ""
+}
+
+func (x *IP2LocationRecord) GetWeatherStationName() string {
+	if x != nil {
+		return x.WeatherStationName
+	}
+	return ""
+}
+
+func (x *IP2LocationRecord) GetMcc() string {
+	if x != nil {
+		return x.Mcc
+	}
+	return ""
+}
+
+func (x *IP2LocationRecord) GetMnc() string {
+	if x != nil {
+		return x.Mnc
+	}
+	return ""
+}
+
+func (x *IP2LocationRecord) GetMobileBrand() string {
+	if x != nil {
+		return x.MobileBrand
+	}
+	return ""
+}
+
+func (x *IP2LocationRecord) GetElevation() float32 {
+	if x != nil {
+		return x.Elevation
+	}
+	return 0
+}
+
+func (x *IP2LocationRecord) GetUsageType() string {
+	if x != nil {
+		return x.UsageType
+	}
+	return ""
+}
+
+func (x *IP2LocationRecord) GetRegionCode() string {
+	if x != nil {
+		return x.RegionCode
+	}
+	return ""
+}
+
+var File_ip2location_proto protoreflect.FileDescriptor
+
+const file_ip2location_proto_rawDesc = "" +
+	"\n" +
+	"\x11ip2location.proto\x12\tip2loc.v1\"\x80\x05\n" +
+	"\x11IP2LocationRecord\x12#\n" +
+	"\rcountry_short\x18\x01 \x01(\tR\fcountryShort\x12!\n" +
+	"\fcountry_long\x18\x02 \x01(\tR\vcountryLong\x12\x16\n" +
+	"\x06region\x18\x03 \x01(\tR\x06region\x12\x12\n" +
+	"\x04city\x18\x04 \x01(\tR\x04city\x12\x10\n" +
+	"\x03isp\x18\x05 \x01(\tR\x03isp\x12\x1a\n" +
+	"\blatitude\x18\x06 \x01(\x02R\blatitude\x12\x1c\n" +
+	"\tlongitude\x18\a \x01(\x02R\tlongitude\x12\x16\n" +
+	"\x06domain\x18\b \x01(\tR\x06domain\x12\x19\n" +
+	"\bzip_code\x18\t \x01(\tR\azipCode\x12\x1a\n" +
+	"\btimezone\x18\n" +
+	" \x01(\tR\btimezone\x12\x1b\n" +
+	"\tnet_speed\x18\v \x01(\tR\bnetSpeed\x12\x19\n" +
+	"\bidd_code\x18\f \x01(\tR\aiddCode\x12\x1b\n" +
+	"\tarea_code\x18\r \x01(\tR\bareaCode\x120\n" +
+	"\x14weather_station_code\x18\x0e \x01(\tR\x12weatherStationCode\x120\n" +
+	"\x14weather_station_name\x18\x0f \x01(\tR\x12weatherStationName\x12\x10\n" +
+	"\x03mcc\x18\x10 \x01(\tR\x03mcc\x12\x10\n" +
+	"\x03mnc\x18\x11 \x01(\tR\x03mnc\x12!\n" +
+	"\fmobile_brand\x18\x12 \x01(\tR\vmobileBrand\x12\x1c\n" +
+	"\televation\x18\x13 \x01(\x02R\televation\x12\x1d\n" +
+	"\n" +
+	"usage_type\x18\x14 \x01(\tR\tusageType\x12\x1f\n" +
+	"\vregion_code\x18\x15 \x01(\tR\n" +
+	"regionCodeB$Z\"github.com/ferluci/ip2loc/ip2locpbb\x06proto3"
+
+var (
+	file_ip2location_proto_rawDescOnce sync.Once
+	file_ip2location_proto_rawDescData []byte
+)
+
+func file_ip2location_proto_rawDescGZIP() []byte {
+	file_ip2location_proto_rawDescOnce.Do(func() {
+		file_ip2location_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_ip2location_proto_rawDesc), len(file_ip2location_proto_rawDesc)))
+	})
+	return file_ip2location_proto_rawDescData
+}
+
+var file_ip2location_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_ip2location_proto_goTypes = []any{
+	(*IP2LocationRecord)(nil), // 0: ip2loc.v1.IP2LocationRecord
+}
+var file_ip2location_proto_depIdxs = []int32{
+	0, // [0:0] is the sub-list for method output_type
+	0, // [0:0] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_ip2location_proto_init() }
+func file_ip2location_proto_init() {
+	if File_ip2location_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_ip2location_proto_rawDesc), len(file_ip2location_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   1,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_ip2location_proto_goTypes,
+		DependencyIndexes: file_ip2location_proto_depIdxs,
+		MessageInfos:      file_ip2location_proto_msgTypes,
+	}.Build()
+	File_ip2location_proto = out.File
+	file_ip2location_proto_goTypes = nil
+	file_ip2location_proto_depIdxs = nil
+}
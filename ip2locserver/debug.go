@@ -0,0 +1,222 @@
+package ip2locserver
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ferluci/ip2loc"
+)
+
+// latencyBuckets are the upper bounds of the lookup latency histogram.
+var latencyBuckets = []time.Duration{
+	50 * time.Microsecond, 100 * time.Microsecond, 250 * time.Microsecond, 500 * time.Microsecond,
+	time.Millisecond, 5 * time.Millisecond, 25 * time.Millisecond,
+}
+
+// metrics counts the lookups of a Server.
+type metrics struct {
+	lookups atomic.Int64
+	mu      sync.Mutex
+	errors  map[ip2loc.ErrorCode]int64
+	latency [8]atomic.Int64
+	total   atomic.Int64 // nanoseconds
+}
+
+func (m *metrics) observe(d time.Duration, err error) {
+	m.lookups.Add(1)
+	if err != nil {
+		m.mu.Lock()
+		if m.errors == nil {
+			m.errors = make(map[ip2loc.ErrorCode]int64)
+		}
+		m.errors[ip2loc.CodeOf(err)]++
+		m.mu.Unlock()
+	}
+	m.total.Add(int64(d))
+	i := 0
+	for i < len(latencyBuckets) && d > latencyBuckets[i] {
+		i++
+	}
+	m.latency[i].Add(1)
+}
+
+// EnableDebug registers GET /debug/vars, serving in the format of expvar the memory statistics of
+// the process and an "ip2loc" variable with the lookup counters, latency histogram, read statistics
+// and memory footprint of the server. With pprof, the runtime profiles are served below
+// /debug/pprof/ for go tool pprof, along with the symbol lookup it uses. Unlike the handlers of the
+// expvar and net/http/pprof packages, which register themselves on http.DefaultServeMux when
+// imported, they are only served by s. Like the admin endpoints, they should only be exposed to
+// operators.
+func (s *Server) EnableDebug(withPprof bool) {
+	s.mux.HandleFunc("GET /debug/vars", s.debugVars)
+	if withPprof {
+		s.mux.HandleFunc("GET /debug/pprof/{$}", profiles)
+		s.mux.HandleFunc("GET /debug/pprof/{name}", profile)
+		s.mux.HandleFunc("GET /debug/pprof/cmdline", cmdline)
+		s.mux.HandleFunc("GET /debug/pprof/profile", cpuProfile)
+		s.mux.HandleFunc("GET /debug/pprof/symbol", symbol)
+		s.mux.HandleFunc("POST /debug/pprof/symbol", symbol)
+		s.mux.HandleFunc("GET /debug/pprof/trace", executionTrace)
+	}
+}
+
+func (s *Server) debugVars(w http.ResponseWriter, r *http.Request) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"cmdline":  os.Args,
+		"memstats": &mem,
+		"ip2loc":   s.vars(),
+	})
+}
+
+// profiles lists the runtime profiles.
+func profiles(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	for _, p := range pprof.Profiles() {
+		fmt.Fprintf(w, "%d\t%s\n", p.Count(), p.Name())
+	}
+	fmt.Fprintln(w, "-\tprofile (CPU, ?seconds=30)")
+	fmt.Fprintln(w, "-\ttrace (?seconds=1)")
+}
+
+// profile writes the runtime profile named in the path, as text with ?debug=1 or 2.
+func profile(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	p := pprof.Lookup(name)
+	if p == nil {
+		http.Error(w, "unknown profile "+name, http.StatusNotFound)
+		return
+	}
+	debug, _ := strconv.Atoi(r.FormValue("debug"))
+	if name == "heap" && r.FormValue("gc") != "" {
+		runtime.GC()
+	}
+	if debug == 0 {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	} else {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	_ = p.WriteTo(w, debug)
+}
+
+func cmdline(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	for i, arg := range os.Args {
+		if i > 0 {
+			fmt.Fprint(w, "\x00")
+		}
+		fmt.Fprint(w, arg)
+	}
+}
+
+// symbol resolves the program counters of the request body, or of the query of a GET request,
+// joined by "+", to function names, as the symbol endpoint of net/http/pprof does for go tool pprof.
+func symbol(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.RawQuery
+	if r.Method == http.MethodPost {
+		b, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+		if err != nil {
+			http.Error(w, "reading request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		query = string(b)
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	// the count only tells whether symbols are available
+	fmt.Fprintln(w, "num_symbols: 1")
+	for _, word := range strings.Split(query, "+") {
+		pc, _ := strconv.ParseUint(strings.TrimSpace(word), 0, 64)
+		if pc == 0 {
+			continue
+		}
+		if f := runtime.FuncForPC(uintptr(pc)); f != nil {
+			fmt.Fprintf(w, "%#x %s\n", pc, f.Name())
+		}
+	}
+}
+
+// cpuProfile writes a CPU profile of ?seconds, 30 by default.
+func cpuProfile(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", `attachment; filename="profile"`)
+	if err := pprof.StartCPUProfile(w); err != nil {
+		w.Header().Del("Content-Disposition")
+		http.Error(w, "cannot start the CPU profile: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	sleep(r, 30*time.Second)
+	pprof.StopCPUProfile()
+}
+
+// executionTrace writes an execution trace of ?seconds, 1 by default.
+func executionTrace(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", `attachment; filename="trace"`)
+	if err := trace.Start(w); err != nil {
+		w.Header().Del("Content-Disposition")
+		http.Error(w, "cannot start the trace: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	sleep(r, time.Second)
+	trace.Stop()
+}
+
+// sleep waits for the duration of the seconds parameter of r, or def, or until r is cancelled.
+func sleep(r *http.Request, def time.Duration) {
+	d := def
+	if sec, err := strconv.ParseFloat(r.FormValue("seconds"), 64); err == nil && sec > 0 {
+		d = time.Duration(sec * float64(time.Second))
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-r.Context().Done():
+	}
+}
+
+// vars returns the "ip2loc" variable of /debug/vars.
+func (s *Server) vars() any {
+	m := &s.metrics
+	latency := map[string]int64{}
+	for i := range m.latency {
+		le := "inf"
+		if i < len(latencyBuckets) {
+			le = latencyBuckets[i].String()
+		}
+		latency[le] = m.latency[i].Load()
+	}
+	errs := map[string]int64{}
+	m.mu.Lock()
+	for code, n := range m.errors {
+		errs[string(code)] = n
+	}
+	m.mu.Unlock()
+
+	v := map[string]any{
+		"lookups":          m.lookups.Load(),
+		"errors":           errs,
+		"latency":          latency,
+		"latency_total_us": m.total.Load() / int64(time.Microsecond),
+		"database_date":    s.db.Date().Format(time.DateOnly),
+		"healthy":          s.db.Healthy(),
+		"reads":            s.db.ReadStats(),
+		"memory":           s.db.MemoryFootprint(),
+	}
+	if last := s.LastReload(); !last.Time.IsZero() {
+		v["last_reload"] = last.Time.UTC().Format(time.RFC3339)
+	}
+	return v
+}
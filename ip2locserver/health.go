@@ -0,0 +1,42 @@
+package ip2locserver
+
+import (
+	"net/http"
+	"time"
+)
+
+// healthz answers as long as the process serves requests.
+func (s *Server) healthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{"status": "ok"})
+}
+
+// readyz reports whether the database can answer lookups: it is loaded, its reads succeed and it
+// is not older than allowed by ip2loc.WithMaxAge. The outcome of the last reload is reported but
+// does not fail the check, since the previous database keeps answering.
+func (s *Server) readyz(w http.ResponseWriter, r *http.Request) {
+	date := s.db.Date()
+	body := map[string]any{
+		"loaded":  !date.IsZero(),
+		"healthy": s.db.Healthy(),
+		"stale":   s.db.Stale(),
+	}
+	if !date.IsZero() {
+		body["database_date"] = date.Format(time.DateOnly)
+		body["age_days"] = int(s.db.Age().Hours() / 24)
+	}
+	if last := s.LastReload(); !last.Time.IsZero() {
+		reload := map[string]any{"time": last.Time.UTC().Format(time.RFC3339)}
+		if last.Err != nil {
+			reload["error"] = last.Err.Error()
+		}
+		body["last_reload"] = reload
+	}
+
+	ready := body["loaded"] == true && body["healthy"] == true && body["stale"] == false
+	body["ready"] = ready
+	status := http.StatusOK
+	if !ready {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, body)
+}
@@ -0,0 +1,80 @@
+package ip2locserver
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// listenFDsStart is the first file descriptor passed by systemd socket activation.
+const listenFDsStart = 3
+
+// Listen returns a listener for addr, which is one of
+//
+//	host:port          a TCP address, e.g. ":8080" or "127.0.0.1:8080"
+//	unix:/path/to/sock a Unix domain socket; a stale socket file left by a previous run is removed
+//	systemd            the socket passed by systemd socket activation
+//	systemd:name       the socket named name by FileDescriptorName= in the socket unit
+//
+// Unix sockets spare sidecars such as nginx auth_request or HAProxy SPOE agents the TCP overhead.
+func Listen(addr string) (net.Listener, error) {
+	switch {
+	case strings.HasPrefix(addr, "unix:"):
+		path := strings.TrimPrefix(addr, "unix:")
+		if fi, err := os.Stat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+			// only an unused socket can be removed: a live server still accepts connections
+			if c, err := net.Dial("unix", path); err == nil {
+				c.Close()
+				return nil, fmt.Errorf("ip2locserver: %s is in use", path)
+			}
+			_ = os.Remove(path)
+		}
+		return net.Listen("unix", path)
+
+	case addr == "systemd" || strings.HasPrefix(addr, "systemd:"):
+		name := strings.TrimPrefix(strings.TrimPrefix(addr, "systemd"), ":")
+		return systemdListener(name)
+	}
+	return net.Listen("tcp", addr)
+}
+
+// systemdListener returns the listener passed by systemd under name, or the only one if name is empty.
+// See sd_listen_fds(3).
+func systemdListener(name string) (net.Listener, error) {
+	if pid, err := strconv.Atoi(os.Getenv("LISTEN_PID")); err != nil || pid != os.Getpid() {
+		return nil, errors.New("ip2locserver: no sockets passed by systemd")
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n < 1 {
+		return nil, errors.New("ip2locserver: no sockets passed by systemd")
+	}
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+
+	fd := -1
+	switch {
+	case name != "":
+		for i := 0; i < n && i < len(names); i++ {
+			if names[i] == name {
+				fd = listenFDsStart + i
+			}
+		}
+		if fd < 0 {
+			return nil, fmt.Errorf("ip2locserver: no socket named %q passed by systemd", name)
+		}
+	case n == 1:
+		fd = listenFDsStart
+	default:
+		return nil, fmt.Errorf("ip2locserver: systemd passed %d sockets, select one by name", n)
+	}
+
+	f := os.NewFile(uintptr(fd), "systemd:"+name)
+	defer f.Close()
+	l, err := net.FileListener(f)
+	if err != nil {
+		return nil, fmt.Errorf("ip2locserver: systemd socket %d: %w", fd, err)
+	}
+	return l, nil
+}
@@ -0,0 +1,63 @@
+package ip2locserver
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestListenUnix(t *testing.T) {
+	// socket paths are limited to about 100 bytes, which t.TempDir may exceed
+	dir, err := os.MkdirTemp("", "ip2loc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "ip2loc.sock")
+
+	l, err := Listen("unix:" + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go http.Serve(l, http.NotFoundHandler())
+	if _, err := Listen("unix:" + path); err == nil {
+		t.Error("listened on a socket in use")
+	}
+
+	// a socket file left behind by a server which did not remove it is replaced
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	l.Close()
+	if _, err := os.Stat(path); err != nil {
+		t.Fatal(err)
+	}
+	l, err = Listen("unix:" + path)
+	if err != nil {
+		t.Fatalf("listening on a stale socket: %v", err)
+	}
+	l.Close()
+}
+
+func TestListenSystemd(t *testing.T) {
+	t.Setenv("LISTEN_PID", "")
+	if _, err := Listen("systemd"); err == nil {
+		t.Error("listened without sockets passed by systemd")
+	}
+
+	t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+	t.Setenv("LISTEN_FDS", "2")
+	t.Setenv("LISTEN_FDNAMES", "http:admin")
+	if _, err := Listen("systemd"); err == nil {
+		t.Error("listened on one of two sockets without a name")
+	}
+	if _, err := Listen("systemd:metrics"); err == nil {
+		t.Error("listened on a socket which was not passed")
+	}
+
+	t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()+1))
+	if _, err := Listen("systemd:http"); err == nil {
+		t.Error("listened on the sockets passed to another process")
+	}
+}
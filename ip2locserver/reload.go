@@ -0,0 +1,53 @@
+package ip2locserver
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ReloadStatus describes the last reload of the database.
+type ReloadStatus struct {
+	Time time.Time // zero if the database was never reloaded
+	Err  error     // nil if the last reload succeeded
+}
+
+// reloads tracks the reloads of a Server.
+type reloads struct {
+	mu   sync.Mutex // serializes reloads
+	last ReloadStatus
+}
+
+// Reload reloads the database with ip2loc.DB.Reload and records the outcome.
+func (s *Server) Reload() error {
+	s.reloads.mu.Lock()
+	defer s.reloads.mu.Unlock()
+	err := s.db.Reload()
+	s.reloads.last = ReloadStatus{Time: time.Now(), Err: err}
+	return err
+}
+
+// LastReload returns the outcome of the last Reload.
+func (s *Server) LastReload() ReloadStatus {
+	s.reloads.mu.Lock()
+	defer s.reloads.mu.Unlock()
+	return s.reloads.last
+}
+
+// EnableAdmin registers the administrative endpoints:
+//
+//	POST /admin/reload   reload the database file
+//
+// They change the state of the server, so expose them only to trusted clients, e.g. on a Unix
+// socket or behind client certificates.
+func (s *Server) EnableAdmin() {
+	s.mux.HandleFunc("POST /admin/reload", s.reload)
+}
+
+func (s *Server) reload(w http.ResponseWriter, r *http.Request) {
+	if err := s.Reload(); err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"reloaded": true, "database_date": s.db.Date().Format(time.DateOnly)})
+}
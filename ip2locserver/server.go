@@ -0,0 +1,116 @@
+// Package ip2locserver answers ip2loc lookups over HTTP, so a database can be shared by services
+// written in other languages or queried by proxies:
+//
+//	GET /lookup/8.8.8.8
+//	GET /lookup?ip=8.8.8.8
+//
+// The response is a JSON object of the fields the loaded database contains, with the address
+// under "ip". Errors are returned as {"code": "...", "message": "..."} with the HTTP status of
+// their ip2loc.ErrorCode. The country code is also set in the X-Country-Code header, for
+// proxies which only look at headers, such as nginx auth_request.
+//
+// GET /healthz and GET /readyz serve as liveness and readiness probes.
+package ip2locserver
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ferluci/ip2loc"
+)
+
+// Server is an http.Handler answering lookups from a DB. Further endpoints can be added with Handle.
+type Server struct {
+	db      *ip2loc.DB
+	mux     *http.ServeMux
+	reloads reloads
+	metrics metrics
+	trustFF bool // locate clients by X-Forwarded-For, set by TrustForwardedFor
+}
+
+// New returns a Server answering lookups from db.
+func New(db *ip2loc.DB) *Server {
+	s := &Server{db: db, mux: http.NewServeMux()}
+	s.mux.HandleFunc("GET /lookup/{ip}", s.lookup)
+	s.mux.HandleFunc("GET /lookup", s.lookup)
+	s.mux.HandleFunc("GET /healthz", s.healthz)
+	s.mux.HandleFunc("GET /readyz", s.readyz)
+	return s
+}
+
+// DB returns the database the server answers from.
+func (s *Server) DB() *ip2loc.DB {
+	return s.db
+}
+
+// TrustForwardedFor locates the client of a lookup without an address by the first address of the
+// X-Forwarded-For header instead of the address of the connection, for servers behind a proxy such
+// as nginx with auth_request. Only enable it if every request passes through a proxy which sets the
+// header; otherwise clients choose the address they are located by.
+func (s *Server) TrustForwardedFor() {
+	s.trustFF = true
+}
+
+// Handle registers an additional handler for pattern, as http.ServeMux.Handle does.
+func (s *Server) Handle(pattern string, h http.Handler) {
+	s.mux.Handle(pattern, h)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) lookup(w http.ResponseWriter, r *http.Request) {
+	ip := r.PathValue("ip")
+	if ip == "" {
+		ip = r.URL.Query().Get("ip")
+	}
+	if ip == "" {
+		// without an address, locate the client
+		ip = s.clientIP(r)
+	}
+
+	start := time.Now()
+	m, err := s.db.GetAllAsMap(ip)
+	s.metrics.observe(time.Since(start), err)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	m["ip"] = s.db.AnonymizedIP(ip)
+	if c, ok := m["country_short"].(string); ok {
+		w.Header().Set("X-Country-Code", c)
+	}
+	writeJSON(w, http.StatusOK, m)
+}
+
+// clientIP returns the remote address of r, or the first address of X-Forwarded-For if it is trusted.
+func (s *Server) clientIP(r *http.Request) string {
+	if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" && s.trustFF {
+		first, _, _ := strings.Cut(fwd, ",")
+		return strings.TrimSpace(first)
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	var e *ip2loc.Error
+	if !errors.As(err, &e) {
+		e = &ip2loc.Error{Code: ip2loc.CodeOf(err), Err: err}
+	}
+	writeJSON(w, e.Code.HTTPStatus(), e)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
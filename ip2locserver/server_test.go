@@ -0,0 +1,245 @@
+//go:build !ip2loc_nofs
+
+package ip2locserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ferluci/ip2loc"
+)
+
+// testDB is the database of the tests of the ip2loc package, written by its TestTestdataDB.
+const testDB = "../testdata/IP2LOCATION-DB5.BIN"
+
+// newServer returns a Server answering from a copy of testDB, which the test may replace, and the
+// path of the copy.
+func newServer(t *testing.T, opts ...ip2loc.Option) (*Server, string) {
+	t.Helper()
+	data, err := os.ReadFile(testDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "IP2LOCATION-DB5.BIN")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	db, err := ip2loc.OpenDB(path, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return New(db), path
+}
+
+// serve returns the response of h to r and its body decoded from JSON, or nil if it is not JSON.
+func serve(t *testing.T, h http.Handler, r *http.Request) (*httptest.ResponseRecorder, map[string]any) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	var body map[string]any
+	if strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s %s: %v: %s", r.Method, r.URL, err, w.Body)
+		}
+	}
+	return w, body
+}
+
+func TestLookup(t *testing.T) {
+	s, _ := newServer(t)
+	anon, _ := newServer(t, ip2loc.WithAnonymization(24, 48))
+
+	for _, tt := range []struct {
+		name      string
+		s         *Server
+		trustFF   bool
+		target    string
+		remote    string
+		forwarded string
+		status    int
+		ip        string
+		country   string
+		code      string
+	}{
+		{name: "path", target: "/lookup/81.2.69.160", status: http.StatusOK, ip: "81.2.69.160", country: "GB"},
+		{name: "query", target: "/lookup?ip=2a00:1450::1", status: http.StatusOK, ip: "2a00:1450::1", country: "IE"},
+		{name: "client", target: "/lookup", remote: "193.0.0.1:50000", status: http.StatusOK, ip: "193.0.0.1", country: "NL"},
+		{name: "client ipv6", target: "/lookup", remote: "[2001:4860::8888]:50000", status: http.StatusOK, ip: "2001:4860::8888", country: "US"},
+		{name: "forwarded untrusted", target: "/lookup", remote: "193.0.0.1:50000", forwarded: "81.2.69.160", status: http.StatusOK, ip: "193.0.0.1", country: "NL"},
+		{name: "forwarded", trustFF: true, target: "/lookup", remote: "10.0.0.1:50000", forwarded: "81.2.69.160, 10.0.0.2", status: http.StatusOK, ip: "81.2.69.160", country: "GB"},
+		{name: "address wins over forwarded", trustFF: true, target: "/lookup/193.0.0.1", forwarded: "81.2.69.160", status: http.StatusOK, ip: "193.0.0.1", country: "NL"},
+		{name: "anonymized", s: anon, target: "/lookup/81.2.69.160", status: http.StatusOK, ip: "81.2.69.0", country: "GB"},
+		{name: "invalid", target: "/lookup/81.2.69", status: ip2loc.CodeInvalidIP.HTTPStatus(), code: string(ip2loc.CodeInvalidIP)},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.s == nil {
+				tt.s = s
+			}
+			tt.s.trustFF = tt.trustFF
+			r := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.remote != "" {
+				r.RemoteAddr = tt.remote
+			}
+			if tt.forwarded != "" {
+				r.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			w, body := serve(t, tt.s, r)
+			if w.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.code != "" {
+				if body["code"] != tt.code || body["message"] == "" {
+					t.Errorf("got %v, want code %s", body, tt.code)
+				}
+				return
+			}
+			if body["ip"] != tt.ip || body["country_short"] != tt.country || w.Header().Get("X-Country-Code") != tt.country {
+				t.Errorf("got %v, X-Country-Code %q, want ip %s, country %s", body, w.Header().Get("X-Country-Code"), tt.ip, tt.country)
+			}
+			// fields the database does not contain are left out
+			if _, ok := body["isp"]; ok {
+				t.Errorf("got isp in %v", body)
+			}
+		})
+	}
+}
+
+func TestReload(t *testing.T) {
+	s, path := newServer(t)
+	reload := func() (*httptest.ResponseRecorder, map[string]any) {
+		return serve(t, s, httptest.NewRequest(http.MethodPost, "/admin/reload", nil))
+	}
+
+	// the admin endpoints are only served when enabled
+	if w, _ := reload(); w.Code != http.StatusNotFound {
+		t.Fatalf("reload without EnableAdmin: status %d", w.Code)
+	}
+	s.EnableAdmin()
+	if w, _ := serve(t, s, httptest.NewRequest(http.MethodGet, "/admin/reload", nil)); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET /admin/reload: status %d", w.Code)
+	}
+
+	before := time.Now()
+	w, body := reload()
+	if w.Code != http.StatusOK || body["reloaded"] != true || body["database_date"] != "2024-01-01" {
+		t.Fatalf("reload: status %d, %v", w.Code, body)
+	}
+	if last := s.LastReload(); last.Err != nil || last.Time.Before(before) {
+		t.Errorf("LastReload() = %+v", last)
+	}
+
+	// a failed reload keeps the database answering and is reported by /readyz
+	broken := filepath.Join(filepath.Dir(path), "broken")
+	if err := os.WriteFile(broken, []byte("not a database"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(broken, path); err != nil {
+		t.Fatal(err)
+	}
+	if w, body := reload(); w.Code == http.StatusOK || body["code"] == nil {
+		t.Fatalf("reload of a broken file: status %d, %v", w.Code, body)
+	}
+	if s.LastReload().Err == nil {
+		t.Error("LastReload() has no error")
+	}
+	if w, body := serve(t, s, httptest.NewRequest(http.MethodGet, "/lookup/81.2.69.160", nil)); w.Code != http.StatusOK || body["city"] != "London" {
+		t.Errorf("lookup after a failed reload: status %d, %v", w.Code, body)
+	}
+	w, body = serve(t, s, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	last, _ := body["last_reload"].(map[string]any)
+	if w.Code != http.StatusOK || last["error"] == nil {
+		t.Errorf("/readyz after a failed reload: status %d, %v", w.Code, body)
+	}
+}
+
+func TestHealth(t *testing.T) {
+	s, _ := newServer(t)
+	stale, _ := newServer(t, ip2loc.WithMaxAge(24*time.Hour, ip2loc.StaleWarn))
+
+	if w, body := serve(t, s, httptest.NewRequest(http.MethodGet, "/healthz", nil)); w.Code != http.StatusOK || body["status"] != "ok" {
+		t.Errorf("/healthz: status %d, %v", w.Code, body)
+	}
+
+	w, body := serve(t, s, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if w.Code != http.StatusOK || body["ready"] != true || body["loaded"] != true || body["stale"] != false ||
+		body["database_date"] != "2024-01-01" || body["last_reload"] != nil {
+		t.Errorf("/readyz: status %d, %v", w.Code, body)
+	}
+
+	// the database of 2024-01-01 is older than a day
+	w, body = serve(t, stale, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if w.Code != http.StatusServiceUnavailable || body["ready"] != false || body["stale"] != true {
+		t.Errorf("/readyz of a stale database: status %d, %v", w.Code, body)
+	}
+	if w, _ := serve(t, stale, httptest.NewRequest(http.MethodGet, "/healthz", nil)); w.Code != http.StatusOK {
+		t.Errorf("/healthz of a stale database: status %d", w.Code)
+	}
+}
+
+func TestDebug(t *testing.T) {
+	status := func(s *Server, target string) int {
+		w, _ := serve(t, s, httptest.NewRequest(http.MethodGet, target, nil))
+		return w.Code
+	}
+
+	// the debug endpoints are only served when enabled, and the profiles only with pprof
+	s, _ := newServer(t)
+	for _, target := range []string{"/debug/vars", "/debug/pprof/", "/debug/pprof/cmdline"} {
+		if code := status(s, target); code != http.StatusNotFound {
+			t.Errorf("%s without EnableDebug: status %d", target, code)
+		}
+	}
+	s.EnableDebug(false)
+	if code := status(s, "/debug/pprof/"); code != http.StatusNotFound {
+		t.Errorf("/debug/pprof/ without pprof: status %d", code)
+	}
+
+	serve(t, s, httptest.NewRequest(http.MethodGet, "/lookup/81.2.69.160", nil))
+	serve(t, s, httptest.NewRequest(http.MethodGet, "/lookup/81.2.69", nil))
+	w, body := serve(t, s, httptest.NewRequest(http.MethodGet, "/debug/vars", nil))
+	vars, _ := body["ip2loc"].(map[string]any)
+	errs, _ := vars["errors"].(map[string]any)
+	if w.Code != http.StatusOK || vars["lookups"] != 2.0 || errs[string(ip2loc.CodeInvalidIP)] != 1.0 ||
+		vars["database_date"] != "2024-01-01" || body["memstats"] == nil {
+		t.Errorf("/debug/vars: status %d, %v", w.Code, body)
+	}
+
+	p, _ := newServer(t)
+	p.EnableDebug(true)
+	for _, target := range []string{"/debug/vars", "/debug/pprof/", "/debug/pprof/cmdline", "/debug/pprof/heap", "/debug/pprof/symbol"} {
+		if code := status(p, target); code != http.StatusOK {
+			t.Errorf("%s with pprof: status %d", target, code)
+		}
+	}
+	if code := status(p, "/debug/pprof/nothing"); code != http.StatusNotFound {
+		t.Errorf("unknown profile: status %d", code)
+	}
+
+	// go tool pprof resolves program counters by POST, others by GET
+	pc := fmt.Sprintf("%#x", reflect.ValueOf(symbol).Pointer())
+	for _, r := range []*http.Request{
+		httptest.NewRequest(http.MethodPost, "/debug/pprof/symbol", strings.NewReader(pc+"+0x0")),
+		httptest.NewRequest(http.MethodGet, "/debug/pprof/symbol?"+pc, nil),
+	} {
+		w, _ := serve(t, p, r)
+		if want := "num_symbols: 1\n" + pc + " github.com/ferluci/ip2loc/ip2locserver.symbol\n"; w.Body.String() != want {
+			t.Errorf("%s symbol: got %q, want %q", r.Method, w.Body, want)
+		}
+	}
+
+	// nothing is registered on the default mux, which other handlers of the process may serve
+	for _, target := range []string{"/debug/vars", "/debug/pprof/", "/debug/pprof/cmdline"} {
+		if _, pattern := http.DefaultServeMux.Handler(httptest.NewRequest(http.MethodGet, target, nil)); pattern != "" {
+			t.Errorf("http.DefaultServeMux serves %s with %q", target, pattern)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package ip2locserver
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// TLSOptions configures TLS for the server.
+type TLSOptions struct {
+	CertFile string // PEM encoded certificate chain of the server
+	KeyFile  string // PEM encoded private key of the server
+	// ClientCAFile holds the PEM encoded CA certificates client certificates are verified against.
+	// If it is empty, client certificates are not requested.
+	ClientCAFile string
+	// RequireClientCert rejects clients without a valid certificate (mutual TLS). Without it, a client
+	// certificate is verified only if one is presented.
+	RequireClientCert bool
+}
+
+// TLSConfig returns the tls.Config described by o, for http.Server.TLSConfig. It accepts
+// TLS 1.2 and later.
+func (o TLSOptions) TLSConfig() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("ip2locserver: loading certificate: %w", err)
+	}
+	cfg := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if o.ClientCAFile == "" {
+		if o.RequireClientCert {
+			return nil, errors.New("ip2locserver: client certificates are required but no client CA is set")
+		}
+		return cfg, nil
+	}
+	pem, err := os.ReadFile(o.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("ip2locserver: loading client CA: %w", err)
+	}
+	cfg.ClientCAs = x509.NewCertPool()
+	if !cfg.ClientCAs.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("ip2locserver: no certificates in %s", o.ClientCAFile)
+	}
+	cfg.ClientAuth = tls.VerifyClientCertIfGiven
+	if o.RequireClientCert {
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return cfg, nil
+}
@@ -0,0 +1,166 @@
+//go:build !ip2loc_nofs
+
+package ip2locserver
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCert is a certificate and its key, written as PEM files.
+type testCert struct {
+	cert              *x509.Certificate
+	key               *ecdsa.PrivateKey
+	certFile, keyFile string
+}
+
+// newCert returns a certificate named name issued by parent, or a self-signed CA if parent is nil.
+func newCert(t *testing.T, name string, parent *testCert) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+	}
+	issuer, signer := tmpl, key
+	if parent == nil {
+		tmpl.IsCA, tmpl.BasicConstraintsValid = true, true
+		tmpl.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		issuer, signer = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, issuer, &key.PublicKey, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &testCert{
+		cert:     cert,
+		key:      key,
+		certFile: filepath.Join(t.TempDir(), name+".pem"),
+		keyFile:  filepath.Join(t.TempDir(), name+"-key.pem"),
+	}
+	for file, block := range map[string]*pem.Block{
+		c.certFile: {Type: "CERTIFICATE", Bytes: der},
+		c.keyFile:  {Type: "EC PRIVATE KEY", Bytes: keyDER},
+	} {
+		if err := os.WriteFile(file, pem.EncodeToMemory(block), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return c
+}
+
+func TestTLSConfig(t *testing.T) {
+	ca := newCert(t, "ca", nil)
+	server := newCert(t, "server", ca)
+	empty := filepath.Join(t.TempDir(), "empty.pem")
+	if err := os.WriteFile(empty, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name string
+		opts TLSOptions
+		auth tls.ClientAuthType
+		ok   bool
+	}{
+		{"server only", TLSOptions{CertFile: server.certFile, KeyFile: server.keyFile}, tls.NoClientCert, true},
+		{"client CA", TLSOptions{CertFile: server.certFile, KeyFile: server.keyFile, ClientCAFile: ca.certFile}, tls.VerifyClientCertIfGiven, true},
+		{"mutual", TLSOptions{CertFile: server.certFile, KeyFile: server.keyFile, ClientCAFile: ca.certFile, RequireClientCert: true}, tls.RequireAndVerifyClientCert, true},
+		{"no certificate", TLSOptions{KeyFile: server.keyFile}, 0, false},
+		{"key of another certificate", TLSOptions{CertFile: server.certFile, KeyFile: ca.keyFile}, 0, false},
+		{"required without CA", TLSOptions{CertFile: server.certFile, KeyFile: server.keyFile, RequireClientCert: true}, 0, false},
+		{"missing CA", TLSOptions{CertFile: server.certFile, KeyFile: server.keyFile, ClientCAFile: empty + ".missing"}, 0, false},
+		{"empty CA", TLSOptions{CertFile: server.certFile, KeyFile: server.keyFile, ClientCAFile: empty}, 0, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := tt.opts.TLSConfig()
+			if !tt.ok {
+				if err == nil {
+					t.Fatal("TLSConfig succeeded")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.ClientAuth != tt.auth || cfg.MinVersion != tls.VersionTLS12 || len(cfg.Certificates) != 1 {
+				t.Errorf("got ClientAuth %v, MinVersion %x, %d certificates", cfg.ClientAuth, cfg.MinVersion, len(cfg.Certificates))
+			}
+		})
+	}
+}
+
+func TestMutualTLS(t *testing.T) {
+	ca := newCert(t, "ca", nil)
+	server := newCert(t, "server", ca)
+	client := newCert(t, "client", ca)
+	other := newCert(t, "client", newCert(t, "other ca", nil))
+
+	cfg, err := TLSOptions{CertFile: server.certFile, KeyFile: server.keyFile, ClientCAFile: ca.certFile, RequireClientCert: true}.TLSConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, _ := newServer(t)
+	ts := httptest.NewUnstartedServer(s)
+	ts.TLS = cfg
+	ts.StartTLS()
+	defer ts.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	get := func(c *testCert) (*http.Response, error) {
+		tc := &tls.Config{RootCAs: roots}
+		if c != nil {
+			tc.Certificates = []tls.Certificate{{Certificate: [][]byte{c.cert.Raw}, PrivateKey: c.key}}
+		}
+		hc := &http.Client{Transport: &http.Transport{TLSClientConfig: tc}}
+		defer hc.CloseIdleConnections()
+		return hc.Get(ts.URL + "/lookup/81.2.69.160")
+	}
+
+	resp, err := get(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("X-Country-Code") != "GB" {
+		t.Errorf("status %d, X-Country-Code %q", resp.StatusCode, resp.Header.Get("X-Country-Code"))
+	}
+	for name, c := range map[string]*testCert{"without a certificate": nil, "with a certificate of another CA": other} {
+		if resp, err := get(c); err == nil {
+			resp.Body.Close()
+			t.Errorf("request %s succeeded", name)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// Command gen writes the timezone grid embedded by ip2loctz from the boundaries of the timezone
+// boundary builder project, as packaged by github.com/ringsaturn/tzf. The boundaries are published
+// under the Open Database License.
+//
+// It is a module of its own, so that the boundaries are not a dependency of ip2loc. Run it with
+// go generate in ip2loctz.
+package main
+
+import (
+	"compress/gzip"
+	"encoding/binary"
+	"flag"
+	"log"
+	"os"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/ringsaturn/tzf"
+)
+
+func main() {
+	out := flag.String("o", "grid.bin.gz", "path of the grid `file` to write")
+	cells := flag.Int("cells", 8, "cells per degree")
+	year := flag.Int("year", time.Now().Year(), "`year` whose UTC offsets the zones are given")
+	flag.Parse()
+
+	f, err := tzf.NewDefaultFinder()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	rows, cols := 180**cells, 360**cells
+	zones := map[string]int{"": 0}
+	names := []string{""}
+	var buf []byte
+	buf = binary.AppendUvarint(buf, uint64(rows))
+	buf = binary.AppendUvarint(buf, uint64(cols))
+	var grid []byte
+	for r := 0; r < rows; r++ {
+		lat := 90 - (float64(r)+0.5)/float64(*cells)
+		var runs [][2]int
+		for c := 0; c < cols; c++ {
+			lon := -180 + (float64(c)+0.5)/float64(*cells)
+			name := f.GetTimezoneName(lon, lat)
+			z, ok := zones[name]
+			if !ok {
+				z = len(names)
+				zones[name] = z
+				names = append(names, name)
+			}
+			if n := len(runs); n > 0 && runs[n-1][0] == z {
+				runs[n-1][1]++
+			} else {
+				runs = append(runs, [2]int{z, 1})
+			}
+		}
+		grid = binary.AppendUvarint(grid, uint64(len(runs)))
+		for _, run := range runs {
+			grid = binary.AppendUvarint(grid, uint64(run[0]))
+			grid = binary.AppendUvarint(grid, uint64(run[1]))
+		}
+	}
+
+	buf = binary.AppendUvarint(buf, uint64(len(names)))
+	for _, name := range names {
+		std, dst := offsets(name, *year)
+		buf = binary.AppendUvarint(buf, uint64(len(name)))
+		buf = append(buf, name...)
+		buf = binary.AppendVarint(buf, int64(std))
+		buf = binary.AppendVarint(buf, int64(dst))
+	}
+	buf = append(buf, grid...)
+
+	w, err := os.Create(*out)
+	if err != nil {
+		log.Fatal(err)
+	}
+	zw, _ := gzip.NewWriterLevel(w, gzip.BestCompression)
+	if _, err := zw.Write(buf); err != nil {
+		log.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		log.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("%d zones, %d by %d cells", len(names)-1, rows, cols)
+}
+
+// offsets returns the standard and daylight saving UTC offsets in seconds of the zone name in year,
+// both the standard offset if the zone does not observe daylight saving time.
+func offsets(name string, year int) (std, dst int) {
+	if name == "" {
+		return 0, 0
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	t := time.Date(year, 1, 1, 12, 0, 0, 0, time.UTC)
+	_, std = t.In(loc).Zone()
+	dst = std
+	for ; t.Year() == year; t = t.AddDate(0, 0, 1) {
+		_, off := t.In(loc).Zone()
+		std, dst = min(std, off), max(dst, off)
+	}
+	return std, dst
+}
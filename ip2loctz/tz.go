@@ -0,0 +1,262 @@
+// Package ip2loctz cross-checks the Timezone field of ip2loc databases against the time zone at
+// their coordinates, found in an embedded grid of the time zone boundaries, to validate the data of
+// a release before deploying it.
+package ip2loctz
+
+//go:generate go run -C internal/gen . -o ../../grid.bin.gz
+
+import (
+	"bytes"
+	"compress/gzip"
+	_ "embed"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"net/netip"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ferluci/ip2loc"
+)
+
+// grid holds the time zone of every cell of a latitude/longitude grid, 1/8 degree or about 14 km
+// wide at the equator, with the UTC offsets of the zones in the year it was generated.
+//
+//go:embed grid.bin.gz
+var grid []byte
+
+// Zone is an IANA time zone with its UTC offsets, e.g. Europe/Berlin with +01:00 and +02:00.
+type Zone struct {
+	Name     string
+	Standard time.Duration
+	Daylight time.Duration // Standard if the zone does not observe daylight saving time
+}
+
+// Has reports whether offset is the standard or daylight saving offset of the zone.
+func (z Zone) Has(offset time.Duration) bool {
+	return offset == z.Standard || offset == z.Daylight
+}
+
+func (z Zone) String() string {
+	if z.Standard == z.Daylight {
+		return fmt.Sprintf("%s (%s)", z.Name, formatOffset(z.Standard))
+	}
+	return fmt.Sprintf("%s (%s/%s)", z.Name, formatOffset(z.Standard), formatOffset(z.Daylight))
+}
+
+// formatOffset formats an offset like the Timezone field, e.g. "-03:30".
+func formatOffset(d time.Duration) string {
+	sign := '+'
+	if d < 0 {
+		sign, d = '-', -d
+	}
+	return fmt.Sprintf("%c%02d:%02d", sign, int(d.Hours()), int(d.Minutes())%60)
+}
+
+// zoneGrid is the decoded grid: the zone indexes of rows from north to south of cells from west to
+// east, run-length encoded.
+type zoneGrid struct {
+	rows, cols int
+	zones      []Zone
+	runs       [][]gridRun
+}
+
+type gridRun struct {
+	zone uint16
+	end  int32 // column after the run
+}
+
+var loadGrid = sync.OnceValues(func() (*zoneGrid, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(grid))
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		return nil, err
+	}
+	r := bytes.NewReader(data)
+	uvarint := func() int {
+		v, e := binary.ReadUvarint(r)
+		if e != nil {
+			err = e
+		}
+		return int(v)
+	}
+	varint := func() time.Duration {
+		v, e := binary.ReadVarint(r)
+		if e != nil {
+			err = e
+		}
+		return time.Duration(v) * time.Second
+	}
+
+	g := &zoneGrid{rows: uvarint(), cols: uvarint()}
+	g.zones = make([]Zone, uvarint())
+	for i := range g.zones {
+		name := make([]byte, uvarint())
+		if _, e := io.ReadFull(r, name); e != nil {
+			err = e
+		}
+		g.zones[i] = Zone{Name: string(name), Standard: varint(), Daylight: varint()}
+	}
+	g.runs = make([][]gridRun, g.rows)
+	for i := range g.runs {
+		end := 0
+		g.runs[i] = make([]gridRun, uvarint())
+		for j := range g.runs[i] {
+			zone := uvarint()
+			end += uvarint()
+			g.runs[i][j] = gridRun{uint16(zone), int32(end)}
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ip2loctz: corrupt grid: %w", err)
+	}
+	return g, nil
+})
+
+// cell returns the zone of the cell in row and column col, wrapping around the antimeridian.
+func (g *zoneGrid) cell(row, col int) Zone {
+	row = min(max(row, 0), g.rows-1)
+	col = (col%g.cols + g.cols) % g.cols
+	runs := g.runs[row]
+	i := sort.Search(len(runs), func(i int) bool { return int(runs[i].end) > col })
+	return g.zones[runs[i].zone]
+}
+
+// locate returns the row and column of the cell holding lat, lon.
+func (g *zoneGrid) locate(lat, lon float64) (row, col int) {
+	row = int(math.Floor((90 - lat) * float64(g.rows) / 180))
+	col = int(math.Floor((lon + 180) * float64(g.cols) / 360))
+	return row, col
+}
+
+// ZoneAt returns the time zone at lat, lon. Over the open sea it returns the nautical zone, e.g.
+// Etc/GMT-3. It returns false for coordinates outside -90..90 and -180..180.
+func ZoneAt(lat, lon float64) (Zone, bool) {
+	g, err := loadGrid()
+	if err != nil || lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+		return Zone{}, false
+	}
+	z := g.cell(g.locate(lat, lon))
+	return z, z.Name != ""
+}
+
+// Mismatch is a location whose Timezone field is not an offset of the time zone at its coordinates
+// nor of the zones of the neighbouring cells.
+type Mismatch struct {
+	Latitude, Longitude float32
+	Timezone            string // the Timezone field
+	CountryShort        string
+	Region              string
+	City                string
+	Zone                Zone       // the time zone at the coordinates
+	First               netip.Addr // the first address of the first range with the location
+	Ranges              int        // the number of ranges with the location and Timezone
+}
+
+func (m Mismatch) String() string {
+	return fmt.Sprintf("%s, %s, %s (%g, %g): %s, expected %s, %d ranges from %s", m.City, m.Region,
+		m.CountryShort, m.Latitude, m.Longitude, m.Timezone, m.Zone, m.Ranges, m.First)
+}
+
+// Report is the result of Check.
+type Report struct {
+	Ranges     int // the ranges of the database
+	Checked    int // the ranges with coordinates and a Timezone field, which were checked
+	Mismatches []Mismatch
+}
+
+// Check compares the Timezone field of every range of db with the time zone at its coordinates and
+// returns the locations which disagree, the ones covering the most ranges first. Either the
+// standard or the daylight saving offset of the zone is accepted, and so is an offset of a zone in a
+// neighbouring cell of the grid, since coordinates near a boundary may be on either side of it in a
+// grid of this resolution. Ranges without coordinates or a Timezone field are skipped.
+//
+// It returns ip2loc.ErrUnsupportedField if db has no coordinates or no Timezone field.
+func Check(db *ip2loc.DB) (Report, error) {
+	g, err := loadGrid()
+	if err != nil {
+		return Report{}, err
+	}
+	c, err := db.Capabilities()
+	if err != nil {
+		return Report{}, err
+	}
+	for _, f := range c.Fields {
+		if (f.Name == "latitude" || f.Name == "timezone") && !f.Populated {
+			return Report{}, fmt.Errorf("%w: %s", ip2loc.ErrUnsupportedField, f.Name)
+		}
+	}
+
+	type location struct {
+		lat, lon float32
+		tz       string
+	}
+	var report Report
+	found := make(map[location]*Mismatch)
+	ok := make(map[location]bool)
+	for r, err := range db.Records() {
+		if err != nil {
+			return Report{}, err
+		}
+		report.Ranges++
+		x := r.Record.WithoutPlaceholders()
+		if x.Timezone == "" || x.Timezone == "-" || x.Latitude == 0 && x.Longitude == 0 {
+			continue
+		}
+		report.Checked++
+		key := location{x.Latitude, x.Longitude, x.Timezone}
+		if m := found[key]; m != nil {
+			m.Ranges++
+			continue
+		}
+		if ok[key] {
+			continue
+		}
+		offset, err := x.UTCOffset()
+		row, col := g.locate(float64(x.Latitude), float64(x.Longitude))
+		if err == nil && g.near(row, col, offset) {
+			ok[key] = true
+			continue
+		}
+		found[key] = &Mismatch{
+			Latitude:     x.Latitude,
+			Longitude:    x.Longitude,
+			Timezone:     x.Timezone,
+			CountryShort: x.CountryShort,
+			Region:       x.Region,
+			City:         x.City,
+			Zone:         g.cell(row, col),
+			First:        r.First,
+			Ranges:       1,
+		}
+	}
+
+	for _, m := range found {
+		report.Mismatches = append(report.Mismatches, *m)
+	}
+	sort.Slice(report.Mismatches, func(i, j int) bool {
+		a, b := report.Mismatches[i], report.Mismatches[j]
+		if a.Ranges != b.Ranges {
+			return a.Ranges > b.Ranges
+		}
+		return a.First.Less(b.First)
+	})
+	return report, nil
+}
+
+// near reports whether offset is an offset of the zone of the cell or of a neighbouring one.
+func (g *zoneGrid) near(row, col int, offset time.Duration) bool {
+	for dr := -1; dr <= 1; dr++ {
+		for dc := -1; dc <= 1; dc++ {
+			if g.cell(row+dr, col+dc).Has(offset) {
+				return true
+			}
+		}
+	}
+	return false
+}
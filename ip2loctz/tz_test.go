@@ -0,0 +1,106 @@
+//go:build !ip2loc_nofs
+
+package ip2loctz
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ferluci/ip2loc"
+)
+
+// testDB is the database of the tests of the ip2loc package, written by its TestTestdataDB.
+const testDB = "../testdata/IP2LOCATION-DB5.BIN"
+
+func TestZoneAt(t *testing.T) {
+	db, err := ip2loc.OpenDB(testDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for _, tt := range []struct {
+		ip   string
+		want Zone
+	}{
+		{"8.8.8.8", Zone{"America/Los_Angeles", -8 * time.Hour, -7 * time.Hour}},
+		{"81.2.69.160", Zone{"Europe/London", 0, time.Hour}},
+		{"193.0.0.1", Zone{"Europe/Amsterdam", time.Hour, 2 * time.Hour}},
+		{"2a00:1450::1", Zone{"Europe/Dublin", 0, time.Hour}},
+	} {
+		x, err := db.GetAll(tt.ip)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, ok := ZoneAt(float64(x.Latitude), float64(x.Longitude)); !ok || got != tt.want {
+			t.Errorf("%s (%s): got %v, %v, want %v", tt.ip, x.City, got, ok, tt.want)
+		}
+	}
+
+	// a record without a location has the coordinates 0, 0, in the Gulf of Guinea, which Check
+	// skips rather than comparing with the nautical zone there
+	x, err := db.GetAll("1.1.1.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if z, ok := ZoneAt(float64(x.Latitude), float64(x.Longitude)); x.Latitude != 0 || x.Longitude != 0 || !ok || z.Name != "Etc/GMT" {
+		t.Errorf("1.1.1.1 (%g, %g): got %v, %v", x.Latitude, x.Longitude, z, ok)
+	}
+
+	for _, c := range [][2]float64{{90.5, 0}, {-91, 10}, {45, 180.5}, {45, -181}} {
+		if z, ok := ZoneAt(c[0], c[1]); ok || z != (Zone{}) {
+			t.Errorf("ZoneAt(%g, %g) = %v, %v", c[0], c[1], z, ok)
+		}
+	}
+	// the grid wraps around the antimeridian and clamps at the poles
+	for _, c := range [][2]float64{{90, 0}, {-90, 0}, {0, 180}, {0, -180}} {
+		if z, ok := ZoneAt(c[0], c[1]); !ok || z.Name == "" {
+			t.Errorf("ZoneAt(%g, %g) = %v, %v", c[0], c[1], z, ok)
+		}
+	}
+}
+
+func TestZone(t *testing.T) {
+	london := Zone{"Europe/London", 0, time.Hour}
+	kolkata := Zone{"Asia/Kolkata", 5*time.Hour + 30*time.Minute, 5*time.Hour + 30*time.Minute}
+	stJohns := Zone{"America/St_Johns", -3*time.Hour - 30*time.Minute, -2*time.Hour - 30*time.Minute}
+
+	for _, tt := range []struct {
+		z    Zone
+		s    string
+		has  []time.Duration
+		hasn []time.Duration
+	}{
+		{london, "Europe/London (+00:00/+01:00)", []time.Duration{0, time.Hour}, []time.Duration{-time.Hour}},
+		{kolkata, "Asia/Kolkata (+05:30)", []time.Duration{5*time.Hour + 30*time.Minute}, []time.Duration{5 * time.Hour}},
+		{stJohns, "America/St_Johns (-03:30/-02:30)", []time.Duration{-3*time.Hour - 30*time.Minute}, []time.Duration{3*time.Hour + 30*time.Minute}},
+	} {
+		if s := tt.z.String(); s != tt.s {
+			t.Errorf("got %q, want %q", s, tt.s)
+		}
+		for _, d := range tt.has {
+			if !tt.z.Has(d) {
+				t.Errorf("%v lacks %v", tt.z, d)
+			}
+		}
+		for _, d := range tt.hasn {
+			if tt.z.Has(d) {
+				t.Errorf("%v has %v", tt.z, d)
+			}
+		}
+	}
+}
+
+func TestCheckUnsupported(t *testing.T) {
+	db, err := ip2loc.OpenDB(testDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// a DB5 database has coordinates but no Timezone field to check them against
+	if r, err := Check(db); !errors.Is(err, ip2loc.ErrUnsupportedField) {
+		t.Errorf("got %+v, %v", r, err)
+	}
+}
@@ -0,0 +1,14 @@
+module github.com/ferluci/ip2loc/ip2loczap
+
+go 1.23.0
+
+require (
+	github.com/ferluci/ip2loc v0.0.0-20261016234639-b580dc0a984c
+	go.uber.org/zap v1.28.0
+)
+
+require (
+	go.uber.org/multierr v1.10.0 // indirect
+	golang.org/x/sync v0.15.0 // indirect
+	golang.org/x/sys v0.33.0 // indirect
+)
@@ -0,0 +1,72 @@
+// Package ip2loczap adds ip2loc locations to go.uber.org/zap log entries:
+//
+//	host, _, _ := net.SplitHostPort(r.RemoteAddr)
+//	logger.Info("request", ip2loczap.IP(db, "ip", host), ip2loczap.Lookup(db, "geo", host))
+//
+// It is the zap counterpart of ip2loc.LogHandler, kept in its own package so that the ip2loc
+// package does not depend on zap.
+package ip2loczap
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/ferluci/ip2loc"
+)
+
+// IP returns a field holding ip under key, truncated as configured by ip2loc.WithAnonymization so
+// that the full address is not logged when db anonymizes.
+func IP(db *ip2loc.DB, key, ip string) zap.Field {
+	return zap.String(key, db.AnonymizedIP(ip))
+}
+
+// Lookup looks up ip and returns a field holding its location as an object under key, with the
+// given record fields or ip2loc.DefaultLogFields. Fields the database does not contain are left
+// out. If the lookup fails, the field is skipped.
+func Lookup(db *ip2loc.DB, key, ip string, fields ...string) zap.Field {
+	m, err := db.GetAllAsMap(ip)
+	if err != nil {
+		return zap.Skip()
+	}
+	return zap.Object(key, location{m, fieldNames(fields)})
+}
+
+// Record returns a field holding the given fields of x, or ip2loc.DefaultLogFields, as an object
+// under key. Empty strings and placeholder messages are left out.
+func Record(key string, x ip2loc.IP2LocationRecord, fields ...string) zap.Field {
+	x = x.WithoutPlaceholders()
+	m := map[string]any{}
+	for _, name := range fieldNames(fields) {
+		if v, ok := x.Field(name); ok {
+			m[name] = v
+		}
+	}
+	return zap.Object(key, location{m, fieldNames(fields)})
+}
+
+func fieldNames(fields []string) []string {
+	if len(fields) == 0 {
+		return ip2loc.DefaultLogFields
+	}
+	return fields
+}
+
+// location encodes the named values of m in the order of names.
+type location struct {
+	m     map[string]any
+	names []string
+}
+
+func (l location) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	for _, name := range l.names {
+		switch v := l.m[name].(type) {
+		case string:
+			if v != "" && v != "-" {
+				enc.AddString(name, v)
+			}
+		case float32:
+			enc.AddFloat32(name, v)
+		}
+	}
+	return nil
+}
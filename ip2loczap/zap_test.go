@@ -0,0 +1,85 @@
+package ip2loczap
+
+import (
+	"reflect"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/ferluci/ip2loc"
+)
+
+// testDB is the database of the tests of the ip2loc package, written by its TestTestdataDB.
+const testDB = "../testdata/IP2LOCATION-DB5.BIN"
+
+// logged returns the context of the entry logged with fields.
+func logged(fields ...zap.Field) map[string]any {
+	core, logs := observer.New(zapcore.InfoLevel)
+	zap.New(core).Info("request", fields...)
+	return logs.All()[0].ContextMap()
+}
+
+func TestFields(t *testing.T) {
+	db, err := ip2loc.OpenDB(testDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	anon, err := ip2loc.OpenDB(testDB, ip2loc.WithAnonymization(24, 48))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer anon.Close()
+
+	london := map[string]any{"country_short": "GB", "region": "England", "city": "London"}
+	x, err := db.GetAll("81.2.69.160")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		name   string
+		fields []zap.Field
+		want   map[string]any
+	}{
+		{
+			name:   "lookup",
+			fields: []zap.Field{IP(db, "ip", "81.2.69.160"), Lookup(db, "geo", "81.2.69.160")},
+			// the database has no isp column
+			want: map[string]any{"ip": "81.2.69.160", "geo": london},
+		},
+		{
+			name:   "anonymized",
+			fields: []zap.Field{IP(anon, "ip", "81.2.69.160"), Lookup(anon, "geo", "81.2.69.160")},
+			want:   map[string]any{"ip": "81.2.69.0", "geo": london},
+		},
+		{
+			name:   "fields",
+			fields: []zap.Field{Lookup(db, "geo", "2a00:1450::1", "country_long", "latitude")},
+			want:   map[string]any{"geo": map[string]any{"country_long": "Ireland", "latitude": float32(53.34399)}},
+		},
+		{
+			name:   "no location",
+			fields: []zap.Field{Lookup(db, "geo", "1.1.1.1")},
+			want:   map[string]any{"geo": map[string]any{}},
+		},
+		{
+			name:   "failed lookup",
+			fields: []zap.Field{Lookup(db, "geo", "81.2.69.160:443")},
+			want:   map[string]any{},
+		},
+		{
+			name:   "record",
+			fields: []zap.Field{Record("geo", x, "city", "longitude", "isp")},
+			want:   map[string]any{"geo": map[string]any{"city": "London", "longitude": float32(-0.12574)}},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := logged(tt.fields...); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
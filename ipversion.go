@@ -0,0 +1,123 @@
+package ip2loc
+
+import (
+	"io"
+	"sort"
+)
+
+// indexSize is the size of the IPv4 or IPv6 index: one pair of row numbers per 16-bit prefix.
+const indexSize = 1 << 16 * 8
+
+// WithIPv4Only restricts the database to IPv4 lookups. Queries for IPv6 addresses return
+// ErrIPVersionDisabled; IPv4-mapped, 6to4 and Teredo addresses are IPv4 lookups and still work.
+// When the database is held in memory, the IPv6 table and index are dropped after opening, and
+// WithRangeIndex only loads the IPv4 rows.
+func WithIPv4Only() Option {
+	return func(d *DB) {
+		d.ipVersion = 4
+	}
+}
+
+// WithIPv6Only restricts the database to IPv6 lookups. Queries for IPv4 addresses, including
+// IPv4-mapped, 6to4 and Teredo addresses, return ErrIPVersionDisabled. When the database is held
+// in memory, the IPv4 table and index are dropped after opening, and WithPrefixTable has no effect.
+func WithIPv6Only() Option {
+	return func(d *DB) {
+		d.ipVersion = 6
+	}
+}
+
+// ipVersionEnabled reports whether lookups of the given IP type are allowed: the IP version is not
+// excluded by an option and, once the database is loaded, its table has rows.
+func (d *DB) ipVersionEnabled(iptype uint32) bool {
+	if d.dbState != nil {
+		if _, _, count, _ := d.tableLayout(iptype); count == 0 {
+			return false
+		}
+	}
+	return d.ipVersion == 0 || uint32(d.ipVersion) == iptype
+}
+
+// dropIPVersion returns a reader over data without the table and index of the disabled IP version.
+func dropIPVersion(data []byte, meta ip2LocationMeta, version int) *partialReader {
+	var gaps [][2]int64
+	addGap := func(start, size int64) {
+		if start > 0 && size > 0 {
+			gaps = append(gaps, [2]int64{start - 1, start - 1 + size})
+		}
+	}
+	if version == 4 {
+		addGap(int64(meta.ipv6DatabaseAddr), (int64(meta.ipv6DatabaseCount)+1)*int64(meta.ipv6ColumnSize))
+		addGap(int64(meta.ipv6IndexBaseAddr), indexSize)
+	} else {
+		addGap(int64(meta.ipv4DatabaseAddr), (int64(meta.ipv4DatabaseCount)+1)*int64(meta.ipv4ColumnSize))
+		addGap(int64(meta.ipv4IndexBaseAddr), indexSize)
+	}
+	sort.Slice(gaps, func(i, j int) bool { return gaps[i][0] < gaps[j][0] })
+
+	r := &partialReader{size: int64(len(data))}
+	var off int64
+	for _, g := range gaps {
+		if end := min(g[0], r.size); end > off {
+			r.segments = append(r.segments, segment{off: off, data: append([]byte(nil), data[off:end]...)})
+		}
+		off = max(off, g[1])
+	}
+	if off < r.size {
+		r.segments = append(r.segments, segment{off: off, data: append([]byte(nil), data[off:]...)})
+	}
+	return r
+}
+
+// segment is a part of the database kept by a partialReader, starting at offset off.
+type segment struct {
+	off  int64
+	data []byte
+}
+
+// partialReader is a DBReader over an in-memory database of which only some segments are kept.
+// Reads touching a dropped part fail.
+type partialReader struct {
+	size     int64
+	pos      int64
+	segments []segment
+}
+
+func (r *partialReader) ReadAt(p []byte, off int64) (int, error) {
+	if off >= r.size {
+		return 0, io.EOF
+	}
+	i := sort.Search(len(r.segments), func(i int) bool {
+		return r.segments[i].off+int64(len(r.segments[i].data)) > off
+	})
+	if i == len(r.segments) || r.segments[i].off > off {
+		return 0, io.ErrUnexpectedEOF
+	}
+	n := copy(p, r.segments[i].data[off-r.segments[i].off:])
+	if n < len(p) {
+		return n, io.ErrUnexpectedEOF
+	}
+	return n, nil
+}
+
+func (r *partialReader) Read(p []byte) (int, error) {
+	n, err := r.ReadAt(p, r.pos)
+	r.pos += int64(n)
+	if err == io.ErrUnexpectedEOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+func (r *partialReader) Close() error {
+	return nil
+}
+
+// len returns the number of bytes held.
+func (r *partialReader) len() int64 {
+	var n int64
+	for _, s := range r.segments {
+		n += int64(len(s.data))
+	}
+	return n
+}
@@ -0,0 +1,51 @@
+package ip2loc
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMissingIPVersion(t *testing.T) {
+	for _, tt := range []struct {
+		name        string
+		v4, v6      []testRow
+		opts        []Option
+		found, lost []string
+	}{
+		{"IPv4 rows", testRows.v4, nil, nil, []string{"8.8.8.8", "::ffff:8.8.8.8", "255.255.255.255"}, []string{"2001:4860::1", "::", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff"}},
+		{"IPv6 rows", nil, testRows.v6, nil, []string{"2001:4860::1", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff"}, []string{"8.8.8.8", "::ffff:8.8.8.8", "0.0.0.0"}},
+		{"WithIPv4Only", testRows.v4, testRows.v6, []Option{WithIPv4Only()}, []string{"8.8.8.8", "::ffff:8.8.8.8"}, []string{"2001:4860::1"}},
+		{"WithIPv6Only", testRows.v4, testRows.v6, []Option{WithIPv6Only()}, []string{"2001:4860::1"}, []string{"8.8.8.8", "::ffff:8.8.8.8"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := OpenBytes(buildDB(tt.v4, tt.v6), tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			for _, ip := range tt.found {
+				if _, err := db.GetAll(ip); err != nil {
+					t.Errorf("%s: %v", ip, err)
+				}
+			}
+			for _, ip := range tt.lost {
+				if _, err := db.GetAll(ip); !errors.Is(err, ErrIPVersionDisabled) {
+					t.Errorf("%s: %v, want ErrIPVersionDisabled", ip, err)
+				}
+			}
+			if db.health.unhealthy.Load() {
+				t.Error("lookups of the missing IP version marked the database unhealthy")
+			}
+			n := 0
+			for _, err := range db.Records() {
+				if err != nil {
+					t.Fatal(err)
+				}
+				n++
+			}
+			if want := len(tt.v4) + len(tt.v6); tt.opts == nil && n != want {
+				t.Errorf("Records returned %d rows, want %d", n, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+package ip2loc
+
+import "errors"
+
+// ErrLocked is returned under WithFailIfLocked when another process holds an exclusive lock on the
+// database file.
+var ErrLocked = errors.New("ip2loc: database file is locked by another process")
+
+// WithFailIfLocked makes opening and reopening the database file fail with ErrLocked while another
+// process holds an exclusive advisory lock on it, e.g. an update script run under flock -x, instead
+// of reading a file which is being rewritten.
+//
+// Database files are always opened read-only under a shared lock where the platform supports it,
+// flock on Unix and LockFileEx on Windows, held while the file is read: for as long as it is open by
+// OpenDB, OpenCachedDB and OpenMmapDB, and while it is read into memory otherwise. A script taking an
+// exclusive lock before truncating or rewriting the file thereby waits until it is no longer read.
+// Without this option a file locked exclusively is opened without the shared lock.
+func WithFailIfLocked() Option {
+	return func(d *DB) {
+		d.strictLock = true
+	}
+}
@@ -0,0 +1,20 @@
+//go:build (darwin || dragonfly || freebsd || linux || netbsd || openbsd) && !ip2loc_nofs
+
+package ip2loc
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// lockShared takes a shared advisory lock on f, released when f is closed. It returns false if
+// another process holds an exclusive lock; file systems without locks are treated as unlocked.
+func lockShared(f *os.File) bool {
+	for {
+		err := syscall.Flock(int(f.Fd()), syscall.LOCK_SH|syscall.LOCK_NB)
+		if err != syscall.EINTR {
+			return !errors.Is(err, syscall.EWOULDBLOCK)
+		}
+	}
+}
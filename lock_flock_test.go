@@ -0,0 +1,51 @@
+//go:build (darwin || dragonfly || freebsd || linux || netbsd || openbsd) && !ip2loc_nofs
+
+package ip2loc
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestFailIfLocked(t *testing.T) {
+	path := testDB(t)
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		t.Skipf("file system without locks: %v", err)
+	}
+
+	for name, open := range map[string]func(string, ...Option) (*DB, error){"OpenDB": OpenDB, "OpenInMemoryDB": OpenInMemoryDB, "OpenMmapDB": OpenMmapDB} {
+		if _, err := open(path, WithFailIfLocked()); !errors.Is(err, ErrLocked) {
+			t.Errorf("%s of a file locked exclusively with WithFailIfLocked: %v, want ErrLocked", name, err)
+		}
+		db, err := open(path)
+		if err != nil {
+			t.Fatalf("%s of a file locked exclusively: %v", name, err)
+		}
+		db.Close()
+	}
+
+	// the lock of an open database keeps a writer from locking the file exclusively
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_UN); err != nil {
+		t.Fatal(err)
+	}
+	db, err := OpenDB(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); !errors.Is(err, syscall.EWOULDBLOCK) {
+		t.Errorf("exclusive lock of an open database: %v, want EWOULDBLOCK", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		t.Errorf("exclusive lock of a closed database: %v", err)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !darwin && !dragonfly && !freebsd && !linux && !netbsd && !openbsd && !windows && !ip2loc_nofs
+
+package ip2loc
+
+import "os"
+
+// lockShared does nothing where advisory locks are unavailable.
+func lockShared(f *os.File) bool {
+	return true
+}
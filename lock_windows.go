@@ -0,0 +1,17 @@
+//go:build windows && !ip2loc_nofs
+
+package ip2loc
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// lockShared takes a shared lock on all of f, released when f is closed. It returns false if
+// another process holds an exclusive lock.
+func lockShared(f *os.File) bool {
+	err := windows.LockFileEx(windows.Handle(f.Fd()), windows.LOCKFILE_FAIL_IMMEDIATELY, 0, ^uint32(0), ^uint32(0), new(windows.Overlapped))
+	return !errors.Is(err, windows.ERROR_LOCK_VIOLATION)
+}
@@ -0,0 +1,35 @@
+//go:build !ip2loc_nofs
+
+package ip2loc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// FetchMETAR downloads the latest METAR report of a station from METARURL in the given format, with
+// http.DefaultClient if client is nil. It returns an empty report if the station has not reported
+// recently.
+func FetchMETAR(ctx context.Context, client *http.Client, icao, format string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, METARURL(icao, format), nil)
+	if err != nil {
+		return nil, err
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return io.ReadAll(resp.Body)
+	case http.StatusNoContent:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("ip2loc: fetching METAR of %s: %s", icao, resp.Status)
+}
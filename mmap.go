@@ -0,0 +1,84 @@
+//go:build !ip2loc_nofs
+
+package ip2loc
+
+import (
+	"fmt"
+	"os"
+)
+
+// MmapReader is a ByteSliceReader over a memory-mapped database file. Lookups read the mapping
+// without system calls and the pages are shared with the page cache and other processes mapping
+// the same file. Close unmaps the file, so the slice returned by Bytes must not be used afterwards.
+type MmapReader struct {
+	*ByteSliceReader
+	f     *os.File // kept open for its shared lock
+	unmap func() error
+}
+
+// Close unmaps the file.
+func (r *MmapReader) Close() error {
+	if r.unmap == nil {
+		return nil
+	}
+	unmap := r.unmap
+	r.unmap = nil
+	err := unmap()
+	if r.f != nil {
+		_ = r.f.Close()
+	}
+	return err
+}
+
+// NewMmapReader maps the file at path into memory. On platforms without mmap support the file is
+// read into memory instead.
+func NewMmapReader(path string) (*MmapReader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return newMmapReader(f, path)
+}
+
+// newMmapReader maps f, which it closes when the mapping is closed or fails.
+func newMmapReader(f *os.File, path string) (*MmapReader, error) {
+	fi, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	size := fi.Size()
+	if size == 0 {
+		_ = f.Close()
+		return &MmapReader{ByteSliceReader: NewByteSliceReader(nil)}, nil
+	}
+	// the mapping is addressed with int, which is 32 bits wide on 386 and arm
+	if int64(int(size)) != size {
+		_ = f.Close()
+		return nil, fmt.Errorf("ip2loc: %s is too large to be mapped on this platform (%d bytes)", path, size)
+	}
+	data, unmap, err := mmapFile(f, int(size))
+	if err != nil {
+		_ = f.Close()
+		return nil, fmt.Errorf("ip2loc: mapping %s: %w", path, err)
+	}
+	return &MmapReader{ByteSliceReader: NewByteSliceReader(data), f: f, unmap: unmap}, nil
+}
+
+// OpenMmapDB takes the path to the IP2Location BIN database file and maps it into memory. Lookups
+// are as fast as with OpenInMemoryDB while the memory is shared with the page cache instead of
+// being copied into the heap.
+//
+// A mapped file must be replaced by renaming a new file over it, never by rewriting it in place:
+// reading a part of the mapping beyond the end of a truncated file crashes the process rather than
+// returning ErrTruncated.
+func OpenMmapDB(dbpath string, opts ...Option) (*DB, error) {
+	open := func(d *DB, sum string) (DBReader, error) {
+		f, err := d.openLocked(dbpath, sum)
+		if err != nil {
+			return nil, err
+		}
+		return newMmapReader(f, dbpath)
+	}
+	return openFile(dbpath, open, opts)
+}
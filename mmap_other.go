@@ -0,0 +1,17 @@
+//go:build !unix && !windows && !ip2loc_nofs
+
+package ip2loc
+
+import (
+	"io"
+	"os"
+)
+
+// mmapFile reads the file into memory where mmap is unavailable, e.g. on js/wasm and wasip1.
+func mmapFile(f *os.File, size int) ([]byte, func() error, error) {
+	data := make([]byte, size)
+	if _, err := io.ReadFull(f, data); err != nil {
+		return nil, nil, err
+	}
+	return data, func() error { return nil }, nil
+}
@@ -0,0 +1,115 @@
+//go:build !ip2loc_nofs
+
+package ip2loc
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+var testIPs = []string{"8.8.8.8", "81.2.69.160", "193.0.10.1", "1.1.1.1", "2001:4860::8888", "2a00:1450:4001::1", "2400::1", "::ffff:8.8.4.4"}
+
+func TestMmapDB(t *testing.T) {
+	path := testDB(t)
+	mapped, err := OpenMmapDB(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mapped.Close()
+	file, err := OpenDB(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	for _, ip := range testIPs {
+		want, err := file.GetAll(ip)
+		if err != nil {
+			t.Fatalf("%s: %v", ip, err)
+		}
+		got, err := mapped.GetAll(ip)
+		if err != nil {
+			t.Fatalf("%s: %v", ip, err)
+		}
+		if got != want {
+			t.Errorf("%s: mapped %+v, read %+v", ip, got, want)
+		}
+	}
+
+	x, err := mapped.GetAll("8.8.8.8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x.CountryShort != "US" || x.City != "Mountain View" || x.Latitude != 37.40599 {
+		t.Errorf("8.8.8.8: got %+v", x)
+	}
+}
+
+func TestMmapAfterClose(t *testing.T) {
+	db, err := OpenMmapDB(testDB(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, err := db.GetAll("81.2.69.160")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the strings of a record are copied out of the mapping, which is gone
+	if x.City != "London" || x.CountryLong != "United Kingdom of Great Britain and Northern Ireland" {
+		t.Errorf("record read before Close: got %+v", x)
+	}
+	if _, err := db.GetAll("81.2.69.160"); !errors.Is(err, ErrClosed) {
+		t.Errorf("lookup after Close: got %v, want ErrClosed", err)
+	}
+	if err := db.Reload(); !errors.Is(err, ErrClosed) {
+		t.Errorf("Reload after Close: got %v, want ErrClosed", err)
+	}
+}
+
+func TestMmapReload(t *testing.T) {
+	path := testDB(t)
+	db, err := OpenMmapDB(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	before, err := db.GetAll("193.0.10.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v4 := slices.Clone(testRows.v4)
+	v4[5].city = "Haarlem"
+	tmp := filepath.Join(filepath.Dir(path), "update.BIN")
+	if err := os.WriteFile(tmp, buildDB(v4, testRows.v6), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the old mapping is unmapped by the swap
+	if before.City != "Amsterdam" || before.Region != "Noord-Holland" {
+		t.Errorf("record read before Reload: got %+v", before)
+	}
+	after, err := db.GetAll("193.0.10.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.City != "Haarlem" {
+		t.Errorf("after Reload: got city %q, want Haarlem", after.City)
+	}
+	if x, err := db.GetAll("2a00:1450:4001::1"); err != nil || x.CountryShort != "IE" {
+		t.Errorf("IPv6 after Reload: got %+v, %v", x, err)
+	}
+}
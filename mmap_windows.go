@@ -0,0 +1,28 @@
+//go:build windows && !ip2loc_nofs
+
+package ip2loc
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+func mmapFile(f *os.File, size int) ([]byte, func() error, error) {
+	// the maximum size is passed as two 32-bit halves; uint64 keeps the shift valid where int is 32 bits
+	n := uint64(size)
+	h, err := syscall.CreateFileMapping(syscall.Handle(f.Fd()), nil, syscall.PAGE_READONLY, uint32(n>>32), uint32(n), nil)
+	if err != nil {
+		return nil, nil, os.NewSyscallError("CreateFileMapping", err)
+	}
+	addr, err := syscall.MapViewOfFile(h, syscall.FILE_MAP_READ, 0, 0, uintptr(size))
+	if err != nil {
+		_ = syscall.CloseHandle(h)
+		return nil, nil, os.NewSyscallError("MapViewOfFile", err)
+	}
+	// the view stays mapped after the handles are closed, until it is unmapped
+	_ = syscall.CloseHandle(h)
+
+	data := unsafe.Slice((*byte)(*(*unsafe.Pointer)(unsafe.Pointer(&addr))), size)
+	return data, func() error { return os.NewSyscallError("UnmapViewOfFile", syscall.UnmapViewOfFile(addr)) }, nil
+}
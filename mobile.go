@@ -0,0 +1,220 @@
+package ip2loc
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// mccCountries maps the mobile country codes of ITU-T E.212 to the ISO 3166-1 alpha-2 code of the
+// country using them, the main one where territories share a code.
+var mccCountries = map[string]string{
+	"202": "GR", "204": "NL", "206": "BE", "208": "FR", "212": "MC", "213": "AD", "214": "ES", "216": "HU",
+	"218": "BA", "219": "HR", "220": "RS", "221": "XK", "222": "IT", "225": "VA", "226": "RO", "228": "CH",
+	"230": "CZ", "231": "SK", "232": "AT", "234": "GB", "235": "GB", "238": "DK", "240": "SE", "242": "NO",
+	"244": "FI", "246": "LT", "247": "LV", "248": "EE", "250": "RU", "255": "UA", "257": "BY", "259": "MD",
+	"260": "PL", "262": "DE", "266": "GI", "268": "PT", "270": "LU", "272": "IE", "274": "IS", "276": "AL",
+	"278": "MT", "280": "CY", "282": "GE", "283": "AM", "284": "BG", "286": "TR", "288": "FO", "290": "GL",
+	"292": "SM", "293": "SI", "294": "MK", "295": "LI", "297": "ME",
+	"302": "CA", "308": "PM", "310": "US", "311": "US", "312": "US", "313": "US", "314": "US", "315": "US",
+	"316": "US", "330": "PR", "332": "VI", "334": "MX", "338": "JM", "340": "GP", "342": "BB", "344": "AG",
+	"346": "KY", "348": "VG", "350": "BM", "352": "GD", "354": "MS", "356": "KN", "358": "LC", "360": "VC",
+	"362": "CW", "363": "AW", "364": "BS", "365": "AI", "366": "DM", "368": "CU", "370": "DO", "372": "HT",
+	"374": "TT", "376": "TC",
+	"400": "AZ", "401": "KZ", "402": "BT", "404": "IN", "405": "IN", "406": "IN", "410": "PK", "412": "AF",
+	"413": "LK", "414": "MM", "415": "LB", "416": "JO", "417": "SY", "418": "IQ", "419": "KW", "420": "SA",
+	"421": "YE", "422": "OM", "424": "AE", "425": "IL", "426": "BH", "427": "QA", "428": "MN", "429": "NP",
+	"430": "AE", "431": "AE", "432": "IR", "434": "UZ", "436": "TJ", "437": "KG", "438": "TM", "440": "JP",
+	"441": "JP", "450": "KR", "452": "VN", "454": "HK", "455": "MO", "456": "KH", "457": "LA", "460": "CN",
+	"461": "CN", "466": "TW", "467": "KP", "470": "BD", "472": "MV",
+	"502": "MY", "505": "AU", "510": "ID", "514": "TL", "515": "PH", "520": "TH", "525": "SG", "528": "BN",
+	"530": "NZ", "536": "NR", "537": "PG", "539": "TO", "540": "SB", "541": "VU", "542": "FJ", "543": "WF",
+	"544": "AS", "545": "KI", "546": "NC", "547": "PF", "548": "CK", "549": "WS", "550": "FM", "551": "MH",
+	"552": "PW", "553": "TV", "554": "TK", "555": "NU",
+	"602": "EG", "603": "DZ", "604": "MA", "605": "TN", "606": "LY", "607": "GM", "608": "SN", "609": "MR",
+	"610": "ML", "611": "GN", "612": "CI", "613": "BF", "614": "NE", "615": "TG", "616": "BJ", "617": "MU",
+	"618": "LR", "619": "SL", "620": "GH", "621": "NG", "622": "TD", "623": "CF", "624": "CM", "625": "CV",
+	"626": "ST", "627": "GQ", "628": "GA", "629": "CG", "630": "CD", "631": "AO", "632": "GW", "633": "SC",
+	"634": "SD", "635": "RW", "636": "ET", "637": "SO", "638": "DJ", "639": "KE", "640": "TZ", "641": "UG",
+	"642": "BI", "643": "MZ", "645": "ZM", "646": "MG", "647": "RE", "648": "ZW", "649": "NA", "650": "MW",
+	"651": "LS", "652": "BW", "653": "SZ", "654": "KM", "655": "ZA", "657": "ER", "658": "SH", "659": "SS",
+	"702": "BZ", "704": "GT", "706": "SV", "708": "HN", "710": "NI", "712": "CR", "714": "PA", "716": "PE",
+	"722": "AR", "724": "BR", "730": "CL", "732": "CO", "734": "VE", "736": "BO", "738": "GY", "740": "EC",
+	"742": "GF", "744": "PY", "746": "SR", "748": "UY", "750": "FK",
+}
+
+// MobileCountry returns the ISO 3166-1 alpha-2 code of the country of a mobile country code, from
+// the E.212 table embedded in the package, or "" for an unknown code.
+func MobileCountry(mcc string) string {
+	return mccCountries[strings.TrimSpace(mcc)]
+}
+
+// MobileNetwork is a mobile network identified by its MCC and MNC.
+type MobileNetwork struct {
+	MCC     string
+	MNC     string
+	Brand   string // canonical name of the carrier
+	Country string // ISO 3166-1 alpha-2
+}
+
+// MobileNetworks maps MCC and MNC pairs to the carriers operating them, to resolve the carrier of a
+// record whose MobileBrand is empty.
+type MobileNetworks struct {
+	networks map[string]MobileNetwork
+}
+
+func mobileKey(mcc, mnc string) string {
+	return strings.TrimSpace(mcc) + "-" + strings.TrimSpace(mnc)
+}
+
+// LoadMobileNetworks reads a CSV of mobile networks with a header row naming the columns mcc, mnc,
+// brand (or operator, network) and optionally iso (country_code, country), as in the common MCC-MNC
+// lists. Rows without an ISO 3166-1 alpha-2 country code get the country of their MCC.
+func LoadMobileNetworks(r io.Reader) (*MobileNetworks, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("ip2loc: reading mobile network header: %w", err)
+	}
+	cols := map[string]int{}
+	for i, name := range header {
+		cols[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	find := func(names ...string) int {
+		for _, name := range names {
+			if i, ok := cols[name]; ok {
+				return i
+			}
+		}
+		return -1
+	}
+	mccCol, mncCol := find("mcc"), find("mnc")
+	brandCol := find("brand", "operator", "network")
+	countryCol := find("iso", "country_code", "country")
+	if mccCol < 0 || mncCol < 0 || brandCol < 0 {
+		return nil, fmt.Errorf("ip2loc: mobile networks need mcc, mnc and brand columns")
+	}
+
+	t := &MobileNetworks{networks: map[string]MobileNetwork{}}
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ip2loc: reading mobile networks: %w", err)
+		}
+		get := func(i int) string {
+			if i < 0 || i >= len(row) {
+				return ""
+			}
+			return strings.TrimSpace(row[i])
+		}
+		n := MobileNetwork{MCC: get(mccCol), MNC: get(mncCol), Brand: get(brandCol), Country: strings.ToUpper(get(countryCol))}
+		if n.MCC == "" || n.MNC == "" || n.Brand == "" {
+			continue
+		}
+		if len(n.Country) != 2 {
+			// missing, or a country name
+			n.Country = MobileCountry(n.MCC)
+		}
+		t.networks[mobileKey(n.MCC, n.MNC)] = n
+	}
+	return t, nil
+}
+
+// BuildMobileNetworks reads every row of the database and returns the carriers of the MCC and MNC
+// pairs of the rows with a MobileBrand, the brand most rows give a pair, so the brand of a pair
+// shows for the rows of the same network without one. It returns ErrUnsupportedField if the
+// database has no MCC, MNC and MobileBrand columns.
+func (d *DB) BuildMobileNetworks() (*MobileNetworks, error) {
+	d.mu.RLock()
+	ok := d.dbState != nil && d.mccEnabled && d.mncEnabled && d.mobileBrandEnabled
+	d.mu.RUnlock()
+	if !ok {
+		return nil, wrapError(ErrUnsupportedField)
+	}
+
+	type pairBrand struct{ key, brand string }
+	counts := map[pairBrand]int{}
+	err := d.walkAll(func(r RangeRecord) bool {
+		x := r.Record
+		if !mobileValue(x.MobileBrand) {
+			return true
+		}
+		for _, mcc := range mobileCodes(x.MCC) {
+			for _, mnc := range mobileCodes(x.MNC) {
+				counts[pairBrand{mobileKey(mcc, mnc), x.MobileBrand}]++
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	t := &MobileNetworks{networks: map[string]MobileNetwork{}}
+	best := map[string]int{}
+	for pb, n := range counts {
+		cur, seen := t.networks[pb.key]
+		if seen && (n < best[pb.key] || n == best[pb.key] && pb.brand > cur.Brand) {
+			continue
+		}
+		mcc, mnc, _ := strings.Cut(pb.key, "-")
+		t.networks[pb.key] = MobileNetwork{MCC: mcc, MNC: mnc, Brand: pb.brand, Country: MobileCountry(mcc)}
+		best[pb.key] = n
+	}
+	return t, nil
+}
+
+// Len returns the number of known networks.
+func (t *MobileNetworks) Len() int {
+	return len(t.networks)
+}
+
+// Lookup returns the network of an MCC and MNC pair.
+func (t *MobileNetworks) Lookup(mcc, mnc string) (MobileNetwork, bool) {
+	n, ok := t.networks[mobileKey(mcc, mnc)]
+	return n, ok
+}
+
+// Resolve returns the mobile network of a record from its MCC and MNC, which may list several
+// codes separated by slashes. A pair missing from t is resolved to the MobileBrand of the record,
+// if it has one, and the country of the MCC. It returns false if neither resolves a network, as for a
+// record without MCC and MNC.
+func (t *MobileNetworks) Resolve(x IP2LocationRecord) (MobileNetwork, bool) {
+	mccs, mncs := mobileCodes(x.MCC), mobileCodes(x.MNC)
+	for _, mcc := range mccs {
+		for _, mnc := range mncs {
+			if n, ok := t.Lookup(mcc, mnc); ok {
+				return n, true
+			}
+		}
+	}
+	if len(mccs) == 0 || len(mncs) == 0 || !mobileValue(x.MobileBrand) {
+		return MobileNetwork{}, false
+	}
+	return MobileNetwork{MCC: mccs[0], MNC: mncs[0], Brand: x.MobileBrand, Country: MobileCountry(mccs[0])}, true
+}
+
+// mobileCodes splits an MCC or MNC column, e.g. "01/02/03", into its codes.
+func mobileCodes(s string) []string {
+	if !mobileValue(s) {
+		return nil
+	}
+	var codes []string
+	for _, c := range strings.Split(s, "/") {
+		if c = strings.TrimSpace(c); c != "" {
+			codes = append(codes, c)
+		}
+	}
+	return codes
+}
+
+// mobileValue reports whether s is a value of a mobile column rather than the "-" of non-mobile
+// rows or a placeholder message.
+func mobileValue(s string) bool {
+	return s != "" && s != "-" && !isPlaceholder(s)
+}
@@ -0,0 +1,40 @@
+package ip2loc
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMobileNetworks(t *testing.T) {
+	const networks = `mcc,mnc,iso,country,brand,operator
+310,410,us,United States of America,AT&T,AT&T Mobility
+234,15,,United Kingdom,Vodafone UK,Vodafone Limited
+262,01,Germany,Germany,Telekom,Telekom Deutschland GmbH
+999,99,,,,
+`
+	t1, err := LoadMobileNetworks(strings.NewReader(networks))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if t1.Len() != 3 {
+		t.Fatalf("%d networks, want 3 with a brand", t1.Len())
+	}
+
+	for _, tt := range []struct {
+		x    IP2LocationRecord
+		want MobileNetwork
+		ok   bool
+	}{
+		{IP2LocationRecord{MCC: "310", MNC: "410"}, MobileNetwork{"310", "410", "AT&T", "US"}, true},
+		{IP2LocationRecord{MCC: "234", MNC: "10/15/20"}, MobileNetwork{"234", "15", "Vodafone UK", "GB"}, true},
+		{IP2LocationRecord{MCC: "262", MNC: "01"}, MobileNetwork{"262", "01", "Telekom", "DE"}, true},
+		{IP2LocationRecord{MCC: "208", MNC: "01/02", MobileBrand: "Orange"}, MobileNetwork{"208", "01", "Orange", "FR"}, true},
+		{IP2LocationRecord{MCC: "208", MNC: "01"}, MobileNetwork{}, false},
+		{IP2LocationRecord{MCC: "-", MNC: "-", MobileBrand: "-"}, MobileNetwork{}, false},
+	} {
+		n, ok := t1.Resolve(tt.x)
+		if n != tt.want || ok != tt.ok {
+			t.Errorf("Resolve(%s %s %q) = %+v, %v, want %+v, %v", tt.x.MCC, tt.x.MNC, tt.x.MobileBrand, n, ok, tt.want, tt.ok)
+		}
+	}
+}
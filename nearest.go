@@ -0,0 +1,138 @@
+package ip2loc
+
+import (
+	"math"
+	"sort"
+)
+
+// CityIndex is a spatial index over the distinct cities of a database, answering which city record
+// lies nearest to given coordinates, e.g. to map GPS positions to the same city names IP lookups return.
+type CityIndex struct {
+	// points form a k-d tree over unit vectors: the middle point of every range splits the
+	// points before and after it along the axis of its depth.
+	points []kdPoint[IP2LocationRecord]
+}
+
+// kdPoint is a point of a k-d tree: a unit vector and the value found at it.
+type kdPoint[T any] struct {
+	v [3]float64
+	x T
+}
+
+type cityKey struct {
+	country, region, city string
+	lat, lon              float32
+}
+
+// BuildCityIndex reads every row of the database and indexes each distinct city with its coordinates.
+// The records in the index only hold the country, region, city, latitude and longitude. Building reads
+// the whole database, so the index is meant to be built once and kept.
+func (d *DB) BuildCityIndex() (*CityIndex, error) {
+	d.mu.RLock()
+	ok := d.dbState != nil && d.latitudeEnabled && d.longitudeEnabled
+	d.mu.RUnlock()
+	if !ok {
+		return nil, wrapError(ErrUnsupportedField)
+	}
+
+	seen := map[cityKey]bool{}
+	idx := &CityIndex{}
+	add := func(r RangeRecord) bool {
+		x := r.Record
+		if x.City == "" || x.City == "-" || isPlaceholder(x.City) {
+			return true
+		}
+		k := cityKey{x.CountryShort, x.Region, x.City, x.Latitude, x.Longitude}
+		if !seen[k] {
+			seen[k] = true
+			idx.points = append(idx.points, kdPoint[IP2LocationRecord]{
+				v: unitVector(x.Latitude64(), x.Longitude64()),
+				x: IP2LocationRecord{
+					CountryShort: x.CountryShort,
+					CountryLong:  x.CountryLong,
+					Region:       x.Region,
+					City:         x.City,
+					Latitude:     x.Latitude,
+					Longitude:    x.Longitude,
+				},
+			})
+		}
+		return true
+	}
+
+	if err := d.walkAll(add); err != nil {
+		return nil, err
+	}
+
+	buildKDTree(idx.points, 0)
+	return idx, nil
+}
+
+// Len returns the number of cities in the index.
+func (c *CityIndex) Len() int {
+	return len(c.points)
+}
+
+// Nearest returns the city record nearest to the given coordinates and its distance in kilometers.
+// It returns false if the index is empty.
+func (c *CityIndex) Nearest(lat, lon float64) (IP2LocationRecord, float64, bool) {
+	if len(c.points) == 0 {
+		return IP2LocationRecord{}, 0, false
+	}
+	x := c.points[kdNearest(c.points, unitVector(lat, lon))].x
+	return x, x.DistanceTo(lat, lon), true
+}
+
+// kdNearest returns the index of the point of the k-d tree points nearest to q, which must not be
+// empty.
+func kdNearest[T any](points []kdPoint[T], q [3]float64) int {
+	best, bestDist := -1, math.Inf(1)
+	kdSearch(points, 0, len(points), 0, q, &best, &bestDist)
+	return best
+}
+
+func kdSearch[T any](points []kdPoint[T], lo, hi, depth int, q [3]float64, best *int, bestDist *float64) {
+	if lo >= hi {
+		return
+	}
+	mid := lo + (hi-lo)/2
+	p := points[mid]
+	if d := chordDistance(p.v, q); d < *bestDist {
+		*best, *bestDist = mid, d
+	}
+
+	axis := depth % 3
+	diff := q[axis] - p.v[axis]
+	near, far := [2]int{lo, mid}, [2]int{mid + 1, hi}
+	if diff > 0 {
+		near, far = far, near
+	}
+	kdSearch(points, near[0], near[1], depth+1, q, best, bestDist)
+	if diff*diff < *bestDist {
+		kdSearch(points, far[0], far[1], depth+1, q, best, bestDist)
+	}
+}
+
+func buildKDTree[T any](points []kdPoint[T], depth int) {
+	if len(points) <= 1 {
+		return
+	}
+	axis := depth % 3
+	sort.Slice(points, func(i, j int) bool { return points[i].v[axis] < points[j].v[axis] })
+	mid := len(points) / 2
+	buildKDTree(points[:mid], depth+1)
+	buildKDTree(points[mid+1:], depth+1)
+}
+
+// unitVector returns the point on the unit sphere at the given coordinates. The straight-line
+// distance between such points grows with the great-circle distance, so it can rank neighbours.
+func unitVector(lat, lon float64) [3]float64 {
+	phi, lambda := toRadians(lat), toRadians(lon)
+	return [3]float64{math.Cos(phi) * math.Cos(lambda), math.Cos(phi) * math.Sin(lambda), math.Sin(phi)}
+}
+
+// chordDistance returns the squared straight-line distance between two unit vectors.
+func chordDistance(a, b [3]float64) float64 {
+	dx, dy, dz := a[0]-b[0], a[1]-b[1], a[2]-b[2]
+	return dx*dx + dy*dy + dz*dz
+}
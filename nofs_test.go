@@ -0,0 +1,43 @@
+//go:build !ip2loc_nofs
+
+package ip2loc
+
+import (
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// TestNoFSBuild builds the module with the ip2loc_nofs tag, for WASI among others, and checks that
+// the package then imports none of the file system and HTTP packages.
+func TestNoFSBuild(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs the go command")
+	}
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go command not found")
+	}
+	run := func(env []string, args ...string) string {
+		t.Helper()
+		cmd := exec.Command(gobin, args...)
+		cmd.Env = append(os.Environ(), env...)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("go %s: %v\n%s", strings.Join(args, " "), err, out)
+		}
+		return string(out)
+	}
+
+	run(nil, "vet", "-tags", "ip2loc_nofs", "./...")
+	run([]string{"GOOS=wasip1", "GOARCH=wasm"}, "build", "-tags", "ip2loc_nofs", "./...")
+
+	imports := strings.Fields(run(nil, "list", "-tags", "ip2loc_nofs", "-f", `{{join .Imports " "}}`, "."))
+	for _, pkg := range []string{"os", "path/filepath", "net/http"} {
+		if slices.Contains(imports, pkg) {
+			t.Errorf("the package imports %s with the ip2loc_nofs tag", pkg)
+		}
+	}
+}
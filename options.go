@@ -0,0 +1,13 @@
+package ip2loc
+
+// Option configures a DB when it is opened.
+type Option func(*DB)
+
+// WithEventHandler registers a function which is called whenever the health of the DB changes,
+// e.g. after a truncated read or a reopen attempt. It is called synchronously from the lookup
+// that observed the change, so it should return quickly.
+func WithEventHandler(fn func(Event)) Option {
+	return func(d *DB) {
+		d.onEvent = fn
+	}
+}
@@ -0,0 +1,97 @@
+package ip2loc
+
+import (
+	"cmp"
+	"math/big"
+	"net/netip"
+	"slices"
+	"strings"
+)
+
+// PrefixCountries is the breakdown of a prefix by the countries its addresses are located in.
+type PrefixCountries struct {
+	Prefix    netip.Prefix
+	Countries []PrefixCountry // by decreasing share of the prefix
+}
+
+// PrefixCountry is the part of a prefix located in one country.
+type PrefixCountry struct {
+	CountryShort string
+	CountryLong  string
+	Share        float64        // of the addresses of the prefix, from 0 to 1
+	Prefixes     []netip.Prefix // the parts of the prefix in the country, in address order
+}
+
+// IntersectPrefixes looks up every prefix of a set, such as the networks of an organization, and
+// breaks each down by country, in the order of prefixes. Overlapping prefixes are broken down
+// independently.
+//
+//	sets, err := db.IntersectPrefixes(offices)
+//	for _, s := range sets {
+//		fmt.Println(s.Prefix, s.Outside(euCountries...))
+//	}
+func (d *DB) IntersectPrefixes(prefixes []netip.Prefix) ([]PrefixCountries, error) {
+	out := make([]PrefixCountries, 0, len(prefixes))
+	for _, p := range prefixes {
+		rows, err := d.LookupPrefix(p)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, prefixCountries(p.Masked(), rows))
+	}
+	return out, nil
+}
+
+// prefixCountries groups the rows of p by country.
+func prefixCountries(p netip.Prefix, rows []RangeRecord) PrefixCountries {
+	bits := p.Addr().BitLen() - p.Bits()
+	size := new(big.Float).SetInt(new(big.Int).Lsh(big.NewInt(1), uint(bits)))
+
+	byCountry := map[string]*PrefixCountry{}
+	counts := map[string]*big.Int{}
+	var order []string
+	for i := 0; i < len(rows); {
+		// adjacent rows of the same country make up fewer prefixes together
+		r := rows[i]
+		j := i + 1
+		for ; j < len(rows) && rows[j].Record.CountryShort == r.Record.CountryShort; j++ {
+			r.Last = rows[j].Last
+		}
+		i = j
+
+		c := byCountry[r.Record.CountryShort]
+		if c == nil {
+			c = &PrefixCountry{CountryShort: r.Record.CountryShort, CountryLong: r.Record.CountryLong}
+			byCountry[c.CountryShort] = c
+			counts[c.CountryShort] = new(big.Int)
+			order = append(order, c.CountryShort)
+		}
+		c.Prefixes = append(c.Prefixes, r.Prefixes()...)
+		_, first := addrNumber(r.First)
+		_, last := addrNumber(r.Last)
+		n := counts[c.CountryShort]
+		n.Add(n, last.Sub(last, first).Add(last, big.NewInt(1)))
+	}
+
+	out := PrefixCountries{Prefix: p, Countries: make([]PrefixCountry, 0, len(order))}
+	for _, code := range order {
+		c := byCountry[code]
+		c.Share, _ = new(big.Float).Quo(new(big.Float).SetInt(counts[code]), size).Float64()
+		out.Countries = append(out.Countries, *c)
+	}
+	slices.SortStableFunc(out.Countries, func(a, b PrefixCountry) int { return cmp.Compare(b.Share, a.Share) })
+	return out
+}
+
+// Outside returns the parts of the prefix located in none of the given countries, named by their
+// ISO 3166-1 alpha-2 codes and matched regardless of case, in address order.
+func (p PrefixCountries) Outside(countries ...string) []netip.Prefix {
+	var out []netip.Prefix
+	for _, c := range p.Countries {
+		if !slices.ContainsFunc(countries, func(code string) bool { return strings.EqualFold(strings.TrimSpace(code), c.CountryShort) }) {
+			out = append(out, c.Prefixes...)
+		}
+	}
+	slices.SortFunc(out, func(a, b netip.Prefix) int { return a.Addr().Compare(b.Addr()) })
+	return out
+}
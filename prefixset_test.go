@@ -0,0 +1,42 @@
+package ip2loc
+
+import (
+	"net/netip"
+	"slices"
+	"testing"
+)
+
+func TestIntersectPrefixes(t *testing.T) {
+	db, err := OpenBytes(buildDB(testRows.v4, testRows.v6))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	sets, err := db.IntersectPrefixes([]netip.Prefix{netip.MustParsePrefix("8.8.0.0/16"), netip.MustParsePrefix("81.2.69.0/24")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sets) != 2 {
+		t.Fatalf("%d breakdowns of 2 prefixes", len(sets))
+	}
+
+	s := sets[0]
+	if len(s.Countries) != 2 || s.Countries[0].CountryShort != "-" || s.Countries[1].CountryShort != "US" {
+		t.Fatalf("countries of %s: %+v, want - and US", s.Prefix, s.Countries)
+	}
+	if us := s.Countries[1]; us.Share != 1.0/256 || !slices.Equal(us.Prefixes, []netip.Prefix{netip.MustParsePrefix("8.8.8.0/24")}) {
+		t.Errorf("US part of %s: %+v, want 8.8.8.0/24 and a share of 1/256", s.Prefix, us)
+	}
+	outside := s.Outside("us")
+	if len(outside) == 0 || slices.Contains(outside, netip.MustParsePrefix("8.8.8.0/24")) {
+		t.Errorf("parts of %s outside the US: %v", s.Prefix, outside)
+	}
+	if !slices.IsSortedFunc(outside, func(a, b netip.Prefix) int { return a.Addr().Compare(b.Addr()) }) {
+		t.Errorf("parts of %s outside the US are not in address order: %v", s.Prefix, outside)
+	}
+
+	if s := sets[1]; len(s.Countries) != 1 || s.Countries[0].CountryShort != "GB" || s.Countries[0].Share != 1 || len(s.Outside("GB")) != 0 {
+		t.Errorf("breakdown of %s: %+v, want all of it in GB", s.Prefix, s.Countries)
+	}
+}
@@ -0,0 +1,72 @@
+package ip2loc
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// RecordFormat is the output format of WriteRecord.
+type RecordFormat int
+
+const (
+	// RecordText writes one "name: value" line per field, as PrintRecord does. Placeholder
+	// messages are written as they are.
+	RecordText RecordFormat = iota
+	// RecordJSON writes the populated fields as one JSON object with sorted keys and a newline.
+	RecordJSON
+	// RecordCSV writes a header row with the field names and a row with their values.
+	// Fields holding a placeholder message are left empty.
+	RecordCSV
+)
+
+// PrintRecord is used to output the geolocation data for debugging purposes.
+func PrintRecord(x IP2LocationRecord) {
+	var b strings.Builder
+	WriteRecord(&b, x, RecordText)
+	fmt.Print(b.String())
+}
+
+// WriteRecord writes the fields of x to w in the given format. The output only depends on the
+// record, so it can be compared against golden files.
+func WriteRecord(w io.Writer, x IP2LocationRecord, format RecordFormat) error {
+	switch format {
+	case RecordText:
+		var b strings.Builder
+		for _, f := range recordFields {
+			if f.isFloat {
+				fmt.Fprintf(&b, "%s: %f\n", camelCase(f.name), f.float(&x))
+			} else {
+				fmt.Fprintf(&b, "%s: %s\n", camelCase(f.name), f.str(&x))
+			}
+		}
+		_, err := io.WriteString(w, b.String())
+		return err
+	case RecordJSON:
+		return json.NewEncoder(w).Encode(recordProperties(&x))
+	case RecordCSV:
+		cw := csv.NewWriter(w)
+		header := make([]string, 0, len(recordFields))
+		row := make([]string, 0, len(recordFields))
+		for _, f := range recordFields {
+			header = append(header, f.name)
+			row = append(row, f.value(&x))
+		}
+		cw.Write(header)
+		cw.Write(row)
+		cw.Flush()
+		return cw.Error()
+	}
+	return fmt.Errorf("ip2loc: unknown record format %d", format)
+}
+
+// camelCase turns a snake_case field name into the lowerCamelCase label PrintRecord has always used.
+func camelCase(name string) string {
+	parts := strings.Split(name, "_")
+	for i := 1; i < len(parts); i++ {
+		parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+	}
+	return strings.Join(parts, "")
+}
@@ -0,0 +1,73 @@
+//go:build !ip2loc_nofs
+
+package ip2loc
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func TestWriteRecordGolden(t *testing.T) {
+	db, err := OpenDB(testDB(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	x, err := db.GetAll("81.2.69.160")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		format RecordFormat
+		golden string
+	}{
+		{RecordText, "record.txt"},
+		{RecordJSON, "record.json"},
+		{RecordCSV, "record.csv"},
+	} {
+		t.Run(tt.golden, func(t *testing.T) {
+			var b bytes.Buffer
+			if err := WriteRecord(&b, x, tt.format); err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join("testdata", tt.golden)
+			if *update {
+				if err := os.WriteFile(path, b.Bytes(), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(b.Bytes(), want) {
+				t.Errorf("output differs from %s; rerun with -update if the change is intended:\n%s", path, b.Bytes())
+			}
+		})
+	}
+}
+
+// TestTestdataDB keeps testdata/IP2LOCATION-DB5.BIN, the database of testRows read by the tests of
+// the other packages and modules, in step with buildDB.
+func TestTestdataDB(t *testing.T) {
+	data := buildDB(testRows.v4, testRows.v6)
+	path := filepath.Join("testdata", "IP2LOCATION-DB5.BIN")
+	if *update {
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, want) {
+		t.Errorf("buildDB differs from %s; rerun with -update if the change is intended", path)
+	}
+}
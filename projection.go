@@ -0,0 +1,316 @@
+package ip2loc
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Field selects database columns for WithFields.
+type Field uint32
+
+const (
+	FieldCountry            = Field(countryShort | countryLong)
+	FieldRegion             = Field(region)
+	FieldCity               = Field(city)
+	FieldISP                = Field(isp)
+	FieldLatitude           = Field(latitude)
+	FieldLongitude          = Field(longitude)
+	FieldDomain             = Field(domain)
+	FieldZipCode            = Field(zipCode)
+	FieldTimezone           = Field(timezone)
+	FieldNetSpeed           = Field(netSpeed)
+	FieldIDDCode            = Field(iddCode)
+	FieldAreaCode           = Field(areaCode)
+	FieldWeatherStationCode = Field(weatherStationCode)
+	FieldWeatherStationName = Field(weatherStationName)
+	FieldMCC                = Field(mcc)
+	FieldMNC                = Field(mnc)
+	FieldMobileBrand        = Field(mobileBrand)
+	FieldElevation          = Field(elevation)
+	FieldUsageType          = Field(usageType)
+)
+
+// WithFields restricts the DB to the given fields, e.g. WithFields(FieldCountry|FieldCity). Lookups
+// of a single other field return ErrUnsupportedField and GetAll leaves the other fields at the
+// placeholder message.
+//
+// When the database is held in memory, as by OpenInMemoryDB, OpenMmapDB or OpenBytes, it is rewritten
+// after opening into the database type with the fewest columns holding the fields, keeping only their
+// columns and the strings they point to; a mapped file is then unmapped. Using one or two fields of a
+// DB24 file this way takes a fraction of the memory. The header of the DB, e.g. its database type,
+// then describes the rewritten database. StripDB writes such a database to a file.
+func WithFields(fields Field) Option {
+	return func(d *DB) {
+		d.fields = uint32(fields)
+	}
+}
+
+// ParseField returns the Field of a record field name as returned by FieldNames, e.g. "city" or
+// "country_short", or of a column name such as "country".
+func ParseField(name string) (Field, error) {
+	if f, ok := fieldByName(name); ok {
+		return Field(f.mode), nil
+	}
+	for _, c := range columnPositions {
+		if c.name == name {
+			return Field(c.mode), nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %q", ErrUnsupportedField, name)
+}
+
+// StripDB writes the database read from r to w, rewritten into the database type with the fewest
+// columns holding the given fields and keeping only their columns and the strings they point to, as
+// WithFields does in memory. The result is a valid, smaller BIN database, e.g. to distribute to edge
+// nodes which need one or two fields. Zipped or gzipped input is decompressed. If no database type
+// holds the fields in fewer columns, the database is written unchanged.
+func StripDB(w io.Writer, r io.Reader, fields Field) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if data, err = decompress(data); err != nil {
+		return fmt.Errorf("ip2loc: decompressing database: %w", err)
+	}
+	state, err := (&DB{}).load(NewByteSliceReader(data))
+	if err != nil {
+		return err
+	}
+	if state.unknownType {
+		return fmt.Errorf("%w: DB%d", ErrUnsupportedDBType, state.meta.databaseType)
+	}
+	if stripped, ok, err := project(data, state.meta, uint32(fields)); err != nil {
+		return err
+	} else if ok {
+		data = stripped
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+// restrictFields disables the columns not serving any field of mode; a mode of 0 keeps all.
+func (d *dbState) restrictFields(mode uint32) {
+	if mode == 0 {
+		return
+	}
+	for _, f := range d.fieldFlags() {
+		if f.mode&mode == 0 {
+			*f.enabled = false
+		}
+	}
+}
+
+// projectedType returns the database type with the fewest columns holding every column of mode.
+func projectedType(mode uint32) uint8 {
+	var best uint8
+	for t := uint8(1); knownDBType(t); t++ {
+		ok := true
+		for _, c := range columnPositions {
+			if c.mode&mode != 0 && c.positions[t] == 0 {
+				ok = false
+				break
+			}
+		}
+		if ok && (best == 0 || requiredColumns(t) < requiredColumns(best)) {
+			best = t
+		}
+	}
+	return best
+}
+
+// project rewrites the database in data into the smallest database type holding the columns of the
+// fields of mode that it contains. It returns false if no type has fewer columns.
+func project(data []byte, meta ip2LocationMeta, mode uint32) ([]byte, bool, error) {
+	mode &= columnsOf(meta.databaseType)
+	dst := projectedType(mode)
+	if requiredColumns(dst) >= meta.databaseColumn {
+		return nil, false, nil
+	}
+	b, err := rewrite(data, meta, dst, mode, [2]rowRange{allRows(meta.ipv4DatabaseCount), allRows(meta.ipv6DatabaseCount)})
+	return b, err == nil, err
+}
+
+// columnsOf returns the query bits of the fields stored by a known database type.
+func columnsOf(dbt uint8) uint32 {
+	var mode uint32
+	for _, c := range columnPositions {
+		if c.positions[dbt] != 0 {
+			mode |= c.mode
+		}
+	}
+	return mode
+}
+
+// rowRange selects the rows first to last of a table, inclusive. A range with last below first selects none.
+type rowRange struct {
+	first, last int64
+}
+
+func allRows(count uint32) rowRange {
+	return rowRange{0, int64(count) - 1}
+}
+
+func (r rowRange) len() int64 {
+	return max(r.last-r.first+1, 0)
+}
+
+// rewrite writes a database of type dst holding the rows of the IPv4 and IPv6 tables of data selected by
+// rows, with the columns of the fields of mode and the strings they point to. The other string columns
+// point to "-". A selection without rows is replaced by one row starting at the highest address, so that
+// lookups find nothing, unless the table had none. Indexes are kept with their row numbers rebased to
+// the selected rows.
+func rewrite(data []byte, meta ip2LocationMeta, dst uint8, mode uint32, rows [2]rowRange) ([]byte, error) {
+	src := meta.databaseType
+	cols := requiredColumns(dst)
+
+	// the header is kept up to the first table or index
+	hdrsize := int64(len(data))
+	for _, addr := range []uint32{meta.ipv4IndexBaseAddr, meta.ipv6IndexBaseAddr, meta.ipv4DatabaseAddr, meta.ipv6DatabaseAddr} {
+		if addr > 0 {
+			hdrsize = min(hdrsize, int64(addr)-1)
+		}
+	}
+	if hdrsize < 29 {
+		return nil, fmt.Errorf("%w: tables overlap the header", ErrNotLoaded)
+	}
+	out := append([]byte(nil), data[:hdrsize]...)
+	out[0], out[1] = dst, cols
+
+	section := func(off, size int64) ([]byte, error) {
+		if off < 0 || off+size > int64(len(data)) {
+			return nil, fmt.Errorf("%w: read 0 of %d bytes at offset %d", ErrTruncated, size, off)
+		}
+		return data[off : off+size], nil
+	}
+
+	// the positions are the 0-based offsets of the header fields
+	type table struct {
+		addr, count, index        uint32
+		rows                      rowRange
+		ipsize                    int64
+		addrPos, countPos, idxPos int
+	}
+	tables := []table{
+		{meta.ipv4DatabaseAddr, meta.ipv4DatabaseCount, meta.ipv4IndexBaseAddr, rows[0], 4, 9, 5, 21},
+		{meta.ipv6DatabaseAddr, meta.ipv6DatabaseCount, meta.ipv6IndexBaseAddr, rows[1], 16, 17, 13, 25},
+	}
+
+	for _, t := range tables {
+		binary.LittleEndian.PutUint32(out[t.idxPos:], 0)
+		if t.index == 0 || t.addr == 0 || t.rows.len() == 0 {
+			continue
+		}
+		b, err := section(int64(t.index)-1, indexSize)
+		if err != nil {
+			return nil, err
+		}
+		binary.LittleEndian.PutUint32(out[t.idxPos:], uint32(len(out)+1))
+		for off := 0; off < len(b); off += 4 {
+			row := int64(binary.LittleEndian.Uint32(b[off:])) - t.rows.first
+			out = binary.LittleEndian.AppendUint32(out, uint32(min(max(row, 0), t.rows.len()-1)))
+		}
+	}
+
+	// the strings follow the tables, so their offsets are known before the rows are written
+	pool := int64(len(out))
+	for _, t := range tables {
+		if t.addr > 0 {
+			pool += (max(t.rows.len(), 1) + 1) * (t.ipsize + int64(cols-1)*4)
+		}
+	}
+	// for the country both the code and the name 3 bytes later
+	blank := uint32(pool)
+	strs := []byte{1, '-', 0, 1, '-'}
+	seen := make(map[uint64]uint32)
+	str := func(ptr uint32, country bool) (uint32, error) {
+		key := uint64(ptr) << 1
+		if country {
+			key |= 1
+		}
+		if p, ok := seen[key]; ok {
+			return p, nil
+		}
+		b, err := section(int64(ptr), 1)
+		if err != nil {
+			return 0, err
+		}
+		size := 1 + int64(b[0])
+		if country {
+			if b, err = section(int64(ptr)+3, 1); err != nil {
+				return 0, err
+			}
+			size = 3 + 1 + int64(b[0])
+		}
+		if b, err = section(int64(ptr), size); err != nil {
+			return 0, err
+		}
+		p := uint32(pool + int64(len(strs)))
+		strs = append(strs, b...)
+		seen[key] = p
+		return p, nil
+	}
+
+	for _, t := range tables {
+		if t.addr == 0 {
+			continue
+		}
+		oldsize := t.ipsize + int64(meta.databaseColumn-1)*4
+		newsize := t.ipsize + int64(cols-1)*4
+		binary.LittleEndian.PutUint32(out[t.addrPos:], uint32(len(out)+1))
+
+		if t.rows.len() == 0 {
+			// a table which had no rows keeps none, so that its IP version stays disabled
+			binary.LittleEndian.PutUint32(out[t.countPos:], min(t.count, 1))
+			row := make([]byte, newsize)
+			for i := range t.ipsize {
+				row[i] = 0xff
+			}
+			for _, c := range columnPositions {
+				if c.positions[dst] != 0 && c.mode != latitude && c.mode != longitude {
+					binary.LittleEndian.PutUint32(row[t.ipsize+int64(c.positions[dst]-2)*4:], blank)
+				}
+			}
+			out = append(append(out, row...), row...)
+			continue
+		}
+
+		n := t.rows.len()
+		if t.rows.first < 0 || t.rows.last >= int64(t.count) {
+			return nil, fmt.Errorf("ip2loc: rows %d to %d are outside the table of %d rows", t.rows.first, t.rows.last, t.count)
+		}
+		binary.LittleEndian.PutUint32(out[t.countPos:], uint32(n))
+		b, err := section(int64(t.addr)-1+t.rows.first*oldsize, (n+1)*oldsize)
+		if err != nil {
+			return nil, err
+		}
+
+		for i := int64(0); i <= n; i++ {
+			old := b[i*oldsize : (i+1)*oldsize]
+			row := make([]byte, newsize)
+			copy(row, old[:t.ipsize])
+			// the row after the last one only closes its range
+			for _, c := range columnPositions {
+				if i == n || c.positions[dst] == 0 {
+					continue
+				}
+				isString := c.mode != latitude && c.mode != longitude
+				if c.mode&mode == 0 {
+					if isString {
+						binary.LittleEndian.PutUint32(row[t.ipsize+int64(c.positions[dst]-2)*4:], blank)
+					}
+					continue
+				}
+				v := binary.LittleEndian.Uint32(old[t.ipsize+int64(c.positions[src]-2)*4:])
+				if isString {
+					if v, err = str(v, c.mode == countryShort|countryLong); err != nil {
+						return nil, err
+					}
+				}
+				binary.LittleEndian.PutUint32(row[t.ipsize+int64(c.positions[dst]-2)*4:], v)
+			}
+			out = append(out, row...)
+		}
+	}
+	return append(out, strs...), nil
+}
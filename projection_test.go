@@ -0,0 +1,135 @@
+package ip2loc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestWithFields(t *testing.T) {
+	fixtures := []struct {
+		name   string
+		v4, v6 []testRow
+	}{
+		{"v4", testRows.v4, nil},
+		{"v6", nil, testRows.v6},
+		{"mixed", testRows.v4, testRows.v6},
+	}
+	for _, f := range fixtures {
+		data := buildDB(f.v4, f.v6)
+		full, err := OpenBytes(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer full.Close()
+		// the addresses of a missing IP version are looked up too, for their error
+		ips := slices.Concat(testEdges(), []string{"0.0.0.0", "::"})
+
+		for _, tt := range []struct {
+			fields  Field
+			columns uint8 // of the rewritten database
+		}{
+			{FieldCountry, 2},
+			{FieldCountry | FieldCity, 4},
+			{FieldRegion | FieldLatitude | FieldLongitude, 6},
+		} {
+			t.Run(fmt.Sprintf("%s/%#x", f.name, tt.fields), func(t *testing.T) {
+				projected, err := OpenBytes(data, WithFields(tt.fields))
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer projected.Close()
+				if got := projected.meta.databaseColumn; got != tt.columns {
+					t.Errorf("rewritten into %d columns, want %d", got, tt.columns)
+				}
+
+				placeholder := loadMessage(parameterIsNotSupported)
+				for _, ip := range ips {
+					want, werr := full.GetAll(ip)
+					got, gerr := projected.GetAll(ip)
+					if fmt.Sprint(gerr) != fmt.Sprint(werr) {
+						t.Errorf("%s: got error %v, want %v", ip, gerr, werr)
+						continue
+					}
+					if werr != nil {
+						continue
+					}
+					for _, rf := range recordFields {
+						src := &want
+						if rf.mode&uint32(tt.fields) == 0 {
+							src = &placeholder
+						}
+						if rf.isFloat {
+							if g, w := rf.float(&got), rf.float(src); g != w {
+								t.Errorf("%s: %s is %v, want %v", ip, rf.name, g, w)
+							}
+						} else if g, w := rf.str(&got), rf.str(src); g != w {
+							t.Errorf("%s: %s is %q, want %q", ip, rf.name, g, w)
+						}
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestStripDB(t *testing.T) {
+	rows := benchRows(1 << 10)
+	data := buildDB(rows, testRows.v6)
+	full, err := OpenBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer full.Close()
+
+	const fields = FieldCountry | FieldCity
+	var buf bytes.Buffer
+	if err := StripDB(&buf, bytes.NewReader(data), fields); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() >= len(data) {
+		t.Errorf("stripped database of %d bytes, original of %d", buf.Len(), len(data))
+	}
+	stripped, err := OpenBytes(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stripped.Close()
+	if got := stripped.meta.databaseType; got != 3 {
+		t.Errorf("stripped into DB%d, want DB3", got)
+	}
+
+	columns := columnsOf(stripped.meta.databaseType)
+	for _, ip := range slices.Concat(rangeEdges(rows), rangeEdges(testRows.v6)) {
+		want, err := full.GetAll(ip)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := stripped.GetAll(ip)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the fields of the stripped type are strings
+		for _, rf := range recordFields {
+			switch {
+			case rf.mode&uint32(fields) != 0:
+				if g, w := rf.str(&got), rf.str(&want); g != w {
+					t.Errorf("%s: %s is %q, want %q", ip, rf.name, g, w)
+				}
+			case rf.name == "region_code":
+				// derived from the region, and empty without WithRegionCodes
+			case rf.mode&columns != 0:
+				// a column of the stripped type which was dropped points to the blank string
+				if g := rf.str(&got); g != "-" {
+					t.Errorf("%s: dropped %s is %q, want -", ip, rf.name, g)
+				}
+			default:
+				if _, err := stripped.query(ip, rf.mode); !errors.Is(err, ErrUnsupportedField) {
+					t.Errorf("%s: %s lookup: got %v, want ErrUnsupportedField", ip, rf.name, err)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package ip2loc
+
+import (
+	"context"
+	"encoding/binary"
+	"math/big"
+	"sort"
+)
+
+// rangeIndexChunk is the number of rows read at once while building a range index.
+const rangeIndexChunk = 1 << 16
+
+// uint128 is an IPv6 address split into its high and low halves.
+type uint128 struct {
+	hi, lo uint64
+}
+
+func (a uint128) less(b uint128) bool {
+	return a.hi < b.hi || a.hi == b.hi && a.lo < b.lo
+}
+
+func uint128FromBig(n *big.Int) uint128 {
+	var b [16]byte
+	n.FillBytes(b[:])
+	return uint128{hi: binary.BigEndian.Uint64(b[:8]), lo: binary.BigEndian.Uint64(b[8:])}
+}
+
+func (a uint128) big() *big.Int {
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], a.hi)
+	binary.BigEndian.PutUint64(b[8:], a.lo)
+	return new(big.Int).SetBytes(b[:])
+}
+
+// rangeIndex holds the first address of every row in memory. Row i covers [from[i], from[i+1]),
+// so like the binary search it reads one row past the table count to close the range of the last
+// row. buckets[b] is the row containing the first address whose top 16 bits are b, which narrows
+// every lookup to the few rows between two buckets.
+type rangeIndex struct {
+	v4        []uint32
+	v4buckets []uint32
+	v6        []uint128
+	v6buckets []uint32
+}
+
+// WithRangeIndex loads the first address of every row into memory when the database is opened,
+// together with a table of the rows each /16 (IPv4) or top 16 bits (IPv6) fall into. Finding the
+// row of an address then takes a few comparisons on plain integers instead of a binary search
+// reading the file, at the cost of 4 bytes per IPv4 row and 16 bytes per IPv6 row plus 512KiB.
+// The index is rebuilt whenever the database is reopened.
+func WithRangeIndex() Option {
+	return func(d *DB) {
+		d.rangeIndex = true
+	}
+}
+
+// WithPrefixTable builds a table of the rows each IPv4 /16 falls into when the database is opened,
+// so that a lookup only searches the few rows of its /16 whether or not the database ships with an
+// index. Unlike WithRangeIndex the rows themselves are still read from the database; the table takes
+// 256KiB. It is rebuilt whenever the database is reopened and is not used together with WithRangeIndex.
+func WithPrefixTable() Option {
+	return func(d *DB) {
+		d.prefixTable = true
+	}
+}
+
+// buildPrefixTable reads the first address of every IPv4 row to find the rows of each /16.
+func (d *DB) buildPrefixTable() ([]uint32, error) {
+	count, colsize, base := d.meta.ipv4DatabaseCount, d.meta.ipv4ColumnSize, d.meta.ipv4DatabaseAddr
+	if err := d.checkTable(base, count, colsize); err != nil {
+		return nil, err
+	}
+	keys := make([]uint32, 0, int(count)+1)
+	err := d.readFirstColumns(base, count, colsize, 4, func(b []byte) {
+		keys = append(keys, binary.LittleEndian.Uint32(b))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return buckets(len(keys), func(b uint32, i int) bool {
+		return keys[i] > b<<16
+	}), nil
+}
+
+// buildRangeIndex reads the first address of every row of the table of the given IP version,
+// or of both tables if version is 0.
+func (d *DB) buildRangeIndex(version int) (*rangeIndex, error) {
+	idx := &rangeIndex{}
+
+	if version != 6 {
+		count, colsize, base := d.meta.ipv4DatabaseCount, d.meta.ipv4ColumnSize, d.meta.ipv4DatabaseAddr
+		if err := d.checkTable(base, count, colsize); err != nil {
+			return nil, err
+		}
+		idx.v4 = make([]uint32, 0, int(count)+1)
+		err := d.readFirstColumns(base, count, colsize, 4, func(b []byte) {
+			idx.v4 = append(idx.v4, binary.LittleEndian.Uint32(b))
+		})
+		if err != nil {
+			return nil, err
+		}
+		idx.v4buckets = buckets(len(idx.v4), func(b uint32, i int) bool {
+			return idx.v4[i] > b<<16
+		})
+	}
+	if version == 4 {
+		return idx, nil
+	}
+
+	count, colsize, base := d.meta.ipv6DatabaseCount, d.meta.ipv6ColumnSize, d.meta.ipv6DatabaseAddr
+	if err := d.checkTable(base, count, colsize); err != nil {
+		return nil, err
+	}
+	idx.v6 = make([]uint128, 0, int(count)+1)
+	err := d.readFirstColumns(base, count, colsize, 16, func(b []byte) {
+		idx.v6 = append(idx.v6, uint128{
+			hi: binary.LittleEndian.Uint64(b[8:]),
+			lo: binary.LittleEndian.Uint64(b[:8]),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	idx.v6buckets = buckets(len(idx.v6), func(b uint32, i int) bool {
+		return idx.v6[i].hi > uint64(b)<<48 || idx.v6[i].hi == uint64(b)<<48 && idx.v6[i].lo > 0
+	})
+	return idx, nil
+}
+
+// checkTable makes sure the table of count rows at base and the row following them lie within the
+// database, before memory is allocated for a table whose size is taken from a possibly corrupt header.
+func (d *DB) checkTable(base, count, colsize uint32) error {
+	if count == 0 {
+		return nil
+	}
+	end := int64(base) + (int64(count)+1)*int64(colsize) - 1
+	return d.readAt(context.Background(), make([]byte, 1), end-1)
+}
+
+// readFirstColumns calls fn with the leading size bytes of the count rows starting at base and of
+// the row following them.
+func (d *DB) readFirstColumns(base, count, colsize uint32, size int, fn func([]byte)) error {
+	if count == 0 {
+		return nil
+	}
+	count++
+	buf := make([]byte, int(min(count, rangeIndexChunk))*int(colsize))
+	for row := uint32(0); row < count; row += rangeIndexChunk {
+		n := min(count-row, rangeIndexChunk)
+		chunk := buf[:int(n)*int(colsize)]
+		if err := d.readAt(context.Background(), chunk, int64(base)+int64(row)*int64(colsize)-1); err != nil {
+			return err
+		}
+		for off := 0; off < len(chunk); off += int(colsize) {
+			fn(chunk[off : off+size])
+		}
+	}
+	return nil
+}
+
+// buckets returns for every 16-bit prefix b the last of the n rows whose first address is not
+// above the first address of b; after(b, i) reports whether row i starts after it.
+func buckets(n int, after func(b uint32, i int) bool) []uint32 {
+	if n == 0 {
+		return nil
+	}
+	t := make([]uint32, 1<<16+1)
+	for b := range t[:1<<16] {
+		i := sort.Search(n, func(i int) bool { return after(uint32(b), i) })
+		t[b] = uint32(max(i-1, 0))
+	}
+	t[1<<16] = uint32(n - 1)
+	return t
+}
+
+// find returns the row covering ipno.
+func (idx *rangeIndex) find(iptype uint32, ipno *big.Int) (uint32, bool) {
+	if iptype == 4 {
+		if len(idx.v4) < 2 || !ipno.IsUint64() || ipno.Uint64() > 1<<32-1 {
+			return 0, false
+		}
+		ip := uint32(ipno.Uint64())
+		lo, hi := idx.v4buckets[ip>>16], idx.v4buckets[ip>>16+1]
+		keys := idx.v4[lo : hi+1]
+		i := sort.Search(len(keys), func(i int) bool { return keys[i] > ip }) - 1
+		return lo + uint32(i), i >= 0 && int(lo)+i+1 < len(idx.v4)
+	}
+
+	if len(idx.v6) < 2 || ipno.BitLen() > 128 {
+		return 0, false
+	}
+	ip := uint128FromBig(ipno)
+	lo, hi := idx.v6buckets[ip.hi>>48], idx.v6buckets[ip.hi>>48+1]
+	keys := idx.v6[lo : hi+1]
+	i := sort.Search(len(keys), func(i int) bool { return ip.less(keys[i]) }) - 1
+	return lo + uint32(i), i >= 0 && int(lo)+i+1 < len(idx.v6)
+}
+
+// bounds returns the range covered by a row returned by find.
+func (idx *rangeIndex) bounds(iptype uint32, row uint32) (ipfrom, ipto *big.Int) {
+	if iptype == 4 {
+		return big.NewInt(int64(idx.v4[row])), big.NewInt(int64(idx.v4[row+1]))
+	}
+	return idx.v6[row].big(), idx.v6[row+1].big()
+}
@@ -0,0 +1,64 @@
+package ip2loc
+
+import (
+	"slices"
+	"testing"
+)
+
+// indexFixtures are databases to compare in-memory search structures with the search of the table,
+// with the addresses to compare them at.
+var indexFixtures = []struct {
+	name string
+	v4   []testRow
+	ips  []string
+}{
+	{"small", testRows.v4, rangeEdges(testRows.v4)},
+	{"large", benchRows(1 << 12), append(rangeEdges(benchRows(1<<12)), benchIPs(1000)...)},
+}
+
+func TestRangeIndex(t *testing.T) {
+	for _, f := range indexFixtures {
+		t.Run(f.name, func(t *testing.T) {
+			data := buildDB(f.v4, testRows.v6)
+			plain, err := OpenBytes(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer plain.Close()
+			indexed, err := OpenBytes(data, WithRangeIndex())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer indexed.Close()
+			if indexed.ranges == nil {
+				t.Fatal("no range index was built")
+			}
+
+			sameLookups(t, plain, indexed, slices.Concat(f.ips, rangeEdges(testRows.v6)))
+		})
+	}
+}
+
+func TestPrefixTable(t *testing.T) {
+	for _, f := range indexFixtures {
+		t.Run(f.name, func(t *testing.T) {
+			data := buildDB(f.v4, testRows.v6)
+			plain, err := OpenBytes(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer plain.Close()
+			for _, s := range []SearchStrategy{BinarySearch, InterpolationSearch} {
+				prefixed, err := OpenBytes(data, WithPrefixTable(), WithSearchStrategy(s))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if prefixed.prefixes == nil {
+					t.Fatal("no prefix table was built")
+				}
+				sameLookups(t, plain, prefixed, slices.Concat(f.ips, rangeEdges(testRows.v6)))
+				prefixed.Close()
+			}
+		})
+	}
+}
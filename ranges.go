@@ -0,0 +1,352 @@
+package ip2loc
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"math/big"
+	"math/bits"
+	"net/netip"
+)
+
+// RangeRecord is a database row overlapping a queried address range: the part of the range the row
+// covers, First to Last inclusive, and the record of the row.
+type RangeRecord struct {
+	First  netip.Addr
+	Last   netip.Addr
+	Record IP2LocationRecord
+}
+
+// LookupPrefix returns the records of all rows intersecting the prefix, in address order, each
+// with the sub-range of the prefix it covers. It shows e.g. which countries and ISPs the addresses
+// of an announced prefix map to. An IPv4-mapped IPv6 prefix is looked up in the IPv4 table.
+func (d *DB) LookupPrefix(p netip.Prefix) ([]RangeRecord, error) {
+	if !p.IsValid() {
+		return nil, wrapError(fmt.Errorf("%w: %q", ErrInvalidIP, p))
+	}
+	p = p.Masked()
+
+	var out []RangeRecord
+	err := d.walkRange(p.Addr(), lastAddr(p), nil, func(r RangeRecord) bool {
+		out = append(out, r)
+		return true
+	})
+	return out, err
+}
+
+// RecordsBetween iterates over the rows overlapping the addresses fromIP to toIP inclusive, in
+// address order, e.g. to investigate an attack range or the data around a range boundary:
+//
+//	for r, err := range db.RecordsBetween("8.8.0.0", "8.8.255.255") {
+//		if err != nil {
+//			return err
+//		}
+//		fmt.Println(r.First, r.Last, r.Record.CountryShort)
+//	}
+//
+// Both addresses must be of the same IP version. An error ends the iteration.
+func (d *DB) RecordsBetween(fromIP, toIP string) iter.Seq2[RangeRecord, error] {
+	return func(yield func(RangeRecord, error) bool) {
+		first, err := netip.ParseAddr(fromIP)
+		if err != nil {
+			yield(RangeRecord{}, wrapError(fmt.Errorf("%w: %q", ErrInvalidIP, fromIP)))
+			return
+		}
+		last, err := netip.ParseAddr(toIP)
+		if err != nil {
+			yield(RangeRecord{}, wrapError(fmt.Errorf("%w: %q", ErrInvalidIP, toIP)))
+			return
+		}
+
+		err = d.walkRange(first, last, nil, func(r RangeRecord) bool {
+			return yield(r, nil)
+		})
+		if err != nil {
+			yield(RangeRecord{}, err)
+		}
+	}
+}
+
+// Records iterates over every row of the database, the IPv4 table first, in address order. Tables
+// excluded by WithIPv4Only or WithIPv6Only are skipped. An error ends the iteration.
+func (d *DB) Records() iter.Seq2[RangeRecord, error] {
+	return func(yield func(RangeRecord, error) bool) {
+		err := d.walkAll(func(r RangeRecord) bool {
+			return yield(r, nil)
+		})
+		if err != nil {
+			yield(RangeRecord{}, err)
+		}
+	}
+}
+
+// walkAll calls fn with every row of the enabled tables until fn returns false, reading the tables
+// through a scanBuffer.
+func (d *DB) walkAll(fn func(RangeRecord) bool) error {
+	scan := newScanBuffer()
+	stopped := false
+	for _, iptype := range []uint32{4, 6} {
+		d.mu.RLock()
+		enabled := d.ipVersionEnabled(iptype)
+		d.mu.RUnlock()
+		if !enabled || stopped {
+			continue
+		}
+
+		first, last := netip.IPv4Unspecified(), netip.AddrFrom4([4]byte{255, 255, 255, 255})
+		if iptype == 6 {
+			first, last = netip.IPv6Unspecified(), netip.AddrFrom16([16]byte{
+				255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255})
+		}
+		err := d.walkRange(first, last, scan, func(r RangeRecord) bool {
+			stopped = !fn(r)
+			return !stopped
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Prefixes returns the smallest list of CIDR prefixes covering exactly First to Last, in address order.
+func (r RangeRecord) Prefixes() []netip.Prefix {
+	if !r.First.IsValid() || !r.Last.IsValid() {
+		return nil
+	}
+	iptype, first := addrNumber(r.First)
+	_, last := addrNumber(r.Last)
+	size := 32
+	if iptype == 6 {
+		size = 128
+	}
+	next, end := uint128FromBig(first), uint128FromBig(last)
+
+	var out []netip.Prefix
+	for !end.less(next) {
+		// the largest block aligned at next which does not extend past end
+		host := min(next.trailingZeros(), size, end.sub(next).log2Above())
+		out = append(out, netip.PrefixFrom(numberAddr(iptype, next.big()), size-host))
+		if host == size {
+			break
+		}
+		last := next.add(uint128{}.setBit(host)).sub(uint128{lo: 1})
+		if last == end {
+			break
+		}
+		next = last.next()
+	}
+	return out
+}
+
+// trailingZeros returns the number of trailing zero bits of a, 128 for 0.
+func (a uint128) trailingZeros() int {
+	if a.lo != 0 {
+		return bits.TrailingZeros64(a.lo)
+	}
+	return 64 + bits.TrailingZeros64(a.hi)
+}
+
+// log2Above returns the largest k with 2^k <= a+1, the number of host bits of the largest block
+// holding at most a+1 addresses.
+func (a uint128) log2Above() int {
+	if a.hi == ^uint64(0) && a.lo == ^uint64(0) {
+		return 128
+	}
+	b := a.next()
+	if b.hi != 0 {
+		return 127 - bits.LeadingZeros64(b.hi)
+	}
+	return 63 - bits.LeadingZeros64(b.lo)
+}
+
+func (a uint128) add(b uint128) uint128 {
+	lo, carry := bits.Add64(a.lo, b.lo, 0)
+	hi, _ := bits.Add64(a.hi, b.hi, carry)
+	return uint128{hi: hi, lo: lo}
+}
+
+func (a uint128) sub(b uint128) uint128 {
+	lo, borrow := bits.Sub64(a.lo, b.lo, 0)
+	hi, _ := bits.Sub64(a.hi, b.hi, borrow)
+	return uint128{hi: hi, lo: lo}
+}
+
+// setBit returns a with bit i, counted from the least significant bit, set; i must be below 128.
+func (a uint128) setBit(i int) uint128 {
+	if i < 64 {
+		a.lo |= 1 << i
+	} else {
+		a.hi |= 1 << (i - 64)
+	}
+	return a
+}
+
+// lastAddr returns the last address of p, which must be masked.
+func lastAddr(p netip.Prefix) netip.Addr {
+	b := p.Addr().AsSlice()
+	for i := p.Bits(); i < len(b)*8; i++ {
+		b[i/8] |= 0x80 >> (i % 8)
+	}
+	a, _ := netip.AddrFromSlice(b)
+	return a
+}
+
+// addrNumber returns the IP type and the number of an address as used in the tables.
+func addrNumber(a netip.Addr) (uint32, *big.Int) {
+	a = a.Unmap()
+	if a.Is4() {
+		b := a.As4()
+		return 4, new(big.Int).SetBytes(b[:])
+	}
+	b := a.As16()
+	return 6, new(big.Int).SetBytes(b[:])
+}
+
+// numberAddr is the reverse of addrNumber.
+func numberAddr(iptype uint32, n *big.Int) netip.Addr {
+	if iptype == 4 {
+		var b [4]byte
+		n.FillBytes(b[:])
+		return netip.AddrFrom4(b)
+	}
+	var b [16]byte
+	n.FillBytes(b[:])
+	return netip.AddrFrom16(b)
+}
+
+// walkRange calls fn with every row overlapping first to last, in address order, until fn returns
+// false. The read lock is only held while a row is read, so fn may use the DB. If the database is
+// reopened in between, the walk continues in the new file after the last address reported. The rows
+// are read through scan if it is not nil.
+func (d *DB) walkRange(first, last netip.Addr, scan *scanBuffer, fn func(RangeRecord) bool) error {
+	if !first.IsValid() || !last.IsValid() {
+		return wrapError(fmt.Errorf("%w: %v-%v", ErrInvalidIP, first, last))
+	}
+	iptype, next := addrNumber(first)
+	lasttype, end := addrNumber(last)
+	if iptype != lasttype || next.Cmp(end) > 0 {
+		return wrapError(fmt.Errorf("%w: %v-%v is not a range", ErrInvalidIP, first, last))
+	}
+
+	var state *dbState
+	var rowoffset int64
+	for {
+		var r RangeRecord
+		var ok bool
+		err := d.retrying(context.Background(), func() error {
+			var err error
+			r, ok, err = d.walkStep(context.Background(), iptype, next, end, &state, &rowoffset, scan)
+			return err
+		})
+		if err != nil || !ok {
+			return wrapError(err)
+		}
+		if !fn(r) {
+			return nil
+		}
+		if r.Last == last.Unmap() {
+			return nil
+		}
+		_, next = addrNumber(r.Last)
+		next.Add(next, big.NewInt(1))
+	}
+}
+
+// walkStep reads the row covering next. If state is still the current database, the row is the
+// one following *rowoffset; otherwise it is searched for. state and rowoffset are updated once the
+// row was read, so a failed step can be retried.
+func (d *DB) walkStep(ctx context.Context, iptype uint32, next, end *big.Int, state **dbState, rowoffset *int64, scan *scanBuffer) (RangeRecord, bool, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.closed {
+		return RangeRecord{}, false, ErrClosed
+	}
+	if d.dbState == nil || !d.metaOk {
+		return RangeRecord{}, false, ErrNotLoaded
+	}
+	if !d.ipVersionEnabled(iptype) {
+		return RangeRecord{}, false, fmt.Errorf("%w: IPv%d", ErrIPVersionDisabled, iptype)
+	}
+
+	colsize, baseaddr, count, _ := d.tableLayout(iptype)
+
+	off := *rowoffset
+	if *state == d.dbState {
+		off += int64(colsize)
+	} else {
+		m, err := d.search(ctx, iptype, new(big.Int).Set(next), d.indexOf(iptype, next))
+		if err != nil || m == nil {
+			return RangeRecord{}, false, err
+		}
+		off = m.rowoffset
+		if scan != nil {
+			scan.reset()
+		}
+	}
+	if off >= int64(baseaddr)+int64(count)*int64(colsize) {
+		return RangeRecord{}, false, nil
+	}
+
+	ipfrom, ipto, x, err := d.readRange(ctx, iptype, off, scan)
+	if err != nil || ipfrom.Cmp(end) > 0 {
+		return RangeRecord{}, false, err
+	}
+	*state, *rowoffset = d.dbState, off
+	if ipfrom.Cmp(next) < 0 {
+		ipfrom = next
+	}
+	if ipto.Cmp(end) > 0 {
+		ipto = end
+	}
+	return RangeRecord{First: numberAddr(iptype, ipfrom), Last: numberAddr(iptype, ipto), Record: x}, true, nil
+}
+
+// readRange reads the row at rowoffset, through scan if it is not nil, with the first and last
+// address it covers.
+func (d *DB) readRange(ctx context.Context, iptype uint32, rowoffset int64, scan *scanBuffer) (ipfrom, ipto *big.Int, x IP2LocationRecord, err error) {
+	colsize, _, _, maxip := d.tableLayout(iptype)
+	if scan != nil {
+		var row []byte
+		if ipfrom, ipto, row, err = scan.readRow(ctx, d, iptype, rowoffset, colsize); err == nil {
+			x, err = d.decodeRow(ctx, iptype, rowoffset, row, all, scan)
+		}
+	} else {
+		if ipfrom, ipto, err = d.readBounds(ctx, iptype, rowoffset, colsize); err == nil {
+			x, err = d.readRecord(ctx, iptype, rowoffset, all)
+		}
+	}
+	if err != nil {
+		return nil, nil, IP2LocationRecord{}, err
+	}
+	// the last row ends at the highest address, which lookups treat as part of it
+	if ipto.Cmp(maxip) < 0 && ipto.Cmp(ipfrom) > 0 {
+		ipto.Sub(ipto, big.NewInt(1))
+	}
+	return ipfrom, ipto, x, nil
+}
+
+// readBounds returns the first address of the row at rowoffset and of the row following it.
+func (d *DB) readBounds(ctx context.Context, iptype uint32, rowoffset int64, colsize uint32) (*big.Int, *big.Int, error) {
+	if iptype == 4 {
+		from, err := d.readUint32(ctx, rowoffset)
+		if err != nil {
+			return nil, nil, err
+		}
+		to, err := d.readUint32(ctx, rowoffset+int64(colsize))
+		if err != nil {
+			return nil, nil, err
+		}
+		return big.NewInt(int64(from)), big.NewInt(int64(to)), nil
+	}
+	from, err := d.readUint128(ctx, rowoffset)
+	if err != nil {
+		return nil, nil, err
+	}
+	to, err := d.readUint128(ctx, rowoffset+int64(colsize))
+	if err != nil {
+		return nil, nil, err
+	}
+	return from, to, nil
+}
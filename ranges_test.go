@@ -0,0 +1,122 @@
+package ip2loc
+
+import (
+	"net/netip"
+	"slices"
+	"testing"
+)
+
+// unalignedRows are IPv4 rows whose ranges do not start or end on CIDR boundaries.
+var unalignedRows = []testRow{
+	{"0.0.0.0", "-", "-", "-", "-", 0, 0},
+	{"10.0.0.5", "US", "United States of America", "California", "Mountain View", 37.40599, -122.078514},
+	{"10.0.1.3", "GB", "United Kingdom of Great Britain and Northern Ireland", "England", "London", 51.508529, -0.12574},
+	{"10.0.1.4", "-", "-", "-", "-", 0, 0},
+	{"255.255.255.250", "NL", "Netherlands (Kingdom of the)", "Noord-Holland", "Amsterdam", 52.37403, 4.88969},
+}
+
+// rangeStrings returns the ranges and countries of rs, e.g. "10.0.0.5-10.0.1.2 US".
+func rangeStrings(rs []RangeRecord) []string {
+	var out []string
+	for _, r := range rs {
+		out = append(out, r.First.String()+"-"+r.Last.String()+" "+r.Record.CountryShort)
+	}
+	return out
+}
+
+func TestLookupPrefix(t *testing.T) {
+	db, err := OpenBytes(buildDB(unalignedRows, testRows.v6))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for _, tt := range []struct {
+		prefix string
+		want   []string
+	}{
+		{"10.0.0.0/24", []string{"10.0.0.0-10.0.0.4 -", "10.0.0.5-10.0.0.255 US"}},
+		{"10.0.1.0/30", []string{"10.0.1.0-10.0.1.2 US", "10.0.1.3-10.0.1.3 GB"}},
+		{"10.0.1.3/32", []string{"10.0.1.3-10.0.1.3 GB"}},
+		{"10.0.1.7/29", []string{"10.0.1.0-10.0.1.2 US", "10.0.1.3-10.0.1.3 GB", "10.0.1.4-10.0.1.7 -"}},
+		{"10.0.0.0/23", []string{"10.0.0.0-10.0.0.4 -", "10.0.0.5-10.0.1.2 US", "10.0.1.3-10.0.1.3 GB", "10.0.1.4-10.0.1.255 -"}},
+		{"255.255.255.248/29", []string{"255.255.255.248-255.255.255.249 -", "255.255.255.250-255.255.255.255 NL"}},
+		{"::ffff:10.0.1.0/126", []string{"10.0.1.0-10.0.1.2 US", "10.0.1.3-10.0.1.3 GB"}},
+		{"2001:4860::/31", []string{"2001:4860::-2001:4860:ffff:ffff:ffff:ffff:ffff:ffff US", "2001:4861::-2001:4861:ffff:ffff:ffff:ffff:ffff:ffff -"}},
+	} {
+		rs, err := db.LookupPrefix(netip.MustParsePrefix(tt.prefix))
+		if err != nil {
+			t.Fatalf("%s: %v", tt.prefix, err)
+		}
+		if got := rangeStrings(rs); !slices.Equal(got, tt.want) {
+			t.Errorf("LookupPrefix(%s) = %v, want %v", tt.prefix, got, tt.want)
+		}
+	}
+
+	rs, err := db.LookupPrefix(netip.MustParsePrefix("10.0.0.0/24"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.5/32"), netip.MustParsePrefix("10.0.0.6/31"), netip.MustParsePrefix("10.0.0.8/29"),
+		netip.MustParsePrefix("10.0.0.16/28"), netip.MustParsePrefix("10.0.0.32/27"), netip.MustParsePrefix("10.0.0.64/26"),
+		netip.MustParsePrefix("10.0.0.128/25"),
+	}
+	if got := rs[1].Prefixes(); !slices.Equal(got, want) {
+		t.Errorf("prefixes of %s-%s: %v, want %v", rs[1].First, rs[1].Last, got, want)
+	}
+	if _, err := db.LookupPrefix(netip.Prefix{}); err == nil {
+		t.Error("LookupPrefix of an invalid prefix succeeded")
+	}
+}
+
+func TestRecordsBetween(t *testing.T) {
+	db, err := OpenBytes(buildDB(unalignedRows, testRows.v6))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for _, tt := range []struct {
+		from, to string
+		want     []string
+	}{
+		{"10.0.0.4", "10.0.0.5", []string{"10.0.0.4-10.0.0.4 -", "10.0.0.5-10.0.0.5 US"}},
+		{"10.0.0.5", "10.0.1.3", []string{"10.0.0.5-10.0.1.2 US", "10.0.1.3-10.0.1.3 GB"}},
+		{"10.0.1.2", "10.0.1.4", []string{"10.0.1.2-10.0.1.2 US", "10.0.1.3-10.0.1.3 GB", "10.0.1.4-10.0.1.4 -"}},
+		{"10.0.0.7", "10.0.0.9", []string{"10.0.0.7-10.0.0.9 US"}},
+		{"255.255.255.249", "255.255.255.255", []string{"255.255.255.249-255.255.255.249 -", "255.255.255.250-255.255.255.255 NL"}},
+		{"0.0.0.0", "0.0.0.0", []string{"0.0.0.0-0.0.0.0 -"}},
+		{"2001:4860:ffff:ffff:ffff:ffff:ffff:ffff", "2001:4861::", []string{
+			"2001:4860:ffff:ffff:ffff:ffff:ffff:ffff-2001:4860:ffff:ffff:ffff:ffff:ffff:ffff US", "2001:4861::-2001:4861:: -"}},
+	} {
+		var rs []RangeRecord
+		for r, err := range db.RecordsBetween(tt.from, tt.to) {
+			if err != nil {
+				t.Fatalf("%s-%s: %v", tt.from, tt.to, err)
+			}
+			rs = append(rs, r)
+		}
+		if got := rangeStrings(rs); !slices.Equal(got, tt.want) {
+			t.Errorf("RecordsBetween(%s, %s) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+
+	// the iteration stops when the loop breaks
+	n := 0
+	for range db.RecordsBetween("0.0.0.0", "255.255.255.255") {
+		if n++; n == 2 {
+			break
+		}
+	}
+	var errs int
+	for _, err := range db.RecordsBetween("10.0.0.0", "::1") {
+		if err == nil {
+			t.Fatal("RecordsBetween of addresses of different IP versions returned a row")
+		}
+		errs++
+	}
+	if errs != 1 {
+		t.Errorf("RecordsBetween of addresses of different IP versions returned %d errors, want 1", errs)
+	}
+}
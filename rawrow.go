@@ -0,0 +1,145 @@
+package ip2loc
+
+import (
+	"context"
+	"encoding/binary"
+	"sort"
+)
+
+// RawRow is a copy of the database row matched by a lookup, kept as evidence of exactly what
+// the database said. Requires WithRawRow.
+type RawRow struct {
+	IPVersion int
+	Offset    int64 // file offset of the row
+	Bytes     []byte
+	Columns   []RawColumn
+}
+
+// RawColumn describes where a column is stored within RawRow.Bytes. Columns holding strings
+// contain a little-endian uint32 file offset of the string; latitude and longitude contain a float32.
+type RawColumn struct {
+	Name   string
+	Offset int
+	Size   int
+	// StringOffset is the file offset of the length-prefixed string of a string column, as read by
+	// DB.StringAt, and -1 for the address, latitude and longitude columns. The country column points
+	// to the country code, followed by the country name 3 bytes later.
+	StringOffset int64
+}
+
+var columnPositions = []struct {
+	name      string
+	positions *[25]uint8
+	mode      uint32 // query bits of the fields read from the column
+}{
+	{"country", &countryPosition, countryShort | countryLong},
+	{"region", &regionPosition, region},
+	{"city", &cityPosition, city},
+	{"isp", &ispPosition, isp},
+	{"latitude", &latitudePosition, latitude},
+	{"longitude", &longitudePosition, longitude},
+	{"domain", &domainPosition, domain},
+	{"zip_code", &zipCodePosition, zipCode},
+	{"timezone", &timeZonePosition, timezone},
+	{"net_speed", &netSpeedPosition, netSpeed},
+	{"idd_code", &iddCodePosition, iddCode},
+	{"area_code", &areaCodePosition, areaCode},
+	{"weather_station_code", &weatherStationCodePosition, weatherStationCode},
+	{"weather_station_name", &weatherStationNamePosition, weatherStationName},
+	{"mcc", &mccPosition, mcc},
+	{"mnc", &mncPosition, mnc},
+	{"mobile_brand", &mobileBrandPosition, mobileBrand},
+	{"elevation", &elevationPosition, elevation},
+	{"usage_type", &usageTypePosition, usageType},
+}
+
+// rowLayout returns the columns of a row of the given database type, ordered by offset.
+func rowLayout(dbt uint8, ipSize int) []RawColumn {
+	cols := []RawColumn{{Name: "ip_from", Offset: 0, Size: ipSize}}
+	if !knownDBType(dbt) {
+		return append(cols, RawColumn{Name: "country", Offset: ipSize, Size: 4})
+	}
+	for _, c := range columnPositions {
+		if p := c.positions[dbt]; p != 0 {
+			cols = append(cols, RawColumn{Name: c.name, Offset: ipSize + int(p-2)*4, Size: 4})
+		}
+	}
+	sort.Slice(cols, func(i, j int) bool { return cols[i].Offset < cols[j].Offset })
+	return cols
+}
+
+// readRawRow copies the row at the given 1-based offset.
+func (d *DB) readRawRow(ctx context.Context, iptype uint32, rowoffset int64, colsize uint32) (*RawRow, error) {
+	raw := &RawRow{IPVersion: int(iptype), Offset: rowoffset - 1, Bytes: make([]byte, colsize)}
+	if err := d.readAt(ctx, raw.Bytes, raw.Offset); err != nil {
+		return nil, err
+	}
+	ipSize := 4
+	if iptype == 6 {
+		ipSize = 16
+	}
+	raw.Columns = rowLayout(d.meta.databaseType, ipSize)
+	for i, c := range raw.Columns {
+		raw.Columns[i].StringOffset = -1
+		if c.Name != "ip_from" && c.Name != "latitude" && c.Name != "longitude" {
+			raw.Columns[i].StringOffset = int64(binary.LittleEndian.Uint32(raw.Bytes[c.Offset:]))
+		}
+	}
+	return raw, nil
+}
+
+// RawRow returns a copy of the row matching ip, with the layout of its columns, without decoding
+// any field. Together with StringAt it allows custom decoders on top of the search.
+func (d *DB) RawRow(ip string) (*RawRow, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	iptype, m, _, err := d.find(context.Background(), ip, all)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	colsize := d.meta.ipv4ColumnSize
+	if iptype == 6 {
+		colsize = d.meta.ipv6ColumnSize
+	}
+	raw, err := d.readRawRow(context.Background(), iptype, m.rowoffset, colsize)
+	return raw, wrapError(err)
+}
+
+// StringAt reads the length-prefixed string at the file offset held by a string column of a RawRow.
+func (d *DB) StringAt(offset int64) (string, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.closed {
+		return "", wrapError(ErrClosed)
+	}
+	if d.dbState == nil || !d.metaOk {
+		return "", wrapError(ErrNotLoaded)
+	}
+	s, err := d.readStr(context.Background(), offset)
+	return s, wrapError(err)
+}
+
+// WithRawRow fills the RawRow field of every successful lookup with a copy of the matched row.
+func WithRawRow() Option {
+	return func(d *DB) {
+		d.rawRow = true
+	}
+}
+
+// knownDBType reports whether the position tables describe the database type.
+func knownDBType(dbt uint8) bool {
+	return dbt > 0 && int(dbt) < len(countryPosition)
+}
+
+// requiredColumns returns the number of columns a row of a known database type consists of.
+func requiredColumns(dbt uint8) uint8 {
+	var n uint8 = 1 // ip from
+	for _, c := range columnPositions {
+		if p := c.positions[dbt]; p > n {
+			n = p
+		}
+	}
+	return n
+}
@@ -0,0 +1,85 @@
+package ip2loc
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// readLimiter bounds the number of concurrent ReadAt calls on the database reader.
+type readLimiter struct {
+	slots    chan struct{}
+	reads    atomic.Uint64
+	waits    atomic.Uint64
+	waitTime atomic.Int64 // nanoseconds
+	maxWait  atomic.Int64 // nanoseconds
+}
+
+// WithMaxConcurrentReads allows at most n reads of the database file at the same time; further
+// reads wait until one finishes. A burst of lookups on a network disk then queues in the process
+// instead of saturating the IOPS of the volume. It is of no use with in-memory readers.
+// ReadStats reports how long reads had to wait. n <= 0 disables the limit.
+func WithMaxConcurrentReads(n int) Option {
+	return func(d *DB) {
+		d.reads = nil
+		if n > 0 {
+			d.reads = &readLimiter{slots: make(chan struct{}, n)}
+		}
+	}
+}
+
+// acquire takes a read slot, recording the time spent waiting for it.
+func (l *readLimiter) acquire() {
+	l.reads.Add(1)
+	select {
+	case l.slots <- struct{}{}:
+		return
+	default:
+	}
+
+	start := time.Now()
+	l.slots <- struct{}{}
+	wait := int64(time.Since(start))
+	l.waits.Add(1)
+	l.waitTime.Add(wait)
+	for {
+		cur := l.maxWait.Load()
+		if wait <= cur || l.maxWait.CompareAndSwap(cur, wait) {
+			break
+		}
+	}
+}
+
+func (l *readLimiter) release() {
+	<-l.slots
+}
+
+// ReadStats describes the reads made under WithMaxConcurrentReads and WithReadRetries.
+type ReadStats struct {
+	Reads    uint64        // reads of the database file
+	Waits    uint64        // reads which had to wait for a slot
+	WaitTime time.Duration // total time spent waiting
+	MaxWait  time.Duration // longest single wait
+	InFlight int           // reads running right now
+	Limit    int           // maximum number of concurrent reads
+	Retries  uint64        // lookups and walk steps repeated after a transient read error
+	GaveUp   uint64        // lookups and walk steps which still failed after the last retry
+}
+
+// ReadStats returns the counters of the read limiter and of the retries. The former are zero unless
+// the DB was opened with WithMaxConcurrentReads, the latter unless with WithReadRetries.
+func (d *DB) ReadStats() ReadStats {
+	var s ReadStats
+	if l := d.reads; l != nil {
+		s.Reads = l.reads.Load()
+		s.Waits = l.waits.Load()
+		s.WaitTime = time.Duration(l.waitTime.Load())
+		s.MaxWait = time.Duration(l.maxWait.Load())
+		s.InFlight = len(l.slots)
+		s.Limit = cap(l.slots)
+	}
+	if r := d.retry; r != nil {
+		s.Retries = r.retried.Load()
+		s.GaveUp = r.exhausted.Load()
+	}
+	return s
+}
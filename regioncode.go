@@ -0,0 +1,71 @@
+package ip2loc
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// RegionCodes maps IP2Location region names to ISO 3166-2 subdivision codes.
+// It is loaded from the IP2Location ISO 3166-2 CSV with the columns country_code, subdivision_name and code.
+type RegionCodes struct {
+	codes map[string]string
+}
+
+func regionKey(country, region string) string {
+	return strings.ToUpper(country) + "|" + strings.ToLower(region)
+}
+
+// LoadRegionCodes reads the IP2Location ISO 3166-2 CSV. A header row is detected by its column names
+// and may list the columns in any order; without a header the columns are expected in the documented order.
+func LoadRegionCodes(r io.Reader) (*RegionCodes, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	rows, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("ip2loc: reading region codes: %w", err)
+	}
+
+	countryCol, nameCol, codeCol := 0, 1, 2
+	if len(rows) > 0 {
+		cols := map[string]int{}
+		for i, name := range rows[0] {
+			cols[strings.ToLower(strings.TrimSpace(name))] = i
+		}
+		c, okC := cols["country_code"]
+		n, okN := cols["subdivision_name"]
+		k, okK := cols["code"]
+		if okC && okN && okK {
+			countryCol, nameCol, codeCol = c, n, k
+			rows = rows[1:]
+		}
+	}
+
+	rc := &RegionCodes{codes: make(map[string]string, len(rows))}
+	for i, row := range rows {
+		if len(row) <= countryCol || len(row) <= nameCol || len(row) <= codeCol {
+			return nil, fmt.Errorf("ip2loc: region codes line %d: expected 3 columns, got %d", i+1, len(row))
+		}
+		rc.codes[regionKey(row[countryCol], row[nameCol])] = row[codeCol]
+	}
+	return rc, nil
+}
+
+// Lookup returns the ISO 3166-2 code of a region name within the given ISO-3166 country.
+func (c *RegionCodes) Lookup(countryShort, region string) (string, bool) {
+	code, ok := c.codes[regionKey(countryShort, region)]
+	return code, ok
+}
+
+// Len returns the number of known regions.
+func (c *RegionCodes) Len() int {
+	return len(c.codes)
+}
+
+// WithRegionCodes fills the RegionCode field of every lookup which includes the region.
+func WithRegionCodes(c *RegionCodes) Option {
+	return func(d *DB) {
+		d.regionCodes = c
+	}
+}
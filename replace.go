@@ -0,0 +1,33 @@
+package ip2loc
+
+import "sync"
+
+// replaceWatchers are the DBs opened with WithReloadOnReplace, by the absolute path of their file.
+// They are registered by watchReplace when their file is opened.
+var replaceWatchers struct {
+	sync.Mutex
+	dbs map[string]map[*DB]struct{}
+}
+
+// WithReloadOnReplace reloads the DB whenever ReplaceDatabaseFile replaces its file from within this
+// process, so that a program updating its databases with a script of its own swaps them in like
+// Updater.Update does. It only applies to databases opened from a path.
+func WithReloadOnReplace() Option {
+	return func(d *DB) {
+		d.replaceWatch = true
+	}
+}
+
+// unwatchReplace drops d from the DBs reloaded by ReplaceDatabaseFile once it is closed.
+func (d *DB) unwatchReplace() {
+	key := d.replaceKey
+	if key == "" {
+		return
+	}
+	replaceWatchers.Lock()
+	defer replaceWatchers.Unlock()
+	delete(replaceWatchers.dbs[key], d)
+	if len(replaceWatchers.dbs[key]) == 0 {
+		delete(replaceWatchers.dbs, key)
+	}
+}
@@ -0,0 +1,44 @@
+//go:build !ip2loc_nofs
+
+package ip2loc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReplaceDatabaseFile(t *testing.T) {
+	live := testDB(t)
+	db, err := OpenDB(live, WithReloadOnReplace())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if x, err := db.GetAll("8.8.8.8"); err != nil || x.CountryShort != "US" {
+		t.Fatalf("before the replace: %+v, %v", x, err)
+	}
+
+	tmp := filepath.Join(t.TempDir(), "new.BIN")
+	if err := os.WriteFile(tmp, []byte("not a database"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ReplaceDatabaseFile(tmp, live); err == nil {
+		t.Fatal("replaced the database with an invalid file")
+	}
+
+	rows := append([]testRow(nil), testRows.v4...)
+	rows[1].country = "CA"
+	if err := os.WriteFile(tmp, buildDB(rows, testRows.v6), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ReplaceDatabaseFile(tmp, live); err != nil {
+		t.Fatal(err)
+	}
+	if x, err := db.GetAll("8.8.8.8"); err != nil || x.CountryShort != "CA" {
+		t.Errorf("after the replace: %+v, %v, want the new file", x, err)
+	}
+	if _, err := os.Stat(tmp); !os.IsNotExist(err) {
+		t.Errorf("the replacement is still at its temporary path: %v", err)
+	}
+}
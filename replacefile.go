@@ -0,0 +1,113 @@
+//go:build !ip2loc_nofs
+
+package ip2loc
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"syscall"
+)
+
+// ReplaceDatabaseFile safely swaps a new database file in for the one at livePath, for update scripts
+// which do not use the Updater. The file at tmpPath must be a complete database: it is validated like
+// OpenMetadataOnly, so a truncated download never replaces a working database, flushed to disk,
+// renamed over livePath in one step and the rename made durable. DBs reading livePath keep reading the
+// file they opened until they are reloaded; those opened with WithReloadOnReplace in this process are
+// reloaded before ReplaceDatabaseFile returns. tmpPath must be on the same file system as livePath.
+//
+// An error reloading a DB is returned after the file was replaced; that DB keeps answering from the
+// old file.
+func ReplaceDatabaseFile(tmpPath, livePath string) error {
+	if _, err := OpenMetadataOnly(tmpPath); err != nil {
+		return fmt.Errorf("ip2loc: not replacing %s: %w", livePath, err)
+	}
+	if err := syncFile(tmpPath); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, livePath); err != nil {
+		return err
+	}
+	if err := syncDir(filepath.Dir(livePath)); err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, db := range replaceWatchersOf(livePath) {
+		if err := db.Reload(); err != nil && !errors.Is(err, ErrClosed) {
+			errs = append(errs, fmt.Errorf("ip2loc: reloading %s: %w", livePath, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// syncFile flushes the file at path to disk.
+func syncFile(path string) error {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// syncDir flushes the directory entries of dir to disk, making a rename in it durable. Directories
+// cannot be synced on Windows, nor on file systems returning EINVAL, where it is left to the system.
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil && !errors.Is(err, syscall.EINVAL) {
+		return err
+	}
+	return nil
+}
+
+// watchReplace registers d to be reloaded when its file at path is replaced.
+func (d *DB) watchReplace(path string) {
+	key, err := filepath.Abs(path)
+	if err != nil {
+		return
+	}
+	replaceWatchers.Lock()
+	defer replaceWatchers.Unlock()
+	if replaceWatchers.dbs == nil {
+		replaceWatchers.dbs = make(map[string]map[*DB]struct{})
+	}
+	if replaceWatchers.dbs[key] == nil {
+		replaceWatchers.dbs[key] = make(map[*DB]struct{})
+	}
+	replaceWatchers.dbs[key][d] = struct{}{}
+	d.replaceKey = key
+}
+
+// watchesReplace reports whether d is reloaded when its file at path is replaced.
+func (d *DB) watchesReplace(path string) bool {
+	return slices.Contains(replaceWatchersOf(path), d)
+}
+
+// replaceWatchersOf returns the DBs to reload after their file at path was replaced.
+func replaceWatchersOf(path string) []*DB {
+	key, err := filepath.Abs(path)
+	if err != nil {
+		return nil
+	}
+	replaceWatchers.Lock()
+	defer replaceWatchers.Unlock()
+	var dbs []*DB
+	for d := range replaceWatchers.dbs[key] {
+		dbs = append(dbs, d)
+	}
+	return dbs
+}
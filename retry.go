@@ -0,0 +1,113 @@
+package ip2loc
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"sync/atomic"
+	"time"
+)
+
+// ErrTransient marks a failed read which may succeed when tried again, such as a 5xx response to an
+// HTTP range request. DBReader implementations wrap it into the errors of such reads, e.g. with
+// fmt.Errorf("%w: status %d", ip2loc.ErrTransient, code), for WithReadRetries to retry them.
+var ErrTransient = errors.New("ip2loc: transient read error")
+
+// IsTransient reports whether err is an error of a read worth retrying: one wrapping ErrTransient, or
+// one with a Timeout or Temporary method returning true, as network errors and os.ErrDeadlineExceeded
+// have.
+func IsTransient(err error) bool {
+	if errors.Is(err, ErrTransient) {
+		return true
+	}
+	var timeout interface{ Timeout() bool }
+	if errors.As(err, &timeout) && timeout.Timeout() {
+		return true
+	}
+	var temporary interface{ Temporary() bool }
+	return errors.As(err, &temporary) && temporary.Temporary()
+}
+
+// readRetry retries the reads of the database reader which fail with a transient error.
+type readRetry struct {
+	retries    int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+
+	retried   atomic.Uint64
+	exhausted atomic.Uint64
+}
+
+// WithReadRetries retries a lookup, or a step of a walk over the ranges, whose read of the database
+// fails with an error IsTransient accepts up to retries times, waiting a random time between half
+// and all of a backoff starting at minBackoff and doubling up to maxBackoff, 10ms and 1s if 0. A
+// remote DBReader thereby rides out a momentary network failure instead of failing the lookups
+// running during it. The waits hold no lock, end when the context of the lookup is done and count
+// towards the timeout of WithLookupTimeout; ReadStats reports the retries. retries <= 0 disables
+// retrying.
+func WithReadRetries(retries int, minBackoff, maxBackoff time.Duration) Option {
+	return func(d *DB) {
+		d.retry = nil
+		if retries <= 0 {
+			return
+		}
+		if minBackoff <= 0 {
+			minBackoff = 10 * time.Millisecond
+		}
+		if maxBackoff <= 0 {
+			maxBackoff = time.Second
+		}
+		d.retry = &readRetry{retries: retries, minBackoff: minBackoff, maxBackoff: max(minBackoff, maxBackoff)}
+	}
+}
+
+// readFile reads p at off from the database reader under the read limit. It fails with the error
+// of ctx once ctx is done, so that a lookup past its deadline stops at its next read and releases
+// the read lock.
+func (d *DB) readFile(ctx context.Context, p []byte, off int64) (int, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+	return d.readOnce(p, off)
+}
+
+// retrying calls op again while it fails with an error IsTransient accepts, up to the retries of
+// WithReadRetries, waiting a backoff between the calls. op takes the read lock itself, so that the
+// lock is not held during the waits and Close and reloads need not wait for them. It stops with
+// the error of ctx once ctx is done.
+func (d *DB) retrying(ctx context.Context, op func() error) error {
+	err := op()
+	r := d.retry
+	if r == nil {
+		return err
+	}
+	backoff := r.minBackoff
+	for attempt := 0; err != nil && ctx.Err() == nil && IsTransient(err); attempt++ {
+		if attempt == r.retries {
+			r.exhausted.Add(1)
+			return err
+		}
+		// jitter keeps the lookups which failed together from retrying together
+		t := time.NewTimer(backoff/2 + rand.N(backoff/2+1))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+		backoff = min(2*backoff, r.maxBackoff)
+
+		r.retried.Add(1)
+		err = op()
+	}
+	return err
+}
+
+// readOnce makes a single read of the database reader, holding a slot of WithMaxConcurrentReads.
+func (d *DB) readOnce(p []byte, off int64) (int, error) {
+	if d.reads != nil {
+		d.reads.acquire()
+		defer d.reads.release()
+	}
+	return d.f.ReadAt(p, off)
+}
@@ -0,0 +1,74 @@
+package ip2loc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyReader fails the given number of reads with ErrTransient once armed.
+type flakyReader struct {
+	*ByteSliceReader
+	failures atomic.Int64
+}
+
+func (r *flakyReader) ReadAt(p []byte, off int64) (int, error) {
+	if r.failures.Add(-1) >= 0 {
+		return 0, fmt.Errorf("%w: injected", ErrTransient)
+	}
+	return r.ByteSliceReader.ReadAt(p, off)
+}
+
+func TestReadRetries(t *testing.T) {
+	r := &flakyReader{ByteSliceReader: NewByteSliceReader(buildDB(testRows.v4, testRows.v6))}
+	db, err := OpenDBWithReader(r, WithReadRetries(2, time.Millisecond, time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	r.failures.Store(2)
+	if x, err := db.GetCountryShort("8.8.8.8"); err != nil || x.CountryShort != "US" {
+		t.Errorf("lookup with two failed reads = %q, %v, want US", x.CountryShort, err)
+	}
+	r.failures.Store(3)
+	if _, err := db.GetCountryShort("8.8.8.8"); !errors.Is(err, ErrTransient) {
+		t.Errorf("lookup with three failed reads: %v, want ErrTransient", err)
+	}
+	if s := db.ReadStats(); s.Retries != 4 || s.GaveUp != 1 {
+		t.Errorf("ReadStats = %d retries, %d given up, want 4 and 1", s.Retries, s.GaveUp)
+	}
+}
+
+func TestReadRetriesEndWithContext(t *testing.T) {
+	r := &flakyReader{ByteSliceReader: NewByteSliceReader(buildDB(testRows.v4, testRows.v6))}
+	db, err := OpenDBWithReader(r, WithReadRetries(1, time.Hour, time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.failures.Store(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	looked := make(chan error)
+	go func() {
+		_, err := db.GetAllContext(ctx, "8.8.8.8")
+		looked <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	// the lookup waits to retry without holding the lock
+	start := time.Now()
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d > 250*time.Millisecond {
+		t.Errorf("Close waited %v for the backoff of a retry", d)
+	}
+	if err := <-looked; !errors.Is(err, ErrTimeout) {
+		t.Errorf("lookup waiting to retry past its deadline: %v, want ErrTimeout", err)
+	}
+}
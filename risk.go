@@ -0,0 +1,129 @@
+package ip2loc
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// RiskSignals are the inputs of a RiskScorer for one address.
+type RiskSignals struct {
+	Record IP2LocationRecord
+	Proxy  ProxyType // from an IP2Proxy database, ProxyTypeUnknown if there is none
+	ASN    uint32    // autonomous system from another source, 0 if unknown
+}
+
+// RiskFactor is a signal which contributed to a RiskScore.
+type RiskFactor struct {
+	Signal string // usage_type, proxy_type, country, asn or net_speed
+	Value  string // e.g. "DCH" or "RU"
+	Points float64
+}
+
+func (f RiskFactor) String() string {
+	return fmt.Sprintf("%s %s %+g", f.Signal, f.Value, f.Points)
+}
+
+// RiskScore is the result of a RiskScorer: a score from 0 (no risk signals) to 100 and the factors
+// adding up to it, largest first.
+type RiskScore struct {
+	Score   int
+	Factors []RiskFactor
+}
+
+// Explain returns the factors as a single line, e.g. "proxy_type VPN +60, usage_type DCH +40".
+func (r RiskScore) Explain() string {
+	parts := make([]string, len(r.Factors))
+	for i, f := range r.Factors {
+		parts[i] = f.String()
+	}
+	return strings.Join(parts, ", ")
+}
+
+// RiskScorer combines the signals of an address into a RiskScore by adding the points of their
+// values, starting from Base, and clamping the sum to 0..100. Negative points lower the score, e.g.
+// for the ASN of a trusted partner. Of several usage type codes only the one with the most points
+// counts. Values without points do not contribute.
+type RiskScorer struct {
+	Base       float64
+	UsageTypes map[UsageType]float64
+	ProxyTypes map[ProxyType]float64
+	NetSpeeds  map[NetSpeed]float64
+	Countries  map[string]float64 // keyed by ISO 3166-1 alpha-2 code
+	ASNs       map[uint32]float64
+}
+
+// DefaultRiskScorer returns a RiskScorer weighting anonymizers and hosting providers. It has no
+// country or ASN points, which depend on the business of the caller; set them on the result.
+func DefaultRiskScorer() *RiskScorer {
+	return &RiskScorer{
+		UsageTypes: map[UsageType]float64{
+			UsageTypeDataCenter:   40,
+			UsageTypeCDN:          20,
+			UsageTypeSearchEngine: 10,
+			UsageTypeReserved:     30,
+		},
+		ProxyTypes: map[ProxyType]float64{
+			ProxyTypeTor:         80,
+			ProxyTypePublic:      70,
+			ProxyTypeVPN:         60,
+			ProxyTypeWeb:         60,
+			ProxyTypeResidential: 50,
+			ProxyTypeConsumerVPN: 40,
+			ProxyTypeDataCenter:  40,
+			ProxyTypeSearchBot:   10,
+			ProxyTypeEnterprise:  10,
+		},
+		NetSpeeds: map[NetSpeed]float64{
+			NetSpeedDial: 10,
+		},
+		Countries: map[string]float64{},
+		ASNs:      map[uint32]float64{},
+	}
+}
+
+// Score returns the RiskScore of the signals.
+func (s *RiskScorer) Score(sig RiskSignals) RiskScore {
+	var factors []RiskFactor
+	add := func(signal, value string, points float64, ok bool) {
+		if ok && points != 0 {
+			factors = append(factors, RiskFactor{signal, value, points})
+		}
+	}
+
+	var best *RiskFactor
+	for _, u := range sig.Record.UsageTypes() {
+		if p, ok := s.UsageTypes[u]; ok && p != 0 && (best == nil || p > best.Points) {
+			best = &RiskFactor{"usage_type", string(u), p}
+		}
+	}
+	if best != nil {
+		factors = append(factors, *best)
+	}
+	p, ok := s.ProxyTypes[sig.Proxy]
+	add("proxy_type", string(sig.Proxy), p, ok && sig.Proxy.Known())
+	speed := sig.Record.NetSpeedType()
+	p, ok = s.NetSpeeds[speed]
+	add("net_speed", string(speed), p, ok && speed.Known())
+	country := strings.ToUpper(sig.Record.CountryShort)
+	p, ok = s.Countries[country]
+	add("country", country, p, ok)
+	p, ok = s.ASNs[sig.ASN]
+	add("asn", fmt.Sprintf("AS%d", sig.ASN), p, ok && sig.ASN != 0)
+
+	sort.SliceStable(factors, func(i, j int) bool { return factors[i].Points > factors[j].Points })
+	total := s.Base
+	for _, f := range factors {
+		total += f.Points
+	}
+	return RiskScore{Score: int(min(max(total, 0), 100) + 0.5), Factors: factors}
+}
+
+// ScoreIP looks up ip in d and scores its record without proxy or ASN data.
+func (s *RiskScorer) ScoreIP(d *DB, ip string) (RiskScore, error) {
+	x, err := d.GetAll(ip)
+	if err != nil {
+		return RiskScore{}, err
+	}
+	return s.Score(RiskSignals{Record: x}), nil
+}
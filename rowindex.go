@@ -0,0 +1,73 @@
+package ip2loc
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+)
+
+// LookupRow looks up ip like GetAll and also returns the range of the matched row and its index
+// within the table of its IP version, the IPv4 table for IPv4-mapped, 6to4 and Teredo addresses.
+// Together with RecordAt it allows walking the neighbouring rows around a hit.
+func (d *DB) LookupRow(ip string) (RangeRecord, int, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	iptype, m, _, err := d.find(context.Background(), ip, all)
+	if err != nil {
+		return RangeRecord{}, -1, wrapError(err)
+	}
+	colsize, baseaddr, _, _ := d.tableLayout(iptype)
+	index := int((m.rowoffset - int64(baseaddr)) / int64(colsize))
+	r, err := d.rowRecord(context.Background(), iptype, m.rowoffset)
+	if err != nil {
+		return RangeRecord{}, -1, wrapError(err)
+	}
+	return r, index, nil
+}
+
+// RecordAt returns the row at index in the table of the given IP version, 4 or 6, with the range it
+// covers. An index outside the table returns ErrNotFound.
+func (d *DB) RecordAt(version int, index int) (RangeRecord, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.closed {
+		return RangeRecord{}, wrapError(ErrClosed)
+	}
+	if d.dbState == nil || !d.metaOk {
+		return RangeRecord{}, wrapError(ErrNotLoaded)
+	}
+	if version != 4 && version != 6 {
+		return RangeRecord{}, wrapError(fmt.Errorf("%w: IP version %d", ErrInvalidIP, version))
+	}
+	iptype := uint32(version)
+	if !d.ipVersionEnabled(iptype) {
+		return RangeRecord{}, wrapError(fmt.Errorf("%w: IPv%d", ErrIPVersionDisabled, iptype))
+	}
+
+	colsize, baseaddr, count, _ := d.tableLayout(iptype)
+	if index < 0 || int64(index) >= int64(count) {
+		return RangeRecord{}, wrapError(fmt.Errorf("%w: row %d of %d", ErrNotFound, index, count))
+	}
+	r, err := d.rowRecord(context.Background(), iptype, int64(baseaddr)+int64(index)*int64(colsize))
+	return r, wrapError(err)
+}
+
+// tableLayout returns the row size, 1-based offset, row count and highest address of the table of
+// the given IP type.
+func (d *DB) tableLayout(iptype uint32) (colsize, baseaddr, count uint32, maxip *big.Int) {
+	if iptype == 6 {
+		return d.meta.ipv6ColumnSize, d.meta.ipv6DatabaseAddr, d.meta.ipv6DatabaseCount, maxIpv6Range
+	}
+	return d.meta.ipv4ColumnSize, d.meta.ipv4DatabaseAddr, d.meta.ipv4DatabaseCount, maxIpv4Range
+}
+
+// rowRecord reads the row at rowoffset together with the range it covers.
+func (d *DB) rowRecord(ctx context.Context, iptype uint32, rowoffset int64) (RangeRecord, error) {
+	ipfrom, ipto, x, err := d.readRange(ctx, iptype, rowoffset, nil)
+	if err != nil {
+		return RangeRecord{}, err
+	}
+	return RangeRecord{First: numberAddr(iptype, ipfrom), Last: numberAddr(iptype, ipto), Record: x}, nil
+}
@@ -0,0 +1,132 @@
+package ip2loc
+
+import (
+	"cmp"
+	"context"
+	"io"
+	"slices"
+)
+
+// maxStrSize is the size of the longest string: a length byte and up to 255 bytes.
+const maxStrSize = 256
+
+// strGap is the largest gap between the strings of a row which are still read with a single ReadAt;
+// reading the bytes in between costs less than another read.
+const strGap = 512
+
+// stringColumn is a string column of the loaded database: the field it holds and where its pointer
+// is in a row.
+type stringColumn struct {
+	mode   uint32
+	offset uint32 // of the pointer, in the row without the ip from column
+	skip   int64  // added to the pointer: 3 for the long country name, which follows the short one
+	set    func(x *IP2LocationRecord, s string)
+}
+
+// strRef is a string of a row to read into the record.
+type strRef struct {
+	pos int64
+	col *stringColumn
+}
+
+// stringColumns returns the string columns of the enabled fields, so that lookups do not walk the
+// position tables for every row read.
+func (d *dbState) stringColumns() []stringColumn {
+	columns := []struct {
+		enabled bool
+		stringColumn
+	}{
+		{d.countryEnabled, stringColumn{countryShort, d.countryPositionOffset, 0, func(x *IP2LocationRecord, s string) { x.CountryShort = s }}},
+		{d.countryEnabled, stringColumn{countryLong, d.countryPositionOffset, 3, func(x *IP2LocationRecord, s string) { x.CountryLong = s }}},
+		{d.regionEnabled, stringColumn{region, d.regionPositionOffset, 0, func(x *IP2LocationRecord, s string) { x.Region = s }}},
+		{d.cityEnabled, stringColumn{city, d.cityPositionOffset, 0, func(x *IP2LocationRecord, s string) { x.City = s }}},
+		{d.ispEnabled, stringColumn{isp, d.ispPositionOffset, 0, func(x *IP2LocationRecord, s string) { x.Isp = s }}},
+		{d.domainEnabled, stringColumn{domain, d.domainPositionOffset, 0, func(x *IP2LocationRecord, s string) { x.Domain = s }}},
+		{d.zipcodeEnabled, stringColumn{zipCode, d.zipcodePositionOffset, 0, func(x *IP2LocationRecord, s string) { x.ZipCode = s }}},
+		{d.timeZoneEnabled, stringColumn{timezone, d.timezonePositionOffset, 0, func(x *IP2LocationRecord, s string) { x.Timezone = s }}},
+		{d.netSpeedEnabled, stringColumn{netSpeed, d.netSpeedPositionOffset, 0, func(x *IP2LocationRecord, s string) { x.NetSpeed = s }}},
+		{d.iddCodeEnabled, stringColumn{iddCode, d.iddCodePositionOffset, 0, func(x *IP2LocationRecord, s string) { x.IddCode = s }}},
+		{d.areaCodeEnabled, stringColumn{areaCode, d.areaCodePositionOffset, 0, func(x *IP2LocationRecord, s string) { x.AreaCode = s }}},
+		{d.weatherStationCodeEnabled, stringColumn{weatherStationCode, d.weatherStationCodePositionOffset, 0, func(x *IP2LocationRecord, s string) { x.WeatherStationCode = s }}},
+		{d.weatherStationNameEnabled, stringColumn{weatherStationName, d.weatherStationNamePositionOffset, 0, func(x *IP2LocationRecord, s string) { x.WeatherStationName = s }}},
+		{d.mccEnabled, stringColumn{mcc, d.mccPositionOffset, 0, func(x *IP2LocationRecord, s string) { x.MCC = s }}},
+		{d.mncEnabled, stringColumn{mnc, d.mncPositionOffset, 0, func(x *IP2LocationRecord, s string) { x.MNC = s }}},
+		{d.mobileBrandEnabled, stringColumn{mobileBrand, d.mobileBrandPositionOffset, 0, func(x *IP2LocationRecord, s string) { x.MobileBrand = s }}},
+		{d.elevationEnabled, stringColumn{elevation, d.elevationPositionOffset, 0, setElevation}},
+		{d.usageTypeEnabled, stringColumn{usageType, d.usageTypePositionOffset, 0, func(x *IP2LocationRecord, s string) { x.UsageType = s }}},
+	}
+	var out []stringColumn
+	for _, c := range columns {
+		if c.enabled {
+			out = append(out, c.stringColumn)
+		}
+	}
+	return out
+}
+
+// readStrings reads the strings of refs into x. Strings close to each other, such as the short and
+// long country names, are read together, so a row costs a read per cluster of strings rather than
+// two per string. With WithUnsafeStrings they alias the database in memory instead.
+func (d *DB) readStrings(ctx context.Context, refs []strRef, x *IP2LocationRecord) error {
+	if d.strData != nil {
+		for _, r := range refs {
+			s, ok := d.aliasStr(r.pos)
+			if !ok {
+				var err error
+				if s, err = d.readStr(ctx, r.pos); err != nil {
+					return err
+				}
+			}
+			r.col.set(x, s)
+		}
+		return nil
+	}
+	slices.SortFunc(refs, func(a, b strRef) int { return cmp.Compare(a.pos, b.pos) })
+	var buf []byte
+	for i := 0; i < len(refs); {
+		start, end := refs[i].pos, refs[i].pos+maxStrSize
+		j := i + 1
+		for ; j < len(refs) && refs[j].pos <= end+strGap; j++ {
+			end = refs[j].pos + maxStrSize
+		}
+		buf = slices.Grow(buf[:0], int(end-start))[:end-start]
+		n, err := d.readAtMost(ctx, buf, start)
+		if err != nil {
+			return err
+		}
+		for _, r := range refs[i:j] {
+			s, ok := parseStr(buf[:n], r.pos-start)
+			if !ok {
+				// past the end of the file or a part of it the reader does not hold
+				if s, err = d.readStr(ctx, r.pos); err != nil {
+					return err
+				}
+			}
+			r.col.set(x, s)
+		}
+		i = j
+	}
+	return nil
+}
+
+// parseStr returns the string at off in buf, or false if it does not end within buf.
+func parseStr(buf []byte, off int64) (string, bool) {
+	if off >= int64(len(buf)) {
+		return "", false
+	}
+	end := off + 1 + int64(buf[off])
+	if end > int64(len(buf)) {
+		return "", false
+	}
+	return string(buf[off+1 : end]), true
+}
+
+// readAtMost reads up to len(p) bytes at off, fewer only where the file or the data held by the
+// reader ends.
+func (d *DB) readAtMost(ctx context.Context, p []byte, off int64) (int, error) {
+	n, err := d.readFile(ctx, p, off)
+	if n == len(p) || err == nil || err == io.EOF || err == io.ErrUnexpectedEOF {
+		return n, nil
+	}
+	return n, err
+}
@@ -0,0 +1,60 @@
+package ip2loc
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+)
+
+// Sample returns n rows picked at random from the table of the given IP version, 4 or 6, or from
+// both tables if version is 0, each with the range it covers. Every row is equally likely to be
+// picked, however large its range, and a row may be picked more than once. It serves to generate
+// realistic test traffic or to estimate how rows are distributed without reading the whole table.
+// A negative n is an error.
+func (d *DB) Sample(n int, version int) ([]RangeRecord, error) {
+	if n < 0 {
+		return nil, fmt.Errorf("ip2loc: cannot sample %d rows", n)
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.closed {
+		return nil, wrapError(ErrClosed)
+	}
+	if d.dbState == nil || !d.metaOk {
+		return nil, wrapError(ErrNotLoaded)
+	}
+	if version != 0 && version != 4 && version != 6 {
+		return nil, wrapError(fmt.Errorf("%w: IP version %d", ErrInvalidIP, version))
+	}
+	if version != 0 && !d.ipVersionEnabled(uint32(version)) {
+		return nil, wrapError(fmt.Errorf("%w: IPv%d", ErrIPVersionDisabled, version))
+	}
+
+	var v4, v6 int64
+	if version != 6 && d.ipVersionEnabled(4) {
+		v4 = int64(d.meta.ipv4DatabaseCount)
+	}
+	if version != 4 && d.ipVersionEnabled(6) {
+		v6 = int64(d.meta.ipv6DatabaseCount)
+	}
+	if v4+v6 == 0 {
+		return nil, wrapError(ErrNotFound)
+	}
+
+	// n comes from the caller; the rows picked beyond the size of the tables are repeats
+	out := make([]RangeRecord, 0, min(int64(n), v4+v6))
+	for range n {
+		iptype, index := uint32(4), rand.Int64N(v4+v6)
+		if index >= v4 {
+			iptype, index = 6, index-v4
+		}
+		colsize, baseaddr, _, _ := d.tableLayout(iptype)
+		r, err := d.rowRecord(context.Background(), iptype, int64(baseaddr)+index*int64(colsize))
+		if err != nil {
+			return nil, wrapError(err)
+		}
+		out = append(out, r)
+	}
+	return out, nil
+}
@@ -0,0 +1,138 @@
+package ip2loc
+
+import (
+	"context"
+	"encoding/binary"
+	"math/big"
+)
+
+const (
+	// scanRowWindow is the size of the reads of rows during a scan.
+	scanRowWindow = 1 << 20
+	// scanStrWindow is the size of the reads of strings during a scan. Strings are not stored in row
+	// order, so a larger window mostly reads strings which are evicted before they are used.
+	scanStrWindow = 64 << 10
+	// scanStrCache is the number of strings a scan keeps; country, region and city names repeat
+	// across many rows.
+	scanStrCache = 1 << 16
+)
+
+// scanBuffer serves the reads of a sequential scan of the tables, as done by Records and the exports
+// built on it, from large windows of the file and a cache of the strings read. A scan thereby costs
+// a read per megabyte of rows and per new cluster of strings rather than several per row, which
+// matters most on network storage.
+type scanBuffer struct {
+	rows  window
+	strs  window
+	cache map[int64]string
+}
+
+func newScanBuffer() *scanBuffer {
+	return &scanBuffer{cache: make(map[int64]string)}
+}
+
+// window is a part of the file read in one go.
+type window struct {
+	off  int64
+	data []byte
+}
+
+// get returns the n bytes at off, reading size bytes from off if the window does not hold them. It
+// returns false if they are past the end of the file or of the data held by the reader.
+func (w *window) get(ctx context.Context, d *DB, off int64, n, size int) ([]byte, bool, error) {
+	if off < w.off || off+int64(n) > w.off+int64(len(w.data)) {
+		size = max(size, n)
+		if cap(w.data) < size {
+			w.data = make([]byte, size)
+		}
+		m, err := d.readAtMost(ctx, w.data[:size], off)
+		if err != nil {
+			return nil, false, err
+		}
+		w.off, w.data = off, w.data[:m]
+		if m < n {
+			return nil, false, nil
+		}
+	}
+	return w.data[off-w.off : off-w.off+int64(n)], true, nil
+}
+
+// reset drops the windows and strings read, after the database was reopened.
+func (s *scanBuffer) reset() {
+	s.rows.data = s.rows.data[:0]
+	s.strs.data = s.strs.data[:0]
+	clear(s.cache)
+}
+
+// readRow returns the bounds of the row at rowoffset like readBounds and the row without its ip
+// from column, valid until the next call.
+func (s *scanBuffer) readRow(ctx context.Context, d *DB, iptype uint32, rowoffset int64, colsize uint32) (*big.Int, *big.Int, []byte, error) {
+	ipsize := 4
+	if iptype == 6 {
+		ipsize = 16
+	}
+	b, ok, err := s.rows.get(ctx, d, rowoffset-1, int(colsize)+ipsize, scanRowWindow)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if !ok {
+		// let the plain reads report the truncation
+		from, to, err := d.readBounds(ctx, iptype, rowoffset, colsize)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		row := make([]byte, int(colsize)-ipsize)
+		if err := d.readAt(ctx, row, rowoffset-1+int64(ipsize)); err != nil {
+			return nil, nil, nil, err
+		}
+		return from, to, row, nil
+	}
+	from, to := rowNumber(b[:ipsize]), rowNumber(b[colsize:])
+	return from, to, b[ipsize:colsize], nil
+}
+
+// rowNumber decodes the little endian address number of an ip from column.
+func rowNumber(b []byte) *big.Int {
+	if len(b) == 4 {
+		return big.NewInt(int64(binary.LittleEndian.Uint32(b)))
+	}
+	var be [16]byte
+	for i := range be {
+		be[i] = b[15-i]
+	}
+	return new(big.Int).SetBytes(be[:])
+}
+
+// readStrings reads the strings of refs into x like DB.readStrings, from the cache or the string
+// window.
+func (s *scanBuffer) readStrings(ctx context.Context, d *DB, refs []strRef, x *IP2LocationRecord) error {
+	for _, r := range refs {
+		str, ok := s.cache[r.pos]
+		if !ok {
+			var err error
+			if str, err = s.readStr(ctx, d, r.pos); err != nil {
+				return err
+			}
+			if len(s.cache) >= scanStrCache {
+				clear(s.cache)
+			}
+			s.cache[r.pos] = str
+		}
+		r.col.set(x, str)
+	}
+	return nil
+}
+
+func (s *scanBuffer) readStr(ctx context.Context, d *DB, pos int64) (string, error) {
+	b, ok, err := s.strs.get(ctx, d, pos, 1, scanStrWindow)
+	if err == nil && ok {
+		b, ok, err = s.strs.get(ctx, d, pos, 1+int(b[0]), scanStrWindow)
+	}
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return d.readStr(ctx, pos)
+	}
+	return string(b[1:]), nil
+}
@@ -0,0 +1,214 @@
+package ip2loc
+
+import (
+	"context"
+	"math/big"
+	"sync/atomic"
+)
+
+// match is a row found by search together with the range it covers.
+type match struct {
+	state     *dbState
+	iptype    uint32
+	rowoffset int64
+	ipfrom    *big.Int
+	ipto      *big.Int
+}
+
+func (m *match) contains(state *dbState, iptype uint32, ipno *big.Int) bool {
+	return m != nil && m.state == state && m.iptype == iptype &&
+		ipno.Cmp(m.ipfrom) >= 0 && ipno.Cmp(m.ipto) < 0
+}
+
+// lastMatch remembers the most recently matched range for WithLastMatch.
+type lastMatch struct {
+	enabled bool
+	m       atomic.Pointer[match]
+}
+
+// WithLastMatch makes every lookup first check the range matched by the previous lookup before
+// searching the table. Sorted inputs such as flow logs often hit the same range many times in a
+// row, so they are answered without any binary search probes. The check is a shared atomic load
+// and store, so workloads with random access across many goroutines are better off without it.
+func WithLastMatch() Option {
+	return func(d *DB) {
+		d.last.enabled = true
+	}
+}
+
+// SearchStrategy selects how a lookup finds the row for an address within the table.
+type SearchStrategy int
+
+const (
+	// BinarySearch halves the candidate rows on every probe. It is the default.
+	BinarySearch SearchStrategy = iota
+	// InterpolationSearch estimates the position of the address from the address range the
+	// candidate rows cover, alternating with binary steps to keep the worst case logarithmic.
+	// Tables whose ranges are spread evenly over the address space, such as large IPv4 tables,
+	// need fewer probes, which matters most when the database is read from disk.
+	InterpolationSearch
+)
+
+// WithSearchStrategy sets the strategy used to search the table.
+func WithSearchStrategy(s SearchStrategy) Option {
+	return func(d *DB) {
+		d.strategy = s
+	}
+}
+
+// interpolate estimates the row holding ipno, assuming the ranges of the rows low to high are
+// spread evenly over [lokey, hikey).
+func interpolate(low, high uint32, ipno, lokey, hikey *big.Int) uint32 {
+	span := new(big.Int).Sub(hikey, lokey)
+	pos := new(big.Int).Sub(ipno, lokey)
+	if span.Sign() <= 0 || pos.Sign() < 0 {
+		return (low + high) >> 1
+	}
+	pos.Mul(pos, big.NewInt(int64(high-low)+1))
+	pos.Quo(pos, span)
+	if !pos.IsUint64() || pos.Uint64() > uint64(high-low) {
+		return high
+	}
+	return low + uint32(pos.Uint64())
+}
+
+// search returns the row whose range contains ipno, or nil if there is none.
+// ipindex is the position of the index entry for ipno, or 0 if the database has no index.
+func (d *DB) search(ctx context.Context, iptype uint32, ipno *big.Int, ipindex int64) (*match, error) {
+	var err error
+	var colsize uint32
+	var baseaddr uint32
+	var low uint32
+	var high uint32
+	var mid uint32
+	var rowoffset int64
+	var rowoffset2 int64
+	var shift uint
+	var count uint32 // rows of the table, followed by the row closing the range of the last one
+	ipfrom := big.NewInt(0)
+	ipto := big.NewInt(0)
+	maxip := big.NewInt(0)
+
+	if iptype == 4 {
+		baseaddr = d.meta.ipv4DatabaseAddr
+		count = d.meta.ipv4DatabaseCount
+		maxip = maxIpv4Range
+		colsize = d.meta.ipv4ColumnSize
+		shift = 16
+	} else {
+		baseaddr = d.meta.ipv6DatabaseAddr
+		count = d.meta.ipv6DatabaseCount
+		maxip = maxIpv6Range
+		colsize = d.meta.ipv6ColumnSize
+		shift = 112
+	}
+
+	if ipno.Cmp(maxip) >= 0 {
+		ipno.Sub(ipno, big.NewInt(1))
+	}
+
+	if d.last.enabled {
+		if m := d.last.m.Load(); m.contains(d.dbState, iptype, ipno) {
+			return m, nil
+		}
+	}
+
+	if d.ranges != nil {
+		row, ok := d.ranges.find(iptype, ipno)
+		if !ok {
+			return nil, nil
+		}
+		m := &match{state: d.dbState, iptype: iptype, rowoffset: int64(baseaddr) + int64(row)*int64(colsize)}
+		if d.last.enabled {
+			m.ipfrom, m.ipto = d.ranges.bounds(iptype, row)
+			d.last.m.Store(m)
+		}
+		return m, nil
+	}
+
+	high = count
+
+	// address range covered by the candidate rows, narrowed by every probe for interpolation
+	lokey := big.NewInt(0)
+	hikey := maxip
+
+	prefixes := iptype == 4 && d.prefixes != nil
+	if prefixes || ipindex > 0 {
+		lokey = new(big.Int).Rsh(ipno, shift)
+		lokey.Lsh(lokey, shift)
+		hikey = new(big.Int).Add(lokey, new(big.Int).Lsh(big.NewInt(1), shift))
+	}
+
+	if prefixes {
+		p := ipno.Uint64() >> 16
+		low, high = d.prefixes[p], d.prefixes[p+1]
+	} else if ipindex > 0 {
+		// reading index
+		low, err = d.readUint32(ctx, ipindex)
+		if err != nil {
+			return nil, err
+		}
+		high, err = d.readUint32(ctx, ipindex+4)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for step := 0; low <= high; step++ {
+		mid = (low + high) >> 1
+		if d.strategy == InterpolationSearch && step%2 == 0 {
+			mid = interpolate(low, high, ipno, lokey, hikey)
+			// the row after the last one only closes its range; binary search never probes it either
+			if count > 0 && mid >= count {
+				mid = count - 1
+			}
+		}
+		// offsets are computed in 64 bits so that tables beyond 4GB do not wrap around
+		rowoffset = int64(baseaddr) + int64(mid)*int64(colsize)
+		rowoffset2 = rowoffset + int64(colsize)
+
+		if iptype == 4 {
+			ipfrom32, err := d.readUint32(ctx, rowoffset)
+			if err != nil {
+				return nil, err
+			}
+			ipfrom = big.NewInt(int64(ipfrom32))
+
+			ipto32, err := d.readUint32(ctx, rowoffset2)
+			if err != nil {
+				return nil, err
+			}
+			ipto = big.NewInt(int64(ipto32))
+
+		} else {
+			ipfrom, err = d.readUint128(ctx, rowoffset)
+			if err != nil {
+				return nil, err
+			}
+
+			ipto, err = d.readUint128(ctx, rowoffset2)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if ipno.Cmp(ipfrom) >= 0 && ipno.Cmp(ipto) < 0 {
+			m := &match{state: d.dbState, iptype: iptype, rowoffset: rowoffset, ipfrom: ipfrom, ipto: ipto}
+			if d.last.enabled {
+				d.last.m.Store(m)
+			}
+			return m, nil
+		}
+		if ipno.Cmp(ipfrom) < 0 {
+			if mid == 0 {
+				break
+			}
+			high = mid - 1
+			hikey = ipfrom
+		} else {
+			low = mid + 1
+			lokey = ipto
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,254 @@
+package ip2loc
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/big"
+	"net/netip"
+	"slices"
+	"testing"
+)
+
+// sparseDB is a DBReader of a DB1 database, country only, whose single table ends beyond 4GB
+// without being held in memory: the bytes are computed from their offset. Row r covers the
+// addresses from (r+1)<<shift, so addresses below 1<<shift are not found, and its country is one of
+// sparseCountries.
+type sparseDB struct {
+	iptype uint32
+	base   int64 // 1-based offset of the table, as in the header
+	count  uint32
+	shift  uint
+}
+
+var sparseCountries = []string{"US", "DE", "JP"}
+
+const sparseStrings = 64 // offset of the country strings, right after the header
+
+func (s *sparseDB) colsize() int64 {
+	if s.iptype == 6 {
+		return 20
+	}
+	return 8
+}
+
+func (s *sparseDB) size() int64 {
+	return s.base - 1 + (int64(s.count)+1)*s.colsize()
+}
+
+// strPos returns the offset of the country string of row r.
+func (s *sparseDB) strPos(r uint32) uint32 {
+	return sparseStrings + 6*(r%uint32(len(sparseCountries)))
+}
+
+func (s *sparseDB) header() []byte {
+	b := make([]byte, sparseStrings)
+	b[0], b[1], b[2], b[3], b[4] = 1, 2, 24, 1, 1 // DB1, 2 columns, 2024-01-01
+	if s.iptype == 4 {
+		binary.LittleEndian.PutUint32(b[5:], s.count)
+		binary.LittleEndian.PutUint32(b[9:], uint32(s.base))
+	} else {
+		binary.LittleEndian.PutUint32(b[13:], s.count)
+		binary.LittleEndian.PutUint32(b[17:], uint32(s.base))
+	}
+	for _, c := range sparseCountries {
+		// the short name followed by the long one, here the same
+		b = append(b, 2, c[0], c[1], 2, c[0], c[1])
+	}
+	return b
+}
+
+// row returns the bytes of row r, the row after the last one holding the highest address.
+func (s *sparseDB) row(r uint32) []byte {
+	b := make([]byte, s.colsize())
+	from := new(big.Int).Lsh(big.NewInt(int64(r)+1), s.shift)
+	if r == s.count {
+		from = maxIpv4Range
+		if s.iptype == 6 {
+			from = maxIpv6Range
+		}
+	}
+	ipsize := 4
+	if s.iptype == 6 {
+		ipsize = 16
+	}
+	be := from.FillBytes(make([]byte, ipsize))
+	for i := range be {
+		b[i] = be[ipsize-1-i]
+	}
+	binary.LittleEndian.PutUint32(b[ipsize:], s.strPos(r))
+	return b
+}
+
+func (s *sparseDB) ReadAt(p []byte, off int64) (int, error) {
+	header := s.header()
+	table := s.base - 1
+	for i := range p {
+		pos := off + int64(i)
+		switch {
+		case pos >= s.size():
+			return i, io.EOF
+		case pos < int64(len(header)):
+			p[i] = header[pos]
+		case pos >= table:
+			r := (pos - table) / s.colsize()
+			p[i] = s.row(uint32(r))[(pos-table)%s.colsize()]
+		default:
+			p[i] = 0
+		}
+	}
+	return len(p), nil
+}
+
+func (s *sparseDB) Read([]byte) (int, error) { return 0, io.EOF }
+func (s *sparseDB) Close() error             { return nil }
+
+func TestSearchBeyond4GB(t *testing.T) {
+	tests := []struct {
+		name string
+		db   *sparseDB
+		ips  []string
+	}{
+		{
+			// 2^24 rows of 8 bytes from 4GB-16MB, each covering 256 addresses
+			name: "IPv4",
+			db:   &sparseDB{iptype: 4, base: 1<<32 - 1<<24, count: 1<<24 - 1, shift: 8},
+			ips:  []string{"0.1.0.0", "1.0.255.255", "2.0.0.1", "128.0.0.0", "200.1.2.3", "255.255.254.1"},
+		},
+		{
+			// 2^22 rows of 20 bytes from 4GB-16MB, each covering 2^106 addresses
+			name: "IPv6",
+			db:   &sparseDB{iptype: 6, base: 1<<32 - 1<<24, count: 1<<22 - 1, shift: 106},
+			ips:  []string{"0:400::1", "2400::1", "2a00:1450::", "8000::", "fe80::1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.db.size() <= 1<<32 {
+				t.Fatalf("the table ends at %d, below 4GB", tt.db.size())
+			}
+			db, err := OpenDBWithReader(tt.db)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+
+			for _, ip := range tt.ips {
+				_, n := addrNumber(netip.MustParseAddr(ip))
+				r := uint32(new(big.Int).Rsh(n, tt.db.shift).Uint64()) - 1
+				want := sparseCountries[r%uint32(len(sparseCountries))]
+
+				x, err := db.GetCountryShort(ip)
+				if err != nil {
+					t.Fatalf("%s: %v", ip, err)
+				}
+				if x.CountryShort != want {
+					t.Errorf("%s: row %d at offset %d: got %q, want %q", ip, r, tt.db.base+int64(r)*tt.db.colsize(), x.CountryShort, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSearchBeforeFirstRow(t *testing.T) {
+	for _, tt := range []struct {
+		db *sparseDB
+		ip string
+	}{
+		{&sparseDB{iptype: 4, base: 1<<32 - 1<<24, count: 1<<24 - 1, shift: 8}, "0.0.0.1"},
+		{&sparseDB{iptype: 6, base: 1<<32 - 1<<24, count: 1<<22 - 1, shift: 106}, "::1:0:0:1"},
+	} {
+		db, err := OpenDBWithReader(tt.db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the search ends at row 0 instead of wrapping around to the last row
+		if _, err := db.GetCountryShort(tt.ip); !errors.Is(err, ErrNotFound) {
+			t.Errorf("%s: got %v, want ErrNotFound", tt.ip, err)
+		}
+		db.Close()
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	// the table starts at 10.0.0.5, so nothing holds the addresses below it
+	data := buildDB(unalignedRows[1:], testRows.v6)
+	for _, opts := range [][]Option{
+		nil,
+		{WithSearchStrategy(InterpolationSearch)},
+		{WithRangeIndex()},
+		{WithPrefixTable()},
+	} {
+		db, err := OpenBytes(data, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, ip := range []string{"0.0.0.0", "10.0.0.4"} {
+			_, err := db.GetAll(ip)
+			if !errors.Is(err, ErrNotFound) {
+				t.Errorf("%s: got %v, want ErrNotFound", ip, err)
+			}
+			var e *Error
+			if !errors.As(err, &e) || e.Code != CodeNotFound {
+				t.Errorf("%s: got %#v, want an *Error with code %s", ip, err, CodeNotFound)
+			}
+		}
+		if rec, err := db.GetAll("10.0.0.5"); err != nil || rec.CountryShort != "US" {
+			t.Errorf("10.0.0.5: got %q, %v, want US", rec.CountryShort, err)
+		}
+		db.Close()
+	}
+}
+
+func TestLastMatch(t *testing.T) {
+	data := buildDB(testRows.v4, testRows.v6)
+	plain, err := OpenBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plain.Close()
+	r := NewInstrumentedReader(NewByteSliceReader(data))
+	db, err := OpenDBWithReader(r, WithLastMatch())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	// a lookup in the range of the previous one skips the search
+	reads := func(ip string) uint64 {
+		before := r.Stats().Reads
+		if _, err := db.GetCountryShort(ip); err != nil {
+			t.Fatalf("%s: %v", ip, err)
+		}
+		return r.Stats().Reads - before
+	}
+	searched := reads("8.8.8.8")
+	if repeated := reads("8.8.8.200"); repeated >= searched {
+		t.Errorf("lookup in the matched range made %d reads, the search before it %d", repeated, searched)
+	}
+
+	// lookups in other ranges, each after one in a neighbouring range, must not take its match
+	edges := testEdges()
+	sameLookups(t, plain, db, edges)
+	slices.Reverse(edges)
+	sameLookups(t, plain, db, edges)
+}
+
+func TestInterpolationSearch(t *testing.T) {
+	data := buildDB(testRows.v4, testRows.v6)
+	binary, err := OpenBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer binary.Close()
+	interpolation, err := OpenBytes(data, WithSearchStrategy(InterpolationSearch))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer interpolation.Close()
+
+	sameLookups(t, binary, interpolation, testEdges())
+	if interpolation.health.unhealthy.Load() {
+		t.Error("lookups at the table ends marked the database unhealthy")
+	}
+}
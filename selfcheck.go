@@ -0,0 +1,213 @@
+package ip2loc
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"math/rand/v2"
+	"net/netip"
+	"sort"
+)
+
+// SelfCheckMismatch is an address for which the search and a linear scan of the table found
+// different rows. Rows are numbered from 0 within the table of the IP version; -1 means no row.
+type SelfCheckMismatch struct {
+	IP         netip.Addr
+	SearchRow  int64
+	ScannedRow int64
+}
+
+// SelfCheckError is returned by SelfCheck when the search disagrees with the linear scan.
+type SelfCheckError struct {
+	Checked    int // addresses looked up
+	Mismatches []SelfCheckMismatch
+}
+
+func (e *SelfCheckError) Error() string {
+	m := e.Mismatches[0]
+	return fmt.Sprintf("ip2loc: search disagrees with a linear scan for %d of %d addresses, e.g. %v: found row %d instead of %d",
+		len(e.Mismatches), e.Checked, m.IP, m.SearchRow, m.ScannedRow)
+}
+
+// errReloaded aborts a self check whose database was reopened while the table was scanned.
+var errReloaded = errors.New("ip2loc: database was reloaded")
+
+// selfCheckSample is an address looked up by SelfCheck and the row the search found for it.
+type selfCheckSample struct {
+	ip  uint128
+	row int64
+}
+
+// SelfCheck looks up sampleSize random addresses in every enabled table and compares the rows found
+// by the search, with all the options the DB was opened with, to those found by a linear scan of the
+// table. Half of the addresses are the first or last address of a random row, where off-by-one
+// errors show. It catches regressions of the index, the range index and the search strategies as
+// well as tables which are not sorted, which are reported with ErrNotLoaded.
+//
+// The scan reads the whole table, about as much as reading the database file once. Lookups continue
+// meanwhile; if the database is reopened, the check starts over. A disagreement is returned as a
+// *SelfCheckError.
+func (d *DB) SelfCheck(sampleSize int) error {
+	for {
+		err := d.selfCheck(sampleSize)
+		if err != errReloaded {
+			return err
+		}
+	}
+}
+
+func (d *DB) selfCheck(sampleSize int) error {
+	d.mu.RLock()
+	state, closed := d.dbState, d.closed
+	d.mu.RUnlock()
+	if closed {
+		return wrapError(ErrClosed)
+	}
+	if state == nil || !state.metaOk {
+		return wrapError(ErrNotLoaded)
+	}
+
+	failed := &SelfCheckError{}
+	for _, iptype := range []uint32{4, 6} {
+		samples, err := d.searchSamples(state, iptype, sampleSize)
+		if err != nil {
+			return err
+		}
+		failed.Checked += len(samples)
+		if err := d.scanSamples(state, iptype, samples, failed); err != nil {
+			return err
+		}
+	}
+	if len(failed.Mismatches) > 0 {
+		return failed
+	}
+	return nil
+}
+
+// lockState takes the read lock if the database is still state.
+func (d *DB) lockState(state *dbState) error {
+	d.mu.RLock()
+	if d.closed {
+		d.mu.RUnlock()
+		return wrapError(ErrClosed)
+	}
+	if d.dbState != state {
+		d.mu.RUnlock()
+		return errReloaded
+	}
+	return nil
+}
+
+// searchSamples searches n addresses of the table of the given IP version, none if it is disabled.
+func (d *DB) searchSamples(state *dbState, iptype uint32, n int) ([]selfCheckSample, error) {
+	if err := d.lockState(state); err != nil {
+		return nil, err
+	}
+	defer d.mu.RUnlock()
+
+	colsize, baseaddr, count, maxip := d.tableLayout(iptype)
+	if !d.ipVersionEnabled(iptype) || count == 0 {
+		return nil, nil
+	}
+
+	samples := make([]selfCheckSample, 0, n)
+	for i := range n {
+		var ipno *big.Int
+		if i%2 == 0 {
+			ipno = randomAddress(iptype)
+		} else {
+			from, to, err := d.readBounds(context.Background(), iptype, int64(baseaddr)+int64(rand.Uint32N(count))*int64(colsize), colsize)
+			if err != nil {
+				return nil, wrapError(err)
+			}
+			ipno = from
+			if i%4 == 3 && to.Cmp(from) > 0 {
+				ipno = to.Sub(to, big.NewInt(1))
+			}
+		}
+		// lookups of the highest address search for the one below it
+		if ipno.Cmp(maxip) >= 0 {
+			ipno.Sub(ipno, big.NewInt(1))
+		}
+
+		m, err := d.search(context.Background(), iptype, new(big.Int).Set(ipno), d.indexOf(iptype, ipno))
+		if err != nil {
+			return nil, wrapError(err)
+		}
+		s := selfCheckSample{ip: uint128FromBig(ipno), row: -1}
+		if m != nil {
+			s.row = (m.rowoffset - int64(baseaddr)) / int64(colsize)
+		}
+		samples = append(samples, s)
+	}
+	return samples, nil
+}
+
+func randomAddress(iptype uint32) *big.Int {
+	if iptype == 4 {
+		return big.NewInt(int64(rand.Uint32()))
+	}
+	return uint128{hi: rand.Uint64(), lo: rand.Uint64()}.big()
+}
+
+// scanSamples reads the first address of every row of the table and of the row following them, and
+// adds the samples whose row differs from the one the search found to failed.
+func (d *DB) scanSamples(state *dbState, iptype uint32, samples []selfCheckSample, failed *SelfCheckError) error {
+	if len(samples) == 0 {
+		return nil
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i].ip.less(samples[j].ip) })
+
+	colsize, baseaddr, count := state.meta.ipv4ColumnSize, state.meta.ipv4DatabaseAddr, state.meta.ipv4DatabaseCount
+	if iptype == 6 {
+		colsize, baseaddr, count = state.meta.ipv6ColumnSize, state.meta.ipv6DatabaseAddr, state.meta.ipv6DatabaseCount
+	}
+
+	next := 0
+	// check the samples below key, which are in row want; all checks the remaining samples
+	check := func(key uint128, want int64, all bool) {
+		for ; next < len(samples) && (all || samples[next].ip.less(key)); next++ {
+			if s := samples[next]; s.row != want {
+				failed.Mismatches = append(failed.Mismatches, SelfCheckMismatch{
+					IP:         numberAddr(iptype, s.ip.big()),
+					SearchRow:  s.row,
+					ScannedRow: want,
+				})
+			}
+		}
+	}
+
+	rows := int64(count) + 1
+	buf := make([]byte, min(rows, rangeIndexChunk)*int64(colsize))
+	var prev uint128
+	for row := int64(0); row < rows; row += rangeIndexChunk {
+		chunk := buf[:min(rows-row, rangeIndexChunk)*int64(colsize)]
+		if err := d.lockState(state); err != nil {
+			return err
+		}
+		err := d.readAt(context.Background(), chunk, int64(baseaddr)+row*int64(colsize)-1)
+		d.mu.RUnlock()
+		if err != nil {
+			return wrapError(err)
+		}
+
+		for off, i := 0, row; off < len(chunk); off, i = off+int(colsize), i+1 {
+			var key uint128
+			if iptype == 4 {
+				key.lo = uint64(binary.LittleEndian.Uint32(chunk[off:]))
+			} else {
+				key.hi, key.lo = binary.LittleEndian.Uint64(chunk[off+8:]), binary.LittleEndian.Uint64(chunk[off:])
+			}
+			if i > 0 && key.less(prev) {
+				return wrapError(fmt.Errorf("%w: row %d of the IPv%d table starts below the row before it", ErrNotLoaded, i, iptype))
+			}
+			prev = key
+			check(key, i-1, false)
+		}
+	}
+	// the samples above the last key are in no row
+	check(uint128{}, -1, true)
+	return nil
+}
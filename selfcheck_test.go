@@ -0,0 +1,86 @@
+//go:build !ip2loc_nofs
+
+package ip2loc
+
+import (
+	"encoding/binary"
+	"errors"
+	"net/netip"
+	"testing"
+)
+
+// searchOptions are the options of the DB which change how a lookup finds its row.
+var searchOptions = []struct {
+	name string
+	opts []Option
+}{
+	{"binary", nil},
+	{"interpolation", []Option{WithSearchStrategy(InterpolationSearch)}},
+	{"range index", []Option{WithRangeIndex()}},
+	{"prefix table", []Option{WithPrefixTable()}},
+	{"last match", []Option{WithLastMatch()}},
+}
+
+func TestSelfCheck(t *testing.T) {
+	for _, f := range indexFixtures {
+		path := writeDB(t, buildDB(f.v4, testRows.v6))
+		for _, m := range benchModes {
+			for _, s := range searchOptions {
+				t.Run(f.name+"/"+m.name+"/"+s.name, func(t *testing.T) {
+					db, err := m.open(path, s.opts...)
+					if err != nil {
+						t.Fatal(err)
+					}
+					defer db.Close()
+					if err := db.SelfCheck(1000); err != nil {
+						t.Error(err)
+					}
+				})
+			}
+		}
+	}
+}
+
+// setRowStart overwrites the first address of row i of the IPv4 table of data, a database of buildDB.
+func setRowStart(data []byte, i int, ip string) {
+	base := int(binary.LittleEndian.Uint32(data[9:])) - 1
+	colsize := 4 * int(data[1])
+	binary.LittleEndian.PutUint32(data[base+i*colsize:], binary.BigEndian.Uint32(netip.MustParseAddr(ip).AsSlice()))
+}
+
+func TestSelfCheckCorrupt(t *testing.T) {
+	t.Run("unsorted", func(t *testing.T) {
+		data := buildDB(unalignedRows, testRows.v6)
+		setRowStart(data, 2, "10.0.2.0") // above the start of row 3
+		db, err := OpenBytes(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+		if err := db.SelfCheck(1000); !errors.Is(err, ErrNotLoaded) {
+			t.Errorf("got %v, want ErrNotLoaded", err)
+		}
+	})
+
+	t.Run("stale range index", func(t *testing.T) {
+		data := buildDB(unalignedRows, testRows.v6)
+		db, err := OpenBytes(data, WithRangeIndex())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+		// the index still starts row 2 at 10.0.1.3, the table now at 10.0.1.1
+		setRowStart(data, 2, "10.0.1.1")
+		err = db.SelfCheck(1000)
+		var failed *SelfCheckError
+		if !errors.As(err, &failed) {
+			t.Fatalf("got %v, want a *SelfCheckError", err)
+		}
+		for _, m := range failed.Mismatches {
+			if m.IP.Less(netip.MustParseAddr("10.0.1.1")) || !m.IP.Less(netip.MustParseAddr("10.0.1.3")) ||
+				m.SearchRow != 1 || m.ScannedRow != 2 {
+				t.Errorf("unexpected mismatch %+v", m)
+			}
+		}
+	})
+}
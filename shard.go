@@ -0,0 +1,293 @@
+//go:build !ip2loc_nofs
+
+package ip2loc
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// ShardManifest is the name of the file listing the shards written by SplitDB.
+const ShardManifest = "shards.json"
+
+// ShardOptions configures SplitDB.
+type ShardOptions struct {
+	IPv4Bits int // prefix length of the IPv4 shards, 4 if 0
+	IPv6Bits int // prefix length of the IPv6 shards, 16 if 0
+}
+
+type shardManifest struct {
+	Shards []shardEntry `json:"shards"`
+}
+
+// shardEntry is a shard file holding the rows of the addresses First to Last.
+type shardEntry struct {
+	First netip.Addr `json:"first"`
+	Last  netip.Addr `json:"last"`
+	File  string     `json:"file"`
+}
+
+// SplitDB splits the database read from r into shards written to dir, one per IPv4 and IPv6 prefix of
+// the lengths given by opts, together with a ShardManifest listing them for OpenShardedDB. Each shard
+// is a valid BIN database holding the rows of its prefix and the strings they point to. Consecutive
+// prefixes covered by a single row, common in the sparse parts of the IPv6 table, share one shard.
+// Zipped or gzipped input is decompressed.
+func SplitDB(r io.Reader, dir string, opts ShardOptions) error {
+	bits := [2]int{opts.IPv4Bits, opts.IPv6Bits}
+	if bits[0] == 0 {
+		bits[0] = 4
+	}
+	if bits[1] == 0 {
+		bits[1] = 16
+	}
+	if bits[0] < 0 || bits[0] > 16 || bits[1] < 0 || bits[1] > 16 {
+		return fmt.Errorf("ip2loc: shard prefix lengths must be between 1 and 16, not /%d and /%d", bits[0], bits[1])
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if data, err = decompress(data); err != nil {
+		return fmt.Errorf("ip2loc: decompressing database: %w", err)
+	}
+	state, err := (&DB{}).load(NewByteSliceReader(data))
+	if err != nil {
+		return err
+	}
+	if state.unknownType {
+		return fmt.Errorf("%w: DB%d", ErrUnsupportedDBType, state.meta.databaseType)
+	}
+	db := &DB{dbState: state}
+
+	var manifest shardManifest
+	for v, iptype := range []uint32{4, 6} {
+		colsize, baseaddr, count, _ := db.tableLayout(iptype)
+		if baseaddr == 0 || count == 0 {
+			continue
+		}
+		keys := make([]uint128, 0, int(count)+1)
+		size := 4
+		if iptype == 6 {
+			size = 16
+		}
+		err := db.readFirstColumns(baseaddr, count, colsize, size, func(b []byte) {
+			keys = append(keys, uint128FromLE(b))
+		})
+		if err != nil {
+			return err
+		}
+		// row returns the row holding a, or -1
+		row := func(a uint128) int64 {
+			return int64(sort.Search(int(count), func(i int) bool { return a.less(keys[i]) })) - 1
+		}
+
+		// shards as row ranges, merged while consecutive prefixes fall into the same single row
+		type shard struct {
+			first, last uint128
+			rows        rowRange
+		}
+		var shards []shard
+		for p := range uint64(1) << bits[v] {
+			first, last := prefixBounds(iptype, p, bits[v])
+			rows := rowRange{max(row(first), 0), row(last)}
+			if rows.len() == 0 {
+				continue
+			}
+			if n := len(shards); n > 0 && rows.len() == 1 && shards[n-1].rows == rows {
+				shards[n-1].last = last
+				continue
+			}
+			shards = append(shards, shard{first, last, rows})
+		}
+
+		for i, s := range shards {
+			var ranges [2]rowRange
+			ranges[1-v] = rowRange{0, -1}
+			ranges[v] = s.rows
+			b, err := rewrite(data, state.meta, state.meta.databaseType, columnsOf(state.meta.databaseType), ranges)
+			if err != nil {
+				return err
+			}
+			name := fmt.Sprintf("ipv%d-%05d.bin", iptype, i)
+			if err := os.WriteFile(filepath.Join(dir, name), b, 0o644); err != nil {
+				return err
+			}
+			manifest.Shards = append(manifest.Shards, shardEntry{
+				First: numberAddr(iptype, s.first.big()),
+				Last:  numberAddr(iptype, s.last.big()),
+				File:  name,
+			})
+		}
+	}
+
+	b, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, ShardManifest), append(b, '\n'), 0o644)
+}
+
+func uint128FromLE(b []byte) uint128 {
+	if len(b) == 4 {
+		return uint128{lo: uint64(binary.LittleEndian.Uint32(b))}
+	}
+	return uint128{hi: binary.LittleEndian.Uint64(b[8:]), lo: binary.LittleEndian.Uint64(b[:8])}
+}
+
+// prefixBounds returns the first and last address of the p-th prefix of the given length.
+func prefixBounds(iptype uint32, p uint64, bits int) (first, last uint128) {
+	if iptype == 4 {
+		first.lo = p << (32 - bits)
+		last.lo = first.lo | (1<<(32-bits) - 1)
+		return first, last
+	}
+	first.hi = p << (64 - bits)
+	last.hi = first.hi | (1<<(64-bits) - 1)
+	last.lo = math.MaxUint64
+	return first, last
+}
+
+// ShardedDB looks up addresses in the shards written by SplitDB. A shard is only opened when an
+// address in it is first looked up, so a process whose traffic stays within a few prefixes only
+// holds the shards of those.
+type ShardedDB struct {
+	dir    string
+	open   func(path string) (*DB, error)
+	shards [2][]shardEntry // IPv4 and IPv6 shards in address order
+
+	mu     sync.Mutex
+	dbs    map[string]*DB
+	closed bool
+}
+
+// OpenShardedDB reads the ShardManifest in dir. The shards are opened with open, e.g. OpenInMemoryDB
+// or a function adding options; OpenDB if it is nil.
+func OpenShardedDB(dir string, open func(path string) (*DB, error)) (*ShardedDB, error) {
+	b, err := os.ReadFile(filepath.Join(dir, ShardManifest))
+	if err != nil {
+		return nil, err
+	}
+	var manifest shardManifest
+	if err := json.Unmarshal(b, &manifest); err != nil {
+		return nil, fmt.Errorf("ip2loc: reading %s: %w", ShardManifest, err)
+	}
+	if open == nil {
+		open = func(path string) (*DB, error) { return OpenDB(path) }
+	}
+
+	s := &ShardedDB{dir: dir, open: open, dbs: make(map[string]*DB)}
+	for _, e := range manifest.Shards {
+		if !e.First.IsValid() || e.First.Is4() != e.Last.Is4() || e.Last.Less(e.First) || filepath.Base(e.File) != e.File {
+			return nil, fmt.Errorf("ip2loc: invalid shard %v-%v %q in %s", e.First, e.Last, e.File, ShardManifest)
+		}
+		v := 0
+		if !e.First.Is4() {
+			v = 1
+		}
+		s.shards[v] = append(s.shards[v], e)
+	}
+	for _, shards := range s.shards {
+		sort.Slice(shards, func(i, j int) bool { return shards[i].First.Less(shards[j].First) })
+	}
+	return s, nil
+}
+
+// OpenCompositeDB opens an IPv4 and an IPv6 database, as shipped in separate files by some
+// distributions, as one ShardedDB which looks up every address in the file of its IP version.
+// IPv4-mapped, 6to4 and Teredo addresses are looked up in the IPv4 file. Either path may be empty,
+// leaving addresses of that version not found, but not both. Both files are opened right away
+// with open, or OpenDB if it is nil. The ShardedDB has the lookup methods of DB.
+func OpenCompositeDB(ipv4Path, ipv6Path string, open func(path string) (*DB, error)) (*ShardedDB, error) {
+	if ipv4Path == "" && ipv6Path == "" {
+		return nil, errors.New("ip2loc: OpenCompositeDB needs an IPv4 or an IPv6 database")
+	}
+	if open == nil {
+		open = func(path string) (*DB, error) { return OpenDB(path) }
+	}
+	s := &ShardedDB{open: open, dbs: make(map[string]*DB)}
+	if ipv4Path != "" {
+		s.shards[0] = []shardEntry{{netip.IPv4Unspecified(), netip.AddrFrom4([4]byte{255, 255, 255, 255}), ipv4Path}}
+	}
+	if ipv6Path != "" {
+		s.shards[1] = []shardEntry{{netip.IPv6Unspecified(), netip.AddrFrom16([16]byte{
+			255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255}), ipv6Path}}
+	}
+	for _, ip := range []string{"0.0.0.0", "::"} {
+		if _, err := s.Shard(ip); err != nil && !errors.Is(err, ErrNotFound) {
+			s.Close()
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Shard returns the DB of the shard holding ip, opening it if needed. IPv4-mapped, 6to4 and Teredo
+// addresses are in the IPv4 shards. It returns ErrNotFound if no shard holds ip.
+func (s *ShardedDB) Shard(ip string) (*DB, error) {
+	iptype, ipno := classifyIP(ip)
+	if iptype == 0 {
+		return nil, wrapError(fmt.Errorf("%w: %q", ErrInvalidIP, ip))
+	}
+	addr := numberAddr(iptype, ipno)
+	shards := s.shards[0]
+	if iptype == 6 {
+		shards = s.shards[1]
+	}
+	i := sort.Search(len(shards), func(i int) bool { return !shards[i].Last.Less(addr) })
+	if i == len(shards) || addr.Less(shards[i].First) {
+		return nil, wrapError(ErrNotFound)
+	}
+	file := shards[i].File
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, wrapError(ErrClosed)
+	}
+	if db, ok := s.dbs[file]; ok {
+		return db, nil
+	}
+	path := file
+	if s.dir != "" {
+		path = filepath.Join(s.dir, file)
+	}
+	db, err := s.open(path)
+	if err != nil {
+		return nil, fmt.Errorf("ip2loc: opening shard %s: %w", file, err)
+	}
+	s.dbs[file] = db
+	return db, nil
+}
+
+// Opened returns the number of shards opened so far.
+func (s *ShardedDB) Opened() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.dbs)
+}
+
+// Close closes the opened shards. Lookups afterwards return ErrClosed.
+func (s *ShardedDB) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	var errs []error
+	for _, db := range s.dbs {
+		errs = append(errs, db.Close())
+	}
+	s.dbs = nil
+	return errors.Join(errs...)
+}
@@ -0,0 +1,125 @@
+//go:build !ip2loc_nofs
+
+package ip2loc
+
+import (
+	"bytes"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestOpenCompositeDB(t *testing.T) {
+	if _, err := OpenCompositeDB("", "", nil); err == nil {
+		t.Error("OpenCompositeDB without a database: no error")
+	}
+
+	v4 := writeDB(t, buildDB(testRows.v4, nil))
+	v6 := writeDB(t, buildDB(nil, testRows.v6))
+	s, err := OpenCompositeDB(v4, v6, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	for ip, want := range map[string]string{"8.8.8.8": "US", "::ffff:81.2.69.160": "GB", "2a00:1450::1": "IE"} {
+		x, err := s.GetCountryShort(ip)
+		if err != nil || x.CountryShort != want {
+			t.Errorf("GetCountryShort(%s) = %q, %v, want %q", ip, x.CountryShort, err, want)
+		}
+	}
+	ips := []string{"2001:4860::1", "bogus", "193.0.0.1", "8.8.8.8"}
+	for i, r := range s.GetAllBatch(ips) {
+		x, err := s.GetAll(ips[i])
+		if r.Record != x || CodeOf(r.Err) != CodeOf(err) {
+			t.Errorf("GetAllBatch result %d for %s = %+v, %v, GetAll returns %+v, %v", i, ips[i], r.Record, r.Err, x, err)
+		}
+	}
+
+	s4, err := OpenCompositeDB(v4, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s4.Close()
+	if _, err := s4.GetCity("2a00:1450::1"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("IPv6 lookup without an IPv6 database: %v, want ErrNotFound", err)
+	}
+}
+
+func TestSplitDB(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		v4   []testRow
+		opts ShardOptions
+	}{
+		{"default", benchRows(1 << 10), ShardOptions{}},
+		// no row starts below 10.0.0.5, so no shard holds 0.0.0.0 to 9.255.255.255
+		{"unaligned", unalignedRows[1:], ShardOptions{IPv4Bits: 8, IPv6Bits: 12}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			data := buildDB(tt.v4, testRows.v6)
+			db, err := OpenBytes(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			dir := t.TempDir()
+			if err := SplitDB(bytes.NewReader(data), dir, tt.opts); err != nil {
+				t.Fatal(err)
+			}
+			s, err := OpenShardedDB(dir, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer s.Close()
+			if n := s.Opened(); n != 0 {
+				t.Errorf("%d shards opened before any lookup", n)
+			}
+
+			// the first and last address of every shard and those next to them
+			ips := slices.Concat(rangeEdges(tt.v4), rangeEdges(testRows.v6), []string{"0.0.0.0", "::"})
+			for _, shards := range s.shards {
+				for _, e := range shards {
+					ips = append(ips, e.First.String(), e.Last.String())
+					if p := e.First.Prev(); p.IsValid() {
+						ips = append(ips, p.String())
+					}
+					if n := e.Last.Next(); n.IsValid() {
+						ips = append(ips, n.String())
+					}
+				}
+			}
+			for _, ip := range ips {
+				want, werr := db.GetAll(ip)
+				got, gerr := s.GetAll(ip)
+				if got != want || CodeOf(gerr) != CodeOf(werr) {
+					t.Errorf("%s: got %+v, %v, want %+v, %v", ip, got, gerr, want, werr)
+				}
+			}
+		})
+	}
+
+	t.Run("outside", func(t *testing.T) {
+		data := buildDB(unalignedRows[1:], testRows.v6)
+		dir := t.TempDir()
+		if err := SplitDB(bytes.NewReader(data), dir, ShardOptions{IPv4Bits: 8}); err != nil {
+			t.Fatal(err)
+		}
+		s, err := OpenShardedDB(dir, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer s.Close()
+		if _, err := s.Shard("9.255.255.255"); !errors.Is(err, ErrNotFound) {
+			t.Errorf("address below every shard: %v, want ErrNotFound", err)
+		}
+		if n := s.Opened(); n != 0 {
+			t.Errorf("%d shards opened for an address outside them", n)
+		}
+	})
+
+	for _, opts := range []ShardOptions{{IPv4Bits: 17}, {IPv6Bits: -1}} {
+		if err := SplitDB(bytes.NewReader(buildDB(testRows.v4, testRows.v6)), t.TempDir(), opts); err == nil {
+			t.Errorf("SplitDB with %+v: no error", opts)
+		}
+	}
+}
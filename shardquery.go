@@ -0,0 +1,161 @@
+//go:build !ip2loc_nofs
+
+package ip2loc
+
+import (
+	"context"
+	"errors"
+)
+
+// query looks up ip in the shard holding it with the fields of mode.
+func (s *ShardedDB) query(ctx context.Context, ip string, mode uint32) (IP2LocationRecord, error) {
+	db, err := s.Shard(ip)
+	switch {
+	case errors.Is(err, ErrInvalidIP):
+		return loadMessage(invalidAddress), err
+	case err != nil:
+		return loadMessage(parameterIsNotSupported), err
+	}
+	return db.queryContext(ctx, ip, mode)
+}
+
+// GetAll will return all geolocation fields based on the queried IP address, from the shard holding it.
+func (s *ShardedDB) GetAll(ip string) (IP2LocationRecord, error) {
+	return s.query(context.Background(), ip, all)
+}
+
+// GetAllContext is GetAll returning when ctx is done, as DB.GetAllContext.
+func (s *ShardedDB) GetAllContext(ctx context.Context, ip string) (IP2LocationRecord, error) {
+	return s.query(ctx, ip, all)
+}
+
+// GetAllBatch looks up every address in ips as DB.GetAllBatch, each batch of addresses in the same
+// shard at once, and returns the results in the order of ips.
+func (s *ShardedDB) GetAllBatch(ips []string) []Result {
+	out := make([]Result, len(ips))
+	var order []*DB
+	byShard := make(map[*DB][]int)
+	for i, ip := range ips {
+		db, err := s.Shard(ip)
+		if err != nil {
+			out[i].IP = ip
+			out[i].Record, out[i].Err = s.query(context.Background(), ip, all)
+			continue
+		}
+		if _, ok := byShard[db]; !ok {
+			order = append(order, db)
+		}
+		byShard[db] = append(byShard[db], i)
+	}
+	for _, db := range order {
+		index := byShard[db]
+		batch := make([]string, len(index))
+		for j, i := range index {
+			batch[j] = ips[i]
+		}
+		for j, r := range db.GetAllBatch(batch) {
+			out[index[j]] = r
+		}
+	}
+	return out
+}
+
+// GetCountryShort will return the ISO-3166 country code based on the queried IP address.
+func (s *ShardedDB) GetCountryShort(ip string) (IP2LocationRecord, error) {
+	return s.query(context.Background(), ip, countryShort)
+}
+
+// GetCountryLong will return the country name based on the queried IP address.
+func (s *ShardedDB) GetCountryLong(ip string) (IP2LocationRecord, error) {
+	return s.query(context.Background(), ip, countryLong)
+}
+
+// GetRegion will return the region name based on the queried IP address.
+func (s *ShardedDB) GetRegion(ip string) (IP2LocationRecord, error) {
+	return s.query(context.Background(), ip, region)
+}
+
+// GetCity will return the city name based on the queried IP address.
+func (s *ShardedDB) GetCity(ip string) (IP2LocationRecord, error) {
+	return s.query(context.Background(), ip, city)
+}
+
+// GetISP will return the Internet Service Provider name based on the queried IP address.
+func (s *ShardedDB) GetISP(ip string) (IP2LocationRecord, error) {
+	return s.query(context.Background(), ip, isp)
+}
+
+// GetLatitude will return the latitude based on the queried IP address.
+func (s *ShardedDB) GetLatitude(ip string) (IP2LocationRecord, error) {
+	return s.query(context.Background(), ip, latitude)
+}
+
+// GetLongitude will return the longitude based on the queried IP address.
+func (s *ShardedDB) GetLongitude(ip string) (IP2LocationRecord, error) {
+	return s.query(context.Background(), ip, longitude)
+}
+
+// GetDomain will return the domain name based on the queried IP address.
+func (s *ShardedDB) GetDomain(ip string) (IP2LocationRecord, error) {
+	return s.query(context.Background(), ip, domain)
+}
+
+// GetZipCode will return the postal code based on the queried IP address.
+func (s *ShardedDB) GetZipCode(ip string) (IP2LocationRecord, error) {
+	return s.query(context.Background(), ip, zipCode)
+}
+
+// GetTimezone will return the time zone based on the queried IP address.
+func (s *ShardedDB) GetTimezone(ip string) (IP2LocationRecord, error) {
+	return s.query(context.Background(), ip, timezone)
+}
+
+// GetNetSpeed will return the Internet connection speed based on the queried IP address.
+func (s *ShardedDB) GetNetSpeed(ip string) (IP2LocationRecord, error) {
+	return s.query(context.Background(), ip, netSpeed)
+}
+
+// GetIDDCode will return the International Direct Dialing code based on the queried IP address.
+func (s *ShardedDB) GetIDDCode(ip string) (IP2LocationRecord, error) {
+	return s.query(context.Background(), ip, iddCode)
+}
+
+// GetAreaCode will return the area code based on the queried IP address.
+func (s *ShardedDB) GetAreaCode(ip string) (IP2LocationRecord, error) {
+	return s.query(context.Background(), ip, areaCode)
+}
+
+// GetWeatherStationCode will return the weather station code based on the queried IP address.
+func (s *ShardedDB) GetWeatherStationCode(ip string) (IP2LocationRecord, error) {
+	return s.query(context.Background(), ip, weatherStationCode)
+}
+
+// GetWeatherStationName will return the weather station name based on the queried IP address.
+func (s *ShardedDB) GetWeatherStationName(ip string) (IP2LocationRecord, error) {
+	return s.query(context.Background(), ip, weatherStationName)
+}
+
+// GetMCC will return the mobile country code based on the queried IP address.
+func (s *ShardedDB) GetMCC(ip string) (IP2LocationRecord, error) {
+	return s.query(context.Background(), ip, mcc)
+}
+
+// GetMNC will return the mobile network code based on the queried IP address.
+func (s *ShardedDB) GetMNC(ip string) (IP2LocationRecord, error) {
+	return s.query(context.Background(), ip, mnc)
+}
+
+// GetMobileBrand will return the mobile carrier brand based on the queried IP address.
+func (s *ShardedDB) GetMobileBrand(ip string) (IP2LocationRecord, error) {
+	return s.query(context.Background(), ip, mobileBrand)
+}
+
+// GetElevation will return the elevation based on the queried IP address, as DB.GetElevation.
+func (s *ShardedDB) GetElevation(ip string) (IP2LocationRecord, error) {
+	return s.query(context.Background(), ip, elevation)
+}
+
+// GetUsageType will return the usage type based on the queried IP address.
+func (s *ShardedDB) GetUsageType(ip string) (IP2LocationRecord, error) {
+	return s.query(context.Background(), ip, usageType)
+}
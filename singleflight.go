@@ -0,0 +1,40 @@
+package ip2loc
+
+import (
+	"context"
+	"strconv"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// WithSingleflight collapses concurrent lookups of the same address and fields into one read:
+// while a lookup is running, identical lookups wait for it and share its result. This helps when
+// many goroutines query a popular address at once, above all with slow or remote readers.
+// Callers sharing a result also share the RawRow and Country pointers of the record.
+func WithSingleflight() Option {
+	return func(d *DB) {
+		d.flight = &singleflight.Group{}
+	}
+}
+
+type flightResult struct {
+	x   IP2LocationRecord
+	err error
+}
+
+// sharedQuery runs queryOnce for the first of the concurrent callers. The shared lookup does not
+// end with the context of that caller; each caller stops waiting for it when its own context is done.
+func (d *DB) sharedQuery(ctx context.Context, ip string, mode uint32) (IP2LocationRecord, error) {
+	key := strconv.FormatUint(uint64(mode), 16) + "|" + ip
+	ch := d.flight.DoChan(key, func() (interface{}, error) {
+		x, err := d.queryOnce(context.WithoutCancel(ctx), ip, mode)
+		return flightResult{x, err}, nil
+	})
+	select {
+	case v := <-ch:
+		r := v.Val.(flightResult)
+		return r.x, r.err
+	case <-ctx.Done():
+		return loadMessage(parameterIsNotSupported), wrapError(timeoutError(ctx.Err()))
+	}
+}
@@ -0,0 +1,155 @@
+package ip2loc
+
+import (
+	"context"
+	"log/slog"
+)
+
+// DefaultLogFields are the record fields LogHandler adds when no others are configured.
+var DefaultLogFields = []string{"country_short", "region", "city", "isp"}
+
+// LogHandlerOptions configures a LogHandler. The zero value uses the defaults.
+type LogHandlerOptions struct {
+	// Key is the attribute holding the address to look up, "ip" by default.
+	Key string
+	// Group is the group the location attributes are added in, "geo" by default.
+	Group string
+	// Fields are the snake_case names of the record fields to add, DefaultLogFields by default.
+	// Unknown names are ignored.
+	Fields []string
+}
+
+// LogHandler is a slog.Handler which looks up the address in the "ip" attribute of every record
+// and adds the location to it before passing it on:
+//
+//	logger := slog.New(ip2loc.NewLogHandler(slog.NewJSONHandler(os.Stderr, nil), db, nil))
+//	host, _, _ := net.SplitHostPort(r.RemoteAddr)
+//	logger.Info("request", "ip", host)
+//	// {"msg":"request","ip":"8.8.8.8","geo":{"country_short":"US","region":"California",...}}
+//
+// An address passed to Logger.With is looked up once. Fields which are empty or unavailable are
+// left out, and nothing is added if the lookup fails. If the DB was opened with WithAnonymization,
+// the address itself is logged truncated.
+type LogHandler struct {
+	next   slog.Handler
+	db     *DB
+	key    string
+	group  string
+	fields []recordField
+	mode   uint32
+}
+
+// NewLogHandler returns a LogHandler adding locations from db to the records passed to next.
+// opts may be nil.
+func NewLogHandler(next slog.Handler, db *DB, opts *LogHandlerOptions) *LogHandler {
+	h := &LogHandler{next: next, db: db, key: "ip", group: "geo"}
+	names := DefaultLogFields
+	if opts != nil {
+		if opts.Key != "" {
+			h.key = opts.Key
+		}
+		if opts.Group != "" {
+			h.group = opts.Group
+		}
+		if opts.Fields != nil {
+			names = opts.Fields
+		}
+	}
+	for _, name := range names {
+		if f, ok := fieldByName(name); ok {
+			h.fields = append(h.fields, f)
+			h.mode |= f.mode
+		}
+	}
+	return h
+}
+
+func (h *LogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *LogHandler) Handle(ctx context.Context, r slog.Record) error {
+	var geo slog.Attr
+	found := false
+	r.Attrs(func(a slog.Attr) bool {
+		if a.Key != h.key {
+			return true
+		}
+		geo, found = h.locate(a.Value), true
+		return false
+	})
+	if !found {
+		return h.next.Handle(ctx, r)
+	}
+	if h.db.anonymize {
+		// the record is rebuilt so that the full address never reaches next
+		c := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+		r.Attrs(func(a slog.Attr) bool {
+			c.AddAttrs(h.anonymized(a))
+			return true
+		})
+		r = c
+	} else if geo.Key != "" {
+		r = r.Clone()
+	}
+	if geo.Key != "" {
+		r.AddAttrs(geo)
+	}
+	return h.next.Handle(ctx, r)
+}
+
+func (h *LogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	for i, a := range attrs {
+		if a.Key == h.key {
+			geo := h.locate(a.Value)
+			attrs = append([]slog.Attr(nil), attrs...)
+			attrs[i] = h.anonymized(a)
+			if geo.Key != "" {
+				attrs = append(attrs, geo)
+			}
+			break
+		}
+	}
+	c.next = h.next.WithAttrs(attrs)
+	return &c
+}
+
+// anonymized returns a with its address truncated as configured by WithAnonymization if it holds
+// the address, or a unchanged.
+func (h *LogHandler) anonymized(a slog.Attr) slog.Attr {
+	if a.Key != h.key || !h.db.anonymize {
+		return a
+	}
+	return slog.String(a.Key, h.db.AnonymizedIP(a.Value.Resolve().String()))
+}
+
+func (h *LogHandler) WithGroup(name string) slog.Handler {
+	c := *h
+	c.next = h.next.WithGroup(name)
+	return &c
+}
+
+// locate looks up the address held by v and returns the location group, or an empty Attr.
+func (h *LogHandler) locate(v slog.Value) slog.Attr {
+	x, err := h.db.query(v.Resolve().String(), h.mode)
+	if err != nil {
+		return slog.Attr{}
+	}
+	supported := h.db.loadedFields()
+	attrs := make([]any, 0, len(h.fields))
+	for _, f := range h.fields {
+		if f.mode&supported == 0 {
+			continue
+		}
+		if f.isFloat {
+			attrs = append(attrs, slog.Float64(f.name, f.float64(&x)))
+		} else if s := f.value(&x); s != "" && s != "-" {
+			attrs = append(attrs, slog.String(f.name, s))
+		}
+	}
+	if len(attrs) == 0 {
+		return slog.Attr{}
+	}
+	return slog.Group(h.group, attrs...)
+}
@@ -0,0 +1,92 @@
+//go:build !ip2loc_nofs
+
+package ip2loc
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"reflect"
+	"testing"
+)
+
+func TestLogHandler(t *testing.T) {
+	london := map[string]any{"country_short": "GB", "region": "England", "city": "London"}
+	for _, tt := range []struct {
+		name  string
+		opts  []Option
+		hopts *LogHandlerOptions
+		log   func(*slog.Logger)
+		ip    any
+		geo   map[string]any
+	}{
+		{
+			name: "record",
+			log:  func(l *slog.Logger) { l.Info("request", "ip", "81.2.69.160") },
+			ip:   "81.2.69.160",
+			geo:  london, // the database has no isp column
+		},
+		{
+			name: "with",
+			log:  func(l *slog.Logger) { l.With("ip", "81.2.69.160").Info("request") },
+			ip:   "81.2.69.160",
+			geo:  london,
+		},
+		{
+			name:  "options",
+			hopts: &LogHandlerOptions{Key: "client", Group: "loc", Fields: []string{"country_long", "latitude"}},
+			log:   func(l *slog.Logger) { l.Info("request", "client", "2a00:1450::1") },
+			geo:   map[string]any{"country_long": "Ireland", "latitude": 53.34399},
+		},
+		{
+			name: "anonymized",
+			opts: []Option{WithAnonymization(24, 48)},
+			log:  func(l *slog.Logger) { l.Info("request", "ip", "81.2.69.160") },
+			ip:   "81.2.69.0",
+			geo:  london,
+		},
+		{
+			name: "anonymized with",
+			opts: []Option{WithAnonymization(24, 48)},
+			log:  func(l *slog.Logger) { l.With("ip", "81.2.69.160").Info("request") },
+			ip:   "81.2.69.0",
+			geo:  london,
+		},
+		{
+			name: "no location",
+			log:  func(l *slog.Logger) { l.Info("request", "ip", "1.1.1.1") },
+			ip:   "1.1.1.1",
+		},
+		{
+			name: "invalid address",
+			log:  func(l *slog.Logger) { l.Info("request", "ip", "81.2.69.160:443") },
+			ip:   "81.2.69.160:443",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			db, err := OpenDB(testDB(t), tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+
+			var b bytes.Buffer
+			tt.log(slog.New(NewLogHandler(slog.NewJSONHandler(&b, nil), db, tt.hopts)))
+			var got map[string]any
+			if err := json.Unmarshal(b.Bytes(), &got); err != nil {
+				t.Fatalf("%v: %s", err, b.Bytes())
+			}
+
+			group := "geo"
+			if tt.hopts != nil {
+				group = tt.hopts.Group
+			}
+			if geo, ok := got[group]; tt.geo == nil && ok || tt.geo != nil && !reflect.DeepEqual(geo, tt.geo) {
+				t.Errorf("%s: got %v, want %v", group, geo, tt.geo)
+			}
+			if tt.ip != nil && got["ip"] != tt.ip {
+				t.Errorf("ip: got %v, want %v", got["ip"], tt.ip)
+			}
+		})
+	}
+}
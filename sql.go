@@ -0,0 +1,155 @@
+package ip2loc
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CountryCode is an ISO-3166 country code which can be read from and written to SQL columns.
+// An empty code is stored as NULL.
+type CountryCode string
+
+// Scan implements the sql.Scanner interface.
+func (c *CountryCode) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*c = ""
+	case string:
+		*c = CountryCode(v)
+	case []byte:
+		*c = CountryCode(v)
+	default:
+		return fmt.Errorf("ip2loc: cannot scan %T into CountryCode", src)
+	}
+	return nil
+}
+
+// Value implements the driver.Valuer interface.
+func (c CountryCode) Value() (driver.Value, error) {
+	if c == "" {
+		return nil, nil
+	}
+	return string(c), nil
+}
+
+// GeoPoint is a latitude/longitude pair which can be read from and written to SQL columns.
+// It is stored as WKT text, e.g. "POINT(-122.078515 37.405992)". An invalid point is stored as NULL.
+type GeoPoint struct {
+	Latitude  float32
+	Longitude float32
+	Valid     bool
+}
+
+// Scan implements the sql.Scanner interface.
+func (p *GeoPoint) Scan(src interface{}) error {
+	var s string
+	switch v := src.(type) {
+	case nil:
+		*p = GeoPoint{}
+		return nil
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	default:
+		return fmt.Errorf("ip2loc: cannot scan %T into GeoPoint", src)
+	}
+
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(strings.ToUpper(s), "POINT(") || !strings.HasSuffix(s, ")") {
+		return fmt.Errorf("ip2loc: invalid GeoPoint %q", s)
+	}
+	coords := strings.Fields(s[len("POINT(") : len(s)-1])
+	if len(coords) != 2 {
+		return fmt.Errorf("ip2loc: invalid GeoPoint %q", s)
+	}
+	lon, err := strconv.ParseFloat(coords[0], 32)
+	if err != nil {
+		return fmt.Errorf("ip2loc: invalid GeoPoint longitude: %v", err)
+	}
+	lat, err := strconv.ParseFloat(coords[1], 32)
+	if err != nil {
+		return fmt.Errorf("ip2loc: invalid GeoPoint latitude: %v", err)
+	}
+	*p = GeoPoint{Latitude: float32(lat), Longitude: float32(lon), Valid: true}
+	return nil
+}
+
+// Value implements the driver.Valuer interface.
+func (p GeoPoint) Value() (driver.Value, error) {
+	if !p.Valid {
+		return nil, nil
+	}
+	return fmt.Sprintf("POINT(%s %s)",
+		strconv.FormatFloat(float64(p.Longitude), 'f', -1, 32),
+		strconv.FormatFloat(float64(p.Latitude), 'f', -1, 32)), nil
+}
+
+// CountryCode will return the ISO-3166 country code based on the queried IP address.
+// The code is empty if the address is not found or the database has no country column.
+func (d *DB) CountryCode(ip string) (CountryCode, error) {
+	x, err := d.query(ip, countryShort)
+	if errors.Is(err, ErrUnsupportedField) || errors.Is(err, ErrNotFound) {
+		return "", nil
+	}
+	if err != nil || len(x.CountryShort) != 2 {
+		return "", err
+	}
+	return CountryCode(x.CountryShort), nil
+}
+
+// GeoPoint will return the coordinates based on the queried IP address.
+// The point is invalid if the address is not found or the database has no latitude and longitude columns.
+func (d *DB) GeoPoint(ip string) (GeoPoint, error) {
+	x, err := d.query(ip, latitude|longitude)
+	if errors.Is(err, ErrUnsupportedField) || errors.Is(err, ErrNotFound) {
+		return GeoPoint{}, nil
+	}
+	if err != nil {
+		return GeoPoint{}, err
+	}
+	return GeoPoint{Latitude: x.Latitude, Longitude: x.Longitude, Valid: true}, nil
+}
+
+// Scanner returns a sql.Scanner which reads an IP address column and fills dst with all
+// geolocation fields for that address, so rows can be enriched while being scanned:
+//
+//	var rec ip2loc.IP2LocationRecord
+//	err := rows.Scan(&id, db.Scanner(&rec))
+//
+// A NULL column leaves dst empty, an invalid address fills dst with the invalid address message and an
+// address which is not found with the default messages.
+func (d *DB) Scanner(dst *IP2LocationRecord) sql.Scanner {
+	return &recordScanner{db: d, dst: dst}
+}
+
+type recordScanner struct {
+	db  *DB
+	dst *IP2LocationRecord
+}
+
+func (s *recordScanner) Scan(src interface{}) error {
+	var ip string
+	switch v := src.(type) {
+	case nil:
+		*s.dst = IP2LocationRecord{}
+		return nil
+	case string:
+		ip = v
+	case []byte:
+		ip = string(v)
+	default:
+		return fmt.Errorf("ip2loc: cannot scan %T as IP address", src)
+	}
+
+	x, err := s.db.GetAll(strings.TrimSpace(ip))
+	if err != nil && !errors.Is(err, ErrInvalidIP) && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	*s.dst = x
+	return nil
+}
@@ -0,0 +1,128 @@
+package ip2loc
+
+import (
+	"context"
+	"errors"
+	"runtime"
+)
+
+// defaultStreamBatchSize is the largest batch a StreamEnricher looks up at once when BatchSize is not set.
+const defaultStreamBatchSize = 64
+
+// EnrichedEvent pairs a stream event with the geolocation of the address extracted from it.
+// Err is set if the lookup failed; Record then holds whatever GetAll returned.
+type EnrichedEvent[T any] struct {
+	Event  T
+	Record IP2LocationRecord
+	Err    error
+}
+
+// StreamEnricher adds geolocation to events flowing through a channel, e.g. messages from a Kafka
+// or NATS consumer. Events are taken from the input in batches of whatever is available, looked up
+// concurrently and delivered in input order. A slow consumer of the output blocks the enricher,
+// which in turn stops reading the input, so backpressure propagates upstream.
+type StreamEnricher[T any] struct {
+	DB *DB
+	// Extract returns the IP address of an event.
+	Extract func(T) string
+	// Workers is the number of concurrent lookups per batch; zero or less uses one per CPU.
+	Workers int
+	// BatchSize is the maximum number of events looked up at once; zero or less uses 64.
+	BatchSize int
+}
+
+// Enrich runs a StreamEnricher with the default settings, see StreamEnricher.Run.
+func Enrich[T any](ctx context.Context, db *DB, in <-chan T, out chan<- EnrichedEvent[T], extract func(T) string) error {
+	e := &StreamEnricher[T]{DB: db, Extract: extract}
+	return e.Run(ctx, in, out)
+}
+
+// Run enriches events from in and sends them to out until in is closed or ctx is done.
+// It closes out when it returns, and returns ctx.Err() if it was cancelled.
+// Events taken from in but not yet delivered when ctx is done are dropped. The lookups run in the
+// WorkerGroup of the DB; a panic of Extract or of a lookup ends Run with its error, and so does
+// ErrStopped once the group is stopped.
+func (e *StreamEnricher[T]) Run(ctx context.Context, in <-chan T, out chan<- EnrichedEvent[T]) error {
+	defer close(out)
+
+	workers := e.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	size := e.BatchSize
+	if size <= 0 {
+		size = defaultStreamBatchSize
+	}
+
+	batch := make([]EnrichedEvent[T], 0, size)
+	for {
+		batch = batch[:0]
+
+		// block for the first event, then take whatever else is ready without waiting
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case ev, ok := <-in:
+			if !ok {
+				return nil
+			}
+			batch = append(batch, EnrichedEvent[T]{Event: ev})
+		}
+		closed := false
+	fill:
+		for len(batch) < size {
+			select {
+			case ev, ok := <-in:
+				if !ok {
+					closed = true
+					break fill
+				}
+				batch = append(batch, EnrichedEvent[T]{Event: ev})
+			default:
+				break fill
+			}
+		}
+
+		if err := e.lookup(ctx, batch, workers); err != nil {
+			return err
+		}
+
+		for _, ev := range batch {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case out <- ev:
+			}
+		}
+		if closed {
+			return nil
+		}
+	}
+}
+
+// lookup looks up batch with the given number of workers, run in the group of the DB.
+func (e *StreamEnricher[T]) lookup(ctx context.Context, batch []EnrichedEvent[T], workers int) error {
+	if workers > len(batch) {
+		workers = len(batch)
+	}
+	g := e.DB.workerGroup()
+	var waits []func() error
+	var err error
+	for w := 0; w < workers && err == nil; w++ {
+		var wait func() error
+		wait, err = g.task(ctx, "stream lookup", func(context.Context) error {
+			for i := w; i < len(batch); i += workers {
+				batch[i].Record, batch[i].Err = e.DB.GetAll(e.Extract(batch[i].Event))
+			}
+			return nil
+		})
+		if err == nil {
+			waits = append(waits, wait)
+		}
+	}
+	errs := []error{err}
+	for _, wait := range waits {
+		errs = append(errs, wait())
+	}
+	return errors.Join(errs...)
+}
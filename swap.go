@@ -0,0 +1,32 @@
+package ip2loc
+
+import "time"
+
+// Swap describes the replacement of the database of a DB by a newly loaded file, by Reload, a
+// refresh of a Manager or the reopen after a truncated read.
+type Swap struct {
+	Time time.Time
+	Old  Header // zero if no database was loaded before
+	New  Header
+}
+
+// WithSwapHandler registers a function which is called after every swap of the database, e.g. to
+// invalidate caches of lookup results or to record which data versions were served. Like the event
+// handler, it is called synchronously once the new database answers lookups.
+func WithSwapHandler(fn func(Swap)) Option {
+	return func(d *DB) {
+		d.onSwap = fn
+	}
+}
+
+// notifySwap must be called without d.mu held.
+func (d *DB) notifySwap(old, state *dbState, t time.Time) {
+	if d.onSwap == nil {
+		return
+	}
+	s := Swap{Time: t, New: state.header()}
+	if old != nil {
+		s.Old = old.header()
+	}
+	d.onSwap(s)
+}
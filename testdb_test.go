@@ -0,0 +1,197 @@
+package ip2loc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/big"
+	"math/rand/v2"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testRow is a row of a database written by buildDB, covering the addresses from from up to the
+// from of the next row.
+type testRow struct {
+	from        string
+	country     string
+	countryLong string
+	region      string
+	city        string
+	lat, lon    float32
+}
+
+// testRows are the rows of the database of testDB.
+var testRows = struct{ v4, v6 []testRow }{
+	v4: []testRow{
+		{"0.0.0.0", "-", "-", "-", "-", 0, 0},
+		{"8.8.8.0", "US", "United States of America", "California", "Mountain View", 37.40599, -122.078514},
+		{"8.8.9.0", "-", "-", "-", "-", 0, 0},
+		{"81.2.69.0", "GB", "United Kingdom of Great Britain and Northern Ireland", "England", "London", 51.508529, -0.12574},
+		{"81.2.70.0", "-", "-", "-", "-", 0, 0},
+		{"193.0.0.0", "NL", "Netherlands (Kingdom of the)", "Noord-Holland", "Amsterdam", 52.37403, 4.88969},
+		{"193.0.32.0", "-", "-", "-", "-", 0, 0},
+	},
+	v6: []testRow{
+		{"::", "-", "-", "-", "-", 0, 0},
+		{"2001:4860::", "US", "United States of America", "California", "Mountain View", 37.40599, -122.078514},
+		{"2001:4861::", "-", "-", "-", "-", 0, 0},
+		{"2a00:1450::", "IE", "Ireland", "Dublin", "Dublin", 53.34399, -6.26719},
+		{"2a00:1451::", "-", "-", "-", "-", 0, 0},
+	},
+}
+
+// buildDB returns a DB5 database, holding the country, region, city, latitude and longitude, with
+// the given IPv4 and IPv6 rows, which must be sorted. The last row of each table reaches the end
+// of its address space.
+func buildDB(v4, v6 []testRow) []byte {
+	const header = 64
+	const columns = 6
+	var strs bytes.Buffer
+	offsets := make(map[string]uint32)
+	str := func(s string) uint32 {
+		if off, ok := offsets[s]; ok {
+			return off
+		}
+		off := uint32(header + strs.Len())
+		strs.WriteByte(byte(len(s)))
+		strs.WriteString(s)
+		offsets[s] = off
+		return off
+	}
+	// the short country name is followed by the long one
+	country := func(r testRow) uint32 {
+		key := r.country + "\x00" + r.countryLong
+		if off, ok := offsets[key]; ok {
+			return off
+		}
+		off := uint32(header + strs.Len())
+		strs.WriteByte(byte(len(r.country)))
+		strs.WriteString(r.country)
+		strs.WriteByte(byte(len(r.countryLong)))
+		strs.WriteString(r.countryLong)
+		offsets[key] = off
+		return off
+	}
+
+	table := func(rows []testRow, ipsize int, maxip *big.Int) []byte {
+		var b []byte
+		for i := 0; i <= len(rows); i++ {
+			from := maxip
+			r := testRow{country: "-", countryLong: "-", region: "-", city: "-"}
+			if i < len(rows) {
+				r = rows[i]
+				_, from = addrNumber(netip.MustParseAddr(r.from))
+			}
+			be := from.FillBytes(make([]byte, ipsize))
+			for j := len(be) - 1; j >= 0; j-- {
+				b = append(b, be[j])
+			}
+			b = binary.LittleEndian.AppendUint32(b, country(r))
+			b = binary.LittleEndian.AppendUint32(b, str(r.region))
+			b = binary.LittleEndian.AppendUint32(b, str(r.city))
+			b = binary.LittleEndian.AppendUint32(b, math.Float32bits(r.lat))
+			b = binary.LittleEndian.AppendUint32(b, math.Float32bits(r.lon))
+		}
+		return b
+	}
+	t4 := table(v4, 4, maxIpv4Range)
+	t6 := table(v6, 16, maxIpv6Range)
+
+	db := make([]byte, header)
+	db[0], db[1], db[2], db[3], db[4] = 5, columns, 24, 1, 1 // DB5, 2024-01-01
+	base4 := header + strs.Len() + 1
+	binary.LittleEndian.PutUint32(db[5:], uint32(len(v4)))
+	binary.LittleEndian.PutUint32(db[9:], uint32(base4))
+	binary.LittleEndian.PutUint32(db[13:], uint32(len(v6)))
+	binary.LittleEndian.PutUint32(db[17:], uint32(base4+len(t4)))
+	db = append(db, strs.Bytes()...)
+	db = append(db, t4...)
+	return append(db, t6...)
+}
+
+// testDB writes the database of testRows to a file in a temporary directory and returns its path.
+func testDB(t testing.TB) string {
+	return writeDB(t, buildDB(testRows.v4, testRows.v6))
+}
+
+// writeDB writes data to a file in a temporary directory and returns its path.
+func writeDB(t testing.TB, data []byte) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "IP2LOCATION-DB5.BIN")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// rangeEdges returns the first and last address of every row of rows, which must be of one IP
+// version; the last row reaches the end of its address space.
+func rangeEdges(rows []testRow) []string {
+	var ips []string
+	for i, r := range rows {
+		first := netip.MustParseAddr(r.from)
+		var last netip.Addr
+		if i+1 < len(rows) {
+			last = netip.MustParseAddr(rows[i+1].from).Prev()
+		} else if first.Is4() {
+			last = netip.AddrFrom4([4]byte{255, 255, 255, 255})
+		} else {
+			last = netip.AddrFrom16([16]byte{255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255, 255})
+		}
+		ips = append(ips, first.String(), last.String())
+	}
+	return ips
+}
+
+// testEdges returns the first and last address of every row of testRows, the ends of both tables
+// included.
+func testEdges() []string {
+	return append(rangeEdges(testRows.v4), rangeEdges(testRows.v6)...)
+}
+
+// sameLookups checks that got answers GetAll of every address of ips like want.
+func sameLookups(t testing.TB, want, got *DB, ips []string) {
+	t.Helper()
+	for _, ip := range ips {
+		wx, werr := want.GetAll(ip)
+		gx, gerr := got.GetAll(ip)
+		if gx != wx || fmt.Sprint(gerr) != fmt.Sprint(werr) {
+			t.Errorf("%s: got %+v, %v, want %+v, %v", ip, gx, gerr, wx, werr)
+		}
+	}
+}
+
+// benchRows returns n IPv4 rows of equal size spanning the whole address space.
+func benchRows(n int) []testRow {
+	cities := []testRow{
+		{country: "US", countryLong: "United States of America", region: "California", city: "Mountain View", lat: 37.40599, lon: -122.078514},
+		{country: "GB", countryLong: "United Kingdom of Great Britain and Northern Ireland", region: "England", city: "London", lat: 51.508529, lon: -0.12574},
+		{country: "NL", countryLong: "Netherlands (Kingdom of the)", region: "Noord-Holland", city: "Amsterdam", lat: 52.37403, lon: 4.88969},
+		{country: "JP", countryLong: "Japan", region: "Tokyo", city: "Tokyo", lat: 35.6895, lon: 139.69171},
+	}
+	step := (1 << 32) / uint64(n)
+	rows := make([]testRow, n)
+	for i := range rows {
+		rows[i] = cities[i%len(cities)]
+		rows[i].from = addr4(uint32(uint64(i) * step))
+	}
+	return rows
+}
+
+// benchIPs returns n random IPv4 addresses, the same on every run.
+func benchIPs(n int) []string {
+	r := rand.New(rand.NewPCG(1, 2))
+	ips := make([]string, n)
+	for i := range ips {
+		ips[i] = addr4(r.Uint32())
+	}
+	return ips
+}
+
+func addr4(v uint32) string {
+	return netip.AddrFrom4([4]byte(binary.BigEndian.AppendUint32(nil, v))).String()
+}
@@ -0,0 +1,76 @@
+package ip2loc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrTimeout is returned by lookups which did not finish within the timeout of WithLookupTimeout or
+// before the deadline of their context.
+var ErrTimeout = errors.New("ip2loc: lookup timed out")
+
+// WithLookupTimeout bounds every lookup to timeout, after which it returns ErrTimeout, so that a
+// request handler reading a database on slow or remote storage can fall back rather than hang.
+//
+// A lookup checks the timeout before each of its reads and gives up at the first read after it. A
+// read in progress cannot be interrupted, so a lookup blocked in a slow read returns ErrTimeout
+// once that read returns.
+func WithLookupTimeout(timeout time.Duration) Option {
+	return func(d *DB) {
+		d.timeout = timeout
+	}
+}
+
+// GetAllContext is GetAll returning when ctx is done, with ErrTimeout if its deadline passed and
+// its error otherwise. The timeout of WithLookupTimeout applies as well.
+func (d *DB) GetAllContext(ctx context.Context, ip string) (IP2LocationRecord, error) {
+	return d.queryContext(ctx, ip, all)
+}
+
+// lookupBounded runs lookup under the read lock, retried as configured by WithReadRetries, until ctx
+// is done or the lookup timeout passes. The lookup checks ctx before each read, so it stops,
+// releasing the lock, at the first read after.
+func (d *DB) lookupBounded(ctx context.Context, ip string, mode uint32) (*dbState, IP2LocationRecord, error) {
+	if d.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.timeout)
+		defer cancel()
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, loadMessage(parameterIsNotSupported), timeoutError(err)
+	}
+
+	var (
+		state *dbState
+		x     IP2LocationRecord
+	)
+	err := d.retrying(ctx, func() error {
+		var err error
+		state, x, err = d.lookupLocked(ctx, ip, mode)
+		return err
+	})
+	if cerr := ctx.Err(); cerr != nil && errors.Is(err, cerr) {
+		return state, loadMessage(parameterIsNotSupported), timeoutError(err)
+	}
+	return state, x, err
+}
+
+// lookupLocked runs lookup under the read lock and returns the state it ran on.
+func (d *DB) lookupLocked(ctx context.Context, ip string, mode uint32) (*dbState, IP2LocationRecord, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	state := d.dbState
+	defer startLookup(state)()
+	x, err := d.lookup(ctx, ip, mode)
+	return state, x, err
+}
+
+// timeoutError returns the error of a lookup whose context is done with err.
+func timeoutError(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %w", ErrTimeout, err)
+	}
+	return err
+}
@@ -0,0 +1,46 @@
+package ip2loc
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+)
+
+// cancelReader cancels a context on the read after the header has been loaded, as a deadline
+// passing in the middle of a lookup would.
+type cancelReader struct {
+	*ByteSliceReader
+	armed  atomic.Bool
+	cancel context.CancelFunc
+	reads  atomic.Int64
+}
+
+func (r *cancelReader) ReadAt(p []byte, off int64) (int, error) {
+	if r.armed.Load() {
+		r.reads.Add(1)
+		r.cancel()
+	}
+	return r.ByteSliceReader.ReadAt(p, off)
+}
+
+func TestLookupStopsWhenContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r := &cancelReader{ByteSliceReader: NewByteSliceReader(buildDB(testRows.v4, testRows.v6)), cancel: cancel}
+	db, err := OpenDBWithReader(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.armed.Store(true)
+
+	if _, err := db.GetAllContext(ctx, "8.8.8.8"); !errors.Is(err, context.Canceled) {
+		t.Errorf("lookup after its context was canceled: %v, want context.Canceled", err)
+	}
+	if n := r.reads.Load(); n != 1 {
+		t.Errorf("lookup made %d reads after its context was canceled, want none after the first", n-1)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,47 @@
+package ip2loc
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// UTCOffset parses the Timezone field, e.g. "+08:00", into an offset from UTC.
+func (x IP2LocationRecord) UTCOffset() (time.Duration, error) {
+	tz := x.Timezone
+	if len(tz) != 6 || (tz[0] != '+' && tz[0] != '-') || tz[3] != ':' {
+		return 0, fmt.Errorf("ip2loc: invalid timezone %q", tz)
+	}
+	hours, err := strconv.Atoi(tz[1:3])
+	if err != nil {
+		return 0, fmt.Errorf("ip2loc: invalid timezone %q", tz)
+	}
+	minutes, err := strconv.Atoi(tz[4:6])
+	if err != nil || minutes >= 60 {
+		return 0, fmt.Errorf("ip2loc: invalid timezone %q", tz)
+	}
+
+	offset := time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute
+	if tz[0] == '-' {
+		offset = -offset
+	}
+	return offset, nil
+}
+
+// Location returns a fixed zone for the Timezone field, named after the offset, e.g. "UTC+08:00".
+func (x IP2LocationRecord) Location() (*time.Location, error) {
+	offset, err := x.UTCOffset()
+	if err != nil {
+		return nil, err
+	}
+	return time.FixedZone("UTC"+x.Timezone, int(offset/time.Second)), nil
+}
+
+// LocalTime converts t into the local time of the queried IP address.
+func (x IP2LocationRecord) LocalTime(t time.Time) (time.Time, error) {
+	loc, err := x.Location()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.In(loc), nil
+}
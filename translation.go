@@ -0,0 +1,127 @@
+package ip2loc
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Translations holds localized country, region and city names keyed by language code.
+type Translations struct {
+	names map[string]string
+}
+
+func translationKey(lang, kind, country, name string) string {
+	return strings.ToLower(lang) + "|" + kind + "|" + strings.ToUpper(country) + "|" + strings.ToLower(name)
+}
+
+// LoadTranslations reads a translation pack CSV. Two layouts are accepted, both with a header row:
+//
+//   - the IP2Location country multilingual CSV with the columns lang, country_alpha2_code and country_name;
+//   - a generic pack with the columns lang, type (country, region or city), country_code, name and translation,
+//     where name is the English name as stored in the BIN file.
+//
+// Further packs can be merged with Load.
+func LoadTranslations(r io.Reader) (*Translations, error) {
+	t := &Translations{names: map[string]string{}}
+	if err := t.Load(r); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Load merges a translation pack CSV into t. See LoadTranslations for the accepted layouts.
+func (t *Translations) Load(r io.Reader) error {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return fmt.Errorf("ip2loc: reading translations header: %w", err)
+	}
+	cols := map[string]int{}
+	for i, name := range header {
+		cols[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	has := func(names ...string) bool {
+		for _, name := range names {
+			if _, ok := cols[name]; !ok {
+				return false
+			}
+		}
+		return true
+	}
+	countryPack := has("lang", "country_alpha2_code", "country_name")
+	genericPack := has("lang", "type", "country_code", "name", "translation")
+	if !countryPack && !genericPack {
+		return fmt.Errorf("ip2loc: unrecognised translations header %v", header)
+	}
+
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("ip2loc: reading translations: %w", err)
+		}
+		get := func(name string) string {
+			if i := cols[name]; i < len(row) {
+				return row[i]
+			}
+			return ""
+		}
+
+		if countryPack {
+			t.names[translationKey(get("lang"), "country", get("country_alpha2_code"), "")] = get("country_name")
+			continue
+		}
+		kind := strings.ToLower(get("type"))
+		name := get("name")
+		if kind == "country" {
+			name = ""
+		}
+		t.names[translationKey(get("lang"), kind, get("country_code"), name)] = get("translation")
+	}
+}
+
+// Country returns the name of a country in the given language.
+func (t *Translations) Country(lang, countryShort string) (string, bool) {
+	s, ok := t.names[translationKey(lang, "country", countryShort, "")]
+	return s, ok
+}
+
+// Region returns the name of a region of the given country in the given language.
+func (t *Translations) Region(lang, countryShort, region string) (string, bool) {
+	s, ok := t.names[translationKey(lang, "region", countryShort, region)]
+	return s, ok
+}
+
+// City returns the name of a city of the given country in the given language.
+func (t *Translations) City(lang, countryShort, city string) (string, bool) {
+	s, ok := t.names[translationKey(lang, "city", countryShort, city)]
+	return s, ok
+}
+
+// Localize replaces CountryLong, Region and City of x with their names in the given language.
+// Names without a translation are kept.
+func (t *Translations) Localize(x *IP2LocationRecord, lang string) {
+	if s, ok := t.Country(lang, x.CountryShort); ok {
+		x.CountryLong = s
+	}
+	if s, ok := t.Region(lang, x.CountryShort, x.Region); ok {
+		x.Region = s
+	}
+	if s, ok := t.City(lang, x.CountryShort, x.City); ok {
+		x.City = s
+	}
+}
+
+// WithTranslations returns CountryLong, Region and City of every lookup in the given language.
+func WithTranslations(t *Translations, lang string) Option {
+	return func(d *DB) {
+		d.translations = t
+		d.language = lang
+	}
+}
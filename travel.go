@@ -0,0 +1,95 @@
+package ip2loc
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// Sighting is an address seen for a user or session at a time, e.g. at a login.
+type Sighting struct {
+	IP   string
+	Time time.Time
+}
+
+// TravelJump is a move between two consecutive sightings faster than a TravelChecker allows.
+type TravelJump struct {
+	From, To             Sighting
+	FromRecord, ToRecord IP2LocationRecord
+	DistanceKm           float64
+	SpeedKmh             float64 // +Inf if both sightings have the same time
+	CountryChanged       bool
+}
+
+func (j TravelJump) String() string {
+	return fmt.Sprintf("%s (%s) to %s (%s): %.0f km in %v, %.0f km/h", j.From.IP, j.FromRecord.CountryShort,
+		j.To.IP, j.ToRecord.CountryShort, j.DistanceKm, j.To.Time.Sub(j.From.Time), j.SpeedKmh)
+}
+
+// TravelChecker flags improbable jumps, or impossible travel, in the sightings of a user or session.
+type TravelChecker struct {
+	// MaxSpeedKmh is the fastest plausible travel, 1000 km/h, an airliner, if 0.
+	MaxSpeedKmh float64
+	// MinDistanceKm is the distance below which jumps are never flagged, since geolocation is only
+	// accurate to a city or region and neighbouring sightings may be placed far apart; 200 km if 0.
+	MinDistanceKm float64
+}
+
+// Check looks up the sightings in d and returns the jumps between consecutive sightings, in time
+// order, which are farther than MinDistanceKm and faster than MaxSpeedKmh. Sightings of addresses
+// which are not found, or which have no coordinates, are skipped. d must have latitude and longitude
+// columns; otherwise Check returns ErrUnsupportedField.
+func (c TravelChecker) Check(d *DB, sightings []Sighting) ([]TravelJump, error) {
+	maxSpeed, minDist := c.MaxSpeedKmh, c.MinDistanceKm
+	if maxSpeed <= 0 {
+		maxSpeed = 1000
+	}
+	if minDist <= 0 {
+		minDist = 200
+	}
+	if d.loadedFields()&latitude == 0 {
+		return nil, wrapError(fmt.Errorf("%w: latitude", ErrUnsupportedField))
+	}
+	sorted := append([]Sighting(nil), sightings...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Time.Before(sorted[j].Time) })
+
+	var jumps []TravelJump
+	var prev *Sighting
+	var prevRecord IP2LocationRecord
+	for i := range sorted {
+		s := &sorted[i]
+		x, err := d.query(s.IP, countryShort|countryLong|latitude|longitude)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if x.Latitude == 0 && x.Longitude == 0 {
+			continue
+		}
+
+		if prev != nil {
+			dist := prevRecord.Distance(x)
+			speed := math.Inf(1)
+			if h := s.Time.Sub(prev.Time).Hours(); h > 0 {
+				speed = dist / h
+			}
+			if dist > minDist && speed > maxSpeed {
+				jumps = append(jumps, TravelJump{
+					From:           *prev,
+					To:             *s,
+					FromRecord:     prevRecord,
+					ToRecord:       x,
+					DistanceKm:     dist,
+					SpeedKmh:       speed,
+					CountryChanged: prevRecord.CountryShort != x.CountryShort,
+				})
+			}
+		}
+		prev, prevRecord = s, x
+	}
+	return jumps, nil
+}
@@ -0,0 +1,46 @@
+package ip2loc
+
+import "unsafe"
+
+// WithUnsafeStrings makes lookups of a database held in memory, as opened by OpenCompressedDB,
+// OpenBytes or OpenMmapDB, return strings which alias the database instead of copies of its bytes,
+// removing the largest allocation of a lookup. Databases read from disk still copy.
+//
+// The strings are only valid as long as the memory holding the database: a database read into the
+// heap stays reachable while its strings are, but a mapped file is unmapped by Close and by a reload
+// replacing it, after which reading the strings of earlier lookups crashes the program. Copy the
+// strings kept beyond that, e.g. with strings.Clone. The bytes of the database must never be
+// modified.
+func WithUnsafeStrings() Option {
+	return func(d *DB) {
+		d.unsafeStrs = true
+	}
+}
+
+// aliasedData returns the bytes of r which strings alias, or nil if WithUnsafeStrings is not given
+// or r does not hold the database in memory.
+func (d *DB) aliasedData(r DBReader) []byte {
+	if !d.unsafeStrs {
+		return nil
+	}
+	if b, ok := r.(interface{ Bytes() []byte }); ok {
+		return b.Bytes()
+	}
+	return nil
+}
+
+// aliasStr returns the string at pos aliasing strData, or false if it does not end within it.
+func (d *DB) aliasStr(pos int64) (string, bool) {
+	data := d.strData
+	if pos < 0 || pos >= int64(len(data)) {
+		return "", false
+	}
+	end := pos + 1 + int64(data[pos])
+	if end > int64(len(data)) {
+		return "", false
+	}
+	if end == pos+1 {
+		return "", true
+	}
+	return unsafe.String(&data[pos+1], end-pos-1), true
+}
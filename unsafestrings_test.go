@@ -0,0 +1,79 @@
+//go:build !ip2loc_nofs
+
+package ip2loc
+
+import (
+	"runtime"
+	"testing"
+	"unsafe"
+)
+
+// aliases reports whether s points into data.
+func aliases(s string, data []byte) bool {
+	p := uintptr(unsafe.Pointer(unsafe.StringData(s)))
+	start := uintptr(unsafe.Pointer(unsafe.SliceData(data)))
+	return len(s) > 0 && p >= start && p < start+uintptr(len(data))
+}
+
+func TestUnsafeStrings(t *testing.T) {
+	for _, m := range []struct {
+		name string
+		open func(path string, opts ...Option) (*DB, error)
+		heap bool // the database is read into the heap, so its strings outlive a reload
+	}{
+		{"memory", OpenInMemoryDB, true},
+		{"compressed", OpenCompressedDB, true},
+		{"mmap", OpenMmapDB, false},
+	} {
+		t.Run(m.name, func(t *testing.T) {
+			path := testDB(t)
+			copied, err := m.open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer copied.Close()
+			aliased, err := m.open(path, WithUnsafeStrings())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer aliased.Close()
+
+			ips := testEdges()
+			sameLookups(t, copied, aliased, ips)
+			x, err := aliased.GetAll("8.8.8.8")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !aliases(x.City, aliased.strData) {
+				t.Errorf("the city %q is a copy", x.City)
+			}
+			if !m.heap {
+				return
+			}
+
+			var kept, want []IP2LocationRecord
+			for _, ip := range ips {
+				x, _ := aliased.GetAll(ip)
+				kept = append(kept, x)
+				x, _ = copied.GetAll(ip)
+				want = append(want, x)
+			}
+			rows := append([]testRow(nil), testRows.v4...)
+			rows[1].city = "Sunnyvale"
+			renameOver(t, path, buildDB(rows, testRows.v6))
+			if err := aliased.Reload(); err != nil {
+				t.Fatal(err)
+			}
+			runtime.GC()
+			// the strings of the old database stay valid, those of new lookups alias the new one
+			for i := range kept {
+				if kept[i] != want[i] {
+					t.Errorf("%s after the reload: %+v, want %+v", ips[i], kept[i], want[i])
+				}
+			}
+			if x, err := aliased.GetAll("8.8.8.8"); err != nil || x.City != "Sunnyvale" || !aliases(x.City, aliased.strData) {
+				t.Errorf("8.8.8.8 after the reload: city %q, %v, want Sunnyvale aliasing the new database", x.City, err)
+			}
+		})
+	}
+}
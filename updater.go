@@ -0,0 +1,325 @@
+//go:build !ip2loc_nofs
+
+package ip2loc
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Updater downloads a new release of a database file and swaps it in. Commercial databases are
+// hundreds of megabytes, so an interrupted download is resumed with a Range request instead of
+// starting over, failed attempts are retried with exponential backoff and the bandwidth can be limited.
+//
+// The download is written next to Path with a .part suffix and swapped in with ReplaceDatabaseFile
+// once complete, so a DB reading Path never sees a partial file. A zipped or gzipped download, as
+// shipped by IP2Location, is unpacked first. The .part file survives failures and the next Download
+// resumes it; a complete download which is no valid database, or does not match Checksum, is
+// deleted instead.
+type Updater struct {
+	URL  string // location of the database file, e.g. the download link of the vendor
+	Path string // file the download replaces
+
+	Client *http.Client // http.DefaultClient if nil
+	// Retries is the number of attempts after the first failed one; a negative value disables
+	// retries. 5 if 0.
+	Retries int
+	// MinBackoff is the wait before the first retry, doubled for every further one up to MaxBackoff.
+	// 1s and 1m if 0. An attempt which made progress resets the backoff.
+	MinBackoff, MaxBackoff time.Duration
+	BytesPerSecond         int64 // limit of the download rate; 0 is unlimited
+
+	// History is a directory keeping the last Keep downloaded releases, 3 if 0, for RollbackTo;
+	// none are kept if it is empty.
+	History string
+	Keep    int
+
+	// Checksum is the hex encoded SHA-256 checksum the download must have before it is unpacked, as
+	// published by the vendor; it is not checked if empty.
+	Checksum string
+}
+
+// errPermanent marks a download failure which retrying cannot fix.
+type errPermanent struct{ error }
+
+func (e errPermanent) Unwrap() error { return e.error }
+
+// Download fetches URL into Path, resuming a previous partial download. With a History, the download
+// is kept there too, and it only replaces Path if no release is pinned by RollbackTo.
+func (u *Updater) Download(ctx context.Context) error {
+	retries := u.Retries
+	if retries == 0 {
+		retries = 5
+	}
+	minBackoff, maxBackoff := u.MinBackoff, u.MaxBackoff
+	if minBackoff <= 0 {
+		minBackoff = time.Second
+	}
+	if maxBackoff <= 0 {
+		maxBackoff = time.Minute
+	}
+
+	part := u.Path + ".part"
+	backoff := minBackoff
+	for attempt := 0; ; attempt++ {
+		progressed, err := u.fetch(ctx, part)
+		if err == nil {
+			break
+		}
+		var perm errPermanent
+		if errors.As(err, &perm) || ctx.Err() != nil || attempt >= retries {
+			return fmt.Errorf("ip2loc: downloading %s: %w", u.URL, err)
+		}
+		if progressed {
+			backoff = minBackoff
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, maxBackoff)
+	}
+
+	_ = os.Remove(part + ".etag")
+	if err := u.validate(part); err != nil {
+		_ = os.Remove(part)
+		return fmt.Errorf("ip2loc: not replacing %s: %w", u.Path, err)
+	}
+	if u.History != "" {
+		pinned, err := u.archive(part)
+		if err != nil {
+			return err
+		}
+		if pinned {
+			return os.Remove(part)
+		}
+	}
+	return ReplaceDatabaseFile(part, u.Path)
+}
+
+// validate checks the completed download at part against Checksum, unpacks it and checks the
+// database like OpenMetadataOnly before it is kept in the History or replaces Path.
+func (u *Updater) validate(part string) error {
+	if u.Checksum != "" {
+		if err := VerifyChecksum(part, u.Checksum); err != nil {
+			return err
+		}
+	}
+	if err := unpack(part); err != nil {
+		return fmt.Errorf("decompressing the download: %w", err)
+	}
+	_, err := OpenMetadataOnly(part)
+	return err
+}
+
+// unpack replaces the file at path by the database it holds if it is zipped or gzipped, detected by
+// its magic bytes like OpenCompressedDB does. The database is written to a temporary file renamed
+// over path.
+func unpack(path string) error {
+	tmp := path + ".tmp"
+	unpacked, err := unpackTo(tmp, path)
+	if err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	if !unpacked {
+		return nil
+	}
+	return os.Rename(tmp, path)
+}
+
+// unpackTo writes the database of the zipped or gzipped file at src to dst. It reports false if src
+// is neither.
+func unpackTo(dst, src string) (bool, error) {
+	f, err := os.Open(src)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(f, magic); err != nil {
+		return false, nil // too short for an archive; the validation rejects it
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return false, err
+	}
+
+	var db io.Reader
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			return false, err
+		}
+		defer zr.Close()
+		db = zr
+	case bytes.Equal(magic, []byte("PK\x03\x04")):
+		fi, err := f.Stat()
+		if err != nil {
+			return false, err
+		}
+		zr, err := zip.NewReader(f, fi.Size())
+		if err != nil {
+			return false, err
+		}
+		entry, err := zipDatabase(zr)
+		if err != nil {
+			return false, err
+		}
+		rc, err := entry.Open()
+		if err != nil {
+			return false, err
+		}
+		defer rc.Close()
+		db = rc
+	default:
+		return false, nil
+	}
+	return true, writeFile(dst, db)
+}
+
+// Update downloads the database with Download and reloads db, which must have been opened from Path.
+// A db opened with WithReloadOnReplace was reloaded by the download already and is not reloaded again.
+func (u *Updater) Update(ctx context.Context, db *DB) error {
+	if err := u.Download(ctx); err != nil {
+		return err
+	}
+	if db.watchesReplace(u.Path) {
+		return nil
+	}
+	return db.Reload()
+}
+
+// fetch makes one attempt to complete the download into part. It reports whether any bytes were written.
+func (u *Updater) fetch(ctx context.Context, part string) (bool, error) {
+	f, err := os.OpenFile(part, os.O_RDWR|os.O_CREATE, 0o644)
+	if err != nil {
+		return false, errPermanent{err}
+	}
+	defer f.Close()
+	offset, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return false, errPermanent{err}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.URL, nil)
+	if err != nil {
+		return false, errPermanent{err}
+	}
+	// the validator of the partial file makes the server send the whole file if it changed since
+	validator, _ := os.ReadFile(part + ".etag")
+	if offset > 0 && len(validator) > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+		req.Header.Set("If-Range", string(validator))
+	}
+
+	client := u.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && contentRangeStart(resp.Header.Get("Content-Range")) == offset:
+	case resp.StatusCode == http.StatusOK:
+		// the file changed or the server ignores ranges; start over
+		if offset, err = 0, f.Truncate(0); err != nil {
+			return false, errPermanent{err}
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return false, errPermanent{err}
+		}
+	case resp.StatusCode == http.StatusPartialContent || resp.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+		// the partial file is not a prefix of the current one; the next attempt starts over
+		if err := f.Truncate(0); err != nil {
+			return false, errPermanent{err}
+		}
+		return false, fmt.Errorf("server cannot resume at byte %d: %s", offset, resp.Status)
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return false, fmt.Errorf("server returned %s", resp.Status)
+	default:
+		return false, errPermanent{fmt.Errorf("server returned %s", resp.Status)}
+	}
+
+	if offset == 0 {
+		v := resp.Header.Get("ETag")
+		if v == "" || strings.HasPrefix(v, "W/") {
+			v = resp.Header.Get("Last-Modified")
+		}
+		if err := os.WriteFile(part+".etag", []byte(v), 0o644); err != nil {
+			return false, errPermanent{err}
+		}
+	}
+
+	var body io.Reader = resp.Body
+	if u.BytesPerSecond > 0 {
+		body = &rateLimitedReader{ctx: ctx, r: body, rate: u.BytesPerSecond, start: time.Now()}
+	}
+	n, err := io.Copy(f, body)
+	if err != nil {
+		return n > 0, err
+	}
+	if resp.ContentLength >= 0 && n != resp.ContentLength {
+		return n > 0, fmt.Errorf("%w: received %d of %d bytes", io.ErrUnexpectedEOF, n, resp.ContentLength)
+	}
+	return n > 0, f.Sync()
+}
+
+// contentRangeStart returns the first byte of a Content-Range header like "bytes 100-199/200", or -1.
+func contentRangeStart(s string) int64 {
+	s, ok := strings.CutPrefix(s, "bytes ")
+	if !ok {
+		return -1
+	}
+	first, _, ok := strings.Cut(s, "-")
+	if !ok {
+		return -1
+	}
+	n, err := strconv.ParseInt(first, 10, 64)
+	if err != nil {
+		return -1
+	}
+	return n
+}
+
+// rateLimitedReader reads r at no more than rate bytes per second on average.
+type rateLimitedReader struct {
+	ctx   context.Context
+	r     io.Reader
+	rate  int64
+	start time.Time
+	n     int64
+}
+
+func (l *rateLimitedReader) Read(p []byte) (int, error) {
+	// small reads keep the rate even
+	if limit := max(l.rate/10, 1); int64(len(p)) > limit {
+		p = p[:limit]
+	}
+	n, err := l.r.Read(p)
+	l.n += int64(n)
+	wait := time.Duration(float64(l.n)/float64(l.rate)*float64(time.Second)) - time.Since(l.start)
+	if wait > 0 {
+		select {
+		case <-l.ctx.Done():
+			return n, l.ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,180 @@
+//go:build !ip2loc_nofs
+
+package ip2loc
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// releaseServer serves body with the ETag etag, honouring Range and If-Range, and records the
+// headers of the requests and the number of body bytes sent.
+type releaseServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []http.Header
+	sent     int
+}
+
+func newReleaseServer(t *testing.T, body []byte, etag string) *releaseServer {
+	s := &releaseServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests = append(s.requests, r.Header.Clone())
+		s.mu.Unlock()
+		w.Header().Set("ETag", etag)
+		http.ServeContent(countingWriter{w, s}, r, "IP2LOCATION-DB5.BIN", time.Time{}, bytes.NewReader(body))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+type countingWriter struct {
+	http.ResponseWriter
+	s *releaseServer
+}
+
+func (w countingWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	w.s.mu.Lock()
+	w.s.sent += n
+	w.s.mu.Unlock()
+	return n, err
+}
+
+func gzipped(t *testing.T, data []byte) []byte {
+	var b bytes.Buffer
+	zw := gzip.NewWriter(&b)
+	if _, err := zw.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func zipped(t *testing.T, data []byte) []byte {
+	var b bytes.Buffer
+	zw := zip.NewWriter(&b)
+	if _, err := zw.Create("LICENSE.TXT"); err != nil {
+		t.Fatal(err)
+	}
+	w, err := zw.Create("IP2LOCATION-DB5.BIN")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+// checkDownloaded checks that path holds data and no partial download is left next to it.
+func checkDownloaded(t *testing.T, path string, data []byte) {
+	t.Helper()
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("%s holds %d bytes, not the database of %d", path, len(got), len(data))
+	}
+	for _, name := range []string{".part", ".part.etag", ".part.tmp"} {
+		if _, err := os.Stat(path + name); !os.IsNotExist(err) {
+			t.Errorf("%s%s is left: %v", path, name, err)
+		}
+	}
+}
+
+func TestUpdaterDownload(t *testing.T) {
+	data := buildDB(testRows.v4, testRows.v6)
+	for _, tt := range []struct {
+		name string
+		body []byte
+	}{
+		{"plain", data},
+		{"gzip", gzipped(t, data)},
+		{"zip", zipped(t, data)},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newReleaseServer(t, tt.body, `"v1"`)
+			sum := sha256.Sum256(tt.body)
+			u := &Updater{
+				URL:      srv.URL,
+				Path:     filepath.Join(t.TempDir(), "IP2LOCATION-DB5.BIN"),
+				Retries:  -1,
+				Checksum: hex.EncodeToString(sum[:]),
+			}
+			if err := u.Download(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			checkDownloaded(t, u.Path, data)
+
+			u.Checksum = hex.EncodeToString(make([]byte, sha256.Size))
+			if err := u.Download(context.Background()); err == nil {
+				t.Error("downloaded a file of another checksum")
+			}
+			checkDownloaded(t, u.Path, data)
+		})
+	}
+}
+
+func TestUpdaterResume(t *testing.T) {
+	data := buildDB(testRows.v4, testRows.v6)
+	body := gzipped(t, data)
+	half := len(body) / 2
+	for _, tt := range []struct {
+		name, validator string
+		sent            int // bytes of the response
+	}{
+		{"same release", `"v1"`, len(body) - half},
+		// the partial file is of an older release, so the server sends the new one whole
+		{"new release", `"v0"`, len(body)},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newReleaseServer(t, body, `"v1"`)
+			u := &Updater{URL: srv.URL, Path: filepath.Join(t.TempDir(), "IP2LOCATION-DB5.BIN"), Retries: -1}
+			// a previous download stopped halfway
+			if err := os.WriteFile(u.Path+".part", body[:half], 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(u.Path+".part.etag", []byte(tt.validator), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if err := u.Download(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			checkDownloaded(t, u.Path, data)
+
+			if len(srv.requests) != 1 {
+				t.Fatalf("%d requests, want 1", len(srv.requests))
+			}
+			h := srv.requests[0]
+			if got, want := h.Get("Range"), fmt.Sprintf("bytes=%d-", half); got != want {
+				t.Errorf("Range: %q, want %q", got, want)
+			}
+			if got := h.Get("If-Range"); got != tt.validator {
+				t.Errorf("If-Range: %q, want %q", got, tt.validator)
+			}
+			if srv.sent != tt.sent {
+				t.Errorf("%d bytes sent, want %d", srv.sent, tt.sent)
+			}
+		})
+	}
+}
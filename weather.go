@@ -0,0 +1,181 @@
+package ip2loc
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// WeatherStationID is a WeatherStationCode split into its parts. The codes are the location IDs of
+// The Weather Channel, e.g. USCA0746: a FIPS 10-4 country code, which differs from ISO 3166 for many
+// countries (UK for the United Kingdom, GM for Germany), a state code in the United States and
+// Canada or XX elsewhere, and a number.
+type WeatherStationID struct {
+	Country string
+	Region  string
+	Number  string
+}
+
+// ParseWeatherStationCode splits a WeatherStationCode. It returns false for an empty or malformed
+// code, such as the "-" of rows without a station.
+func ParseWeatherStationCode(code string) (WeatherStationID, bool) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) != 8 {
+		return WeatherStationID{}, false
+	}
+	for i := range 8 {
+		c := code[i]
+		letter := c >= 'A' && c <= 'Z'
+		digit := c >= '0' && c <= '9'
+		if i < 2 && !letter || i < 4 && !letter && !digit || i >= 4 && !digit {
+			return WeatherStationID{}, false
+		}
+	}
+	return WeatherStationID{Country: code[:2], Region: code[2:4], Number: code[4:]}, true
+}
+
+// Station is a weather reporting station of a StationTable.
+type Station struct {
+	ICAO               string // ICAO location indicator, e.g. KNUQ, under which its METAR reports are published
+	Name               string
+	Country            string // as given by the table, usually ISO 3166-1 alpha-2
+	Latitude           float64
+	Longitude          float64
+	WeatherStationCode string // the IP2Location code of the station, if the table maps it
+}
+
+// StationTable maps the weather columns of IP2Location records to ICAO weather stations, to join
+// them with sources of METAR observations and forecasts. It is loaded from a CSV of stations such
+// as the NOAA ISD station history or the OurAirports airport list.
+type StationTable struct {
+	byCode   map[string]int
+	stations []kdPoint[Station] // a k-d tree
+}
+
+// LoadStationTable reads a CSV of stations with a header row. The columns are matched by name:
+// icao (or icao_code, gps_code, ident), latitude (lat, latitude_deg) and longitude (lon,
+// longitude_deg) are required, name (station name) and country (ctry, iso_country) optional. A
+// weather_station_code column maps IP2Location codes to stations directly. Rows without an ICAO
+// indicator of four letters or coordinates are skipped.
+func LoadStationTable(r io.Reader) (*StationTable, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, fmt.Errorf("ip2loc: reading station header: %w", err)
+	}
+	cols := map[string]int{}
+	for i, name := range header {
+		cols[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	find := func(names ...string) int {
+		for _, name := range names {
+			if i, ok := cols[name]; ok {
+				return i
+			}
+		}
+		return -1
+	}
+	icaoCol := find("icao", "icao_code", "gps_code", "ident")
+	latCol := find("latitude", "lat", "latitude_deg")
+	lonCol := find("longitude", "lon", "longitude_deg")
+	nameCol := find("name", "station name", "station_name")
+	countryCol := find("country", "ctry", "iso_country")
+	codeCol := find("weather_station_code")
+	if icaoCol < 0 || latCol < 0 || lonCol < 0 {
+		return nil, fmt.Errorf("ip2loc: stations need icao, latitude and longitude columns")
+	}
+
+	t := &StationTable{byCode: map[string]int{}}
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("ip2loc: reading stations: %w", err)
+		}
+		get := func(i int) string {
+			if i < 0 || i >= len(row) {
+				return ""
+			}
+			return strings.TrimSpace(row[i])
+		}
+
+		icao := strings.ToUpper(get(icaoCol))
+		lat, errLat := strconv.ParseFloat(get(latCol), 64)
+		lon, errLon := strconv.ParseFloat(get(lonCol), 64)
+		if !isICAO(icao) || errLat != nil || errLon != nil {
+			continue
+		}
+		s := Station{ICAO: icao, Name: get(nameCol), Country: get(countryCol), Latitude: lat, Longitude: lon,
+			WeatherStationCode: strings.ToUpper(get(codeCol))}
+		t.stations = append(t.stations, kdPoint[Station]{v: unitVector(lat, lon), x: s})
+	}
+
+	buildKDTree(t.stations, 0)
+	for i, p := range t.stations {
+		if p.x.WeatherStationCode != "" {
+			t.byCode[p.x.WeatherStationCode] = i
+		}
+	}
+	return t, nil
+}
+
+// isICAO reports whether s is an ICAO location indicator, four letters. Local airfield identifiers
+// with digits, which the ident column of OurAirports holds for small fields, do not publish METARs.
+func isICAO(s string) bool {
+	if len(s) != 4 {
+		return false
+	}
+	for i := range 4 {
+		if s[i] < 'A' || s[i] > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// Len returns the number of stations.
+func (t *StationTable) Len() int {
+	return len(t.stations)
+}
+
+// Nearest returns the station nearest to the given coordinates and its distance in kilometers. It
+// returns false if the table is empty.
+func (t *StationTable) Nearest(lat, lon float64) (Station, float64, bool) {
+	if len(t.stations) == 0 {
+		return Station{}, 0, false
+	}
+	s := t.stations[kdNearest(t.stations, unitVector(lat, lon))].x
+	return s, Distance(lat, lon, s.Latitude, s.Longitude), true
+}
+
+// Lookup returns the station of a record: the one its WeatherStationCode is mapped to if the table
+// maps it, otherwise the station nearest to its coordinates, which must lie within maxKm
+// kilometers. It returns false if neither finds a station.
+func (t *StationTable) Lookup(x IP2LocationRecord, maxKm float64) (Station, bool) {
+	if i, ok := t.byCode[strings.ToUpper(x.WeatherStationCode)]; ok {
+		return t.stations[i].x, true
+	}
+	if x.Latitude == 0 && x.Longitude == 0 {
+		return Station{}, false
+	}
+	s, km, ok := t.Nearest(x.Latitude64(), x.Longitude64())
+	if !ok || km > maxKm {
+		return Station{}, false
+	}
+	return s, true
+}
+
+// METARURL is the address of the latest METAR report of the station with the given ICAO indicator
+// at the Aviation Weather Center of the US National Weather Service. format is "raw" for the
+// encoded report, or "json", "geojson" or "xml" for the decoded observation with the station's
+// metadata.
+func METARURL(icao, format string) string {
+	q := url.Values{"ids": {strings.ToUpper(icao)}, "format": {format}}
+	return "https://aviationweather.gov/api/data/metar?" + q.Encode()
+}
@@ -0,0 +1,46 @@
+package ip2loc
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStationTable(t *testing.T) {
+	const stations = `ident,name,latitude_deg,longitude_deg,iso_country,weather_station_code
+KNUQ,Moffett Federal Airfield,37.4161,-122.049,US,USCA0746
+EGLL,London Heathrow Airport,51.4706,-0.461941,GB,
+EHAM,Amsterdam Airport Schiphol,52.308601,4.76389,NL,
+00CA,Goldstone Gts Airport,35.35474,-116.885329,US,
+XXXX,No coordinates,,,,
+`
+	st, err := LoadStationTable(strings.NewReader(stations))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Len() != 3 {
+		t.Fatalf("%d stations, want 3 with an ICAO indicator and coordinates", st.Len())
+	}
+
+	for _, tt := range []struct {
+		x    IP2LocationRecord
+		want string
+	}{
+		{IP2LocationRecord{WeatherStationCode: "usca0746", Latitude: 51.5, Longitude: -0.1}, "KNUQ"},
+		{IP2LocationRecord{WeatherStationCode: "UKXX0085", Latitude: 51.508529, Longitude: -0.12574}, "EGLL"},
+		{IP2LocationRecord{Latitude: 52.37403, Longitude: 4.88969}, "EHAM"},
+		{IP2LocationRecord{Latitude: -33.9, Longitude: 151.2}, ""},
+		{IP2LocationRecord{}, ""},
+	} {
+		s, ok := st.Lookup(tt.x, 100)
+		if ok != (tt.want != "") || s.ICAO != tt.want {
+			t.Errorf("Lookup(%s at %v,%v) = %q, %v, want %q", tt.x.WeatherStationCode, tt.x.Latitude, tt.x.Longitude, s.ICAO, ok, tt.want)
+		}
+	}
+
+	if id, ok := ParseWeatherStationCode("usca0746"); !ok || id != (WeatherStationID{"US", "CA", "0746"}) {
+		t.Errorf("ParseWeatherStationCode(usca0746) = %+v, %v", id, ok)
+	}
+	if _, ok := ParseWeatherStationCode("-"); ok {
+		t.Error("ParseWeatherStationCode(-) succeeded")
+	}
+}
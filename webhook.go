@@ -0,0 +1,132 @@
+//go:build !ip2loc_nofs
+
+package ip2loc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Webhook posts every Swap as JSON to a URL: {"time": ..., "old": Header, "new": Header}. Pass its
+// Notify method to WithSwapHandler.
+type Webhook struct {
+	URL     string
+	Client  *http.Client  // http.DefaultClient if nil
+	Timeout time.Duration // of a request, 10s if 0
+	// OnError, if set, is called when a request fails or is answered with a status other than 2xx.
+	OnError func(error)
+	// Workers is the group the requests run in, which must be running. If nil, the Webhook starts a
+	// group of its own with the first request and stops it on Close.
+	Workers *WorkerGroup
+
+	mu      sync.Mutex
+	closed  bool
+	ctx     context.Context // of the requests, cancelled by Close
+	cancel  context.CancelFunc
+	own     *WorkerGroup
+	pending sync.WaitGroup // the requests running
+}
+
+// Notify posts s in the background, so a slow endpoint does not hold up the swap. Swaps notified
+// after Close are not posted.
+func (w *Webhook) Notify(s Swap) {
+	body, err := json.Marshal(struct {
+		Time time.Time `json:"time"`
+		Old  Header    `json:"old"`
+		New  Header    `json:"new"`
+	}{s.Time, s.Old, s.New})
+	if err != nil {
+		w.fail(err)
+		return
+	}
+	w.fail(w.start(body))
+}
+
+// start runs the request posting body in the group of w.
+func (w *Webhook) start(body []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return nil
+	}
+	if w.ctx == nil {
+		w.ctx, w.cancel = context.WithCancel(context.Background())
+		if w.Workers == nil {
+			w.own = &WorkerGroup{}
+			w.own.Start(w.ctx)
+		}
+	}
+	g := w.Workers
+	if g == nil {
+		g = w.own
+	}
+	w.pending.Add(1)
+	_, err := g.task(w.ctx, "webhook", func(ctx context.Context) error {
+		defer w.pending.Done()
+		w.fail(w.post(ctx, body))
+		return nil
+	})
+	if err != nil {
+		w.pending.Done()
+	}
+	return err
+}
+
+// Close cancels the pending requests and waits for them to return. Swaps notified afterwards are
+// dropped.
+func (w *Webhook) Close() error {
+	w.mu.Lock()
+	w.closed = true
+	cancel := w.cancel
+	w.mu.Unlock()
+
+	if cancel == nil {
+		return nil
+	}
+	cancel()
+	w.pending.Wait()
+	if w.own != nil {
+		return w.own.Stop()
+	}
+	return nil
+}
+
+func (w *Webhook) post(ctx context.Context, body []byte) error {
+	timeout := w.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := w.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("ip2loc: webhook %s returned %s", w.URL, resp.Status)
+	}
+	return nil
+}
+
+func (w *Webhook) fail(err error) {
+	if err != nil && w.OnError != nil {
+		w.OnError(err)
+	}
+}
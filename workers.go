@@ -0,0 +1,214 @@
+package ip2loc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// workerRestartDelay is how long a failed worker waits before it is restarted.
+const workerRestartDelay = time.Second
+
+// ErrStopped is returned by calls which run workers, such as BulkEnricher.Run, when the WorkerGroup
+// of their DB is not running, e.g. because the DB was closed.
+var ErrStopped = errors.New("ip2loc: worker group is not running")
+
+// WorkerGroup supervises the background goroutines of the package (reload watchers, updaters,
+// enrichment workers and the like), so that an embedding program owns their whole lifetime:
+// nothing runs before Start and nothing is left running after Stop returns.
+//
+// A worker added with Go that panics or returns an error while the group is running is restarted
+// after a short delay; a worker returning nil is finished. Workers added with GoOnce run once.
+// Panics are recovered and reported as errors.
+//
+// Every DB runs the workers of its calls, e.g. of a BulkEnricher or StreamEnricher, in a group: one
+// it owns and stops on Close, or the one passed to WithWorkerGroup.
+type WorkerGroup struct {
+	// OnError, if set, is called with every error returned or panic recovered from a worker.
+	OnError func(name string, err error)
+
+	mu      sync.Mutex
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	pending []worker
+	errs    []error
+}
+
+type worker struct {
+	name string
+	fn   func(ctx context.Context) error
+	once bool // not restarted
+}
+
+// Go adds a worker to the group. If the group is already running, the worker starts immediately,
+// otherwise it starts with Start.
+func (g *WorkerGroup) Go(name string, fn func(ctx context.Context) error) {
+	g.add(worker{name: name, fn: fn})
+}
+
+// GoOnce adds a worker which is not restarted when it fails, like Go otherwise.
+func (g *WorkerGroup) GoOnce(name string, fn func(ctx context.Context) error) {
+	g.add(worker{name: name, fn: fn, once: true})
+}
+
+func (g *WorkerGroup) add(w worker) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.ctx == nil {
+		g.pending = append(g.pending, w)
+		return
+	}
+	g.run(w)
+}
+
+// Start runs all added workers. The workers are stopped when ctx is done or Stop is called.
+func (g *WorkerGroup) Start(ctx context.Context) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.ctx != nil {
+		return
+	}
+	g.ctx, g.cancel = context.WithCancel(ctx)
+	for _, w := range g.pending {
+		g.run(w)
+	}
+	g.pending = nil
+}
+
+// Stop cancels all workers, waits for them to return and reports the errors they produced.
+func (g *WorkerGroup) Stop() error {
+	g.mu.Lock()
+	if g.cancel != nil {
+		g.cancel()
+	}
+	g.mu.Unlock()
+
+	g.wg.Wait()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	err := errors.Join(g.errs...)
+	g.errs = nil
+	g.ctx, g.cancel = nil, nil
+	return err
+}
+
+// WithWorkerGroup runs the workers of the DB in g instead of a group of its own, so the embedding
+// program starts and stops them together with its other workers. Calls which need workers fail with
+// ErrStopped while g is not running. Close does not stop g.
+func WithWorkerGroup(g *WorkerGroup) Option {
+	return func(d *DB) {
+		d.workers, d.ownWorkers = g, false
+	}
+}
+
+// workerGroup returns the group the workers of d run in, creating and starting one owned by d if
+// WithWorkerGroup was not given.
+func (d *DB) workerGroup() *WorkerGroup {
+	d.workersOnce.Do(func() {
+		if d.workers == nil {
+			d.workers, d.ownWorkers = &WorkerGroup{}, true
+			d.workers.Start(context.Background())
+		}
+	})
+	return d.workers
+}
+
+// stopWorkers stops the group of d if d owns it.
+func (d *DB) stopWorkers() {
+	d.workersOnce.Do(func() {}) // no group is created after Close
+	if d.ownWorkers {
+		_ = d.workers.Stop()
+	}
+}
+
+// Wait waits for the workers to return without cancelling them and reports the errors they
+// produced. The group keeps running, so more workers can be added afterwards.
+func (g *WorkerGroup) Wait() error {
+	g.wg.Wait()
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	err := errors.Join(g.errs...)
+	g.errs = nil
+	return err
+}
+
+// task runs fn once in a worker of the group, with a context which is done when ctx is or when the
+// group stops. The returned function waits for fn to return and returns its error or recovered
+// panic, which are not reported to OnError. It fails with ErrStopped if the group is not running.
+func (g *WorkerGroup) task(ctx context.Context, name string, fn func(ctx context.Context) error) (func() error, error) {
+	if g == nil {
+		return nil, ErrStopped // the DB was closed before it needed workers
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.ctx == nil || g.ctx.Err() != nil {
+		return nil, ErrStopped
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(g.ctx, cancel)
+	done := make(chan struct{})
+	var err error
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		defer close(done)
+		defer cancel()
+		defer stop()
+		err = g.call(ctx, worker{name: name, fn: fn})
+	}()
+	return func() error {
+		<-done
+		return err
+	}, nil
+}
+
+// run must be called with g.mu held.
+func (g *WorkerGroup) run(w worker) {
+	ctx := g.ctx
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		for {
+			err := g.call(ctx, w)
+			if err != nil && !errors.Is(err, context.Canceled) {
+				g.report(w.name, err)
+			}
+			if err == nil || w.once || ctx.Err() != nil {
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(workerRestartDelay):
+			}
+		}
+	}()
+}
+
+// call runs w, recovering a panic as its error.
+func (g *WorkerGroup) call(ctx context.Context, w worker) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("ip2loc: worker %s panicked: %v", w.name, r)
+		}
+	}()
+	return w.fn(ctx)
+}
+
+func (g *WorkerGroup) report(name string, err error) {
+	g.mu.Lock()
+	g.errs = append(g.errs, err)
+	g.mu.Unlock()
+
+	if g.OnError != nil {
+		g.OnError(name, err)
+	}
+}